@@ -0,0 +1,270 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/exitcode"
+)
+
+// command is a monify subcommand. setup registers the command's flags and
+// returns the function that runs it with the remaining arguments.
+type command struct {
+	name    string
+	args    string // Positional arguments, for the usage line
+	summary string
+	setup   func(fs *flag.FlagSet) func(args []string)
+}
+
+// commands lists the subcommands in the order they are shown in the help
+var commands = []command{
+	{"run", "", "Start the monitoring agent in the foreground (used by systemd)", func(fs *flag.FlagSet) func([]string) {
+		dryRun := fs.Bool("dry-run", false, "Print payloads to stdout instead of sending them")
+		interval := fs.Int("interval", 0, "Collection interval in seconds, overrides MONIFY_INTERVAL")
+		debug := fs.Bool("debug", false, "Enable debug logging, like MONIFY_DEBUG")
+		return func(args []string) {
+			noArgs(fs, args)
+			if *dryRun {
+				config.Override("MONIFY_DRY_RUN", "1")
+			}
+			if *interval > 0 {
+				config.Override("MONIFY_INTERVAL", strconv.Itoa(*interval))
+			}
+			if *debug {
+				config.Override("MONIFY_DEBUG", "1")
+			}
+			runAgent()
+		}
+	}},
+	{"start", "", "Start the agent service", serviceCommand("start")},
+	{"stop", "", "Stop the agent service", serviceCommand("stop")},
+	{"restart", "", "Restart the agent service, e.g. after changing settings", serviceCommand("restart")},
+	{"status", "", "Show agent status and troubleshooting hints", func(fs *flag.FlagSet) func([]string) {
+		asJSON := fs.Bool("json", false, "Print the service state, configuration and live agent status as JSON")
+		noCheck := fs.Bool("no-check", false, "Do not look up the latest release (also MONIFY_UPDATE_CHECK=false)")
+		return func(args []string) {
+			noArgs(fs, args)
+			showStatus(*asJSON, !*noCheck && config.IsUpdateCheckEnabled())
+		}
+	}},
+	{"collect", "", "Collect metrics once and print them, without sending", func(fs *flag.FlagSet) func([]string) {
+		withStatic := fs.Bool("static", false, "Include static metrics")
+		asJSON := fs.Bool("json", false, "Print the full payload as JSON")
+		return func(args []string) {
+			noArgs(fs, args)
+			handleCollect(*withStatic, *asJSON)
+		}
+	}},
+	{"benchmark", "", "Run the collectors repeatedly and report the latency, CPU time and allocations of each", func(fs *flag.FlagSet) func([]string) {
+		iterations := fs.Int("iterations", 10, "Number of times each collector runs")
+		withStatic := fs.Bool("static", false, "Include the static collectors")
+		asJSON := fs.Bool("json", false, "Print the report as JSON")
+		return func(args []string) {
+			noArgs(fs, args)
+			if *iterations < 1 {
+				fmt.Fprintln(fs.Output(), "--iterations must be at least 1")
+				fs.Usage()
+				os.Exit(exitcode.Usage)
+			}
+			handleBenchmark(*iterations, *withStatic, *asJSON)
+		}
+	}},
+	{"config", "list|get KEY|set KEY VALUE|unset KEY", "Show or change settings in the env file, with validation", func(fs *flag.FlagSet) func([]string) {
+		return func(args []string) { handleConfig(fs, args) }
+	}},
+	{"enable", "COLLECTOR...", "Enable collectors in the env file and reload the agent", collectorCommand(true)},
+	{"disable", "COLLECTOR...", "Disable collectors in the env file and reload the agent", collectorCommand(false)},
+	{"validate", "", "Check the env file and config.yaml for unknown settings, invalid values and settings without effect", func(fs *flag.FlagSet) func([]string) {
+		asJSON := fs.Bool("json", false, "Print the problems as JSON")
+		return func(args []string) {
+			noArgs(fs, args)
+			handleValidate(*asJSON)
+		}
+	}},
+	{"doctor", "", "Check configuration, connectivity and collectors, with hints for fixing failures", func(fs *flag.FlagSet) func([]string) {
+		asJSON := fs.Bool("json", false, "Print the checks as JSON")
+		return func(args []string) {
+			noArgs(fs, args)
+			handleDoctor(*asJSON)
+		}
+	}},
+	{"test-connection", "", "Send an empty payload with the configured token; show status, latency and TLS details", func(fs *flag.FlagSet) func([]string) {
+		asJSON := fs.Bool("json", false, "Print the result as JSON")
+		return func(args []string) {
+			noArgs(fs, args)
+			handleTestConnection(*asJSON)
+		}
+	}},
+	{"support-bundle", "", "Gather logs, redacted config, doctor output, payloads and system info into a tar.gz", func(fs *flag.FlagSet) func([]string) {
+		output := fs.String("output", "", "File to write, default monify-support-HOSTNAME-TIME.tar.gz in the current directory")
+		return func(args []string) {
+			noArgs(fs, args)
+			handleSupportBundle(*output)
+		}
+	}},
+	{"login", "[TOKEN]", "Login and save authentication token (prompts when TOKEN is omitted)", func(fs *flag.FlagSet) func([]string) {
+		tokenFile := fs.String("token-file", "", "Read the token from a file, keeping it off the command line")
+		fromStdin := fs.Bool("stdin", false, "Read the token from standard input")
+		noVerify := fs.Bool("no-verify", false, "Save the token without checking it with the server")
+		encrypt := fs.Bool("encrypt", false, "Seal the token with systemd-creds (TPM2 if present) instead of saving it in plain text")
+		return func(args []string) {
+			maxArgs(fs, args, 1)
+			if sources := len(args) + btoi(*tokenFile != "") + btoi(*fromStdin); sources > 1 {
+				fmt.Fprintln(fs.Output(), "Pass the token only one way: as argument, with --token-file or with --stdin")
+				fs.Usage()
+				os.Exit(exitcode.Usage)
+			}
+			handleLogin(args, *tokenFile, *fromStdin, !*noVerify, *encrypt)
+		}
+	}},
+	{"logout", "", "Remove token and stop agent", func(fs *flag.FlagSet) func([]string) {
+		return func(args []string) {
+			noArgs(fs, args)
+			handleLogout()
+		}
+	}},
+	{"update", "[VERSION]", "Update agent to the latest (or given) verified release", func(fs *flag.FlagSet) func([]string) {
+		return func(args []string) {
+			maxArgs(fs, args, 1)
+			handleUpdate(args)
+		}
+	}},
+	{"install", "[TOKEN]", "Install the running binary as a hardened systemd service and start it", func(fs *flag.FlagSet) func([]string) {
+		asRoot := fs.Bool("root", false, "Keep running as root instead of switching to the monify user")
+		noStart := fs.Bool("no-start", false, "Do not enable and start the service")
+		force := fs.Bool("force", false, "Replace a different token that is already configured")
+		return func(args []string) {
+			maxArgs(fs, args, 1)
+			handleInstall(args, *asRoot, *noStart, *force)
+		}
+	}},
+	{"uninstall", "", "Remove the agent, its configuration, spool and state", func(fs *flag.FlagSet) func([]string) {
+		var yes bool
+		fs.BoolVar(&yes, "yes", false, "Do not ask for confirmation")
+		fs.BoolVar(&yes, "y", false, "Shorthand for --yes")
+		purge := fs.Bool("purge", false, "Also remove the monify user created by install")
+		return func(args []string) {
+			noArgs(fs, args)
+			handleUninstall(yes, *purge)
+		}
+	}},
+	{"pause", "DURATION [REASON]", `Start a maintenance window, e.g. "pause 2h kernel upgrade" (alerts suppressed)`, func(fs *flag.FlagSet) func([]string) {
+		return func(args []string) { handlePause(args) }
+	}},
+	{"resume", "", "End the maintenance window", func(fs *flag.FlagSet) func([]string) {
+		return func(args []string) {
+			noArgs(fs, args)
+			handleResume()
+		}
+	}},
+	{"version", "", "Show version information", func(fs *flag.FlagSet) func([]string) {
+		asJSON := fs.Bool("json", false, "Print version information as JSON")
+		noCheck := fs.Bool("no-check", false, "Do not look up the latest release (also MONIFY_UPDATE_CHECK=false)")
+		return func(args []string) {
+			noArgs(fs, args)
+			showVersion(*asJSON, !*noCheck && config.IsUpdateCheckEnabled())
+		}
+	}},
+}
+
+// serviceCommand sets up a command that passes action to the service manager
+func serviceCommand(action string) func(fs *flag.FlagSet) func([]string) {
+	return func(fs *flag.FlagSet) func([]string) {
+		return func(args []string) {
+			noArgs(fs, args)
+			handleService(action)
+		}
+	}
+}
+
+// collectorCommand sets up monify enable or disable
+func collectorCommand(enable bool) func(fs *flag.FlagSet) func([]string) {
+	return func(fs *flag.FlagSet) func([]string) {
+		return func(args []string) {
+			if len(args) == 0 {
+				fmt.Fprintln(fs.Output(), "Missing collector name")
+				fs.Usage()
+				os.Exit(exitcode.Usage)
+			}
+			handleCollectors(args, enable)
+		}
+	}
+}
+
+// findCommand returns the command called name, nil if there is none
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// newFlagSet creates the flag set of cmd with the options every command
+// accepts: --config and --no-color
+func newFlagSet(cmd *command) (*flag.FlagSet, func(args []string)) {
+	fs := flag.NewFlagSet("monify "+cmd.name, flag.ExitOnError)
+	fs.StringVar(&config.EnvFilePath, "config", config.EnvFilePath, "Env file to read and write settings")
+	fs.StringVar(&config.ConfigFilePath, "config-yaml", config.ConfigFilePath, "YAML file with further settings, overridden by the env file")
+	fs.BoolVar(&noColor, "no-color", noColor, "Disable colored output (also NO_COLOR)")
+	run := cmd.setup(fs)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: monify %s [options]", cmd.name)
+		if cmd.args != "" {
+			fmt.Fprintf(out, " %s", cmd.args)
+		}
+		fmt.Fprintf(out, "\n\n%s\n\nOptions:\n", cmd.summary)
+		fs.PrintDefaults()
+	}
+	return fs, run
+}
+
+// noArgs exits with the command's usage if positional arguments were given
+func noArgs(fs *flag.FlagSet, args []string) {
+	maxArgs(fs, args, 0)
+}
+
+// maxArgs exits with the command's usage if more than max positional
+// arguments were given
+func maxArgs(fs *flag.FlagSet, args []string, max int) {
+	if len(args) > max {
+		fmt.Fprintf(fs.Output(), "Unexpected argument: %s\n", args[max])
+		fs.Usage()
+		os.Exit(exitcode.Usage)
+	}
+}
+
+// btoi returns 1 for true, 0 for false
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// noColor disables ANSI colors, set by --no-color or NO_COLOR
+var noColor = os.Getenv("NO_COLOR") != ""
+
+// ANSI colors for status labels
+const (
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+)
+
+// colorize wraps text in an ANSI color when stdout is a terminal and colors
+// are not disabled
+func colorize(color, text string) string {
+	if noColor {
+		return text
+	}
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return text
+	}
+	return "\033[" + color + "m" + text + "\033[0m"
+}
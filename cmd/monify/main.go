@@ -2,94 +2,222 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/monify-labs/agent/internal/agent"
 	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/exitcode"
+	"github.com/monify-labs/agent/internal/install"
+	"github.com/monify-labs/agent/internal/sender"
+	"github.com/monify-labs/agent/internal/support"
+	"github.com/monify-labs/agent/internal/uninstall"
+	"github.com/monify-labs/agent/internal/update"
+	"github.com/monify-labs/agent/pkg/models"
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	// The flags below default to the resolved paths
+	config.ResolvePaths()
+
+	// Global options may also come before the command
+	global := flag.NewFlagSet("monify", flag.ExitOnError)
+	global.StringVar(&config.EnvFilePath, "config", config.EnvFilePath, "")
+	global.StringVar(&config.ConfigFilePath, "config-yaml", config.ConfigFilePath, "")
+	global.BoolVar(&noColor, "no-color", noColor, "")
+	global.Usage = printUsage
+	global.Parse(os.Args[1:])
+	args := global.Args()
+	if len(args) < 1 {
 		printUsage()
-		os.Exit(1)
+		os.Exit(exitcode.Usage)
 	}
 
-	// Load environment file
-	if err := config.LoadEnvFile(); err != nil {
-		fmt.Printf("Warning: Failed to load env file: %v\n", err)
-	}
-
-	command := os.Args[1]
-
-	switch command {
-	case "run":
-		runAgent()
-	case "status":
-		showStatus()
-	case "login":
-		handleLogin()
-	case "logout":
-		handleLogout()
-	case "update":
-		handleUpdate()
-	case "version":
-		showVersion()
-	case "help", "-h", "--help":
+	name := args[0]
+	if name == "help" {
+		// "help COMMAND" shows the options of COMMAND
+		if len(args) > 1 {
+			if cmd := findCommand(args[1]); cmd != nil {
+				fs, _ := newFlagSet(cmd)
+				fs.SetOutput(os.Stdout)
+				fs.Usage()
+				return
+			}
+		}
 		printUsage()
-	default:
-		fmt.Printf("Unknown command: %s\n", command)
+		return
+	}
+
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Printf("Unknown command: %s\n", name)
 		printUsage()
-		os.Exit(1)
+		os.Exit(exitcode.Usage)
 	}
+	fs, run := newFlagSet(cmd)
+	fs.Parse(args[1:])
+
+	// Load environment file
+	if err := config.LoadEnvFile(); err != nil {
+		fmt.Printf("Warning: Failed to load configuration: %v\n", err)
+	}
+
+	run(fs.Args())
 }
 
 func printUsage() {
-	fmt.Println(`Monify Agent - Server Monitoring Agent
+	fmt.Print(`Monify Agent - Server Monitoring Agent
 
 Usage:
-  monify <command>
+  monify <command> [options] [arguments]
 
 Commands:
-  run       Start the monitoring agent
-  status    Show agent status
-  login     Login and save authentication token
-  logout    Remove token and stop agent
-  update    Update agent to latest version
-  version   Show version information
-  help      Show this help message
+`)
+	for _, cmd := range commands {
+		fmt.Printf("  %-16s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Printf("  %-16s %s\n", "help [COMMAND]", "Show this help message, or the options and arguments of COMMAND")
+
+	fmt.Println(`
+Global Options:
+  --config FILE    Env file to read and write settings (default: MONIFY_CONFIG_PATH, /etc/monify/env,
+                   or ~/.config/monify/env for users who cannot read it)
+  --config-yaml FILE
+                   YAML file with further settings, overridden by the env file (default: /etc/monify/config.yaml,
+                   or ~/.config/monify/config.yaml with the user env file)
+  --no-color       Disable colored output (also NO_COLOR)
 
 Environment Variables:
-  MONIFY_TOKEN       Authentication token (required for run)
-  MONIFY_SERVER_URL  Server URL (optional, default: https://api.monify.cloud/v1/agent/metrics)
-  MONIFY_DEBUG       Enable debug logging (true/1)
+  MONIFY_CONFIG_PATH                Env file to use instead of /etc/monify/env, like --config
+  MONIFY_TOKEN                      Authentication token (required for run)
+  MONIFY_TOKEN_CREDENTIAL           Token encrypted with systemd-creds, used when MONIFY_TOKEN is unset (set by login --encrypt)
+  MONIFY_SERVER_URL                 Server URL (optional, default: https://api.monify.cloud/v1/agent/metrics)
+  MONIFY_TIMEOUT                    Seconds a request to the server or another destination may take (default: 10)
+  MONIFY_REFRESH_TOKEN              Refresh token exchanged for short-lived access tokens
+  MONIFY_TOKEN_URL                  Token endpoint (default: derived from the server URL)
+  MONIFY_SERVER_URL_FALLBACK        Fallback server URL used when the primary keeps failing
+  MONIFY_FAILOVER_THRESHOLD         Consecutive failures before failing over (default: 3)
+  MONIFY_CIRCUIT_BREAKER_THRESHOLD  Consecutive failures before sends pause (default: 5, 0 disables)
+  MONIFY_STATUS_ADDR                Local status API address (default: 127.0.0.1:9123, empty disables)
+  MONIFY_PROMETHEUS_ADDR            Serve metrics in Prometheus format on this loopback address (e.g. 127.0.0.1:9101)
+  MONIFY_PLUGIN_DIR                 Directory of exec plugins (default: /etc/monify/plugins.d, empty disables)
+  MONIFY_PLUGIN_INTERVAL            Seconds between plugin runs (default: 60)
+  MONIFY_PLUGIN_TIMEOUT             Seconds a plugin may run before it is killed (default: 10)
+  MONIFY_LABELS                     Labels attached to every payload (comma-separated key=value pairs)
+  MONIFY_REGISTRATION               Register with the server on startup (default: true)
+  MONIFY_REGISTRATION_URL           Registration URL (default: derived from the server URL)
+  MONIFY_DIAGNOSTICS_URL            Diagnostics upload URL (default: derived from the server URL)
+  MONIFY_DEBUG                      Enable debug logging (true/1)
+  MONIFY_UPDATE_URL                 Release download base URL for monify update (default: GitHub releases)
+  MONIFY_UPDATE_PUBLIC_KEY          Ed25519 key (base64) release checksums must be signed with (default: built in)
+  MONIFY_UPDATE_CHECK               Show whether a newer release exists in status and version (default: true)
+  MONIFY_UPDATE_TIMEOUT             Seconds monify update may take to download a release (default: 300)
+  MONIFY_ALERT_RULES                Local alert rules separated by semicolons, e.g. "disk_used_percent > 95 for 5m"
+  MONIFY_ALERT_HOOK                 Executable run when a local alert fires or resolves
+  MONIFY_ALERT_HOOK_TIMEOUT         Seconds the alert hook may run before it is killed (default: 30)
+  MONIFY_ANOMALY_THRESHOLD          Standard deviations from the baseline that count as an anomaly (default: 4, 0 disables)
+  MONIFY_NICE                       Nice value the agent lowers itself to (0-19, default: unchanged)
+  MONIFY_IONICE                     I/O scheduling class of the agent (idle or best-effort, default: unchanged)
+  MONIFY_CPU_LIMIT                  CPU percent of one core above which optional collectors are shed (0 disables)
+  MONIFY_MEMORY_LIMIT_MB            Agent memory in MB above which optional collectors are shed (0 disables)
+  MONIFY_DISABLE_COLLECTORS         Comma-separated dynamic collectors to turn off (network turns off all network collectors)
+  MONIFY_COLLECTOR_INTERVALS        Run collectors less often than every collection (name=seconds,...)
+  MONIFY_DISK_INCLUDE               Comma-separated mountpoint or device patterns the disk collectors are limited to (glob or re:regex)
+  MONIFY_DISK_EXCLUDE               Comma-separated mountpoint or device patterns the disk collectors skip (e.g. /snap/*)
+  MONIFY_DISK_SKIP_FSTYPES          Comma-separated filesystem types skipped in addition to tmpfs, overlay, ... (e.g. fuse.*)
+  MONIFY_DISK_KEEP_FSTYPES          Comma-separated filesystem types reported although skipped by default (e.g. overlay)
+  MONIFY_COLLECTOR_TIMEOUT          Seconds each collector may run before it is left out of the payload (default: 8)
+  MONIFY_COLLECTOR_QUARANTINE       Consecutive failures before a collector is skipped and retried every 5 minutes (default: 5, 0 disables)
+  MONIFY_AUTH_FAILURES              Consecutive token rejections before giving up (default: 5)
+  MONIFY_AUTH_FAILURE_WINDOW        Seconds the rejections must last before giving up (default: 600)
+  MONIFY_AUTH_FAILURE_ACTION        On giving up: exit (default, exit code 3) or retry
+  MONIFY_DRY_RUN                    Print payloads to stdout instead of sending them (true/1)
+  MONIFY_USER                       Switch to this user after startup instead of running as root
+  MONIFY_CAPABILITIES               Capabilities kept after switching user (default: dac_read_search,sys_ptrace,net_raw)
+  MONIFY_READ_ONLY                  Never execute server commands (true/1)
+  MONIFY_COMMAND_ALLOWLIST          Comma-separated server commands allowed to run (default: non-destructive commands, empty refuses all)
+  MONIFY_COMMAND_PUBLIC_KEY         Ed25519 key (base64 or PEM file path) server commands must be signed with (default: built-in key)
+  MONIFY_ALLOW_UNSIGNED_COMMANDS    Run allowlisted server commands without a signature (true/1)
+  MONIFY_COMMAND_AUDIT_LOG          Server command audit log (default: /var/log/monify/commands.log, empty disables)
+  MONIFY_IP_FAMILY                  Address family for server connections: auto, ipv4 or ipv6 (default: auto)
+  MONIFY_DNS_REFRESH                Seconds between forced DNS re-resolution of the server (default: 300, 0 disables)
+  MONIFY_CA_CERT                    Custom CA bundle (PEM) trusted for the server URL
+  MONIFY_HMAC_SECRET                Sign request bodies with this shared secret (HMAC-SHA256)
+  MONIFY_TLS_SERVER_NAME            Name to verify the server certificate against (default: URL host)
+  MONIFY_TLS_INSECURE_SKIP_VERIFY   Skip server certificate verification (true/1, testing only)
+  MONIFY_CLIENT_CERT                Client certificate (PEM) for mutual TLS; the token becomes optional
+  MONIFY_CLIENT_KEY                 Client private key (PEM, default: MONIFY_CLIENT_CERT)
+  MONIFY_SOCKS5_PROXY               Tunnel outbound connections through a SOCKS5 proxy (socks5://[user:pass@]host:port)
+  MONIFY_COLLECT_PACKAGES           Include installed package inventory (true/1)
+  MONIFY_SYSCTLS                    Comma-separated sysctl names to report (empty disables)
+  MONIFY_PUBLIC_IP_URLS             Comma-separated echo services asked for the public IP (default: ipify, icanhazip, ifconfig.me, empty disables)
+  MONIFY_CLOUD_METADATA             Probe cloud metadata services for provider, region and tags (default: true)
+  MONIFY_COMMAND_STREAM             Receive server commands over a persistent stream (true/1)
+  MONIFY_COMMAND_STREAM_URL         Command stream URL (default: derived from the server URL)
+  MONIFY_HEARTBEAT                  Send a small heartbeat every 5s while metrics sends fail (true/1)
+  MONIFY_HEARTBEAT_URL              Heartbeat URL (default: derived from the server URL)
+  MONIFY_RELAY_SOCKET               Hand payloads to a local relay over this Unix socket instead of HTTPS
+  MONIFY_MQTT_URL                   Publish via MQTT broker instead of HTTPS (mqtt:// or mqtts://)
+  MONIFY_MQTT_TOPIC                 MQTT topic prefix (default: monify/metrics)
+  MONIFY_TENANTS                    Also send metrics to other organizations (name=token[@url],...)
+  MONIFY_REMOTE_WRITE_URL           Also write metrics to a Prometheus remote_write endpoint
+  MONIFY_REMOTE_WRITE_TOKEN         Bearer token for the remote_write endpoint
+  MONIFY_GRAPHITE_ADDR              Also write metrics to Graphite (host:port, plaintext protocol)
+  MONIFY_GRAPHITE_PREFIX            Graphite path prefix (default: monify)
+  MONIFY_GRAPHITE_INTERVAL          Align Graphite points to this many seconds (default: 0)
+  MONIFY_COMPRESSION                Request compression: gzip, zstd or auto (default: auto)
+  MONIFY_COMPRESSION_LEVEL          Compression level (gzip 1-9, zstd 1-22, default: 0)
+  MONIFY_INTERVAL                   Collection interval in seconds (default: 15, minimum: 5)
+  MONIFY_COLLECTION_JITTER          Random start delay in seconds to spread sends (default: 5, 0 disables)
+  MONIFY_BATCH_INTERVALS            Collection intervals sent per request (default: 1)
+  MONIFY_SPOOL_DIR                  Offline spool directory (default: /var/lib/monify/spool)
+  MONIFY_SPOOL_MAX_MB               Offline spool size limit in MB (default: 100, 0 disables)
+  MONIFY_PROBES                     Comma-separated service check URLs (smtp://, imap://, ...)
 
 Configuration File:
   /etc/monify/env    Environment variables file
 
 Examples:
   sudo monify login YOUR_TOKEN
+  sudo monify run --dry-run --interval 5
+  monify help collect
   sudo monify update
   monify status
   monify version`)
 }
 
 func runAgent() {
-	// Check if running as root (required for some metrics)
+	// A dry run prints payloads to stdout, so everything else goes to stderr
+	dryRun := config.IsDryRun()
+	out := os.Stdout
+	if dryRun {
+		out = os.Stderr
+	}
+
+	// Without root, the agent logs each unavailable feature once it has probed them
 	if os.Geteuid() != 0 {
-		fmt.Println("Warning: Running without root privileges. Some metrics may not be available.")
+		fmt.Fprintln(out, "Running without root privileges: unavailable features are logged at startup and reported to the server.")
 	}
 
-	// Get token
+	// Get token (optional with a client certificate or a refresh token, not needed for a dry run)
 	token, err := config.GetToken()
-	if err != nil {
+	if err != nil && config.GetClientCertPath() == "" && config.GetRefreshToken() == "" && !dryRun {
 		fmt.Printf("Error: %v\n", err)
 		fmt.Println("Please run 'sudo monify login' to configure the agent.")
-		os.Exit(1)
+		os.Exit(exitcode.NotConfigured)
 	}
 
 	// Get server URL
@@ -102,7 +230,7 @@ func runAgent() {
 	a, err := agent.NewAgent(serverURL, token, debug)
 	if err != nil {
 		fmt.Printf("Error creating agent: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitcode.Failure)
 	}
 
 	// Setup context with cancellation
@@ -115,24 +243,663 @@ func runAgent() {
 
 	go func() {
 		<-sigChan
-		fmt.Println("\nReceived shutdown signal...")
+		fmt.Fprintln(out, "\nReceived shutdown signal...")
 		cancel()
 	}()
 
 	// Start agent
-	fmt.Printf("Starting Monify Agent v%s\n", config.Version)
-	fmt.Printf("Server: %s\n", serverURL)
+	fmt.Fprintf(out, "Starting Monify Agent v%s\n", config.Version)
+	fmt.Fprintf(out, "Server: %s\n", serverURL)
+	if dryRun {
+		fmt.Fprintln(out, "Dry run: payloads are printed to stdout and not sent")
+	}
 	if debug {
-		fmt.Println("Debug mode: enabled")
+		fmt.Fprintln(out, "Debug mode: enabled")
+	}
+	if config.IsReadOnlyMode() {
+		fmt.Fprintln(out, "Read-only mode: enabled")
+	}
+	if config.IsTLSInsecureSkipVerify() {
+		fmt.Fprintln(out, "Warning: TLS certificate verification is disabled (MONIFY_TLS_INSECURE_SKIP_VERIFY)")
 	}
 
 	if err := a.Start(ctx); err != nil {
+		// Exit with special code to prevent systemd restart
+		if errors.Is(err, agent.ErrAuthFailed) {
+			os.Exit(exitcode.AuthFailed)
+		}
+		if errors.Is(err, agent.ErrRestartRequested) {
+			restartAgent()
+		}
 		fmt.Printf("Agent error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitcode.Failure)
+	}
+}
+
+// restartAgent starts the agent again after a server restart command. Under
+// systemd the process exits and Restart=always starts a fresh one; otherwise
+// the binary re-executes itself with its original environment.
+func restartAgent() {
+	if os.Getenv("INVOCATION_ID") != "" {
+		fmt.Println("Restart requested, exiting for systemd to restart the agent")
+		os.Exit(0)
+	}
+
+	exe, err := os.Executable()
+	if err == nil {
+		fmt.Println("Restart requested, re-executing")
+		err = syscall.Exec(exe, os.Args, config.ProcessEnviron())
+	}
+	fmt.Printf("Error: restart failed: %v\n", err)
+	os.Exit(exitcode.Failure)
+}
+
+// handleCollect runs a single collection and prints the result
+func handleCollect(withStatic, asJSON bool) {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	payload, err := agent.CollectOnce(ctx, withStatic)
+	if err != nil {
+		fmt.Printf("Error: collection failed: %v\n", err)
+		os.Exit(exitcode.CollectFailed)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(payload); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+		return
+	}
+	printCollection(payload)
+}
+
+// handleCollectors enables or disables collectors in
+// MONIFY_DISABLE_COLLECTORS and reloads the running service
+func handleCollectors(names []string, enable bool) {
+	action := "disable"
+	if enable {
+		action = "enable"
+	}
+	if os.Geteuid() != 0 {
+		fmt.Printf("Error: %s requires root privileges.\n", action)
+		fmt.Printf("Please run: sudo monify %s %s\n", action, strings.Join(names, " "))
+		os.Exit(exitcode.NoPermission)
+	}
+	known := agent.KnownCollectors()
+	for _, name := range names {
+		if name == "network" || slices.Contains(known, name) {
+			continue
+		}
+		if match := config.ClosestMatch(name, known); match != "" {
+			fmt.Printf("Error: unknown collector %q, did you mean %q?\n", name, match)
+		} else {
+			fmt.Printf("Error: unknown collector %q; known collectors: %s\n", name, strings.Join(known, ", "))
+		}
+		os.Exit(exitcode.Usage)
+	}
+
+	settings, err := config.ReadEnvFile()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
+	var entries []string
+	for _, entry := range strings.Split(settings["MONIFY_DISABLE_COLLECTORS"], ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	before := strings.Join(entries, ",")
+	for _, name := range names {
+		targets := config.CollectorNames(name)
+		if !enable {
+			disabled := make(map[string]bool)
+			for _, entry := range entries {
+				for _, collector := range config.CollectorNames(entry) {
+					disabled[collector] = true
+				}
+			}
+			if !slices.ContainsFunc(targets, func(t string) bool { return !disabled[t] }) {
+				fmt.Printf("%s is already disabled\n", name)
+				continue
+			}
+			entries = append(entries, name)
+			continue
+		}
+
+		// "network" is split up when one of its collectors is enabled
+		found := false
+		var kept []string
+		for _, entry := range entries {
+			expanded := config.CollectorNames(entry)
+			remaining := slices.DeleteFunc(slices.Clone(expanded), func(c string) bool { return slices.Contains(targets, c) })
+			if len(remaining) == len(expanded) {
+				kept = append(kept, entry)
+				continue
+			}
+			found = true
+			if len(remaining) > 0 {
+				kept = append(kept, remaining...)
+			}
+		}
+		if !found {
+			fmt.Printf("%s is already enabled\n", name)
+		}
+		entries = kept
+	}
+	if strings.Join(entries, ",") == before {
+		return
+	}
+
+	if len(entries) == 0 {
+		err = config.UnsetEnvFile("MONIFY_DISABLE_COLLECTORS")
+	} else {
+		err = config.SaveEnvFile(map[string]string{"MONIFY_DISABLE_COLLECTORS": strings.Join(entries, ",")})
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
+	if len(entries) == 0 {
+		fmt.Println("✓ All collectors enabled")
+	} else {
+		fmt.Printf("✓ MONIFY_DISABLE_COLLECTORS=%s\n", strings.Join(entries, ","))
+	}
+
+	// Apply it right away when the service is running
+	if status, _ := getServiceStatus(); status != "running" {
+		fmt.Println("The agent is not running; the change applies when it starts.")
+		return
+	}
+	if out, err := exec.Command("systemctl", "reload", "monify").CombinedOutput(); err != nil {
+		fmt.Printf("✗ Failed to reload Monify Agent: %s\n", strings.TrimSpace(string(out)))
+		fmt.Println("  Apply it with: sudo monify restart")
+		os.Exit(exitcode.ServiceFailed)
+	}
+	fmt.Println("✓ Monify Agent reloaded")
+}
+
+// handleBenchmark measures what each collector costs on this host
+func handleBenchmark(iterations int, withStatic, asJSON bool) {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if !asJSON {
+		fmt.Printf("Running each collector %d times, one at a time...\n\n", iterations)
+	}
+	report, err := agent.Benchmark(ctx, iterations, withStatic)
+	if err != nil {
+		fmt.Printf("Error: benchmark failed: %v\n", err)
+		os.Exit(exitcode.CollectFailed)
+	}
+	if asJSON {
+		printJSON(report)
+		return
+	}
+
+	fmt.Printf("%-20s %9s %9s %9s %9s %11s %8s\n", "COLLECTOR", "MEAN", "P95", "MAX", "CPU", "ALLOC", "OBJECTS")
+	var total models.CollectorBenchmark
+	for _, c := range report.Collectors {
+		name := c.Name
+		if c.Static {
+			name += " (static)"
+		}
+		line := fmt.Sprintf("%-20s %7.1fms %7.1fms %7.1fms %7.1fms %9.1fKB %8d", name, c.MeanMs, c.P95Ms, c.MaxMs, c.CPUMs, float64(c.AllocBytes)/1024, c.Allocs)
+		if c.Failures > 0 {
+			line += colorize(colorRed, fmt.Sprintf("  %d/%d failed: %s", c.Failures, c.Runs, c.Error))
+		}
+		fmt.Println(line)
+		if c.Static {
+			continue
+		}
+		total.MeanMs += c.MeanMs
+		total.CPUMs += c.CPUMs
+		total.AllocBytes += c.AllocBytes
+	}
+
+	fmt.Println()
+	// Static collectors refresh hourly and are left out of the totals
+	fmt.Printf("Per collection: %.1fms run one at a time, %.1fms CPU, %.1fKB allocated\n", total.MeanMs, total.CPUMs, float64(total.AllocBytes)/1024)
+	fmt.Printf("Background sampling: %.2f%% CPU\n", report.SamplerCPUPercent)
+	fmt.Printf("Memory: %.1fMB resident, %.1fMB heap\n", float64(report.RSSBytes)/1e6, float64(report.HeapBytes)/1e6)
+	fmt.Printf("At an interval of %s the collectors use about %.3f%% CPU on top of sampling.\n",
+		config.GetCollectionInterval(), total.CPUMs/float64(config.GetCollectionInterval().Milliseconds())*100)
+}
+
+// handleConfig shows and edits the settings in the env file, validating
+// values before they are written
+func handleConfig(fs *flag.FlagSet, args []string) {
+	usage := func() {
+		fs.Usage()
+		os.Exit(exitcode.Usage)
+	}
+	if len(args) == 0 {
+		usage()
+	}
+	action, args := args[0], args[1:]
+
+	switch action {
+	case "list":
+		list := flag.NewFlagSet("monify config list", flag.ExitOnError)
+		showSecrets := list.Bool("show-secrets", false, "Show tokens and secrets instead of masking them")
+		list.Parse(args)
+		if list.NArg() > 0 {
+			usage()
+		}
+		settings, err := config.ReadEnvFile()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+		names := make([]string, 0, len(settings))
+		for name := range settings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := settings[name]
+			if config.IsSecretSetting(name) && value != "" && !*showSecrets {
+				value = "********"
+			}
+			fmt.Printf("%s=%s\n", name, value)
+		}
+
+	case "get":
+		if len(args) != 1 {
+			usage()
+		}
+		name := config.SettingName(args[0])
+		settings, err := config.ReadEnvFile()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+		value, ok := settings[name]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s is not set in %s\n", name, config.EnvFilePath)
+			os.Exit(exitcode.Failure)
+		}
+		fmt.Println(value)
+
+	case "set", "unset":
+		if (action == "set" && len(args) != 2) || (action == "unset" && len(args) != 1) {
+			usage()
+		}
+		if !config.EnvFileWritable() {
+			fmt.Printf("Error: config %s requires root privileges to write %s.\n", action, config.EnvFilePath)
+			fmt.Printf("Run: sudo monify config %s ...\n", action)
+			os.Exit(exitcode.NoPermission)
+		}
+		name := config.SettingName(args[0])
+		var err error
+		if action == "set" {
+			if err = config.ValidateSetting(name, args[1]); err == nil {
+				err = config.SaveEnvFile(map[string]string{name: args[1]})
+			}
+		} else if err = config.ValidateSetting(name, ""); err == nil {
+			err = config.UnsetEnvFile(name)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+		if action == "set" {
+			fmt.Printf("✓ %s set in %s\n", name, config.EnvFilePath)
+		} else {
+			fmt.Printf("✓ %s removed from %s\n", name, config.EnvFilePath)
+		}
+		if os.Geteuid() == 0 {
+			fmt.Println("Apply it with: sudo systemctl reload monify")
+		} else {
+			fmt.Println("Apply it by sending SIGHUP to the agent: kill -HUP $(pidof monify)")
+		}
+
+	default:
+		usage()
 	}
 }
 
-func showStatus() {
+// handleTestConnection sends an authenticated empty payload and reports how
+// the server answered
+func handleTestConnection(asJSON bool) {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	result, err := agent.TestConnection(ctx)
+	if asJSON {
+		output := struct {
+			*sender.PingResult
+			OK    bool   `json:"ok"`
+			Error string `json:"error,omitempty"`
+		}{PingResult: result, OK: err == nil}
+		if err != nil {
+			output.Error = err.Error()
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(output)
+		if err != nil {
+			os.Exit(connectionExitCode(err))
+		}
+		return
+	}
+
+	if result != nil {
+		fmt.Printf("Server:      %s\n", result.URL)
+		if result.RemoteAddr != "" {
+			fmt.Printf("Connected:   %s\n", result.RemoteAddr)
+		}
+		if result.TLSVersion != "" {
+			fmt.Printf("TLS:         %s, %s\n", result.TLSVersion, result.CipherSuite)
+			fmt.Printf("Certificate: %s, issued by %s, expires %s\n", result.CertSubject, result.CertIssuer, result.CertExpiry.Format("2006-01-02"))
+		}
+		if result.StatusCode != 0 {
+			fmt.Printf("Response:    %s in %d ms\n", result.Status, result.Latency.Milliseconds())
+		}
+		fmt.Printf("Trace ID:    %s\n", result.TraceID)
+		if result.RequestID != "" {
+			fmt.Printf("Request ID:  %s\n", result.RequestID)
+		}
+		fmt.Println()
+	}
+
+	switch {
+	case err == nil:
+		fmt.Println(colorize(colorGreen, "✓ Connection and token OK"))
+		return
+	case errors.Is(err, sender.ErrUnauthorized):
+		fmt.Println(colorize(colorRed, "✗ The server rejected the token"))
+		fmt.Println("Run 'sudo monify login' with the token from https://dash.monify.cloud")
+	case errors.Is(err, sender.ErrNetwork):
+		fmt.Println(colorize(colorRed, "✗ "+err.Error()))
+		fmt.Println("Run 'sudo monify doctor' to check DNS, proxy and firewall settings")
+	default:
+		fmt.Println(colorize(colorRed, "✗ "+err.Error()))
+	}
+	os.Exit(connectionExitCode(err))
+}
+
+// connectionExitCode returns the exit status for an error talking to the server
+func connectionExitCode(err error) int {
+	switch {
+	case errors.Is(err, sender.ErrUnauthorized):
+		return exitcode.AuthFailed
+	case errors.Is(err, sender.ErrNetwork), errors.Is(err, sender.ErrCertificate), errors.Is(err, sender.ErrServerUnavailable):
+		return exitcode.Unreachable
+	default:
+		return exitcode.Failure
+	}
+}
+
+// handleValidate checks the configuration without starting the agent and
+// exits with exitcode.InvalidConfig if it has errors, so it can gate a restart
+func handleValidate(asJSON bool) {
+	problems, err := agent.ValidateConfig()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		if os.IsPermission(err) {
+			fmt.Println("Please run: sudo monify validate")
+			os.Exit(exitcode.NoPermission)
+		}
+		os.Exit(exitcode.Failure)
+	}
+
+	errorCount := 0
+	for _, problem := range problems {
+		if problem.Severity == config.ProblemError {
+			errorCount++
+		}
+	}
+
+	if asJSON {
+		if problems == nil {
+			problems = []config.Problem{}
+		}
+		printJSON(problems)
+	} else {
+		labels := map[string]string{
+			config.ProblemError:   colorize(colorRed, "ERROR"),
+			config.ProblemWarning: colorize(colorYellow, "WARN"),
+		}
+		for _, problem := range problems {
+			line := fmt.Sprintf("[%s] ", labels[problem.Severity])
+			if problem.File != "" {
+				line += filepath.Base(problem.File) + " "
+			}
+			if problem.Line > 0 {
+				line += fmt.Sprintf("line %d: ", problem.Line)
+			} else if problem.File != "" {
+				line += ": "
+			}
+			if problem.Setting != "" {
+				line += problem.Setting + ": "
+			}
+			fmt.Println(line + problem.Message)
+		}
+		checked := config.EnvFilePath
+		if _, err := os.Stat(config.ConfigFilePath); err == nil {
+			checked += " and " + config.ConfigFilePath
+		}
+		if errorCount == 0 {
+			fmt.Printf("✓ No errors in %s", checked)
+			if warnings := len(problems); warnings > 0 {
+				fmt.Printf(" (%d warnings)", warnings)
+			}
+			fmt.Println()
+		} else {
+			fmt.Printf("✗ %d errors, %d warnings in %s\n", errorCount, len(problems)-errorCount, checked)
+		}
+	}
+
+	if errorCount > 0 {
+		os.Exit(exitcode.InvalidConfig)
+	}
+}
+
+// handleDoctor runs the diagnostics suite and collector smoke tests, and
+// exits non-zero when a check fails
+func handleDoctor(asJSON bool) {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	checks := agent.Doctor(ctx, config.GetServerURL())
+
+	failed := 0
+	for _, check := range checks {
+		if check.Status == models.DiagnosticFail {
+			failed++
+		}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(checks); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+	} else {
+		labels := map[string]string{
+			models.DiagnosticOK:   colorize(colorGreen, "PASS"),
+			models.DiagnosticWarn: colorize(colorYellow, "WARN"),
+			models.DiagnosticFail: colorize(colorRed, "FAIL"),
+		}
+		for _, check := range checks {
+			line := fmt.Sprintf("[%s] %s", labels[check.Status], check.Name)
+			if check.Detail != "" {
+				line += ": " + check.Detail
+			}
+			fmt.Println(line)
+			if check.Hint != "" {
+				fmt.Printf("       Hint: %s\n", check.Hint)
+			}
+		}
+		if failed > 0 {
+			fmt.Printf("\n%d of %d checks failed.\n", failed, len(checks))
+		} else {
+			fmt.Printf("\nAll %d checks passed.\n", len(checks))
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(exitcode.Failure)
+	}
+}
+
+// printCollection prints a summary of a collected payload
+func printCollection(payload *models.MetricPayload) {
+	fmt.Printf("Hostname: %s\n", payload.Hostname)
+	fmt.Printf("Collected: %s\n", payload.Timestamp.Format(time.RFC3339))
+
+	if s := payload.StaticMetrics; s != nil {
+		fmt.Printf("Platform: %s %s (kernel %s, %s)\n", s.Platform, s.PlatformVersion, s.KernelVersion, s.Arch)
+		fmt.Printf("Hardware: %s, %d cores / %d threads, %.1f GB memory\n", s.CPUModel, s.CPUCores, s.CPUThreads, float64(s.TotalMemory)/1e9)
+		if s.CloudProvider != "" {
+			fmt.Printf("Cloud: %s %s %s\n", s.CloudProvider, s.Region, s.InstanceType)
+		}
+		fmt.Printf("Internal IPs: %s\n", strings.Join(s.InternalIPs, ", "))
+		if s.PublicIP != "" || s.PublicIPv6 != "" {
+			fmt.Printf("Public IP: %s %s\n", s.PublicIP, s.PublicIPv6)
+		}
+	}
+
+	if m := payload.DynamicMetrics; m != nil {
+		if m.CPU != nil {
+			fmt.Printf("CPU: %.1f%% (load %.2f %.2f %.2f)\n", m.CPU.UsagePercent, m.CPU.LoadAvg1m, m.CPU.LoadAvg5m, m.CPU.LoadAvg15m)
+		}
+		if m.Memory != nil {
+			fmt.Printf("Memory: %.1f%% of %.1f GB used\n", m.Memory.UsedPercent, float64(m.Memory.Total)/1e9)
+		}
+		if m.Swap != nil && m.Swap.Total > 0 {
+			fmt.Printf("Swap: %.1f%% of %.1f GB used\n", m.Swap.UsedPercent, float64(m.Swap.Total)/1e9)
+		}
+		if m.DiskSpace != nil {
+			fmt.Printf("Disk: %.1f%% of %.1f GB used\n", m.DiskSpace.UsedPercent, float64(m.DiskSpace.Total)/1e9)
+		}
+		if m.DiskIO != nil {
+			fmt.Printf("Disk I/O: read %.2f MB/s (%.0f IOPS), write %.2f MB/s (%.0f IOPS)\n", m.DiskIO.ReadMBps, m.DiskIO.ReadIOPS, m.DiskIO.WriteMBps, m.DiskIO.WriteIOPS)
+		}
+		if m.NetworkPublic != nil {
+			fmt.Printf("Network public: in %.2f Mbps, out %.2f Mbps\n", m.NetworkPublic.RecvMbps, m.NetworkPublic.SendMbps)
+		}
+		if m.NetworkPrivate != nil {
+			fmt.Printf("Network private: in %.2f Mbps, out %.2f Mbps\n", m.NetworkPrivate.RecvMbps, m.NetworkPrivate.SendMbps)
+		}
+		if m.System != nil {
+			fmt.Printf("System: up %s, %d processes\n", time.Duration(m.System.Uptime)*time.Second, m.System.ProcessCount)
+		}
+		for _, probe := range m.Probes {
+			if probe.Up {
+				fmt.Printf("Probe %s: up (%.0f ms)\n", probe.Target, probe.LatencyMs)
+			} else {
+				fmt.Printf("Probe %s: down (%s)\n", probe.Target, probe.Error)
+			}
+		}
+	}
+
+	if payload.Agent != nil {
+		for _, c := range payload.Agent.Collectors {
+			fmt.Printf("Collector %s: failing (%s)\n", c.Name, c.Error)
+		}
+		if c := payload.Agent.Capabilities; c != nil {
+			for _, feature := range c.Unavailable {
+				fmt.Printf("Unavailable %s: %s\n", feature.Feature, feature.Reason)
+			}
+		}
+	}
+}
+
+// statusReport is the output of "monify status --json"
+type statusReport struct {
+	Service      string              `json:"service"`             // running, stopped, stopped (auth failed), failed, ...
+	ExitCode     int                 `json:"exit_code,omitempty"` // Last exit status, 3 after an authentication failure
+	Agent        *models.AgentStatus `json:"agent,omitempty"`     // Live status of the running agent
+	AgentError   string              `json:"agent_error,omitempty"`
+	Token        string              `json:"token"` // configured, empty or not configured
+	RefreshToken bool                `json:"refresh_token"`
+	ClientCert   string              `json:"client_cert,omitempty"`
+	ServerURL    string              `json:"server_url"`
+	ReadOnly     bool                `json:"read_only"`
+	TLSInsecure  bool                `json:"tls_insecure_skip_verify"`
+	Version      string              `json:"version"`
+	updateCheck
+}
+
+// updateCheck is the outcome of the release lookup in status and version
+type updateCheck struct {
+	LatestVersion    string `json:"latest_version,omitempty"`
+	UpdateAvailable  bool   `json:"update_available,omitempty"`
+	UpdateCheckError string `json:"update_check_error,omitempty"`
+}
+
+// checkForUpdate looks up the latest release, with a short timeout so status
+// and version stay fast when the release server is unreachable
+func checkForUpdate() updateCheck {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	latest, err := update.Latest(ctx)
+	if err != nil {
+		return updateCheck{UpdateCheckError: err.Error()}
+	}
+	return updateCheck{LatestVersion: latest, UpdateAvailable: update.Newer(latest, config.Version)}
+}
+
+// print shows the outcome of the release lookup
+func (c updateCheck) print() {
+	switch {
+	case c.UpdateCheckError != "":
+		fmt.Printf("Update check: failed (%s)\n", c.UpdateCheckError)
+	case c.UpdateAvailable:
+		fmt.Printf("Update available: v%s. Run: sudo monify update\n", colorize(colorYellow, c.LatestVersion))
+	default:
+		fmt.Printf("Latest release: v%s (up to date)\n", c.LatestVersion)
+	}
+}
+
+func showStatus(asJSON, checkUpdate bool) {
+	if asJSON {
+		report := statusReport{
+			RefreshToken: config.GetRefreshToken() != "",
+			ClientCert:   config.GetClientCertPath(),
+			ServerURL:    config.GetServerURL(),
+			ReadOnly:     config.IsReadOnlyMode(),
+			TLSInsecure:  config.IsTLSInsecureSkipVerify(),
+			Version:      config.Version,
+		}
+		report.Service, report.ExitCode = getServiceStatus()
+		if checkUpdate {
+			report.updateCheck = checkForUpdate()
+		}
+		if live, err := fetchAgentStatus(); err == nil {
+			report.Agent = live
+		} else {
+			report.AgentError = err.Error()
+		}
+		token, err := config.GetToken()
+		switch {
+		case err != nil:
+			report.Token = "not configured"
+		case token == "":
+			report.Token = "empty"
+		default:
+			report.Token = "configured"
+		}
+		printJSON(report)
+
+		service := report.Service
+		if report.Agent != nil {
+			service = "running"
+		}
+		exitStatus(service, report.ExitCode, err == nil && token != "")
+		return
+	}
+
 	fmt.Println("Monify Agent Status")
 	fmt.Println("-------------------")
 
@@ -140,6 +907,15 @@ func showStatus() {
 	status, exitCode := getServiceStatus()
 	fmt.Printf("Service: %s\n", status)
 
+	// Ask the running agent for its live status
+	live, liveErr := fetchAgentStatus()
+	if liveErr == nil {
+		status = live.Status
+		printAgentStatus(live)
+	} else {
+		fmt.Printf("Agent: not reachable (%v)\n", liveErr)
+	}
+
 	// Check configuration
 	token, tokenErr := config.GetToken()
 	if tokenErr != nil {
@@ -150,23 +926,163 @@ func showStatus() {
 		fmt.Println("Token: configured")
 	}
 
+	if config.GetRefreshToken() != "" {
+		fmt.Println("Refresh token: configured")
+	}
+	if path := config.GetClientCertPath(); path != "" {
+		fmt.Printf("Client certificate: %s\n", path)
+	}
+
 	fmt.Printf("Server URL: %s\n", config.GetServerURL())
+	if config.IsReadOnlyMode() {
+		fmt.Println("Read-only: enabled (server commands refused)")
+	}
+	if config.IsTLSInsecureSkipVerify() {
+		fmt.Println("TLS verification: disabled")
+	}
 	fmt.Printf("Version: %s\n", config.Version)
+	if checkUpdate {
+		checkForUpdate().print()
+	}
 
 	// Show troubleshooting hints if service is not running
 	if status != "running" {
 		fmt.Println("")
 		fmt.Println("Troubleshooting:")
 
-		if tokenErr != nil || token == "" {
+		if (tokenErr != nil || token == "") && config.GetClientCertPath() == "" && config.GetRefreshToken() == "" {
 			fmt.Println("  → Token not configured. Run: sudo monify login")
-		} else if exitCode == 3 {
+		} else if exitCode == exitcode.AuthFailed {
 			fmt.Println("  → Authentication failed (invalid token).")
 			fmt.Println("    Run: sudo monify login")
-			fmt.Println("    Then: sudo systemctl start monify")
+			fmt.Println("    Then: sudo monify start")
 		} else {
 			fmt.Println("  → Check logs: journalctl -u monify --no-pager -n 20")
-			fmt.Println("  → Start service: sudo systemctl start monify")
+			fmt.Println("  → Start service: sudo monify start")
+		}
+	} else if liveErr == nil {
+		if hints := liveHints(live); len(hints) > 0 {
+			fmt.Println("")
+			fmt.Println("Troubleshooting:")
+			for _, hint := range hints {
+				fmt.Printf("  → %s\n", hint)
+			}
+		}
+	}
+	exitStatus(status, exitCode, tokenErr == nil && token != "")
+}
+
+// exitStatus ends monify status with an exit status telling why the agent
+// is not running, for scripts
+func exitStatus(status string, exitCode int, hasToken bool) {
+	switch {
+	case status == "running":
+		return
+	case exitCode == exitcode.AuthFailed:
+		os.Exit(exitcode.AuthFailed)
+	case !hasToken && config.GetClientCertPath() == "" && config.GetRefreshToken() == "":
+		os.Exit(exitcode.NotConfigured)
+	}
+	if _, err := os.Stat(uninstall.ServiceFile); err != nil {
+		os.Exit(exitcode.NotInstalled)
+	}
+	os.Exit(exitcode.ServiceFailed)
+}
+
+// liveHints returns hints for problems the running agent reports
+func liveHints(status *models.AgentStatus) []string {
+	var hints []string
+	stale := 3 * config.GetCollectionInterval() * time.Duration(config.GetBatchIntervals())
+	if status.Uptime > uint64(stale.Seconds()) && (status.LastSend.IsZero() || time.Since(status.LastSend) > stale) {
+		hints = append(hints, "No payload sent recently. Check: sudo monify test-connection")
+	}
+	if status.Spooled > 0 || (status.Circuit != "" && status.Circuit != "closed") {
+		hints = append(hints, "Server unreachable; payloads are kept and resent later. Check: sudo monify doctor")
+	}
+	for _, c := range status.Collectors {
+		if c.QuarantinedUntil != nil || !c.OK {
+			hints = append(hints, "Collectors failing. Check: sudo monify doctor")
+			break
+		}
+	}
+	if status.Version != "" && status.Version != config.Version {
+		hints = append(hints, fmt.Sprintf("Agent runs v%s, installed binary is v%s. Apply: sudo monify restart", status.Version, config.Version))
+	}
+	return hints
+}
+
+// fetchAgentStatus queries the local status API of the running agent
+func fetchAgentStatus() (*models.AgentStatus, error) {
+	addr := config.GetStatusAddress()
+	if addr == "" {
+		return nil, fmt.Errorf("status API disabled")
+	}
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get("http://" + addr + "/status")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var status models.AgentStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// printAgentStatus prints the live status reported by the agent
+func printAgentStatus(status *models.AgentStatus) {
+	fmt.Printf("Agent: %s (up %s)\n", status.Status, time.Duration(status.Uptime)*time.Second)
+	fmt.Printf("Hostname: %s\n", status.Hostname)
+	if status.Version != "" {
+		fmt.Printf("Agent version: %s\n", status.Version)
+	}
+	if !status.LastCollection.IsZero() {
+		fmt.Printf("Last collection: %s (%s ago)\n", status.LastCollection.Format(time.RFC3339), time.Since(status.LastCollection).Round(time.Second))
+	}
+	if status.LastSend.IsZero() {
+		fmt.Println("Last send: never")
+	} else {
+		fmt.Printf("Last send: %s (%s ago)\n", status.LastSend.Format(time.RFC3339), time.Since(status.LastSend).Round(time.Second))
+	}
+
+	fmt.Printf("Payloads: %d, errors: %d\n", status.MetricsCount, status.ErrorCount)
+	if status.Spooled > 0 {
+		fmt.Printf("Spooled: %d payloads waiting\n", status.Spooled)
+	}
+	if status.Circuit != "" && status.Circuit != "closed" {
+		fmt.Printf("Circuit breaker: %s\n", status.Circuit)
+	}
+	if status.ThrottledUntil != nil {
+		fmt.Printf("Throttled until: %s\n", status.ThrottledUntil.Format(time.RFC3339))
+	}
+	if m := status.Maintenance; m != nil {
+		fmt.Printf("Maintenance: until %s", m.Until.Format(time.RFC3339))
+		if m.Reason != "" {
+			fmt.Printf(" (%s)", m.Reason)
+		}
+		fmt.Println()
+	}
+	for _, alert := range status.Alerts {
+		fmt.Printf("Alert firing: %s (value %g, since %s)\n", alert.Rule, alert.Value, alert.Since.Format(time.RFC3339))
+	}
+	if status.HighResUntil != nil {
+		fmt.Printf("High-resolution mode until: %s\n", status.HighResUntil.Format(time.RFC3339))
+	}
+	if p := status.LastPayload; p != nil {
+		fmt.Printf("Last payload: seq %d, cpu %.1f%%, mem %.1f%%, static=%v\n", p.Sequence, p.CPUUsage, p.MemoryUsage, p.Static)
+	}
+	for _, c := range status.Collectors {
+		if c.QuarantinedUntil != nil {
+			fmt.Printf("Collector %s: quarantined until %s after %d failures (%s)\n", c.Name, c.QuarantinedUntil.Local().Format(time.RFC3339), c.Failures, c.Error)
+		} else if !c.OK {
+			fmt.Printf("Collector %s: failing (%s)\n", c.Name, c.Error)
 		}
 	}
 }
@@ -194,8 +1110,8 @@ func getServiceStatus() (string, int) {
 		// Check why it's inactive - get last exit code
 		cmd := exec.Command("systemctl", "show", "monify", "--property=ExecMainStatus")
 		output, _ := cmd.Output()
-		if strings.Contains(string(output), "=3") {
-			return "stopped (auth failed)", 3
+		if strings.TrimSpace(string(output)) == fmt.Sprintf("ExecMainStatus=%d", exitcode.AuthFailed) {
+			return "stopped (auth failed)", exitcode.AuthFailed
 		}
 		return "stopped", exitCode
 	case "failed":
@@ -205,50 +1121,148 @@ func getServiceStatus() (string, int) {
 	}
 }
 
-func handleLogin() {
-	// Check if running as root
+func handleSupportBundle(output string) {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if output == "" {
+		hostname, _ := os.Hostname()
+		output = fmt.Sprintf("monify-support-%s-%s.tar.gz", hostname, time.Now().Format("20060102-150405"))
+	}
 	if os.Geteuid() != 0 {
+		fmt.Println("Warning: not running as root; the config, logs and spool may be missing. Run: sudo monify support-bundle")
+	}
+
+	// The bundle holds the configuration, so it is readable by its owner only
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
+	fmt.Println("Gathering support bundle (this runs the doctor checks)...")
+	status, _ := fetchAgentStatus()
+	err = support.Write(ctx, f, status)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
+
+	fmt.Printf("✓ Support bundle written to %s\n", output)
+	fmt.Println("  Tokens and other credentials in the config are redacted; attach it to your support ticket.")
+}
+
+func handleLogin(args []string, tokenFile string, fromStdin bool, verify bool, encrypt bool) {
+	// Root is needed for the system env file, and to seal the token
+	if !config.EnvFileWritable() || (encrypt && os.Geteuid() != 0) {
 		fmt.Println("Error: login requires root privileges.")
 		fmt.Println("Please run: sudo monify login [TOKEN]")
-		os.Exit(1)
+		os.Exit(exitcode.NoPermission)
 	}
 
 	var token string
 
-	// Check if token is passed as argument
-	if len(os.Args) >= 3 {
-		token = os.Args[2]
-	} else {
-		// Interactive mode
+	// The token comes from the argument, a file, stdin or the prompt. Files and
+	// stdin keep it out of ps and the shell history, for provisioning tools.
+	switch {
+	case len(args) > 0:
+		token = strings.TrimSpace(args[0])
+	case tokenFile != "":
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			fmt.Printf("Error reading token file: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+		token = strings.TrimSpace(string(data))
+	case fromStdin:
+		var err error
+		if token, err = readLine(os.Stdin); err != nil {
+			fmt.Printf("Error reading token from stdin: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+	default:
+		// Interactive mode, without echoing the token
 		fmt.Println("Monify Agent Login")
 		fmt.Println("------------------")
-		fmt.Print("Enter your server token: ")
 
-		_, err := fmt.Scanln(&token)
-		if err != nil {
-			fmt.Println("Error reading token")
-			os.Exit(1)
+		var err error
+		if token, err = readSecret("Enter your server token (input is hidden): "); err != nil {
+			fmt.Printf("Error reading token: %v\n", err)
+			os.Exit(exitcode.Failure)
 		}
 	}
 
 	if token == "" {
 		fmt.Println("Error: Token cannot be empty")
-		os.Exit(1)
+		os.Exit(exitcode.Failure)
+	}
+	if strings.ContainsAny(token, "\r\n") {
+		fmt.Println("Error: Token must be a single line")
+		os.Exit(exitcode.Failure)
 	}
 
-	// Save token to env file
-	err := config.SaveEnvFile(map[string]string{
-		"MONIFY_TOKEN": token,
-	})
+	// Catch typos before the token replaces a working one
+	if verify {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		err := agent.VerifyToken(ctx, token)
+		cancel()
+		switch {
+		case err == nil:
+			fmt.Println(colorize(colorGreen, "✓ Token accepted by the server"))
+		case errors.Is(err, sender.ErrUnauthorized):
+			fmt.Println(colorize(colorRed, "✗ The server rejected the token; it was not saved"))
+			fmt.Println("Copy the token again from https://dash.monify.cloud")
+			os.Exit(exitcode.AuthFailed)
+		default:
+			fmt.Printf("Warning: could not verify the token, saving it anyway: %v\n", err)
+		}
+	}
+
+	// Save the token, or a reference to the sealed token, to the env file
+	var err error
+	if encrypt {
+		if err = install.SealToken(token); err != nil {
+			fmt.Printf("Error encrypting token: %v\n", err)
+			if errors.Is(err, install.ErrNoSystemdCreds) {
+				fmt.Println("Run monify login without --encrypt to save it in plain text.")
+			}
+			os.Exit(exitcode.Failure)
+		}
+		err = config.SaveEnvFile(map[string]string{"MONIFY_TOKEN_CREDENTIAL": install.TokenCredentialPath()})
+		if err == nil {
+			err = config.UnsetEnvFile("MONIFY_TOKEN")
+		}
+	} else {
+		err = config.SaveEnvFile(map[string]string{"MONIFY_TOKEN": token})
+		if err == nil && config.EnvFileValue("MONIFY_TOKEN_CREDENTIAL") != "" {
+			if err = config.UnsetEnvFile("MONIFY_TOKEN_CREDENTIAL"); err == nil {
+				err = install.RemoveTokenCredential()
+			}
+		}
+	}
 	if err != nil {
 		fmt.Printf("Error saving token: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitcode.Failure)
 	}
 
+	if encrypt {
+		fmt.Printf("Token encrypted to %s\n", install.TokenCredentialPath())
+		fmt.Println("")
+		fmt.Println("systemd decrypts it when the service starts. To apply it, run:")
+		fmt.Println("  sudo monify restart")
+		return
+	}
 	fmt.Println("Token saved successfully!")
 	fmt.Println("")
 	fmt.Println("To start the agent, run:")
-	fmt.Println("  sudo systemctl start monify")
+	if os.Geteuid() == 0 {
+		fmt.Println("  sudo monify start")
+	} else {
+		fmt.Println("  monify run")
+	}
 }
 
 func handleLogout() {
@@ -256,7 +1270,7 @@ func handleLogout() {
 	if os.Geteuid() != 0 {
 		fmt.Println("Error: logout requires root privileges.")
 		fmt.Println("Please run: sudo monify logout")
-		os.Exit(1)
+		os.Exit(exitcode.NoPermission)
 	}
 
 	fmt.Println("Logging out...")
@@ -265,13 +1279,18 @@ func handleLogout() {
 	cmd := exec.Command("systemctl", "stop", "monify")
 	cmd.Run() // Ignore error if service not running
 
-	// Remove token from env file
+	// Remove token from env file, and the sealed token if any
 	err := config.SaveEnvFile(map[string]string{
 		"MONIFY_TOKEN": "",
 	})
+	if err == nil && config.EnvFileValue("MONIFY_TOKEN_CREDENTIAL") != "" {
+		if err = config.UnsetEnvFile("MONIFY_TOKEN_CREDENTIAL"); err == nil {
+			err = install.RemoveTokenCredential()
+		}
+	}
 	if err != nil {
 		fmt.Printf("Error removing token: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitcode.Failure)
 	}
 
 	fmt.Println("✓ Service stopped")
@@ -280,32 +1299,307 @@ func handleLogout() {
 	fmt.Println("To login again: sudo monify login [TOKEN]")
 }
 
-func handleUpdate() {
+func handleUpdate(args []string) {
 	// Check if running as root
 	if os.Geteuid() != 0 {
 		fmt.Println("Error: update requires root privileges.")
 		fmt.Println("Please run: sudo monify update")
-		os.Exit(1)
+		os.Exit(exitcode.NoPermission)
+	}
+
+	version := ""
+	if len(args) > 0 {
+		version = args[0]
 	}
 
 	fmt.Println("Updating Monify Agent...")
 	fmt.Printf("Current version: %s\n", config.Version)
 	fmt.Println("")
 
-	// Run install script without token (it will use existing token)
-	cmd := exec.Command("bash", "-c", "curl -sSL https://monify.cloud/install.sh | bash")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	
-	if err := cmd.Run(); err != nil {
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
 		fmt.Printf("Update failed: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitcode.Failure)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetUpdateTimeout())
+	defer cancel()
+	result, err := update.Run(ctx, version, exe)
+	if err != nil {
+		fmt.Printf("Update failed: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
+	if !result.Updated {
+		fmt.Println("✓ Already up to date")
+		return
+	}
+	fmt.Printf("✓ Verified and installed v%s\n", result.Version)
+
+	// Restart the service so it runs the new binary
+	if exec.Command("systemctl", "is-active", "--quiet", "monify").Run() == nil {
+		if err := exec.Command("systemctl", "restart", "monify").Run(); err != nil {
+			fmt.Printf("Restart failed: %v\n", err)
+			fmt.Println("Run: sudo monify restart")
+			os.Exit(exitcode.Failure)
+		}
+		fmt.Println("✓ Service restarted")
+	}
+}
+
+func handleService(action string) {
+	// Check if running as root
+	if os.Geteuid() != 0 {
+		fmt.Printf("Error: %s requires root privileges.\n", action)
+		fmt.Printf("Please run: sudo monify %s\n", action)
+		os.Exit(exitcode.NoPermission)
+	}
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		fmt.Println("Error: systemd is not running; start the agent with: monify run")
+		os.Exit(exitcode.NotInstalled)
+	}
+	if _, err := os.Stat(uninstall.ServiceFile); err != nil {
+		fmt.Println("Error: the agent is not installed as a service.")
+		fmt.Println("Please run: sudo monify install [TOKEN]")
+		os.Exit(exitcode.NotInstalled)
+	}
+
+	if out, err := exec.Command("systemctl", action, "monify").CombinedOutput(); err != nil {
+		fmt.Printf("✗ Failed to %s Monify Agent: %s\n", action, strings.TrimSpace(string(out)))
+		fmt.Println("  Check logs: journalctl -u monify --no-pager -n 20")
+		os.Exit(exitcode.ServiceFailed)
+	}
+	if action == "stop" {
+		fmt.Println("✓ Monify Agent stopped")
+		return
+	}
+
+	// A rejected token ends the agent shortly after startup
+	time.Sleep(3 * time.Second)
+	if status, exitCode := getServiceStatus(); status != "running" {
+		fmt.Printf("✗ Monify Agent is %s\n", status)
+		if exitCode == exitcode.AuthFailed {
+			fmt.Println("  Authentication failed (invalid token). Run: sudo monify login")
+			os.Exit(exitcode.AuthFailed)
+		}
+		fmt.Println("  Check logs: journalctl -u monify --no-pager -n 20")
+		os.Exit(exitcode.ServiceFailed)
+	}
+	fmt.Printf("✓ Monify Agent %sed\n", action)
+}
+
+func handlePause(args []string) {
+	// Check if running as root
+	if os.Geteuid() != 0 {
+		fmt.Println("Error: pause requires root privileges.")
+		fmt.Println("Please run: sudo monify pause DURATION [REASON]")
+		os.Exit(exitcode.NoPermission)
+	}
+	if len(args) < 1 {
+		fmt.Println("Usage: monify pause DURATION [REASON]   (e.g. monify pause 2h kernel upgrade)")
+		os.Exit(exitcode.Usage)
+	}
+
+	duration, err := time.ParseDuration(args[0])
+	if err != nil {
+		fmt.Printf("Invalid duration %q: use e.g. 30m or 2h\n", args[0])
+		os.Exit(exitcode.Usage)
+	}
+	window, err := agent.Pause(duration, strings.Join(args[1:], " "))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
+
+	fmt.Printf("✓ Maintenance mode until %s\n", window.Until.Local().Format(time.RFC1123))
+	fmt.Println("  Metrics are still sent, marked so that no alerts are raised.")
+	fmt.Println("  To end it early: sudo monify resume")
+}
+
+func handleResume() {
+	// Check if running as root
+	if os.Geteuid() != 0 {
+		fmt.Println("Error: resume requires root privileges.")
+		fmt.Println("Please run: sudo monify resume")
+		os.Exit(exitcode.NoPermission)
+	}
+
+	if err := agent.Resume(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
+	fmt.Println("✓ Maintenance mode ended")
+}
+
+func handleInstall(args []string, asRoot, noStart, force bool) {
+	// Check if running as root
+	if os.Geteuid() != 0 {
+		fmt.Println("Error: install requires root privileges.")
+		fmt.Println("Please run: sudo monify install [TOKEN]")
+		os.Exit(exitcode.NoPermission)
+	}
+
+	settings, err := config.ReadEnvFile()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
+	existing := settings["MONIFY_TOKEN"]
+
+	var token string
+	if len(args) > 0 {
+		token = args[0]
+	}
+	switch {
+	case token == "" && (existing != "" || settings["MONIFY_TOKEN_CREDENTIAL"] != ""):
+		fmt.Println("Using existing token")
+	case token == "" && settings["MONIFY_REFRESH_TOKEN"] == "" && settings["MONIFY_CLIENT_CERT"] == "":
+		if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			fmt.Println("Error: a token is required.")
+			fmt.Println("Please run: sudo monify install YOUR_TOKEN")
+			os.Exit(exitcode.Failure)
+		}
+		token, err = readSecret("Enter your server token (input is hidden): ")
+		if err != nil || token == "" {
+			fmt.Println("Error: Token cannot be empty")
+			os.Exit(exitcode.Failure)
+		}
+	}
+	if token != "" && existing != "" && token != existing && !force {
+		fmt.Println("Error: a different token is already configured.")
+		fmt.Println("To replace it, run: sudo monify install --force NEW_TOKEN")
+		os.Exit(exitcode.Failure)
+	}
+
+	opts := install.Options{User: uninstall.ServiceUser}
+	if asRoot {
+		opts.User = ""
+	}
+	fmt.Println("Installing Monify Agent...")
+	if err := install.Run(opts); err != nil {
+		fmt.Printf("Install failed: %v\n", err)
+		if errors.Is(err, install.ErrNoSystemd) {
+			os.Exit(exitcode.NotInstalled)
+		}
+		os.Exit(exitcode.Failure)
+	}
+	fmt.Printf("✓ Binary installed to %s\n", uninstall.BinaryPath)
+	fmt.Printf("✓ Service written to %s\n", uninstall.ServiceFile)
+
+	// An existing MONIFY_USER is kept, also with --root
+	updates := map[string]string{}
+	if token != "" {
+		updates["MONIFY_TOKEN"] = token
+	}
+	runAs, ok := settings["MONIFY_USER"]
+	if !ok && opts.User != "" {
+		runAs = opts.User
+		updates["MONIFY_USER"] = runAs
+	}
+	if len(updates) > 0 {
+		if err := config.SaveEnvFile(updates); err != nil {
+			fmt.Printf("Error saving configuration: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+	}
+	fmt.Printf("✓ Configuration saved to %s\n", config.EnvFilePath)
+	if runAs != "" {
+		fmt.Printf("✓ Agent switches to user %s after startup\n", runAs)
+	}
+
+	if noStart {
+		fmt.Println("")
+		fmt.Println("To start the agent, run:")
+		fmt.Println("  sudo systemctl enable --now monify")
+		return
+	}
+	if err := install.Start(); err != nil {
+		fmt.Printf("✗ %v\n", err)
+		if errors.Is(err, install.ErrAuthFailed) {
+			fmt.Println("  Get a valid token at https://dash.monify.cloud, then run: sudo monify login YOUR_NEW_TOKEN")
+			os.Exit(exitcode.AuthFailed)
+		}
+		fmt.Println("  Check logs: journalctl -u monify --no-pager -n 20")
+		os.Exit(exitcode.ServiceFailed)
 	}
+	fmt.Println("✓ Monify Agent is running")
 }
 
-func showVersion() {
+func handleUninstall(confirmed, purge bool) {
+	// Check if running as root
+	if os.Geteuid() != 0 {
+		fmt.Println("Error: uninstall requires root privileges.")
+		fmt.Println("Please run: sudo monify uninstall")
+		os.Exit(exitcode.NoPermission)
+	}
+
+	// Ask for confirmation when run from a terminal, unless --yes is given
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 && !confirmed {
+		fmt.Print("This will completely remove Monify Agent from your system. Continue? [y/N] ")
+		var answer string
+		fmt.Scanln(&answer)
+		if answer != "y" && answer != "Y" {
+			fmt.Println("Uninstallation cancelled")
+			return
+		}
+	}
+
+	fmt.Println("Uninstalling Monify Agent...")
+	if err := uninstall.Run(purge); err != nil {
+		fmt.Printf("Uninstall incomplete: %v\n", err)
+		fmt.Printf("To finish, run: %s\n", uninstall.FallbackCommand)
+		os.Exit(exitcode.Failure)
+	}
+
+	fmt.Println("✓ Service stopped and removed")
+	fmt.Printf("✓ Removed %s, %s, %s and %s\n", uninstall.BinaryPath, uninstall.ConfigDir, uninstall.LogDir, uninstall.StateDir)
+	if purge {
+		fmt.Printf("✓ Removed user %s\n", uninstall.ServiceUser)
+	}
+}
+
+// versionReport is the output of "monify version --json"
+type versionReport struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"` // GOOS/GOARCH
+	updateCheck
+}
+
+func showVersion(asJSON, checkUpdate bool) {
+	if asJSON {
+		report := versionReport{
+			Version:   config.Version,
+			Commit:    config.Commit,
+			BuildDate: config.BuildDate,
+			GoVersion: runtime.Version(),
+			Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		}
+		if checkUpdate {
+			report.updateCheck = checkForUpdate()
+		}
+		printJSON(report)
+		return
+	}
 	fmt.Printf("Monify Agent v%s\n", config.Version)
 	fmt.Printf("Commit: %s\n", config.Commit)
 	fmt.Printf("Build Date: %s\n", config.BuildDate)
+	if checkUpdate {
+		checkForUpdate().print()
+	}
 	fmt.Println("https://monify.cloud")
 }
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
+}
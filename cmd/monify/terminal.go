@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/monify-labs/agent/internal/exitcode"
+)
+
+// Control characters handled while reading a secret
+const (
+	keyEOF       = 0x04 // Ctrl-D
+	keyBackspace = 0x08 // Ctrl-H
+	keyDelete    = 0x7f
+	keyKillLine  = 0x15 // Ctrl-U
+)
+
+// readSecret prompts for a secret on the terminal without echoing it. The
+// terminal is switched out of canonical mode, so pasted values are not cut
+// at the line length limit of the tty. Input that is not a terminal is read
+// up to the end of the first line.
+func readSecret(prompt string) (string, error) {
+	fd := os.Stdin.Fd()
+	var state syscall.Termios
+	if termios(fd, syscall.TCGETS, &state) != nil {
+		return readLine(os.Stdin)
+	}
+
+	raw := state
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON // Keep ISIG, so Ctrl-C still works
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	fmt.Print(prompt)
+	if err := termios(fd, syscall.TCSETS, &raw); err != nil {
+		return "", err
+	}
+
+	// Restore the terminal also when interrupted
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			termios(fd, syscall.TCSETS, &state)
+			fmt.Println()
+			os.Exit(exitcode.Interrupted)
+		case <-done:
+		}
+	}()
+	defer func() {
+		close(done)
+		signal.Stop(signals)
+		termios(fd, syscall.TCSETS, &state)
+		fmt.Println()
+	}()
+
+	var secret []byte
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch c {
+		case '\r', '\n':
+			return strings.TrimSpace(string(secret)), nil
+		case keyEOF:
+			if len(secret) == 0 {
+				return "", io.EOF
+			}
+		case keyBackspace, keyDelete:
+			if len(secret) > 0 {
+				secret = secret[:len(secret)-1]
+			}
+		case keyKillLine:
+			secret = secret[:0]
+		default:
+			secret = append(secret, c)
+		}
+	}
+}
+
+// readLine reads the first line of r, which may lack a line break
+func readLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// termios gets or sets the terminal attributes of fd
+func termios(fd uintptr, request uintptr, state *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(state))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -2,7 +2,11 @@ module github.com/monify-labs/agent
 
 go 1.24.0
 
-require github.com/shirou/gopsutil/v4 v4.25.11
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/shirou/gopsutil/v4 v4.25.11
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/ebitengine/purego v0.9.1 // indirect
@@ -2,43 +2,98 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"math/rand/v2"
 	"os"
-	"os/exec"
 	"os/signal"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/monify-labs/agent/internal/config"
 	"github.com/monify-labs/agent/internal/sender"
+	"github.com/monify-labs/agent/internal/spool"
+	"github.com/monify-labs/agent/internal/uninstall"
 	"github.com/monify-labs/agent/pkg/models"
 )
 
+// ErrAuthFailed is returned by Start when the server permanently rejects the token
+var ErrAuthFailed = errors.New("authentication failed")
+
+// ErrRestartRequested is returned by Start after the server sent a restart
+// command; the agent has stopped cleanly and the caller should start it again
+var ErrRestartRequested = errors.New("restart requested by server")
+
+// PayloadHandler receives every assembled payload. Handlers run synchronously
+// in the collection loop and must not modify the payload.
+type PayloadHandler func(payload *models.MetricPayload)
+
 // Agent is the main monitoring agent
 type Agent struct {
 	serverURL        string
 	token            string
 	debug            bool
-	sender           sender.Sender
+	readOnly         bool // Local read-only mode, never overridden by the server
+	dryRun           bool // Payloads are printed to stdout instead of being sent
+	*senderSet            // Replaced as a whole on configuration reload
 	staticCollector  *StaticCollector
 	dynamicCollector *DynamicCollector
+	chunker          *payloadChunker
+	sequencer        *sequencer
+	telemetry        selfTelemetry
+	spool            *spool.Spool   // nil when offline buffering is disabled
+	batchSize        int            // Collection intervals per request (1 disables batching)
+	commandPolicy    *commandPolicy // Allowlist and signature checks for server commands
+	alerts           *alertEvaluator
+	budget           *resourceBudget // Own CPU and memory limits
+
+	// Embedding
+	handlers       []PayloadHandler
+	sendEnabled    bool           // When false, payloads are only delivered to handlers
+	handleSignals  bool           // When false, the host application owns signal handling
+	statusAddr     string         // Local status API address, empty when disabled
+	prometheusAddr string         // Local Prometheus endpoint address, empty when disabled
+	localAPI       sync.WaitGroup // Running local API servers
 
 	// State
 	mu             sync.RWMutex
 	running        bool
-	authFailed     bool // When true, authentication has failed permanently
+	exited         chan struct{}           // Closed when the last run of Start returned, nil before the first
+	authFailed     bool                    // When true, authentication has failed permanently
+	authFailures   int                     // Consecutive token rejections
+	authSince      time.Time               // First of the consecutive token rejections
+	authGaveUp     bool                    // Rejections exceeded the limits, but the agent keeps retrying
+	throttledUntil time.Time               // Sends are held until then after a Retry-After
+	highResUntil   time.Time               // High-resolution mode ends then, zero when inactive
+	inMaintenance  bool                    // Last payload was collected in a maintenance window
+	sendFailing    bool                    // Last metrics send failed
+	diagnosing     atomic.Bool             // A diagnostics run is in progress
+	scanningPorts  atomic.Bool             // A scan_ports command is in progress
+	portReport     *models.PortReport      // Attached to the next payload
+	tokenScopes    []string                // Scopes last reported by the server for our token
+	serverFeatures []string                // Features the server announced at registration
+	batch          []*models.MetricPayload // Payloads waiting for a full batch
 	hostname       string
 	startTime      time.Time
 	lastCollection time.Time
 	lastSend       time.Time
+	lastPayload    *models.MetricPayload     // Last assembled payload, for the local endpoints
+	startupStatic  *models.StaticMetrics     // Collected at startup, sent with the first payload
+	permissions    *models.AgentCapabilities // Detected after dropping privileges, sent with static metrics
 	metricsCount   uint64
 	errorCount     uint64
 
 	// Channels
-	stopChan chan struct{}
+	stopChan       chan struct{}       // Closed by Stop, recreated by the next Start
+	restartChan    chan string         // Reason of a pending server restart command
+	reloadChan     chan struct{}       // Configuration reload requested by a server command
+	highResChan    chan highResolution // High-resolution mode requested by a server command
+	stopBackground context.CancelFunc  // Stops the command stream and heartbeat
 }
 
 // NewAgent creates a new monitoring agent
@@ -47,34 +102,170 @@ func NewAgent(serverURL, token string, debug bool) (*Agent, error) {
 	staticCollector := NewStaticCollector()
 	dynamicCollector := NewDynamicCollector()
 
-	// Initialize sender
-	httpSender := sender.NewHTTPSender(serverURL, token)
+	senders, err := newSenderSet(serverURL, token)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := newCommandPolicy(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// A dry run leaves no state behind
+	dryRun := config.IsDryRun()
+	sequenceFile := config.SequenceFile
+	if dryRun {
+		sequenceFile = ""
+	}
+
+	// Open offline spool; the agent still runs without it
+	var payloadSpool *spool.Spool
+	if maxBytes := config.GetSpoolMaxBytes(); maxBytes > 0 && !dryRun {
+		payloadSpool, err = spool.Open(config.GetSpoolDir(), maxBytes)
+		if err != nil {
+			log.Printf("WARN: Offline spool disabled: %v", err)
+		} else if n := payloadSpool.Len(); n > 0 {
+			log.Printf("INFO: Found spooled payloads to replay [spooled=%d]", n)
+		}
+	}
 
 	return &Agent{
 		serverURL:        serverURL,
 		token:            token,
 		debug:            debug,
-		sender:           httpSender,
+		readOnly:         config.IsReadOnlyMode(),
+		dryRun:           dryRun,
+		commandPolicy:    policy,
+		alerts:           newAlertEvaluator(nil),
+		budget:           newResourceBudget(nil),
+		senderSet:        senders,
 		staticCollector:  staticCollector,
 		dynamicCollector: dynamicCollector,
+		chunker:          newPayloadChunker(config.GetMaxSectionItems()),
+		sequencer:        newSequencer(sequenceFile),
+		spool:            payloadSpool,
+		batchSize:        config.GetBatchIntervals(),
+		sendEnabled:      true,
+		handleSignals:    true,
+		statusAddr:       config.GetStatusAddress(),
+		prometheusAddr:   config.GetPrometheusAddress(),
 		stopChan:         make(chan struct{}),
+		restartChan:      make(chan string, 1),
+		reloadChan:       make(chan struct{}, 1),
+		highResChan:      make(chan highResolution, 1),
 	}, nil
 }
 
-// Start starts the agent
+// agentEndpoint derives a sibling endpoint of the metrics URL, e.g.
+// https://api.monify.cloud/v1/agent/metrics -> .../v1/agent/commands/stream
+func agentEndpoint(serverURL, path string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(serverURL, "/"), "/metrics") + "/" + path
+}
+
+// newServerSender creates the HTTP sender, with failover when a fallback URL is
+// configured. tokens is nil when the static token is used.
+func newServerSender(serverURL, token string, tokens *sender.TokenSource) (sender.Sender, error) {
+	primary, err := sender.NewHTTPSender(serverURL, token)
+	if err != nil {
+		return nil, err
+	}
+	if tokens != nil {
+		primary.SetTokenSource(tokens)
+	}
+
+	fallbackURL := config.GetFallbackServerURL()
+	if fallbackURL == "" {
+		return primary, nil
+	}
+
+	fallback, err := sender.NewHTTPSender(fallbackURL, token)
+	if err != nil {
+		return nil, err
+	}
+	if tokens != nil {
+		fallback.SetTokenSource(tokens)
+	}
+
+	return sender.NewFailoverSender(
+		sender.Destination{Name: serverURL, Sender: primary},
+		sender.Destination{Name: fallbackURL, Sender: fallback},
+		config.GetFailoverThreshold(),
+		config.FailoverProbeInterval,
+	), nil
+}
+
+// OnPayload registers a handler called with every assembled payload
+func (a *Agent) OnPayload(handler PayloadHandler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.handlers = append(a.handlers, handler)
+}
+
+// SetSendEnabled controls whether payloads are sent to the server
+func (a *Agent) SetSendEnabled(enabled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sendEnabled = enabled
+}
+
+// SetSignalHandling controls whether the agent handles SIGINT/SIGTERM/SIGHUP itself
+func (a *Agent) SetSignalHandling(enabled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.handleSignals = enabled
+}
+
+// SetStatusAddress sets the loopback address of the local status API (empty disables)
+func (a *Agent) SetStatusAddress(addr string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.statusAddr = addr
+}
+
+// SetPrometheusAddress sets the loopback address of the local Prometheus endpoint (empty disables)
+func (a *Agent) SetPrometheusAddress(addr string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.prometheusAddr = addr
+}
+
+// Start starts the agent. It can be called again after it returned, e.g.
+// by an embedding application that stopped the agent.
 func (a *Agent) Start(ctx context.Context) error {
+	// A Stop from another goroutine returns before the previous run has
+	// cleaned up; wait for it
+	a.mu.RLock()
+	previous := a.exited
+	a.mu.RUnlock()
+	if previous != nil {
+		<-previous
+	}
+
 	a.mu.Lock()
 	if a.running {
 		a.mu.Unlock()
 		return fmt.Errorf("agent is already running")
 	}
+	if a.exited != nil {
+		if err := a.prepareRestart(); err != nil {
+			a.mu.Unlock()
+			return fmt.Errorf("failed to restart agent: %w", err)
+		}
+	}
 	a.running = true
 	a.startTime = time.Now()
+	exited := make(chan struct{})
+	a.exited = exited
+	stopChan := a.stopChan
 	a.mu.Unlock()
+	defer close(exited)
+
+	applyPriority()
 
 	// Start background samplers
 	a.dynamicCollector.Start()
-	defer a.dynamicCollector.Stop()
+	defer func() { a.dynamicCollector.Stop() }()
 
 	// Initial static collection to get hostname
 	staticMetrics, err := a.staticCollector.Collect(ctx)
@@ -82,17 +273,102 @@ func (a *Agent) Start(ctx context.Context) error {
 		log.Printf("WARN: %v - %s", err, "Failed to collect initial static metrics")
 	} else {
 		a.hostname = staticMetrics.Hostname
+		a.startupStatic = staticMetrics
 	}
 
+	// Leave root once the privileged startup work is done
+	if err := dropPrivileges(); err != nil {
+		a.mu.Lock()
+		a.running = false
+		a.mu.Unlock()
+		return fmt.Errorf("failed to drop privileges: %w", err)
+	}
+	permissions := detectPermissions()
+	for _, feature := range permissions.Unavailable {
+		log.Printf("WARN: Unavailable without more privileges [feature=%s reason=%s]", feature.Feature, feature.Reason)
+	}
+	a.mu.Lock()
+	a.permissions = permissions
+	a.mu.Unlock()
+
 	log.Printf("INFO: %s [%s=%v]", "Agent starting", "hostname", a.hostname)
+	if a.dryRun {
+		log.Printf("INFO: %s", "Dry run: payloads are printed to stdout and not sent")
+	}
+	if a.readOnly {
+		log.Printf("INFO: %s", "Read-only mode enabled: server commands will not be executed")
+	}
 
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	a.mu.RLock()
+	handleSignals := a.handleSignals
+	a.mu.RUnlock()
+	if handleSignals {
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		defer signal.Stop(sigChan)
+	}
+
+	// Local status API and Prometheus endpoint
+	apiCtx, cancelAPI := context.WithCancel(ctx)
+	defer func() {
+		cancelAPI()
+		a.localAPI.Wait() // Free the addresses for a restart
+	}()
+	a.startLocalAPI(apiCtx)
+
+	// Command stream and heartbeat run alongside the collection loop
+	a.startBackground(ctx)
+	defer func() { a.stopBackground() }()
+
+	// Under a Type=notify service, systemd waits for READY=1 and restarts the
+	// agent if it stops sending WATCHDOG=1. READY=1 goes out before the start
+	// jitter, which may exceed the start timeout. The pings come from the
+	// collection loop itself rather than after each cycle: a hung cycle stops
+	// them, but the interval may be longer than WatchdogSec, and failing sends
+	// do not, as they are spooled and a restart would not fix an outage.
+	if err := sdNotify("READY=1", a.notifyStatus()); err != nil {
+		log.Printf("WARN: %v - %s", err, "Failed to notify systemd")
+	}
+	var watchdog <-chan time.Time
+	if every := watchdogInterval(); every > 0 {
+		watchdogTicker := time.NewTicker(every)
+		defer watchdogTicker.Stop()
+		watchdog = watchdogTicker.C
+	}
+
+	// Random start offset so agents started together do not register and
+	// send in lockstep
+	if jitter := config.GetCollectionJitter(); jitter > 0 {
+		delay := rand.N(jitter)
+		if a.debug {
+			log.Printf("DEBUG: Delaying collection start [jitter=%s]", delay.Round(time.Millisecond))
+		}
+		start := time.NewTimer(delay)
+		defer start.Stop()
+		for waiting := true; waiting; {
+			select {
+			case <-ctx.Done():
+				log.Printf("INFO: %s", "Agent stopping: context cancelled")
+				return a.Stop()
+			case <-stopChan:
+				return nil
+			case <-watchdog:
+				sdNotify("WATCHDOG=1", a.notifyStatus())
+			case <-start.C:
+				waiting = false
+			}
+		}
+	}
+
+	// Announce ourselves and take the server's configuration before the first payload
+	a.register(ctx)
 
 	// Start collection loop
-	ticker := time.NewTicker(config.CollectionInterval)
+	interval := config.GetCollectionInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
+	var highResEnd <-chan time.Time // Fires when high-resolution mode ends
 
 	// Collect immediately on start
 	a.collectAndSend(ctx)
@@ -103,19 +379,64 @@ func (a *Agent) Start(ctx context.Context) error {
 			log.Printf("INFO: %s", "Agent stopping: context cancelled")
 			return a.Stop()
 
-		case <-a.stopChan:
+		case <-stopChan:
 			log.Printf("INFO: %s", "Agent stopping: stop signal received")
 			return nil
 
 		case sig := <-sigChan:
 			switch sig {
 			case syscall.SIGHUP:
-				log.Printf("INFO: %s", "Received SIGHUP (configuration reload not supported)")
+				log.Printf("INFO: %s", "Received SIGHUP, reloading configuration")
+				if newInterval := a.reload(ctx, interval); newInterval != interval {
+					interval = newInterval
+					if highResEnd == nil {
+						ticker.Reset(interval)
+					}
+				}
 			case syscall.SIGINT, syscall.SIGTERM:
 				log.Printf("INFO: %s", "Received shutdown signal")
 				return a.Stop()
 			}
 
+		case <-a.reloadChan:
+			if newInterval := a.reload(ctx, interval); newInterval != interval {
+				interval = newInterval
+				if highResEnd == nil {
+					ticker.Reset(interval)
+				}
+			}
+
+		case req := <-a.highResChan:
+			if req.duration == 0 {
+				if highResEnd != nil {
+					highResEnd = nil
+					a.setHighResolutionUntil(time.Time{})
+					ticker.Reset(interval)
+					log.Printf("INFO: High-resolution mode cancelled [interval=%s]", interval)
+				}
+				break
+			}
+			highResEnd = time.After(req.duration)
+			a.setHighResolutionUntil(time.Now().Add(req.duration))
+			ticker.Reset(req.interval)
+			log.Printf("INFO: High-resolution mode enabled [interval=%s duration=%s]", req.interval, req.duration)
+
+		case <-highResEnd:
+			highResEnd = nil
+			a.setHighResolutionUntil(time.Time{})
+			ticker.Reset(interval)
+			log.Printf("INFO: High-resolution mode ended [interval=%s]", interval)
+
+		case <-watchdog:
+			sdNotify("WATCHDOG=1", a.notifyStatus())
+
+		case reason := <-a.restartChan:
+			log.Printf("INFO: Agent restarting [reason=%s]", reason)
+			if err := a.Stop(); err != nil {
+				log.Printf("ERROR: %v - %s", err, "Error during stop")
+			}
+			return ErrRestartRequested
+
 		case <-ticker.C:
 			// Check if auth failed
 			a.mu.RLock()
@@ -131,28 +452,30 @@ func (a *Agent) Start(ctx context.Context) error {
 					log.Printf("ERROR: %v - %s", err, "Error during stop")
 				}
 
-				// Exit with special code to prevent systemd restart
-				os.Exit(3)
+				return ErrAuthFailed
 			}
 
 			a.collectAndSend(ctx)
+			sdNotify(a.notifyStatus())
 		}
 	}
 }
 
 // collectAndSend collects metrics and sends them to the server
 func (a *Agent) collectAndSend(ctx context.Context) {
-	// Single timeout for entire operation (collection + send)
-	opCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
-	// Check if static metrics need refreshing
-	var staticMetrics *models.StaticMetrics
-	if a.staticCollector.ShouldRefresh() {
+	// Collectors have their own deadlines (see collectGroup) and the send
+	// has a separate one, so slow collectors cannot starve it
+	// Static metrics collected at startup go out with the first payload;
+	// otherwise check if they need refreshing
+	a.mu.Lock()
+	staticMetrics := a.startupStatic
+	a.startupStatic = nil
+	a.mu.Unlock()
+	if staticMetrics == nil && a.staticCollector.ShouldRefresh() {
 		if a.debug {
 			log.Printf("INFO: Refreshing static metrics")
 		}
-		static, err := a.staticCollector.Collect(opCtx)
+		static, err := a.staticCollector.Collect(ctx)
 		if err != nil {
 			log.Printf("ERROR: Failed to collect static metrics: %v", err)
 		} else {
@@ -166,20 +489,85 @@ func (a *Agent) collectAndSend(ctx context.Context) {
 		}
 	}
 
+	// Skip optional collectors while the agent itself is over budget
+	usage := a.telemetry.collect()
+	a.mu.RLock()
+	budget := a.budget
+	a.mu.RUnlock()
+	usage.Shedding = budget.shed(usage)
+	a.dynamicCollector.Shed(usage.Shedding)
+
 	// Always collect dynamic metrics
-	dynamicMetrics, err := a.dynamicCollector.Collect(opCtx)
+	dynamicMetrics, err := a.dynamicCollector.Collect(ctx)
 	if err != nil {
 		log.Printf("ERROR: Failed to collect dynamic metrics: %v", err)
 		a.incrementErrorCount()
 		return
 	}
+	usage.Collectors = failingCollectors(append(a.staticCollector.Health(), a.dynamicCollector.Health()...))
+	if staticMetrics != nil {
+		a.mu.RLock()
+		usage.Capabilities = a.permissions
+		a.mu.RUnlock()
+	}
 
 	// Create payload
 	payload := &models.MetricPayload{
+		ID:             newPayloadID(),
+		Sequence:       a.sequencer.next(),
 		Hostname:       a.hostname,
 		Timestamp:      time.Now(),
 		StaticMetrics:  staticMetrics, // nil if not refreshed
 		DynamicMetrics: dynamicMetrics,
+		Labels:         a.staticCollector.Labels(),
+		Maintenance:    a.maintenance(),
+		Agent:          usage,
+	}
+
+	// Attach a finished port report once
+	a.mu.Lock()
+	payload.PortReport, a.portReport = a.portReport, nil
+	alerts := a.alerts
+	a.mu.Unlock()
+
+	payload.Alerts = alerts.evaluate(payload)
+
+	// Deliver to embedding application
+	a.mu.RLock()
+	handlers := a.handlers
+	sendEnabled := a.sendEnabled
+	a.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(payload)
+	}
+
+	if !sendEnabled {
+		a.mu.Lock()
+		a.lastCollection = payload.Timestamp
+		a.lastPayload = payload
+		a.metricsCount++
+		a.mu.Unlock()
+		return
+	}
+
+	// Split oversized sections across consecutive payloads
+	payload = a.chunker.apply(payload)
+	a.telemetry.recordPayload(payload)
+
+	a.mu.Lock()
+	a.lastPayload = payload
+	a.mu.Unlock()
+
+	// Dry run: show exactly what would be sent
+	if a.dryRun {
+		if err := json.NewEncoder(os.Stdout).Encode(payload); err != nil {
+			log.Printf("ERROR: %v - %s", err, "Failed to print payload")
+		}
+		a.mu.Lock()
+		a.lastCollection = payload.Timestamp
+		a.metricsCount++
+		a.mu.Unlock()
+		return
 	}
 
 	// Debug mode - log detailed payload
@@ -194,41 +582,123 @@ func (a *Agent) collectAndSend(ctx context.Context) {
 				memUsage = dynamicMetrics.Memory.UsedPercent
 			}
 		}
-		log.Printf("DEBUG: Sending metrics [hostname=%s static=%v cpu=%.1f%% mem=%.1f%%]",
-			payload.Hostname, staticMetrics != nil, cpuUsage, memUsage)
+		log.Printf("DEBUG: Sending metrics [hostname=%s static=%v cpu=%.1f%% mem=%.1f%% chunks=%d pending_chunks=%d]",
+			payload.Hostname, staticMetrics != nil, cpuUsage, memUsage, len(payload.Chunks), a.chunker.pendingChunks())
+	}
+
+	// Batching mode: hold payloads until a full batch is collected
+	payloads := []*models.MetricPayload{payload}
+	if a.batchSize > 1 {
+		a.mu.Lock()
+		a.batch = append(a.batch, payload)
+		if len(a.batch) < a.batchSize {
+			a.lastCollection = payload.Timestamp
+			a.mu.Unlock()
+			return
+		}
+		payloads, a.batch = a.batch, nil
+		a.mu.Unlock()
+	}
+
+	// Server asked us to back off: hold payloads until Retry-After has passed
+	if a.isThrottled() {
+		if a.composite != nil {
+			a.composite.Mirror(payloads)
+		}
+		a.spoolPayloads(payloads)
+		if a.debug {
+			log.Printf("DEBUG: Skipping send while throttled [until=%s]", a.throttleDeadline().Format(time.RFC3339))
+		}
+		return
+	}
+
+	// Keep delivery in order while older payloads are waiting in the spool
+	if a.spool != nil && a.spool.Len() > 0 {
+		if a.composite != nil {
+			a.composite.Mirror(payloads) // Secondary destinations are not behind
+		}
+		a.spoolPayloads(payloads)
+		a.replaySpool(ctx)
+		return
 	}
 
 	// Send to server
-	serverResp, err := a.sender.Send(opCtx, payload)
+	sendStart := time.Now()
+	sendCtx, cancel := context.WithTimeout(ctx, config.GetTimeout())
+	serverResp, err := a.send(sendCtx, a.sender, payloads)
+	cancel()
 	if err != nil {
 		// Check if this is an authentication error
 		if errors.Is(err, sender.ErrUnauthorized) {
-			log.Printf("ERROR: Authentication failed - token invalid/expired")
-			log.Printf("ERROR: Please login again: sudo monify login")
-
-			// Mark auth as failed
 			a.mu.Lock()
-			a.authFailed = true
+			a.sendFailing = true
 			a.mu.Unlock()
 
+			// Keep the payloads in case the rejection is transient
+			a.authRejected(err)
+			a.spoolPayloads(payloads)
 			return
 		}
 
-		log.Printf("ERROR: Failed to send metrics: %v", err)
-		a.incrementErrorCount()
+		a.mu.Lock()
+		a.sendFailing = true
+		a.mu.Unlock()
+
+		if errors.Is(err, sender.ErrCircuitOpen) {
+			// Logged by the breaker on state changes only
+			if a.debug {
+				log.Printf("DEBUG: Circuit open, spooling payloads")
+			}
+		} else {
+			log.Printf("ERROR: Failed to send metrics: %v", err)
+			a.incrementErrorCount()
+			a.throttle(err)
+		}
+
+		// Keep the payloads for replay once the server is reachable again
+		if sender.IsRetryable(err) {
+			a.spoolPayloads(payloads)
+		}
 		return
 	}
 
+	a.telemetry.recordSend(time.Since(sendStart))
+	a.handleSent(ctx, payloads, serverResp)
+}
+
+// send delivers one payload, or several as a single batch request
+func (a *Agent) send(ctx context.Context, s sender.Sender, payloads []*models.MetricPayload) (*models.ServerResponse, error) {
+	if len(payloads) == 1 {
+		return s.Send(ctx, payloads[0])
+	}
+	return s.SendBatch(ctx, payloads)
+}
+
+// handleSent records delivered payloads and acts on the server response
+func (a *Agent) handleSent(ctx context.Context, payloads []*models.MetricPayload, serverResp *models.ServerResponse) {
+	for _, payload := range payloads {
+		a.staticCollector.MarkSent(payload.StaticMetrics)
+	}
+
 	// Update stats (single lock)
 	now := time.Now()
 	a.mu.Lock()
 	a.lastCollection = now
 	a.lastSend = now
-	a.metricsCount++
+	a.sendFailing = false
+	a.metricsCount += uint64(len(payloads))
 	a.mu.Unlock()
+	a.authAccepted()
 
 	if a.debug {
-		log.Printf("DEBUG: Metrics sent successfully")
+		log.Printf("DEBUG: Metrics sent successfully [payloads=%d]", len(payloads))
+	}
+
+	// Track token scopes reported by the server
+	if serverResp != nil && len(serverResp.Scopes) > 0 {
+		a.mu.Lock()
+		a.tokenScopes = serverResp.Scopes
+		a.mu.Unlock()
 	}
 
 	// Process server commands if any
@@ -237,6 +707,137 @@ func (a *Agent) collectAndSend(ctx context.Context) {
 	}
 }
 
+// markAuthFailed stops further sends after the server rejected the token
+func (a *Agent) markAuthFailed(err error) {
+	log.Printf("ERROR: Authentication failed - token invalid/expired [error=%v]", err)
+	log.Printf("ERROR: Please login again: sudo monify login")
+
+	a.mu.Lock()
+	a.authFailed = true
+	a.mu.Unlock()
+}
+
+// throttle pauses sending when err carries a Retry-After from the server
+func (a *Agent) throttle(err error) {
+	retryAfter, ok := sender.RetryAfter(err)
+	if !ok {
+		return
+	}
+
+	until := time.Now().Add(retryAfter)
+	log.Printf("WARN: Server is rate limiting, pausing sends [retry_after=%s until=%s]", retryAfter, until.Format(time.RFC3339))
+
+	a.mu.Lock()
+	a.throttledUntil = until
+	a.mu.Unlock()
+}
+
+// isThrottled reports whether sends are paused by a Retry-After
+func (a *Agent) isThrottled() bool {
+	return time.Now().Before(a.throttleDeadline())
+}
+
+// throttleDeadline returns when the current Retry-After pause ends
+func (a *Agent) throttleDeadline() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.throttledUntil
+}
+
+// spoolPayloads persists payloads that could not be sent
+func (a *Agent) spoolPayloads(payloads []*models.MetricPayload) {
+	if a.spool == nil {
+		return
+	}
+
+	for _, payload := range payloads {
+		if err := a.spool.Push(payload); err != nil {
+			log.Printf("ERROR: Failed to spool payload: %v", err)
+			return
+		}
+	}
+
+	if a.debug {
+		log.Printf("DEBUG: Payloads spooled [spooled=%d]", a.spool.Len())
+	}
+}
+
+// replaySpool sends spooled payloads oldest first in batches, stopping at the
+// first transient failure. At most config.SpoolReplayRequests batches are sent
+// per call so a long backlog does not delay collection.
+func (a *Agent) replaySpool(ctx context.Context) {
+	for i := 0; i < config.SpoolReplayRequests; i++ {
+		payloads, ids, err := a.spool.Peek(config.SpoolReplayBatch)
+		if err != nil {
+			log.Printf("WARN: %v", err)
+		}
+		if len(payloads) == 0 {
+			break
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, config.GetTimeout())
+		serverResp, err := a.send(sendCtx, a.primary, payloads)
+		cancel()
+		if err != nil {
+			if errors.Is(err, sender.ErrUnauthorized) {
+				a.authRejected(err)
+				return
+			}
+			if sender.IsRetryable(err) {
+				if a.debug {
+					log.Printf("DEBUG: Server still unreachable, keeping spool [spooled=%d error=%v]", a.spool.Len(), err)
+				}
+				a.incrementErrorCount()
+				a.throttle(err)
+				return
+			}
+
+			// The server rejected the batch: retry one by one so a single
+			// bad payload does not take the others down with it
+			if len(payloads) > 1 {
+				a.replayIndividually(ctx, payloads, ids)
+				continue
+			}
+
+			log.Printf("WARN: Dropping spooled payload rejected by server [timestamp=%s error=%v]",
+				payloads[0].Timestamp.Format(time.RFC3339), err)
+			a.spool.Remove(ids...)
+			continue
+		}
+
+		a.spool.Remove(ids...)
+		a.handleSent(ctx, payloads, serverResp)
+	}
+
+	if remaining := a.spool.Len(); remaining > 0 {
+		log.Printf("INFO: Replaying spooled payloads [remaining=%d]", remaining)
+	} else {
+		log.Printf("INFO: Spool drained, all buffered payloads delivered")
+	}
+}
+
+// replayIndividually sends spooled payloads one per request, dropping those
+// the server rejects. Payloads hitting a transient failure stay spooled.
+func (a *Agent) replayIndividually(ctx context.Context, payloads []*models.MetricPayload, ids []string) {
+	for i, payload := range payloads {
+		sendCtx, cancel := context.WithTimeout(ctx, config.GetTimeout())
+		serverResp, err := a.primary.Send(sendCtx, payload)
+		cancel()
+		if err != nil {
+			if sender.IsRetryable(err) || errors.Is(err, sender.ErrUnauthorized) {
+				return
+			}
+			log.Printf("WARN: Dropping spooled payload rejected by server [timestamp=%s error=%v]",
+				payload.Timestamp.Format(time.RFC3339), err)
+			a.spool.Remove(ids[i])
+			continue
+		}
+
+		a.spool.Remove(ids[i])
+		a.handleSent(ctx, []*models.MetricPayload{payload}, serverResp)
+	}
+}
+
 // Stop stops the agent gracefully
 func (a *Agent) Stop() error {
 	a.mu.Lock()
@@ -247,9 +848,16 @@ func (a *Agent) Stop() error {
 	}
 
 	log.Printf("INFO: %s", "Stopping agent")
+	sdNotify("STOPPING=1")
 	close(a.stopChan)
 	a.running = false
 
+	// Keep a partially collected batch for replay after restart
+	if len(a.batch) > 0 {
+		a.spoolPayloads(a.batch)
+		a.batch = nil
+	}
+
 	// Stop dynamic collectors
 	a.dynamicCollector.Stop()
 
@@ -261,6 +869,34 @@ func (a *Agent) Stop() error {
 	return nil
 }
 
+// prepareRestart replaces what the previous run's Stop closed: the stop
+// channel, the senders and the background samplers. Requests left over from
+// the previous run are dropped. Called with a.mu held.
+func (a *Agent) prepareRestart() error {
+	senders, err := newSenderSet(a.serverURL, a.token)
+	if err != nil {
+		return err
+	}
+	if a.senderSet.heartbeat != nil {
+		a.senderSet.heartbeat.Close()
+	}
+	a.senderSet = senders
+	a.dynamicCollector = NewDynamicCollector()
+	a.stopChan = make(chan struct{})
+	a.highResUntil = time.Time{}
+
+	for drained := false; !drained; {
+		select {
+		case <-a.restartChan:
+		case <-a.reloadChan:
+		case <-a.highResChan:
+		default:
+			drained = true
+		}
+	}
+	return nil
+}
+
 // GetStatus returns the current status of the agent
 func (a *Agent) GetStatus() *models.AgentStatus {
 	a.mu.RLock()
@@ -271,6 +907,28 @@ func (a *Agent) GetStatus() *models.AgentStatus {
 		status = "running"
 	}
 
+	spooled := 0
+	if a.spool != nil {
+		spooled = a.spool.Len()
+	}
+
+	circuit := ""
+	if a.breaker != nil {
+		circuit = a.breaker.State()
+	}
+
+	var throttledUntil *time.Time
+	if time.Now().Before(a.throttledUntil) {
+		until := a.throttledUntil
+		throttledUntil = &until
+	}
+
+	var highResUntil *time.Time
+	if !a.highResUntil.IsZero() {
+		until := a.highResUntil
+		highResUntil = &until
+	}
+
 	uptime := uint64(0)
 	if !a.startTime.IsZero() {
 		uptime = uint64(time.Since(a.startTime).Seconds())
@@ -285,12 +943,94 @@ func (a *Agent) GetStatus() *models.AgentStatus {
 		MetricsCount:   a.metricsCount,
 		ErrorCount:     a.errorCount,
 		Status:         status,
+		ReadOnly:       !a.commandsAllowedLocked(),
+		Spooled:        spooled,
+		ThrottledUntil: throttledUntil,
+		Circuit:        circuit,
+		HighResUntil:   highResUntil,
+		Maintenance:    CurrentMaintenance(),
+		Alerts:         a.alerts.firing(),
+
+		ServerCapabilities: a.serverFeatures,
+		LastPayload:        summarize(a.lastPayload),
+		Collectors:         append(a.staticCollector.Health(), a.dynamicCollector.Health()...),
+	}
+}
+
+// commandsAllowed reports whether server commands may be executed
+func (a *Agent) commandsAllowed() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.commandsAllowedLocked()
+}
+
+// commandsAllowedLocked reports whether server commands may be executed.
+// Caller must hold a.mu. Commands are refused when local read-only mode is
+// enabled, or when the server reports scopes that do not include commands.
+func (a *Agent) commandsAllowedLocked() bool {
+	if a.readOnly {
+		return false
+	}
+
+	// No scopes reported: server predates token scoping
+	if len(a.tokenScopes) == 0 {
+		return true
+	}
+
+	for _, scope := range a.tokenScopes {
+		if scope == models.ScopeCommands {
+			return true
+		}
+	}
+	return false
+}
+
+// runCommandStream executes commands pushed by the server until ctx is cancelled.
+// Commands in metrics responses keep working if the stream is unavailable.
+func (a *Agent) runCommandStream(ctx context.Context, stream *sender.CommandStream) {
+	err := stream.Run(ctx, func(commands []models.ServerCommand) {
+		a.processServerCommands(ctx, commands)
+	})
+	stream.Close()
+
+	switch {
+	case errors.Is(err, sender.ErrUnauthorized):
+		a.authRejected(err)
+	case errors.Is(err, sender.ErrStreamUnsupported):
+		log.Printf("INFO: Server does not offer a command stream, commands arrive with metrics responses")
 	}
 }
 
 // processServerCommands processes commands received from server
 func (a *Agent) processServerCommands(ctx context.Context, commands []models.ServerCommand) {
+	// Read-only enforcement: never execute anything locally
+	if !a.commandsAllowed() {
+		for _, cmd := range commands {
+			log.Printf("WARN: Refusing server command in read-only mode [command=%s]", cmd.Command)
+			auditRefusal(cmd, fmt.Errorf("read-only mode"))
+		}
+		return
+	}
+
+	a.mu.RLock()
+	policy, hostname := a.commandPolicy, a.hostname
+	a.mu.RUnlock()
+
 	for _, cmd := range commands {
+		err := policy.authorize(cmd, hostname)
+		if err == nil && cmd.Command == "uninstall" && os.Geteuid() != 0 {
+			err = fmt.Errorf("uninstall requires root, the agent dropped its privileges")
+		}
+		if err != nil {
+			log.Printf("WARN: Refusing server command: %v [command=%s id=%s]", err, cmd.Command, cmd.ID)
+			auditRefusal(cmd, err)
+			continue
+		}
+		if err := auditCommand(cmd, nil); err != nil {
+			log.Printf("ERROR: Refusing server command that cannot be audited: %v [command=%s id=%s]", err, cmd.Command, cmd.ID)
+			continue
+		}
+
 		if a.debug {
 			log.Printf("INFO: Processing server command [command=%s]", cmd.Command)
 		}
@@ -304,9 +1044,37 @@ func (a *Agent) processServerCommands(ctx context.Context, commands []models.Ser
 			log.Printf("WARN: Received uninstall command [reason=%s]", reason)
 			go func() {
 				time.Sleep(2 * time.Second)
-				a.runUninstallScript()
+				a.uninstall()
 			}()
 
+		case "diagnostics":
+			requestID, _ := cmd.Params["id"].(string)
+			a.startDiagnostics(ctx, requestID)
+
+		case "update_config":
+			a.updateConfig(cmd.Params)
+
+		case "scan_ports":
+			a.startPortScan(ctx, cmd.Params)
+
+		case "high_resolution":
+			a.requestHighResolution(cmd.Params)
+
+		case "pause":
+			a.pause(cmd.Params)
+
+		case "resume":
+			if err := Resume(); err != nil {
+				log.Printf("ERROR: %v - %s", err, "Failed to end maintenance window")
+			}
+
+		case "restart":
+			reason := "Requested by server"
+			if r, ok := cmd.Params["reason"].(string); ok {
+				reason = r
+			}
+			a.requestRestart(reason)
+
 		default:
 			if a.debug {
 				log.Printf("DEBUG: Ignoring unsupported command [command=%s]", cmd.Command)
@@ -315,10 +1083,48 @@ func (a *Agent) processServerCommands(ctx context.Context, commands []models.Ser
 	}
 }
 
-// runUninstallScript executes the uninstall script to remove the agent
-func (a *Agent) runUninstallScript() {
-	log.Printf("INFO: Executing uninstall script")
-	exec.Command("bash", "-c", "curl -sSL https://monify.cloud/uninstall.sh | sudo bash").Start()
+// requestRestart asks the collection loop to stop and return ErrRestartRequested.
+// Restarts shortly after startup are ignored so a command the server repeats
+// cannot keep the agent in a restart loop.
+func (a *Agent) requestRestart(reason string) {
+	a.mu.RLock()
+	uptime := time.Since(a.startTime)
+	a.mu.RUnlock()
+
+	if uptime < config.RestartMinUptime {
+		log.Printf("WARN: Ignoring restart command, agent started recently [uptime=%s]", uptime.Round(time.Second))
+		return
+	}
+
+	log.Printf("WARN: Received restart command [reason=%s]", reason)
+	select {
+	case a.restartChan <- reason:
+	default: // Restart already pending
+	}
+}
+
+// uninstall removes the agent from the host. Under systemd the removal runs
+// in a transient unit that also stops the service; otherwise it runs here and
+// the agent stops itself.
+func (a *Agent) uninstall() {
+	if os.Getenv("INVOCATION_ID") != "" {
+		err := uninstall.Detach()
+		if err == nil {
+			log.Printf("INFO: Uninstall started [unit=monify-uninstall]")
+			return
+		}
+		log.Printf("WARN: %v - %s", err, "Cannot start uninstall unit, uninstalling in-process")
+	}
+
+	log.Printf("INFO: Uninstalling agent")
+	if err := uninstall.Run(false); err != nil {
+		log.Printf("ERROR: %v - %s", err, "Uninstall incomplete, finish with: "+uninstall.FallbackCommand)
+	} else {
+		log.Printf("INFO: Agent uninstalled")
+	}
+	if err := a.Stop(); err != nil {
+		log.Printf("ERROR: %v - %s", err, "Error during stop")
+	}
 }
 
 // incrementErrorCount increments the error counter
@@ -0,0 +1,313 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/sender"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// alertRulePattern matches `name{label="value",...} op threshold [for duration]`
+var alertRulePattern = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)\s*(?:\{([^}]*)\})?\s*(>=|<=|==|!=|>|<)\s*(\S+)(?:\s+for\s+(\S+))?$`)
+
+// alertMatcherPattern matches one label matcher of a rule
+var alertMatcherPattern = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*=\s*"([^"]*)"\s*$`)
+
+// alertRule is a threshold on a metric, with the names and labels of the
+// Prometheus endpoint (without the monify_ prefix)
+type alertRule struct {
+	text      string
+	metric    string
+	matchers  map[string]string // Labels the series must have
+	op        string
+	threshold float64
+	duration  time.Duration // How long the condition must hold before firing
+}
+
+// parseAlertRule parses a rule such as `disk_used_percent > 95 for 5m`
+func parseAlertRule(text string) (alertRule, error) {
+	m := alertRulePattern.FindStringSubmatch(text)
+	if m == nil {
+		return alertRule{}, fmt.Errorf("expected `metric{label=\"value\"} > threshold for duration`")
+	}
+
+	rule := alertRule{text: text, metric: m[1], matchers: map[string]string{}, op: m[3]}
+	if m[2] != "" {
+		for _, matcher := range strings.Split(m[2], ",") {
+			lm := alertMatcherPattern.FindStringSubmatch(matcher)
+			if lm == nil {
+				return alertRule{}, fmt.Errorf("invalid label matcher %q", strings.TrimSpace(matcher))
+			}
+			rule.matchers[lm[1]] = lm[2]
+		}
+	}
+
+	var err error
+	if rule.threshold, err = strconv.ParseFloat(m[4], 64); err != nil {
+		return alertRule{}, fmt.Errorf("invalid threshold %q", m[4])
+	}
+	if m[5] != "" {
+		if rule.duration, err = time.ParseDuration(m[5]); err != nil || rule.duration < 0 {
+			return alertRule{}, fmt.Errorf("invalid duration %q", m[5])
+		}
+	}
+	return rule, nil
+}
+
+// matches reports whether a series has all labels of the rule
+func (r alertRule) matches(labels [][2]string) bool {
+	for name, value := range r.matchers {
+		found := false
+		for _, label := range labels {
+			if label[0] == name && label[1] == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// holds reports whether value meets the rule's condition
+func (r alertRule) holds(value float64) bool {
+	switch r.op {
+	case ">":
+		return value > r.threshold
+	case ">=":
+		return value >= r.threshold
+	case "<":
+		return value < r.threshold
+	case "<=":
+		return value <= r.threshold
+	case "==":
+		return value == r.threshold
+	default: // "!="
+		return value != r.threshold
+	}
+}
+
+// alertState tracks one series of a rule while its condition holds
+type alertState struct {
+	rule   string
+	labels map[string]string
+	value  float64
+	since  time.Time
+	firing bool
+}
+
+// alertEvaluator evaluates local alert rules on every collection, so alerts
+// are raised and hooks run even while the server is unreachable
+type alertEvaluator struct {
+	rules []alertRule
+	hook  string // Empty when no hook is configured
+
+	mu     sync.Mutex
+	states map[string]*alertState // By rule and series
+}
+
+// newAlertEvaluator creates an evaluator for the configured rules. Invalid
+// rules are logged and skipped. The state of rules that are still configured
+// is taken over from previous, so a reload neither repeats nor loses alerts.
+func newAlertEvaluator(previous *alertEvaluator) *alertEvaluator {
+	e := &alertEvaluator{hook: config.GetAlertHook(), states: make(map[string]*alertState)}
+	for _, text := range config.GetAlertRules() {
+		rule, err := parseAlertRule(text)
+		if err != nil {
+			log.Printf("WARN: Ignoring alert rule: %v [rule=%s]", err, text)
+			continue
+		}
+		e.rules = append(e.rules, rule)
+	}
+
+	if previous != nil {
+		previous.mu.Lock()
+		for key, state := range previous.states {
+			for _, rule := range e.rules {
+				if rule.text == state.rule {
+					e.states[key] = state
+				}
+			}
+		}
+		previous.mu.Unlock()
+	}
+	return e
+}
+
+// evaluate updates the rules with a payload's metrics and returns the alerts
+// that started or stopped firing. Hooks are not run during maintenance.
+func (e *alertEvaluator) evaluate(payload *models.MetricPayload) []models.AlertEvent {
+	if len(e.rules) == 0 {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := payload.Timestamp
+	metrics := sender.PrometheusMetrics(payload)
+	seen := make(map[string]bool)
+	var events []models.AlertEvent
+
+	for _, rule := range e.rules {
+		for _, metric := range metrics {
+			if metric.Name != "monify_"+rule.metric || !rule.matches(metric.Labels) {
+				continue
+			}
+			key := rule.text + "\x00" + seriesKey(metric.Labels)
+			seen[key] = true
+			state := e.states[key]
+
+			if !rule.holds(metric.Value) {
+				if state != nil && state.firing {
+					state.value = metric.Value
+					events = append(events, state.event(models.AlertResolved, now))
+				}
+				delete(e.states, key)
+				continue
+			}
+
+			if state == nil {
+				state = &alertState{rule: rule.text, labels: labelMap(metric.Labels), since: now}
+				e.states[key] = state
+			}
+			state.value = metric.Value
+			if !state.firing && now.Sub(state.since) >= rule.duration {
+				state.firing = true
+				events = append(events, state.event(models.AlertFiring, now))
+			}
+		}
+	}
+
+	// A series that disappeared (e.g. an unmounted filesystem) resolves its alert
+	for key, state := range e.states {
+		if !seen[key] {
+			if state.firing {
+				events = append(events, state.event(models.AlertResolved, now))
+			}
+			delete(e.states, key)
+		}
+	}
+
+	for _, event := range events {
+		log.Printf("WARN: Alert %s [rule=%s labels=%s value=%g]", event.State, event.Rule, formatLabels(event.Labels), event.Value)
+		if e.hook != "" && payload.Maintenance == nil {
+			go runAlertHook(e.hook, event)
+		}
+	}
+	return events
+}
+
+// firing returns the alerts currently firing
+func (e *alertEvaluator) firing() []models.AlertEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var alerts []models.AlertEvent
+	for _, state := range e.states {
+		if state.firing {
+			alerts = append(alerts, state.event(models.AlertFiring, state.since))
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Since.Before(alerts[j].Since) })
+	return alerts
+}
+
+// event describes the state as an alert event
+func (s *alertState) event(state string, now time.Time) models.AlertEvent {
+	return models.AlertEvent{
+		Rule:      s.rule,
+		State:     state,
+		Labels:    s.labels,
+		Value:     s.value,
+		Since:     s.since,
+		Timestamp: now,
+	}
+}
+
+// runAlertHook runs the hook with the event in its environment. Like plugins,
+// the hook gets a minimal environment without the agent's token, and must be
+// owned by root (or the agent's user) and not writable by others.
+func runAlertHook(path string, event models.AlertEvent) {
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Printf("ERROR: %v - %s", err, "Failed to run alert hook")
+		return
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || (stat.Uid != 0 && stat.Uid != uint32(os.Geteuid())) || info.Mode().Perm()&0o022 != 0 {
+		log.Printf("ERROR: Refusing to run alert hook writable by others [path=%s]", path)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetAlertHookTimeout())
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = []string{
+		"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		"LANG=C",
+		"MONIFY_ALERT_RULE=" + event.Rule,
+		"MONIFY_ALERT_STATE=" + event.State,
+		"MONIFY_ALERT_LABELS=" + formatLabels(event.Labels),
+		"MONIFY_ALERT_VALUE=" + strconv.FormatFloat(event.Value, 'g', -1, 64),
+		"MONIFY_ALERT_SINCE=" + event.Since.Format(time.RFC3339),
+	}
+	cmd.Stderr = &stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		log.Printf("ERROR: Alert hook failed: %v [rule=%s stderr=%s]", err, event.Rule, strings.TrimSpace(stderr.String()))
+	}
+}
+
+// seriesKey identifies a series by its labels
+func seriesKey(labels [][2]string) string {
+	var b strings.Builder
+	for _, label := range labels {
+		b.WriteString(label[0] + "=" + label[1] + "\x00")
+	}
+	return b.String()
+}
+
+// labelMap converts label pairs to a map
+func labelMap(labels [][2]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(labels))
+	for _, label := range labels {
+		m[label[0]] = label[1]
+	}
+	return m
+}
+
+// formatLabels renders labels as sorted, comma-separated key=value pairs
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
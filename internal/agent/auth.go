@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+)
+
+// authRejected handles the server rejecting the token. A single rejection,
+// e.g. during a backend deploy, is not fatal: the agent keeps retrying and
+// picks up new credentials from the env file until the token has been
+// rejected AuthFailureThreshold times in a row over AuthFailureWindow.
+func (a *Agent) authRejected(err error) {
+	now := time.Now()
+	a.mu.Lock()
+	if a.authFailures == 0 {
+		a.authSince = now
+	}
+	a.authFailures++
+	failures, since, gaveUp := a.authFailures, a.authSince, a.authGaveUp
+	giveUp := failures >= config.GetAuthFailureThreshold() && now.Sub(since) >= config.GetAuthFailureWindow()
+	if giveUp {
+		a.authGaveUp = true
+	}
+	a.mu.Unlock()
+
+	switch {
+	case !giveUp:
+		log.Printf("WARN: Server rejected the token, retrying [failures=%d since=%s error=%v]", failures, since.Format(time.RFC3339), err)
+		a.refreshCredentials()
+	case config.IsAuthFailureExit():
+		a.markAuthFailed(err)
+	case !gaveUp:
+		log.Printf("ERROR: Token still rejected, retrying until it is accepted [failures=%d since=%s error=%v]", failures, since.Format(time.RFC3339), err)
+		log.Printf("ERROR: Please login again: sudo monify login")
+	default:
+		a.refreshCredentials()
+		if a.debug {
+			log.Printf("DEBUG: Token still rejected [failures=%d error=%v]", failures, err)
+		}
+	}
+}
+
+// authAccepted resets the rejection count after the server accepted the token
+func (a *Agent) authAccepted() {
+	a.mu.Lock()
+	failures := a.authFailures
+	a.authFailures = 0
+	a.authGaveUp = false
+	a.mu.Unlock()
+
+	if failures > 0 {
+		log.Printf("INFO: Token accepted again [failures=%d]", failures)
+	}
+}
+
+// refreshCredentials reloads the configuration when the credentials in the
+// env file differ from those in use, e.g. after "monify login". Short-lived
+// access tokens are already refreshed by the sender on each rejection.
+func (a *Agent) refreshCredentials() {
+	for _, key := range []string{"MONIFY_TOKEN", "MONIFY_REFRESH_TOKEN"} {
+		if value := config.EnvFileValue(key); value != "" && value != os.Getenv(key) {
+			log.Printf("INFO: Credentials changed in %s, reloading configuration", config.EnvFilePath)
+			select {
+			case a.reloadChan <- struct{}{}:
+			default: // A reload is already pending
+			}
+			return
+		}
+	}
+}
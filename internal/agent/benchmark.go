@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// benchmarkRun is the measurement of a single collector run
+type benchmarkRun struct {
+	elapsed    time.Duration
+	cpu        time.Duration
+	allocBytes uint64
+	allocs     uint64
+	err        string
+}
+
+// benchmark collects the runs of each collector
+type benchmark struct {
+	mu     sync.Mutex
+	runs   map[string][]benchmarkRun
+	static map[string]bool // Collectors of the static collector
+}
+
+// observer returns an observer that measures each run and takes its
+// outcome from health
+func (b *benchmark) observer(health *collectorHealth, static bool) observer {
+	return func(name string, run func()) {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		cpu := processCPUTime()
+		start := time.Now()
+
+		run()
+
+		measured := benchmarkRun{elapsed: time.Since(start), cpu: processCPUTime() - cpu}
+		runtime.ReadMemStats(&after)
+		measured.allocBytes = after.TotalAlloc - before.TotalAlloc
+		measured.allocs = after.Mallocs - before.Mallocs
+		health.mu.Lock()
+		if result, ok := health.results[name]; ok && !result.OK {
+			measured.err = result.Error
+		}
+		health.mu.Unlock()
+
+		b.mu.Lock()
+		b.runs[name] = append(b.runs[name], measured)
+		b.static[name] = static
+		b.mu.Unlock()
+	}
+}
+
+// Benchmark runs every enabled collector iterations times and measures the
+// latency, CPU time and allocations of each run, for monify benchmark.
+// Collectors run one at a time so that the process-wide counters can be
+// attributed to them; the agent normally runs them in parallel. Collectors
+// that work in the background (directories, plugins) are covered by the
+// sampler CPU usage. Nothing is sent or written.
+func Benchmark(ctx context.Context, iterations int, withStatic bool) (*models.BenchmarkReport, error) {
+	b := &benchmark{runs: make(map[string][]benchmarkRun), static: make(map[string]bool)}
+
+	staticCollector := NewStaticCollector()
+	staticCollector.baselinePath = ""
+	staticCollector.observe = b.observer(&staticCollector.health, true)
+	dynamicCollector := NewDynamicCollector()
+	dynamicCollector.intervals = nil // Every collector runs in each iteration
+	dynamicCollector.health.quarantineAfter = 0
+	dynamicCollector.observe = b.observer(&dynamicCollector.health, false)
+	dynamicCollector.Start()
+	defer dynamicCollector.Stop()
+
+	// The samplers need a window before the first collection, which also
+	// shows what they cost on their own
+	cpu, start := processCPUTime(), time.Now()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(config.CollectOnceSampling):
+	}
+	report := &models.BenchmarkReport{
+		Iterations:        iterations,
+		SamplerCPUPercent: float64(processCPUTime()-cpu) / float64(time.Since(start)) * 100,
+	}
+
+	for i := 0; i < iterations; i++ {
+		if withStatic {
+			if _, err := staticCollector.Collect(ctx); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := dynamicCollector.Collect(ctx); err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	report.RSSBytes = residentBytes()
+	report.HeapBytes = mem.HeapAlloc
+	for name, runs := range b.runs {
+		result := summarizeRuns(name, runs)
+		result.Static = b.static[name]
+		report.Collectors = append(report.Collectors, result)
+	}
+	sort.Slice(report.Collectors, func(i, j int) bool { return report.Collectors[i].Name < report.Collectors[j].Name })
+	return report, nil
+}
+
+// summarizeRuns averages the runs of a collector
+func summarizeRuns(name string, runs []benchmarkRun) models.CollectorBenchmark {
+	result := models.CollectorBenchmark{Name: name, Runs: len(runs)}
+	elapsed := make([]time.Duration, 0, len(runs))
+	var total, cpu time.Duration
+	var allocBytes, allocs uint64
+	for _, run := range runs {
+		elapsed = append(elapsed, run.elapsed)
+		total += run.elapsed
+		cpu += run.cpu
+		allocBytes += run.allocBytes
+		allocs += run.allocs
+		if run.err != "" {
+			result.Failures++
+			result.Error = run.err
+		}
+	}
+	sort.Slice(elapsed, func(i, j int) bool { return elapsed[i] < elapsed[j] })
+
+	n := time.Duration(len(runs))
+	result.MeanMs = milliseconds(total / n)
+	result.P95Ms = milliseconds(elapsed[(len(elapsed)*95+99)/100-1])
+	result.MaxMs = milliseconds(elapsed[len(elapsed)-1])
+	result.CPUMs = milliseconds(cpu / n)
+	result.AllocBytes = allocBytes / uint64(len(runs))
+	result.Allocs = allocs / uint64(len(runs))
+	return result
+}
+
+// milliseconds converts d to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// Chunked section names
+const (
+	sectionStaticDisks      = "static_info.disks"
+	sectionStaticInterfaces = "static_info.interfaces"
+	sectionManagedProcesses = "metrics.managed_processes"
+)
+
+// chunkedSection holds the chunks of a section that are still to be sent
+type chunkedSection struct {
+	snapshot int64
+	chunks   []json.RawMessage
+	next     int
+}
+
+// payloadChunker splits list sections that are too large for one payload
+// into chunks carried by consecutive payloads, one chunk per section each time.
+type payloadChunker struct {
+	maxItems int
+
+	mu      sync.Mutex
+	pending map[string]*chunkedSection
+}
+
+// newPayloadChunker creates a chunker splitting sections above maxItems entries
+func newPayloadChunker(maxItems int) *payloadChunker {
+	return &payloadChunker{
+		maxItems: maxItems,
+		pending:  make(map[string]*chunkedSection),
+	}
+}
+
+// apply returns a copy of the payload with oversized sections moved out and the
+// next pending chunks attached. The original payload is left untouched.
+func (c *payloadChunker) apply(original *models.MetricPayload) *models.MetricPayload {
+	if c.maxItems <= 0 {
+		return original
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	payload := *original
+	snapshot := payload.Timestamp.UnixMilli()
+
+	// Static inventory: a newer snapshot replaces any unfinished one
+	if payload.StaticMetrics != nil {
+		static := *payload.StaticMetrics
+		if len(static.Disks) > c.maxItems {
+			c.start(sectionStaticDisks, snapshot, splitChunks(static.Disks, c.maxItems), true)
+			static.Disks = nil
+		}
+		if len(static.Interfaces) > c.maxItems {
+			c.start(sectionStaticInterfaces, snapshot, splitChunks(static.Interfaces, c.maxItems), true)
+			static.Interfaces = nil
+		}
+		payload.StaticMetrics = &static
+	}
+
+	// Dynamic lists: finish the current snapshot before starting another
+	if payload.DynamicMetrics != nil && len(payload.DynamicMetrics.ManagedProcesses) > c.maxItems {
+		dyn := *payload.DynamicMetrics
+		c.start(sectionManagedProcesses, snapshot, splitChunks(dyn.ManagedProcesses, c.maxItems), false)
+		dyn.ManagedProcesses = nil
+		payload.DynamicMetrics = &dyn
+	}
+
+	// Attach one chunk per pending section, in stable order
+	sections := make([]string, 0, len(c.pending))
+	for section := range c.pending {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	for _, section := range sections {
+		pending := c.pending[section]
+		payload.Chunks = append(payload.Chunks, models.PayloadChunk{
+			Section:  section,
+			Snapshot: pending.snapshot,
+			Index:    pending.next,
+			Total:    len(pending.chunks),
+			Items:    pending.chunks[pending.next],
+		})
+
+		pending.next++
+		if pending.next >= len(pending.chunks) {
+			delete(c.pending, section)
+		}
+	}
+
+	return &payload
+}
+
+// start queues the chunks of a new section snapshot
+func (c *payloadChunker) start(section string, snapshot int64, chunks []json.RawMessage, replace bool) {
+	if _, exists := c.pending[section]; exists && !replace {
+		return
+	}
+	if len(chunks) == 0 {
+		return
+	}
+
+	c.pending[section] = &chunkedSection{
+		snapshot: snapshot,
+		chunks:   chunks,
+	}
+}
+
+// splitChunks marshals items into JSON arrays of at most size entries
+func splitChunks[T any](items []T, size int) []json.RawMessage {
+	var chunks []json.RawMessage
+	for start := 0; start < len(items); start += size {
+		end := min(start+size, len(items))
+		data, err := json.Marshal(items[start:end])
+		if err != nil {
+			return nil
+		}
+		chunks = append(chunks, data)
+	}
+	return chunks
+}
+
+// pendingChunks returns how many chunks are waiting to be sent
+func (c *payloadChunker) pendingChunks() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := 0
+	for _, pending := range c.pending {
+		count += len(pending.chunks) - pending.next
+	}
+	return count
+}
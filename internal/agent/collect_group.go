@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// collectFunc runs one collector and returns a function that stores its
+// result. store is only called while the collection is still waiting for it.
+type collectFunc func(ctx context.Context) (store func(), err error)
+
+// collectGroup runs collectors in parallel, each with its own deadline, so a
+// collector that hangs (e.g. statfs on a dead NFS server) is given up on
+// without holding back the others or the send
+type collectGroup struct {
+	ctx     context.Context
+	timeout time.Duration // Per-collector deadline
+	health  *collectorHealth
+	skip    map[string]bool // Collectors that are not due this collection
+	skipped []string        // Collectors passed to Go that were not due
+	observe observer        // Runs collectors one at a time when set
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// observer runs a collector, given as run, and measures it. run returns once
+// the collector finished or missed its deadline.
+type observer func(name string, run func())
+
+// newCollectGroup creates a group whose collectors stop at the earlier of
+// ctx's deadline and timeout
+func newCollectGroup(ctx context.Context, timeout time.Duration, health *collectorHealth) *collectGroup {
+	return &collectGroup{ctx: ctx, timeout: timeout, health: health}
+}
+
+// Go runs the named collector unless it is not due, disabled or
+// quarantined. A collector that misses its deadline is recorded as failed
+// and its result is dropped when it eventually returns. With observe set,
+// Go returns only once the collector is done.
+func (g *collectGroup) Go(name string, collect collectFunc) {
+	if g.skip[name] {
+		g.skipped = append(g.skipped, name)
+		return
+	}
+	if !g.health.allow(name) {
+		return
+	}
+
+	run := func() {
+		ctx, cancel := context.WithTimeout(g.ctx, g.timeout)
+		defer cancel()
+
+		type outcome struct {
+			store func()
+			err   error
+		}
+		done := make(chan outcome, 1) // Buffered so an abandoned collector can still finish
+		start := time.Now()
+		go func() {
+			store, err := collect(ctx)
+			done <- outcome{store, err}
+		}()
+
+		select {
+		case result := <-done:
+			if result.err == nil && result.store != nil {
+				g.mu.Lock()
+				result.store()
+				g.mu.Unlock()
+			}
+			g.health.record(name, result.err)
+		case <-ctx.Done():
+			g.health.record(name, fmt.Errorf("timed out after %s", time.Since(start).Round(time.Millisecond)))
+		}
+	}
+
+	if g.observe != nil {
+		g.observe(name, run)
+		return
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		run()
+	}()
+}
+
+// Wait blocks until every collector has finished or missed its deadline
+func (g *collectGroup) Wait() {
+	g.wg.Wait()
+}
+
+// store runs fn with the group's results lock held, for results gathered
+// outside of Go
+func (g *collectGroup) store(fn func()) {
+	g.mu.Lock()
+	fn()
+	g.mu.Unlock()
+}
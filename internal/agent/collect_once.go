@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// CollectOnce runs a single collection without the daemon loop and returns
+// the payload it would send. The background samplers run for
+// CollectOnceSampling first so averages and rates are meaningful. No state
+// is written: the payload sequence and inventory baseline are left alone.
+func CollectOnce(ctx context.Context, withStatic bool) (*models.MetricPayload, error) {
+	payload, _, err := collectOnce(ctx, withStatic)
+	return payload, err
+}
+
+// collectOnce implements CollectOnce and also returns the health of every
+// collector that ran
+func collectOnce(ctx context.Context, withStatic bool) (*models.MetricPayload, []models.CollectorHealth, error) {
+	var telemetry selfTelemetry
+	telemetry.collect() // Baseline for the agent's own CPU usage
+
+	staticCollector := NewStaticCollector()
+	staticCollector.baselinePath = ""
+	dynamicCollector := NewDynamicCollector()
+	dynamicCollector.Start()
+	defer dynamicCollector.Stop()
+
+	payload := &models.MetricPayload{ID: newPayloadID()}
+	if withStatic {
+		staticMetrics, err := staticCollector.Collect(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		payload.StaticMetrics = staticMetrics
+		payload.Hostname = staticMetrics.Hostname
+	} else {
+		payload.Hostname, _ = os.Hostname()
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-time.After(config.CollectOnceSampling):
+	}
+
+	dynamicMetrics, err := dynamicCollector.Collect(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload.DynamicMetrics = dynamicMetrics
+	payload.Timestamp = time.Now()
+	payload.Labels = staticCollector.Labels()
+	payload.Agent = telemetry.collect()
+	health := append(staticCollector.Health(), dynamicCollector.Health()...)
+	payload.Agent.Collectors = failingCollectors(health)
+	if withStatic {
+		payload.Agent.Capabilities = detectPermissions()
+	}
+	return payload, health, nil
+}
@@ -0,0 +1,266 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// commandPolicy decides which server commands may run: they must be in the
+// local allowlist and correctly signed, unless unsigned commands were
+// explicitly allowed
+type commandPolicy struct {
+	allowed       map[string]bool
+	publicKey     ed25519.PublicKey // nil when no key is pinned
+	allowUnsigned bool              // MONIFY_ALLOW_UNSIGNED_COMMANDS opt-out
+	seen          *seenCommands     // Kept across configuration reloads
+}
+
+// seenCommands remembers the IDs of executed signed commands until they expire
+type seenCommands struct {
+	mu  sync.Mutex
+	ids map[string]time.Time
+}
+
+// add records id and reports false if it was already recorded
+func (s *seenCommands) add(id string, expires time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for seen, until := range s.ids {
+		if !now.Before(until) {
+			delete(s.ids, seen)
+		}
+	}
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+	s.ids[id] = expires
+	return true
+}
+
+// newCommandPolicy creates the policy from the current configuration. seen
+// is shared with the previous policy on reload; nil starts empty.
+func newCommandPolicy(seen *seenCommands) (*commandPolicy, error) {
+	if seen == nil {
+		seen = &seenCommands{ids: make(map[string]time.Time)}
+	}
+	p := &commandPolicy{
+		allowed:       make(map[string]bool),
+		allowUnsigned: config.IsUnsignedCommandsAllowed(),
+		seen:          seen,
+	}
+	for _, command := range config.GetCommandAllowlist() {
+		p.allowed[command] = true
+	}
+
+	if value := config.GetCommandPublicKey(); value != "" {
+		key, err := parsePublicKey(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command public key: %w", err)
+		}
+		p.publicKey = key
+	}
+	return p, nil
+}
+
+// parsePublicKey reads an Ed25519 public key, given base64-encoded or as the
+// path of a PEM file
+func parsePublicKey(value string) (ed25519.PublicKey, error) {
+	if !strings.HasPrefix(value, "/") {
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("expected %d base64-encoded bytes", ed25519.PublicKeySize)
+		}
+		return ed25519.PublicKey(raw), nil
+	}
+
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", value)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", value)
+	}
+	return key, nil
+}
+
+// authorize returns why cmd must not run, or nil if it may
+func (p *commandPolicy) authorize(cmd models.ServerCommand, hostname string) error {
+	if !p.allowed[cmd.Command] {
+		return fmt.Errorf("command not in allowlist")
+	}
+	if cmd.Signature == "" && p.allowUnsigned {
+		return nil
+	}
+	if p.publicKey == nil {
+		return fmt.Errorf("no command public key pinned and unsigned commands are not allowed")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(cmd.Signature)
+	if err != nil || cmd.Signature == "" {
+		return fmt.Errorf("missing or malformed signature")
+	}
+	message, err := signedMessage(cmd.Raw)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(p.publicKey, message, signature) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	// A valid signature alone does not stop a captured command from being
+	// replayed, on this host or another one
+	now := time.Now()
+	expires := time.Unix(cmd.Expires, 0)
+	switch {
+	case cmd.ID == "":
+		return fmt.Errorf("signed command has no id")
+	case !now.Before(expires):
+		return fmt.Errorf("command expired")
+	case expires.Sub(now) > config.CommandMaxValidity:
+		return fmt.Errorf("command expires too far in the future")
+	case cmd.Host != "" && cmd.Host != hostname:
+		return fmt.Errorf("command is for host %s", cmd.Host)
+	}
+
+	if !p.seen.add(cmd.ID, expires) {
+		return fmt.Errorf("command already executed")
+	}
+	return nil
+}
+
+// signedMessage returns the bytes a command's signature covers: the command
+// object exactly as the server sent it, with the signature member and its
+// separating comma removed. Working on the raw bytes rather than a
+// re-encoding keeps numbers and key order as the server signed them.
+func signedMessage(raw []byte) ([]byte, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("signed command has no raw encoding")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("command is not a JSON object")
+	}
+
+	start, end := -1, -1
+	prevEnd := dec.InputOffset() // End of '{' or of the previous member
+	for first := true; dec.More(); first = false {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("malformed command: %w", err)
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("malformed command: %w", err)
+		}
+		valueEnd := dec.InputOffset()
+
+		if token == "signature" {
+			if start >= 0 {
+				return nil, fmt.Errorf("command has more than one signature")
+			}
+			// Drop the comma before the member, or after it when it is first
+			start, end = int(prevEnd), int(valueEnd)
+			if first {
+				rest := bytes.TrimLeft(raw[end:], " \t\r\n")
+				if len(rest) > 0 && rest[0] == ',' {
+					end = len(raw) - len(rest) + 1
+				}
+			}
+		}
+		prevEnd = valueEnd
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("command has no signature")
+	}
+
+	message := make([]byte, 0, len(raw)-(end-start))
+	message = append(message, raw[:start]...)
+	return append(message, raw[end:]...), nil
+}
+
+// auditMu serializes writes to the audit log
+var auditMu sync.Mutex
+
+// auditEntry is one line of the command audit log
+type auditEntry struct {
+	Time    time.Time      `json:"time"`
+	Command string         `json:"command"`
+	ID      string         `json:"id,omitempty"`
+	Params  map[string]any `json:"params,omitempty"`
+	Result  string         `json:"result"` // "executed" or "refused"
+	Reason  string         `json:"reason,omitempty"`
+}
+
+// auditCommand appends the outcome of a server command to the audit log.
+// A refusal is recorded with its reason. An error means the command was not
+// recorded, so a command about to be executed must not run.
+func auditCommand(cmd models.ServerCommand, refusal error) error {
+	path := config.GetCommandAuditLog()
+	if path == "" {
+		return nil
+	}
+
+	entry := auditEntry{Time: time.Now(), Command: cmd.Command, ID: cmd.ID, Params: cmd.Params, Result: "executed"}
+	if refusal != nil {
+		entry.Result, entry.Reason = "refused", refusal.Error()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode command audit entry: %w", err)
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if info, err := os.Stat(path); err == nil && info.Size() > config.CommandAuditMaxMB*1024*1024 {
+		os.Rename(path, path+".1")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to write command audit log: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write command audit log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write command audit log: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write command audit log: %w", err)
+	}
+	return nil
+}
+
+// auditRefusal records a refused command; failing to do so is only logged
+func auditRefusal(cmd models.ServerCommand, refusal error) {
+	if err := auditCommand(cmd, refusal); err != nil {
+		log.Printf("ERROR: %v [command=%s id=%s]", err, cmd.Command, cmd.ID)
+	}
+}
@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// signCommand signs body and returns the command as the server would send
+// it, with the signature appended as the last member
+func signCommand(t *testing.T, key ed25519.PrivateKey, body string) models.ServerCommand {
+	t.Helper()
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(body)))
+	raw := strings.TrimSuffix(body, "}") + `,"signature":"` + signature + `"}`
+	return decodeCommand(t, raw)
+}
+
+// decodeCommand decodes raw like a server response would be
+func decodeCommand(t *testing.T, raw string) models.ServerCommand {
+	t.Helper()
+	var cmd models.ServerCommand
+	if err := json.Unmarshal([]byte(raw), &cmd); err != nil {
+		t.Fatalf("decode %s: %v", raw, err)
+	}
+	return cmd
+}
+
+// newTestPolicy creates a policy pinning a fresh key and allowing allowlist
+func newTestPolicy(t *testing.T, allowlist string) (*commandPolicy, ed25519.PrivateKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MONIFY_COMMAND_PUBLIC_KEY", base64.StdEncoding.EncodeToString(public))
+	t.Setenv("MONIFY_COMMAND_ALLOWLIST", allowlist)
+	t.Setenv("MONIFY_ALLOW_UNSIGNED_COMMANDS", "")
+
+	policy, err := newCommandPolicy(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policy, private
+}
+
+func TestAuthorizeSignature(t *testing.T) {
+	policy, key := newTestPolicy(t, "restart,diagnostics")
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	expires := time.Now().Add(time.Minute).Unix()
+	body := func(id string) string {
+		return fmt.Sprintf(`{"command":"restart","expires":%d,"id":%q,"params":{"n":1.0,"big":9007199254740993}}`, expires, id)
+	}
+
+	tests := []struct {
+		name    string
+		cmd     models.ServerCommand
+		wantErr string
+	}{
+		{"valid", signCommand(t, key, body("c-1")), ""},
+		{"signature first", func() models.ServerCommand {
+			signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(body("c-3"))))
+			return decodeCommand(t, `{"signature":"`+signature+`",`+strings.TrimPrefix(body("c-3"), "{"))
+		}(), ""},
+		{"wrong key", signCommand(t, otherKey, body("c-4")), "invalid signature"},
+		{"tampered", func() models.ServerCommand {
+			cmd := signCommand(t, key, body("c-5"))
+			cmd.Raw = []byte(strings.Replace(string(cmd.Raw), `"n":1.0`, `"n":2.0`, 1))
+			return cmd
+		}(), "invalid signature"},
+		{"reformatted numbers", func() models.ServerCommand {
+			cmd := signCommand(t, key, body("c-6"))
+			cmd.Raw = []byte(strings.Replace(string(cmd.Raw), `"n":1.0`, `"n":1`, 1))
+			return cmd
+		}(), "invalid signature"},
+		{"unsigned", decodeCommand(t, body("c-7")), "missing or malformed signature"},
+		{"not allowlisted", signCommand(t, key, strings.Replace(body("c-8"), "restart", "uninstall", 1)), "not in allowlist"},
+		{"duplicate signature", decodeCommand(t, `{"command":"diagnostics","signature":"AAAA","signature":"BBBB"}`), "more than one signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.authorize(tt.cmd, "web-1")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("authorize: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("authorize error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorizeExpiry(t *testing.T) {
+	policy, key := newTestPolicy(t, "restart")
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		expires int64
+		wantErr string
+	}{
+		{"valid", now.Add(time.Minute).Unix(), ""},
+		{"expired", now.Add(-time.Second).Unix(), "expired"},
+		{"missing", 0, "expired"},
+		{"too far ahead", now.Add(2 * time.Hour).Unix(), "too far in the future"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := signCommand(t, key, fmt.Sprintf(`{"command":"restart","expires":%d,"id":"e-%d"}`, tt.expires, i))
+			err := policy.authorize(cmd, "web-1")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("authorize: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("authorize error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorizeHostBinding(t *testing.T) {
+	policy, key := newTestPolicy(t, "restart")
+	expires := time.Now().Add(time.Minute).Unix()
+
+	bound := signCommand(t, key, fmt.Sprintf(`{"command":"restart","expires":%d,"host":"web-1","id":"h-1"}`, expires))
+	if err := policy.authorize(bound, "web-2"); err == nil || !strings.Contains(err.Error(), "for host web-1") {
+		t.Fatalf("authorize on another host: error = %v", err)
+	}
+	if err := policy.authorize(bound, "web-1"); err != nil {
+		t.Fatalf("authorize on bound host: %v", err)
+	}
+
+	unbound := signCommand(t, key, fmt.Sprintf(`{"command":"restart","expires":%d,"id":"h-2"}`, expires))
+	if err := policy.authorize(unbound, "web-2"); err != nil {
+		t.Fatalf("authorize unbound command: %v", err)
+	}
+}
+
+func TestAuthorizeReplay(t *testing.T) {
+	policy, key := newTestPolicy(t, "restart")
+	cmd := signCommand(t, key, fmt.Sprintf(`{"command":"restart","expires":%d,"id":"r-1"}`, time.Now().Add(time.Minute).Unix()))
+
+	if err := policy.authorize(cmd, "web-1"); err != nil {
+		t.Fatalf("first authorize: %v", err)
+	}
+	if err := policy.authorize(cmd, "web-1"); err == nil || !strings.Contains(err.Error(), "already executed") {
+		t.Fatalf("replayed authorize: error = %v", err)
+	}
+
+	// Seen IDs survive a configuration reload
+	reloaded, err := newCommandPolicy(policy.seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.authorize(cmd, "web-1"); err == nil || !strings.Contains(err.Error(), "already executed") {
+		t.Fatalf("replayed authorize after reload: error = %v", err)
+	}
+
+	noID := signCommand(t, key, fmt.Sprintf(`{"command":"restart","expires":%d}`, time.Now().Add(time.Minute).Unix()))
+	if err := policy.authorize(noID, "web-1"); err == nil || !strings.Contains(err.Error(), "no id") {
+		t.Fatalf("authorize without id: error = %v", err)
+	}
+}
+
+func TestAuthorizeUnsigned(t *testing.T) {
+	t.Setenv("MONIFY_COMMAND_PUBLIC_KEY", "")
+	t.Setenv("MONIFY_COMMAND_ALLOWLIST", "restart")
+	cmd := decodeCommand(t, `{"command":"restart"}`)
+
+	t.Setenv("MONIFY_ALLOW_UNSIGNED_COMMANDS", "")
+	policy, err := newCommandPolicy(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policy.authorize(cmd, "web-1"); err == nil {
+		t.Fatal("unsigned command authorized without a key or opt-out")
+	}
+
+	t.Setenv("MONIFY_ALLOW_UNSIGNED_COMMANDS", "true")
+	policy, err = newCommandPolicy(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policy.authorize(cmd, "web-1"); err != nil {
+		t.Fatalf("unsigned command with opt-out: %v", err)
+	}
+}
+
+func TestDefaultAllowlistIsNonDestructive(t *testing.T) {
+	for _, command := range []string{"restart", "uninstall", "update_config"} {
+		if slices.Contains(config.DefaultCommandAllowlist, command) {
+			t.Errorf("%s is allowed by default", command)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/sender"
+)
+
+// TestConnection sends an authenticated empty payload to the configured
+// server, for monify test-connection. Only HTTPS delivery can be tested.
+func TestConnection(ctx context.Context) (*sender.PingResult, error) {
+	token, _ := config.GetToken() // Optional with a client certificate or a refresh token
+	return ping(ctx, token, true)
+}
+
+// VerifyToken checks a token with the configured server before it is saved,
+// for monify login. An error wrapping sender.ErrUnauthorized means the
+// server rejected it; other errors mean it could not be checked.
+func VerifyToken(ctx context.Context, token string) error {
+	_, err := ping(ctx, token, false)
+	return err
+}
+
+// ping sends an empty payload with token, exchanged through the configured
+// refresh token if refresh is set
+func ping(ctx context.Context, token string, refresh bool) (*sender.PingResult, error) {
+	switch {
+	case config.GetRelaySocket() != "":
+		return nil, fmt.Errorf("payloads are sent to the relay socket %s, not to the server", config.GetRelaySocket())
+	case config.GetMQTTBrokerURL() != "":
+		return nil, fmt.Errorf("payloads are published to the MQTT broker %s, not sent to the server", config.GetMQTTBrokerURL())
+	}
+
+	serverURL := config.GetServerURL()
+	server, err := sender.NewHTTPSender(serverURL, token)
+	if err != nil {
+		return nil, err
+	}
+	defer server.Close()
+	if refresh {
+		tokens, err := newTokenSource(serverURL, token)
+		if err != nil {
+			return nil, err
+		}
+		if tokens != nil {
+			server.SetTokenSource(tokens)
+		}
+	}
+	return server.Ping(ctx)
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/diagnostics"
+	"github.com/monify-labs/agent/internal/sender"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// startDiagnostics runs the self-check suite in the background and uploads
+// the report for support. Only one run is in progress at a time.
+func (a *Agent) startDiagnostics(ctx context.Context, requestID string) {
+	if !a.diagnosing.CompareAndSwap(false, true) {
+		log.Printf("WARN: Diagnostics already running, ignoring command [request_id=%s]", requestID)
+		return
+	}
+
+	log.Printf("INFO: Running diagnostics [request_id=%s]", requestID)
+	go func() {
+		defer a.diagnosing.Store(false)
+
+		report := a.runDiagnostics(ctx, requestID)
+		if err := a.uploadDiagnostics(ctx, report); err != nil {
+			log.Printf("ERROR: Failed to upload diagnostics: %v", err)
+			return
+		}
+		log.Printf("INFO: Diagnostics uploaded [request_id=%s]", requestID)
+	}()
+}
+
+// runDiagnostics runs all checks and logs the ones that did not pass
+func (a *Agent) runDiagnostics(ctx context.Context, requestID string) *models.DiagnosticsReport {
+	a.mu.RLock()
+	serverURL := a.serverURL
+	a.mu.RUnlock()
+
+	report := &models.DiagnosticsReport{
+		RequestID: requestID,
+		Version:   config.Version,
+		Timestamp: time.Now(),
+		Checks:    diagnostics.Run(ctx, serverURL),
+		Agent:     a.GetStatus(),
+	}
+	report.Hostname = report.Agent.Hostname
+	if report.Hostname == "" {
+		report.Hostname, _ = os.Hostname()
+	}
+
+	counts := map[string]int{}
+	for _, check := range report.Checks {
+		counts[check.Status]++
+		if check.Status != models.DiagnosticOK {
+			log.Printf("WARN: Diagnostic check %s [check=%s detail=%s]", check.Status, check.Name, check.Detail)
+		}
+	}
+	log.Printf("INFO: Diagnostics finished [ok=%d warn=%d fail=%d]",
+		counts[models.DiagnosticOK], counts[models.DiagnosticWarn], counts[models.DiagnosticFail])
+
+	return report
+}
+
+// uploadDiagnostics posts the report to the diagnostics endpoint over HTTPS,
+// also in MQTT and relay modes
+func (a *Agent) uploadDiagnostics(ctx context.Context, report *models.DiagnosticsReport) error {
+	a.mu.RLock()
+	serverURL, token, tokens := a.serverURL, a.token, a.tokens
+	a.mu.RUnlock()
+
+	uploadURL := config.GetDiagnosticsURL()
+	if uploadURL == "" {
+		uploadURL = agentEndpoint(serverURL, "diagnostics")
+	}
+
+	uploader, err := sender.NewHTTPSender(uploadURL, token)
+	if err != nil {
+		return err
+	}
+	defer uploader.Close()
+	if tokens != nil {
+		uploader.SetTokenSource(tokens)
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, config.GetTimeout())
+	defer cancel()
+	return uploader.SendDiagnostics(sendCtx, report)
+}
@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"github.com/monify-labs/agent/internal/diagnostics"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// Doctor runs the diagnostics suite against serverURL followed by a smoke
+// test of every collector, for monify doctor. Nothing is sent or written.
+func Doctor(ctx context.Context, serverURL string) []models.DiagnosticCheck {
+	checks := diagnostics.Run(ctx, serverURL)
+
+	_, health, err := collectOnce(ctx, true)
+	if err != nil {
+		return append(checks, models.DiagnosticCheck{
+			Name:   "collectors",
+			Status: models.DiagnosticFail,
+			Detail: err.Error(),
+			Hint:   "run 'monify collect --static' to see which collector fails",
+		})
+	}
+
+	for _, collector := range health {
+		check := models.DiagnosticCheck{Name: "collector:" + collector.Name, Status: models.DiagnosticOK}
+		if !collector.OK {
+			check.Status = models.DiagnosticFail
+			check.Detail = collector.Error
+			check.Hint = "disable it with MONIFY_DISABLE_COLLECTORS=" + collector.Name + " if this host does not provide it"
+			if strings.HasPrefix(collector.Error, "timed out") {
+				check.Hint = "raise MONIFY_COLLECTOR_TIMEOUT, or check for hung mounts"
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// collectorHealth records the outcome of each collector's last run. With
+// quarantineAfter set, collectors that keep failing are skipped and only
+// retried every retryInterval.
+type collectorHealth struct {
+	mu              sync.Mutex
+	results         map[string]*models.CollectorHealth
+	disabled        map[string]bool // Collectors turned off by configuration, never run
+	quarantineAfter int             // Consecutive failures before a collector is quarantined, 0 disables
+	retryInterval   time.Duration   // How often a quarantined collector is retried
+}
+
+// allow reports whether a collector should run: it is enabled and not
+// quarantined, or its next retry is due
+func (h *collectorHealth) allow(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.disabled[name] {
+		return false
+	}
+	result, ok := h.results[name]
+	return !ok || result.QuarantinedUntil == nil || !time.Now().Before(*result.QuarantinedUntil)
+}
+
+// record stores the outcome of a collector run
+func (h *collectorHealth) record(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.results == nil {
+		h.results = make(map[string]*models.CollectorHealth)
+	}
+	result, ok := h.results[name]
+	if !ok {
+		result = &models.CollectorHealth{Name: name}
+		h.results[name] = result
+	}
+
+	result.LastRun = time.Now()
+	result.OK = err == nil
+	result.Error = ""
+	if err != nil {
+		result.Error = err.Error()
+		result.Failures++
+		if h.quarantineAfter > 0 && result.Failures >= h.quarantineAfter {
+			// Logged once; failed retries extend the quarantine quietly
+			if result.QuarantinedUntil == nil {
+				log.Printf("WARN: Collector quarantined after repeated failures [collector=%s failures=%d retry=%s error=%v]", name, result.Failures, h.retryInterval, err)
+			}
+			until := result.LastRun.Add(h.retryInterval)
+			result.QuarantinedUntil = &until
+		}
+	} else {
+		if result.QuarantinedUntil != nil {
+			log.Printf("INFO: Collector recovered, quarantine lifted [collector=%s failures=%d]", name, result.Failures)
+		}
+		result.LastSuccess = result.LastRun
+		result.Failures = 0
+		result.QuarantinedUntil = nil
+	}
+}
+
+// snapshot returns a copy of all results, sorted by collector name
+func (h *collectorHealth) snapshot() []models.CollectorHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	results := make([]models.CollectorHealth, 0, len(h.results))
+	for _, result := range h.results {
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results
+}
+
+// failingCollectors returns the collectors whose last run failed
+func failingCollectors(health []models.CollectorHealth) []models.CollectorHealth {
+	var failing []models.CollectorHealth
+	for _, collector := range health {
+		if !collector.OK {
+			failing = append(failing, collector)
+		}
+	}
+	return failing
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/sender"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// Heartbeat reasons
+const (
+	heartbeatSendFailing  = "send_failing"
+	heartbeatSendDisabled = "send_disabled"
+)
+
+// runHeartbeat sends a small liveness request while full metrics sends are
+// failing or disabled, so the server can tell a down host from a broken
+// metrics pipeline. It stops when ctx is cancelled.
+func (a *Agent) runHeartbeat(ctx context.Context, hb *sender.HTTPSender) {
+	ticker := time.NewTicker(config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		a.mu.RLock()
+		reason := ""
+		switch {
+		case a.authFailed || time.Now().Before(a.throttledUntil):
+			// Server rejected us or asked for quiet: no heartbeat either
+		case !a.sendEnabled:
+			reason = heartbeatSendDisabled
+		case a.sendFailing:
+			reason = heartbeatSendFailing
+		}
+		heartbeat := &models.Heartbeat{
+			Hostname:  a.hostname,
+			Version:   config.Version,
+			Status:    "running",
+			Reason:    reason,
+			Timestamp: time.Now(),
+			LastSend:  a.lastSend,
+		}
+		a.mu.RUnlock()
+
+		if reason == "" {
+			continue
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, config.GetTimeout())
+		err := hb.SendHeartbeat(sendCtx, heartbeat)
+		cancel()
+		if err != nil {
+			if errors.Is(err, sender.ErrUnauthorized) {
+				a.authRejected(err)
+				continue
+			}
+			if a.debug {
+				log.Printf("DEBUG: Heartbeat failed [reason=%s error=%v]", reason, err)
+			}
+			continue
+		}
+		if a.debug {
+			log.Printf("DEBUG: Heartbeat sent [reason=%s]", reason)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"log"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+)
+
+// highResolution asks the collection loop to use a faster interval for a
+// while. A zero duration ends an active high-resolution period.
+type highResolution struct {
+	interval time.Duration
+	duration time.Duration
+}
+
+// requestHighResolution handles the high_resolution command. The "interval"
+// and "duration" params are in seconds; a newer command replaces the current
+// period and a duration of 0 reverts to the configured interval immediately.
+func (a *Agent) requestHighResolution(params map[string]any) {
+	req := highResolution{
+		interval: config.HighResolutionInterval,
+		duration: config.HighResolutionDuration,
+	}
+	if v, ok := params["interval"].(float64); ok {
+		req.interval = time.Duration(v * float64(time.Second))
+	}
+	if v, ok := params["duration"].(float64); ok {
+		req.duration = time.Duration(v * float64(time.Second))
+	}
+
+	if req.interval < time.Second || req.duration < 0 || req.duration > config.HighResolutionMaxDuration {
+		log.Printf("WARN: Refusing high-resolution mode: interval must be at least 1s and duration at most %s [interval=%v duration=%v]",
+			config.HighResolutionMaxDuration, params["interval"], params["duration"])
+		return
+	}
+
+	// Latest command wins over one the loop has not picked up yet
+	select {
+	case <-a.highResChan:
+	default:
+	}
+	select {
+	case a.highResChan <- req:
+	default:
+	}
+}
+
+// setHighResolutionUntil records the end of high-resolution mode for the status API
+func (a *Agent) setHighResolutionUntil(until time.Time) {
+	a.mu.Lock()
+	a.highResUntil = until
+	a.mu.Unlock()
+}
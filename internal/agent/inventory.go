@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// loadInventory reads the inventory persisted by the previous refresh, nil
+// if there is none
+func loadInventory(path string) *models.StaticMetrics {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("WARN: Failed to read previous inventory [path=%s error=%v]", path, err)
+		}
+		return nil
+	}
+
+	var inventory models.StaticMetrics
+	if err := json.Unmarshal(data, &inventory); err != nil {
+		log.Printf("WARN: Ignoring corrupt previous inventory [path=%s error=%v]", path, err)
+		return nil
+	}
+	return &inventory
+}
+
+// saveInventory persists the inventory atomically, without the package list
+// and the changes it carried
+func saveInventory(path string, inventory *models.StaticMetrics) error {
+	stored := *inventory
+	stored.Packages = nil
+	stored.Changes = nil
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// diffInventory returns what changed from previous to current. Values that
+// are empty in current are skipped, as their collector may have failed.
+func diffInventory(previous, current *models.StaticMetrics) []models.InventoryChange {
+	if previous == nil || current == nil {
+		return nil
+	}
+
+	var changes []models.InventoryChange
+	field := func(name, old, new string) {
+		if new != "" && old != new {
+			changes = append(changes, models.InventoryChange{Field: name, Action: "changed", Old: old, New: new})
+		}
+	}
+	count := func(name string, old, new uint64) {
+		if new != 0 && old != new {
+			changes = append(changes, models.InventoryChange{
+				Field: name, Action: "changed",
+				Old: strconv.FormatUint(old, 10), New: strconv.FormatUint(new, 10),
+			})
+		}
+	}
+
+	// System
+	field("platform_version", previous.PlatformVersion, current.PlatformVersion)
+	field("kernel_version", previous.KernelVersion, current.KernelVersion)
+	field("hostname", previous.Hostname, current.Hostname)
+	field("fqdn", previous.FQDN, current.FQDN)
+
+	// Network
+	field("public_ip", previous.PublicIP, current.PublicIP)
+	field("public_ipv6", previous.PublicIPv6, current.PublicIPv6)
+	field("default_gateway", previous.DefaultGateway, current.DefaultGateway)
+	field("default_gateway_ipv6", previous.DefaultGatewayIPv6, current.DefaultGatewayIPv6)
+	if len(current.InternalIPs) > 0 {
+		changes = append(changes, diffSets("internal_ip", previous.InternalIPs, current.InternalIPs)...)
+	}
+
+	// Hardware
+	field("cpu_model", previous.CPUModel, current.CPUModel)
+	count("cpu_cores", uint64(previous.CPUCores), uint64(current.CPUCores))
+	count("cpu_threads", uint64(previous.CPUThreads), uint64(current.CPUThreads))
+	count("total_memory", previous.TotalMemory, current.TotalMemory)
+	field("product_serial", previous.ProductSerial, current.ProductSerial)
+	field("bios_version", previous.BIOSVersion, current.BIOSVersion)
+	field("instance_type", previous.InstanceType, current.InstanceType)
+	field("region", previous.Region, current.Region)
+
+	// Disks, by mount point
+	if len(current.Disks) > 0 {
+		old := make(map[string]models.DiskInventoryMetrics, len(previous.Disks))
+		for _, disk := range previous.Disks {
+			old[disk.MountPoint] = disk
+		}
+		seen := make(map[string]bool, len(current.Disks))
+		for _, disk := range current.Disks {
+			seen[disk.MountPoint] = true
+			before, ok := old[disk.MountPoint]
+			switch {
+			case !ok:
+				changes = append(changes, models.InventoryChange{Field: "disk", Action: "added", Item: disk.MountPoint, New: disk.Device})
+			case before.Device != disk.Device:
+				changes = append(changes, models.InventoryChange{Field: "disk", Action: "changed", Item: disk.MountPoint, Old: before.Device, New: disk.Device})
+			case before.Total != disk.Total:
+				changes = append(changes, models.InventoryChange{
+					Field: "disk_size", Action: "changed", Item: disk.MountPoint,
+					Old: strconv.FormatUint(before.Total, 10), New: strconv.FormatUint(disk.Total, 10),
+				})
+			}
+		}
+		for _, disk := range previous.Disks {
+			if !seen[disk.MountPoint] {
+				changes = append(changes, models.InventoryChange{Field: "disk", Action: "removed", Item: disk.MountPoint, Old: disk.Device})
+			}
+		}
+	}
+
+	// Network interfaces, by name. Container veth pairs come and go with
+	// their containers and are not reported.
+	if len(current.Interfaces) > 0 {
+		old := make(map[string]models.NetworkInterfaceMetrics, len(previous.Interfaces))
+		for _, nic := range previous.Interfaces {
+			old[nic.Name] = nic
+		}
+		seen := make(map[string]bool, len(current.Interfaces))
+		for _, nic := range current.Interfaces {
+			seen[nic.Name] = true
+			if nic.Driver == "veth" {
+				continue
+			}
+			before, ok := old[nic.Name]
+			switch {
+			case !ok:
+				changes = append(changes, models.InventoryChange{Field: "interface", Action: "added", Item: nic.Name, New: nic.MAC})
+			case before.MAC != nic.MAC:
+				changes = append(changes, models.InventoryChange{Field: "interface_mac", Action: "changed", Item: nic.Name, Old: before.MAC, New: nic.MAC})
+			}
+		}
+		for _, nic := range previous.Interfaces {
+			if !seen[nic.Name] && nic.Driver != "veth" {
+				changes = append(changes, models.InventoryChange{Field: "interface", Action: "removed", Item: nic.Name, Old: nic.MAC})
+			}
+		}
+	}
+
+	// Kernel parameters
+	keys := make([]string, 0, len(current.Sysctls))
+	for key := range current.Sysctls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if before, ok := previous.Sysctls[key]; ok && before != current.Sysctls[key] {
+			changes = append(changes, models.InventoryChange{Field: "sysctl", Action: "changed", Item: key, Old: before, New: current.Sysctls[key]})
+		}
+	}
+
+	return changes
+}
+
+// diffSets returns the values added to and removed from a list
+func diffSets(field string, previous, current []string) []models.InventoryChange {
+	old := make(map[string]bool, len(previous))
+	for _, value := range previous {
+		old[value] = true
+	}
+	seen := make(map[string]bool, len(current))
+
+	var changes []models.InventoryChange
+	for _, value := range current {
+		seen[value] = true
+		if !old[value] {
+			changes = append(changes, models.InventoryChange{Field: field, Action: "added", New: value})
+		}
+	}
+	for _, value := range previous {
+		if !seen[value] {
+			changes = append(changes, models.InventoryChange{Field: field, Action: "removed", Old: value})
+		}
+	}
+	return changes
+}
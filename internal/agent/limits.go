@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"log"
+	"math"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"syscall"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// I/O scheduling classes and encoding from linux/ioprio.h
+const (
+	ioprioWhoProcess  = 1
+	ioprioClassShift  = 13
+	ioprioClassBE     = 2
+	ioprioClassIdle   = 3
+	ioprioLowestLevel = 7 // Lowest priority within the best-effort class
+)
+
+// applyPriority lowers the CPU and I/O priority of the agent as configured.
+// Both are per thread on Linux, so every existing thread is changed; threads
+// created later and child processes such as plugins inherit them.
+func applyPriority() {
+	nice := config.GetNice()
+	class := config.GetIONiceClass()
+
+	var ioprio uintptr
+	switch class {
+	case "":
+	case "idle":
+		ioprio = ioprioClassIdle << ioprioClassShift
+	case "best-effort":
+		ioprio = ioprioClassBE<<ioprioClassShift | ioprioLowestLevel
+	default:
+		log.Printf("WARN: Ignoring unknown I/O scheduling class [ionice=%s]", class)
+		class = ""
+	}
+	if nice == 0 && class == "" {
+		return
+	}
+
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		log.Printf("WARN: %v - %s", err, "Failed to lower agent priority")
+		return
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		if nice > 0 {
+			if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, nice); err != nil {
+				log.Printf("WARN: %v - %s", err, "Failed to set agent nice value")
+				return
+			}
+		}
+		if ioprio != 0 {
+			if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprio); errno != 0 {
+				log.Printf("WARN: %v - %s", errno, "Failed to set agent I/O priority")
+				return
+			}
+		}
+	}
+	log.Printf("INFO: Lowered agent priority [nice=%d ionice=%s]", nice, class)
+}
+
+// resourceBudget sheds optional collectors while the agent uses more CPU or
+// memory than configured
+type resourceBudget struct {
+	cpuPercent  float64 // Percent of one core, 0 when unlimited
+	memoryBytes uint64  // Resident memory, 0 when unlimited
+	shedding    bool
+}
+
+// newResourceBudget creates the budget from the current configuration and
+// applies the memory limit to the Go runtime. The shedding state is taken
+// over from previous, so a reload does not bring collectors back early.
+func newResourceBudget(previous *resourceBudget) *resourceBudget {
+	b := &resourceBudget{cpuPercent: config.GetCPULimit(), memoryBytes: config.GetMemoryLimitBytes()}
+	if previous != nil {
+		b.shedding = previous.shedding
+	}
+
+	// A soft limit makes the garbage collector work harder before the
+	// budget is reached
+	switch {
+	case b.memoryBytes > 0:
+		debug.SetMemoryLimit(int64(b.memoryBytes))
+	case previous != nil && previous.memoryBytes > 0:
+		debug.SetMemoryLimit(math.MaxInt64)
+	}
+	return b
+}
+
+// shed updates the shedding state with the agent's latest usage and reports
+// whether optional collectors should be skipped. Once shedding, usage must
+// drop well below the budget before they are resumed.
+func (b *resourceBudget) shed(usage *models.AgentMetrics) bool {
+	if b.cpuPercent == 0 && b.memoryBytes == 0 {
+		b.shedding = false
+		return false
+	}
+
+	factor := 1.0
+	if b.shedding {
+		factor = config.ResourceLimitResume
+	}
+	overCPU := b.cpuPercent > 0 && usage.CPUPercent > b.cpuPercent*factor
+	overMemory := b.memoryBytes > 0 && float64(usage.RSSBytes) > float64(b.memoryBytes)*factor
+
+	switch over := overCPU || overMemory; {
+	case over && !b.shedding:
+		log.Printf("WARN: Agent over its resource budget, shedding optional collectors [cpu=%.1f%% rss_mb=%d]", usage.CPUPercent, usage.RSSBytes/1024/1024)
+	case !over && b.shedding:
+		log.Printf("INFO: Agent back within its resource budget, resuming optional collectors [cpu=%.1f%% rss_mb=%d]", usage.CPUPercent, usage.RSSBytes/1024/1024)
+	}
+	b.shedding = overCPU || overMemory
+	return b.shedding
+}
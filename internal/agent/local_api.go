@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/monify-labs/agent/internal/sender"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// startLocalAPI starts the local status API and Prometheus endpoint, sharing
+// one listener when both use the same address. They stop when ctx is cancelled.
+func (a *Agent) startLocalAPI(ctx context.Context) {
+	a.mu.RLock()
+	statusAddr, prometheusAddr := a.statusAddr, a.prometheusAddr
+	a.mu.RUnlock()
+
+	muxes := make(map[string]*http.ServeMux)
+	route := func(addr, pattern string, handler http.HandlerFunc) {
+		if addr == "" {
+			return
+		}
+		if muxes[addr] == nil {
+			muxes[addr] = http.NewServeMux()
+		}
+		muxes[addr].HandleFunc(pattern, handler)
+	}
+	route(statusAddr, "GET /status", a.handleStatus)
+	route(prometheusAddr, "GET /metrics", a.handlePrometheus)
+
+	for addr, mux := range muxes {
+		a.localAPI.Add(1)
+		go func() {
+			defer a.localAPI.Done()
+			a.runLocalAPI(ctx, addr, mux)
+		}()
+	}
+}
+
+// runLocalAPI serves handler on addr until ctx is cancelled. Only loopback
+// addresses are accepted: local endpoints are not meant for the network.
+func (a *Agent) runLocalAPI(ctx context.Context, addr string, handler http.Handler) {
+	if !isLoopbackAddress(addr) {
+		log.Printf("WARN: Local API disabled: address is not loopback [addr=%s]", addr)
+		return
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Printf("WARN: Local API disabled: %v", err)
+		return
+	}
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	if a.debug {
+		log.Printf("DEBUG: Local API listening [addr=%s]", listener.Addr())
+	}
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("ERROR: %v - %s", err, "Local API stopped")
+	}
+}
+
+// handleStatus writes the current AgentStatus as JSON
+func (a *Agent) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a.GetStatus())
+}
+
+// handlePrometheus writes the last collected metrics and the agent's
+// self-metrics in Prometheus text format
+func (a *Agent) handlePrometheus(w http.ResponseWriter, r *http.Request) {
+	a.mu.RLock()
+	payload := a.lastPayload
+	a.mu.RUnlock()
+
+	metrics := sender.PrometheusMetrics(payload)
+	metrics = append(metrics, selfMetrics(a.GetStatus())...)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	sender.WritePrometheus(w, metrics)
+}
+
+// selfMetrics describes the agent itself as Prometheus metrics
+func selfMetrics(status *models.AgentStatus) []sender.PrometheusMetric {
+	gauge := func(name string, value float64, labels ...[2]string) sender.PrometheusMetric {
+		return sender.PrometheusMetric{Name: "monify_agent_" + name, Type: "gauge", Labels: labels, Value: value}
+	}
+	counter := func(name string, value float64) sender.PrometheusMetric {
+		return sender.PrometheusMetric{Name: "monify_agent_" + name, Type: "counter", Value: value}
+	}
+	flag := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	metrics := []sender.PrometheusMetric{
+		gauge("info", 1, [2]string{"version", status.Version}),
+		gauge("uptime_seconds", float64(status.Uptime)),
+		counter("payloads_total", float64(status.MetricsCount)),
+		counter("errors_total", float64(status.ErrorCount)),
+		gauge("spooled_payloads", float64(status.Spooled)),
+		gauge("throttled", flag(status.ThrottledUntil != nil)),
+		gauge("maintenance", flag(status.Maintenance != nil)),
+		gauge("alerts_firing", float64(len(status.Alerts))),
+	}
+	if !status.LastSend.IsZero() {
+		metrics = append(metrics, gauge("last_send_timestamp_seconds", float64(status.LastSend.Unix())))
+	}
+	if status.Circuit != "" {
+		metrics = append(metrics, gauge("circuit_open", flag(status.Circuit != sender.CircuitClosed)))
+	}
+	for _, collector := range status.Collectors {
+		metrics = append(metrics, gauge("collector_up", flag(collector.OK), [2]string{"collector", collector.Name}))
+	}
+	return metrics
+}
+
+// isLoopbackAddress reports whether addr (host:port) binds to loopback only
+func isLoopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// summarize describes a payload for the status API, nil before the first collection
+func summarize(payload *models.MetricPayload) *models.PayloadSummary {
+	if payload == nil {
+		return nil
+	}
+	summary := &models.PayloadSummary{
+		ID:        payload.ID,
+		Sequence:  payload.Sequence,
+		Timestamp: payload.Timestamp,
+		Static:    payload.StaticMetrics != nil,
+		Chunks:    len(payload.Chunks),
+	}
+	if dynamicMetrics := payload.DynamicMetrics; dynamicMetrics != nil {
+		if dynamicMetrics.CPU != nil {
+			summary.CPUUsage = dynamicMetrics.CPU.UsagePercent
+		}
+		if dynamicMetrics.Memory != nil {
+			summary.MemoryUsage = dynamicMetrics.Memory.UsedPercent
+		}
+	}
+	return summary
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// Pause starts a maintenance window of duration, replacing any current one.
+// The window is persisted so it survives the reboots it is planned for.
+func Pause(duration time.Duration, reason string) (*models.Maintenance, error) {
+	if duration <= 0 || duration > config.MaintenanceMaxDuration {
+		return nil, fmt.Errorf("maintenance duration must be between 0 and %s", config.MaintenanceMaxDuration)
+	}
+
+	window := &models.Maintenance{Until: time.Now().Add(duration).Truncate(time.Second), Reason: reason}
+	data, err := json.Marshal(window)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(config.MaintenanceFile), 0700); err != nil {
+		return nil, err
+	}
+	tmp := config.MaintenanceFile + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, config.MaintenanceFile); err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+// Resume ends the current maintenance window, if any
+func Resume() error {
+	if err := os.Remove(config.MaintenanceFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// CurrentMaintenance returns the active maintenance window, or nil
+func CurrentMaintenance() *models.Maintenance {
+	data, err := os.ReadFile(config.MaintenanceFile)
+	if err != nil {
+		return nil
+	}
+	var window models.Maintenance
+	if err := json.Unmarshal(data, &window); err != nil || !time.Now().Before(window.Until) {
+		return nil
+	}
+	return &window
+}
+
+// pause handles the pause command. The "duration" param is in seconds
+// (default: one hour) and "reason" is optional.
+func (a *Agent) pause(params map[string]any) {
+	duration := time.Hour
+	if v, ok := params["duration"].(float64); ok {
+		duration = time.Duration(v * float64(time.Second))
+	}
+	reason, _ := params["reason"].(string)
+
+	if _, err := Pause(duration, reason); err != nil {
+		log.Printf("ERROR: %v - %s", err, "Failed to start maintenance window")
+	}
+}
+
+// maintenance returns the active maintenance window and logs when one
+// starts or ends
+func (a *Agent) maintenance() *models.Maintenance {
+	window := CurrentMaintenance()
+
+	a.mu.Lock()
+	wasActive := a.inMaintenance
+	a.inMaintenance = window != nil
+	a.mu.Unlock()
+
+	switch {
+	case window != nil && !wasActive:
+		log.Printf("INFO: Maintenance mode started, alerts suppressed [until=%s reason=%s]", window.Until.Format(time.RFC3339), window.Reason)
+	case window == nil && wasActive:
+		log.Printf("INFO: %s", "Maintenance mode ended")
+	}
+	return window
+}
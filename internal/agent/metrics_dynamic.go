@@ -2,9 +2,12 @@ package agent
 
 import (
 	"context"
-	"sync"
+	"log"
+	"time"
 
+	"github.com/monify-labs/agent/internal/config"
 	"github.com/monify-labs/agent/internal/metrics/dynamic"
+	"github.com/monify-labs/agent/internal/mountfilter"
 	"github.com/monify-labs/agent/pkg/models"
 )
 
@@ -13,25 +16,124 @@ type DynamicCollector struct {
 	cpu     *dynamic.CPUCollector
 	memory  *dynamic.MemoryCollector
 	diskIO  *dynamic.DiskIOCollector
+	growth  *dynamic.DiskGrowthCollector
 	network *dynamic.NetworkCollector
+	managed *dynamic.ManagedProcessCollector
+	mounts  *dynamic.NetworkMountCollector
+	dirs    *dynamic.DirectoryCollector
+	disks   *mountfilter.Filter // Filesystems the disk collectors report
+	probes  *dynamic.ProbeCollector
+	plugins *dynamic.PluginCollector
+	health  collectorHealth
+	timeout time.Duration // Deadline of each collector
+	observe observer      // Set by Benchmark
+
+	anomalies *dynamic.AnomalyDetector // nil when anomaly detection is disabled
+	shedding  bool                     // Optional collectors are skipped while set
+
+	intervals map[string]time.Duration // Collectors that run less often than every collection
+	lastRun   map[string]time.Time
+	last      *models.DynamicMetrics // Previous result, sections of collectors not due are taken from it
 }
 
 // NewDynamicCollector creates a new dynamic metrics collector
 func NewDynamicCollector() *DynamicCollector {
-	return &DynamicCollector{
+	disks := diskFilter()
+	d := &DynamicCollector{
 		cpu:     dynamic.NewCPUCollector(),
 		memory:  dynamic.NewMemoryCollector(),
 		diskIO:  dynamic.NewDiskIOCollector(),
+		growth:  dynamic.NewDiskGrowthCollector(disks),
 		network: dynamic.NewNetworkCollector(),
+		managed: dynamic.NewManagedProcessCollector(),
+		mounts:  dynamic.NewNetworkMountCollector(disks),
+		dirs:    dynamic.NewDirectoryCollector(config.GetWatchDirs()),
+		disks:   disks,
+		probes:  dynamic.NewProbeCollector(config.GetProbes()),
+		plugins: dynamic.NewPluginCollector(config.GetPluginDir(), config.GetPluginInterval(), config.GetPluginTimeout()),
+		timeout: config.GetCollectorTimeout(),
+		health: collectorHealth{
+			disabled:        config.GetDisabledCollectors(),
+			quarantineAfter: config.GetCollectorQuarantineFailures(),
+			retryInterval:   config.CollectorRetryInterval,
+		},
+		intervals: config.GetCollectorIntervals(),
+		lastRun:   make(map[string]time.Time),
+	}
+
+	for name := range d.health.disabled {
+		if !isKnownCollector(name) {
+			log.Printf("WARN: Ignoring unknown collector in MONIFY_DISABLE_COLLECTORS [collector=%s]", name)
+		}
 	}
+	for name := range d.intervals {
+		switch {
+		case !isKnownCollector(name):
+			log.Printf("WARN: Ignoring unknown collector in MONIFY_COLLECTOR_INTERVALS [collector=%s]", name)
+		case name == "directories" || name == "plugins" || name == "anomalies":
+			log.Printf("WARN: Ignoring collector with its own schedule in MONIFY_COLLECTOR_INTERVALS [collector=%s]", name)
+		default:
+			continue
+		}
+		delete(d.intervals, name)
+	}
+
+	if threshold := config.GetAnomalyThreshold(); threshold > 0 && !d.health.disabled["anomalies"] {
+		d.anomalies = dynamic.NewAnomalyDetector(config.AnomalyWindow, config.AnomalyWarmup, threshold)
+		d.cpu.DetectAnomalies(d.anomalies)
+		d.memory.DetectAnomalies(d.anomalies)
+		d.diskIO.DetectAnomalies(d.anomalies)
+		d.network.DetectAnomalies(d.anomalies)
+	}
+	return d
 }
 
-// Start begins background sampling for all dynamic collectors
+// diskFilterOptions returns the MONIFY_DISK_* filter settings
+func diskFilterOptions() mountfilter.Options {
+	return mountfilter.Options{
+		Include:   config.GetDiskInclude(),
+		Exclude:   config.GetDiskExclude(),
+		SkipTypes: config.GetDiskSkipFSTypes(),
+		KeepTypes: config.GetDiskKeepFSTypes(),
+	}
+}
+
+// diskFilter returns the filesystems the disk collectors report. With an
+// invalid pattern the filters are ignored with a warning, and only the
+// default filesystem types are skipped.
+func diskFilter() *mountfilter.Filter {
+	filter, err := mountfilter.Parse(diskFilterOptions())
+	if err != nil {
+		log.Printf("WARN: Ignoring disk filters [error=%v]", err)
+		return nil
+	}
+	return filter
+}
+
+// Start begins background sampling for all enabled dynamic collectors
 func (d *DynamicCollector) Start() {
-	d.cpu.Start()
-	d.memory.Start()
-	d.diskIO.Start()
-	d.network.Start()
+	disabled := d.health.disabled
+	if !disabled["cpu"] {
+		d.cpu.Start()
+	}
+	if !disabled["memory"] {
+		d.memory.Start()
+	}
+	if !disabled["disk_io"] {
+		d.diskIO.Start()
+	}
+	if !disabled["disk_growth"] {
+		d.growth.Start()
+	}
+	if !disabled["network_public"] || !disabled["network_private"] || !disabled["network_health"] {
+		d.network.Start()
+	}
+	if !disabled["directories"] {
+		d.dirs.Start()
+	}
+	if !disabled["plugins"] {
+		d.plugins.Start()
+	}
 }
 
 // Stop halts background sampling for all dynamic collectors
@@ -39,108 +141,254 @@ func (d *DynamicCollector) Stop() {
 	d.cpu.Stop()
 	d.memory.Stop()
 	d.diskIO.Stop()
+	d.growth.Stop()
 	d.network.Stop()
+	d.dirs.Stop()
+	d.plugins.Stop()
+}
+
+// Shed skips the optional collectors (network mounts, directories, plugins,
+// neighbor table, probes, gateway and managed processes) while enabled, to
+// keep the agent within its resource budget. Call it between collections.
+func (d *DynamicCollector) Shed(enabled bool) {
+	d.shedding = enabled
+	d.dirs.SetPaused(enabled)
+	d.plugins.SetPaused(enabled)
+}
+
+// Health returns the outcome of each dynamic collector's last run
+func (d *DynamicCollector) Health() []models.CollectorHealth {
+	return d.health.snapshot()
+}
+
+// dynamicCollectors are the dynamic collectors that always run unless disabled
+var dynamicCollectors = []string{
+	"cpu", "memory", "swap", "disk_space", "disk_growth", "disk_io",
+	"network_public", "network_private", "network_health", "network_mounts",
+	"neighbor_table", "gateway", "system", "managed_processes",
 }
 
-// Collect gathers all dynamic metrics in parallel
+// optionalCollectors are the dynamic collectors that only run when configured
+var optionalCollectors = []string{"probes", "directories", "plugins", "anomalies"}
+
+// KnownCollectors returns the names of all dynamic collectors, which can be
+// disabled
+func KnownCollectors() []string {
+	return append(append([]string(nil), dynamicCollectors...), optionalCollectors...)
+}
+
+// Collectors returns the names of the enabled dynamic collectors
+func (d *DynamicCollector) Collectors() []string {
+	names := append([]string(nil), dynamicCollectors...)
+	if len(config.GetProbes()) > 0 {
+		names = append(names, "probes")
+	}
+	if len(config.GetWatchDirs()) > 0 {
+		names = append(names, "directories")
+	}
+	if config.GetPluginDir() != "" {
+		names = append(names, "plugins")
+	}
+	if d.anomalies != nil {
+		names = append(names, "anomalies")
+	}
+
+	enabled := names[:0]
+	for _, name := range names {
+		if !d.health.disabled[name] {
+			enabled = append(enabled, name)
+		}
+	}
+	return enabled
+}
+
+// Collect gathers all dynamic metrics in parallel. Each collector has
+// its own deadline; those that miss it are left out of the result.
 func (d *DynamicCollector) Collect(ctx context.Context) (*models.DynamicMetrics, error) {
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+	group := newCollectGroup(ctx, d.timeout, &d.health)
+	group.skip = d.notDue()
+	group.observe = d.observe
 	result := &models.DynamicMetrics{}
+	shedding := d.shedding
 
 	// CPU (with sampling)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if cpu, err := d.cpu.Collect(ctx); err == nil {
-			mu.Lock()
-			result.CPU = cpu
-			mu.Unlock()
-		}
-	}()
+	group.Go("cpu", func(ctx context.Context) (func(), error) {
+		cpu, err := d.cpu.Collect(ctx)
+		return func() { result.CPU = cpu }, err
+	})
 
 	// Memory (with sampling)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if mem, err := d.memory.Collect(ctx); err == nil {
-			mu.Lock()
-			result.Memory = mem
-			mu.Unlock()
-		}
-	}()
+	group.Go("memory", func(ctx context.Context) (func(), error) {
+		mem, err := d.memory.Collect(ctx)
+		return func() { result.Memory = mem }, err
+	})
 
 	// Swap (instant query)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if swap, err := dynamic.CollectSwap(ctx); err == nil {
-			mu.Lock()
-			result.Swap = swap
-			mu.Unlock()
-		}
-	}()
+	group.Go("swap", func(ctx context.Context) (func(), error) {
+		swap, err := dynamic.CollectSwap(ctx)
+		return func() { result.Swap = swap }, err
+	})
 
 	// Disk Space (instant aggregation)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if diskSpace, err := dynamic.CollectDiskSpace(ctx); err == nil {
-			mu.Lock()
-			result.DiskSpace = diskSpace
-			mu.Unlock()
+	group.Go("disk_space", func(ctx context.Context) (func(), error) {
+		diskSpace, err := dynamic.CollectDiskSpace(ctx, d.disks)
+		return func() { result.DiskSpace = diskSpace }, err
+	})
+
+	// Disk growth (rolling window, kept across collections)
+	group.Go("disk_growth", func(ctx context.Context) (func(), error) {
+		growth, err := d.growth.Collect(ctx)
+		return func() { result.DiskGrowth = growth }, err
+	})
+
+	// Network mounts (per-mount timeouts)
+	if !shedding {
+		group.Go("network_mounts", func(ctx context.Context) (func(), error) {
+			mounts, err := d.mounts.Collect(ctx)
+			return func() { result.NetworkMounts = mounts }, err
+		})
+	}
+
+	// Watched directories (scanned in background)
+	if !shedding && d.health.allow("directories") {
+		dirs, err := d.dirs.Collect(ctx)
+		d.health.record("directories", err)
+		if err == nil {
+			group.store(func() { result.Directories = dirs })
 		}
-	}()
+	}
 
-	// Disk I/O (with sampling)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if diskIO, err := d.diskIO.Collect(ctx); err == nil {
-			mu.Lock()
-			result.DiskIO = diskIO
-			mu.Unlock()
+	// Exec plugins (run in background)
+	if !shedding && d.health.allow("plugins") {
+		plugins, err := d.plugins.Collect(ctx)
+		d.health.record("plugins", err)
+		if err == nil {
+			group.store(func() { result.Plugins = plugins })
 		}
-	}()
+	}
+
+	// Anomalies found in the 1-second samples since the last collection
+	anomalies := d.anomalies.Collect()
+	group.store(func() { result.Anomalies = anomalies })
+
+	// Disk I/O (with sampling)
+	group.Go("disk_io", func(ctx context.Context) (func(), error) {
+		diskIO, err := d.diskIO.Collect(ctx)
+		return func() { result.DiskIO = diskIO }, err
+	})
 
 	// Network (with sampling)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-
-		// Public network
-		if pub, err := d.network.CollectPublic(ctx); err == nil {
-			mu.Lock()
-			result.NetworkPublic = pub
-			mu.Unlock()
-		}
+	group.Go("network_public", func(ctx context.Context) (func(), error) {
+		pub, err := d.network.CollectPublic(ctx)
+		return func() { result.NetworkPublic = pub }, err
+	})
+	group.Go("network_private", func(ctx context.Context) (func(), error) {
+		priv, err := d.network.CollectPrivate(ctx)
+		return func() { result.NetworkPrivate = priv }, err
+	})
+	group.Go("network_health", func(ctx context.Context) (func(), error) {
+		health, err := d.network.CollectHealth(ctx)
+		return func() { result.NetworkHealth = health }, err
+	})
 
-		// Private network
-		if priv, err := d.network.CollectPrivate(ctx); err == nil {
-			mu.Lock()
-			result.NetworkPrivate = priv
-			mu.Unlock()
-		}
+	// Neighbor table (instant query)
+	if !shedding {
+		group.Go("neighbor_table", func(ctx context.Context) (func(), error) {
+			neighbors, err := dynamic.CollectNeighborTable(ctx)
+			return func() { result.NeighborTable = neighbors }, err
+		})
+	}
 
-		// Network health
-		if health, err := d.network.CollectHealth(ctx); err == nil {
-			mu.Lock()
-			result.NetworkHealth = health
-			mu.Unlock()
-		}
-	}()
+	// Service check probes
+	if !shedding {
+		group.Go("probes", func(ctx context.Context) (func(), error) {
+			probes, err := d.probes.Collect(ctx)
+			return func() { result.Probes = probes }, err
+		})
+	}
+
+	// Default gateway reachability
+	if !shedding {
+		group.Go("gateway", func(ctx context.Context) (func(), error) {
+			gateway, err := dynamic.CollectGateway(ctx)
+			return func() { result.Gateway = gateway }, err
+		})
+	}
 
 	// System dynamic (instant query)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if sysDynamic, err := dynamic.CollectSystemDynamic(ctx); err == nil {
-			mu.Lock()
-			result.System = sysDynamic
-			mu.Unlock()
-		}
-	}()
+	group.Go("system", func(ctx context.Context) (func(), error) {
+		sysDynamic, err := dynamic.CollectSystemDynamic(ctx)
+		return func() { result.System = sysDynamic }, err
+	})
+
+	// Managed processes (supervisord/pm2, if present)
+	if !shedding {
+		group.Go("managed_processes", func(ctx context.Context) (func(), error) {
+			procs, err := d.managed.Collect(ctx)
+			return func() { result.ManagedProcesses = procs }, err
+		})
+	}
+
+	group.Wait()
 
-	wg.Wait()
+	// Collectors that were not due report their last result
+	if d.last != nil {
+		for _, name := range group.skipped {
+			carryOver(name, d.last, result)
+		}
+	}
+	d.last = result
 	return result, nil
 }
+
+// notDue returns the collectors with their own interval that ran too
+// recently, and marks the others as run now
+func (d *DynamicCollector) notDue() map[string]bool {
+	now := time.Now()
+	skip := make(map[string]bool)
+	for name, interval := range d.intervals {
+		// Allow for ticker jitter so a 60s collector runs every fourth 15s tick
+		if last, ok := d.lastRun[name]; ok && now.Sub(last)+config.CollectorIntervalSlack < interval {
+			skip[name] = true
+			continue
+		}
+		d.lastRun[name] = now
+	}
+	return skip
+}
+
+// carryOver copies the section of the named collector from previous to result
+func carryOver(name string, previous, result *models.DynamicMetrics) {
+	switch name {
+	case "cpu":
+		result.CPU = previous.CPU
+	case "memory":
+		result.Memory = previous.Memory
+	case "swap":
+		result.Swap = previous.Swap
+	case "disk_space":
+		result.DiskSpace = previous.DiskSpace
+	case "disk_growth":
+		result.DiskGrowth = previous.DiskGrowth
+	case "disk_io":
+		result.DiskIO = previous.DiskIO
+	case "network_public":
+		result.NetworkPublic = previous.NetworkPublic
+	case "network_private":
+		result.NetworkPrivate = previous.NetworkPrivate
+	case "network_health":
+		result.NetworkHealth = previous.NetworkHealth
+	case "network_mounts":
+		result.NetworkMounts = previous.NetworkMounts
+	case "neighbor_table":
+		result.NeighborTable = previous.NeighborTable
+	case "probes":
+		result.Probes = previous.Probes
+	case "gateway":
+		result.Gateway = previous.Gateway
+	case "system":
+		result.System = previous.System
+	case "managed_processes":
+		result.ManagedProcesses = previous.ManagedProcesses
+	}
+}
@@ -2,10 +2,13 @@ package agent
 
 import (
 	"context"
+	"log"
 	"sync"
 	"time"
 
+	"github.com/monify-labs/agent/internal/config"
 	"github.com/monify-labs/agent/internal/metrics/static"
+	"github.com/monify-labs/agent/internal/mountfilter"
 	"github.com/monify-labs/agent/pkg/models"
 )
 
@@ -13,31 +16,61 @@ const staticRefreshInterval = 1 * time.Hour
 
 // StaticCollector orchestrates collection of all static metrics
 type StaticCollector struct {
-	networkInfo *static.NetworkInfoCollector
-	lastRefresh time.Time
-	cache       *models.StaticMetrics
-	mu          sync.RWMutex
+	networkInfo  *static.NetworkInfoCollector
+	packages     bool                // Installed package inventory enabled
+	sysctls      []string            // Kernel parameters to report
+	disks        *mountfilter.Filter // Filesystems in the disk inventory
+	cloud        bool                // Probe the cloud metadata services
+	cloudTags    bool                // Fetch instance tags from the cloud metadata service
+	configLabels map[string]string   // Labels from MONIFY_LABELS, win over cloud tags
+	labels       map[string]string
+	packagesSent string // Checksum of the last package list delivered to the server
+	lastRefresh  time.Time
+	cache        *models.StaticMetrics
+	baseline     *models.StaticMetrics // Inventory of the previous refresh, possibly from before a restart
+	baselinePath string                // Empty when the baseline is kept in memory only
+	health       collectorHealth
+	timeout      time.Duration // Deadline of each collector
+	observe      observer      // Set by Benchmark
+	mu           sync.RWMutex
 }
 
 // NewStaticCollector creates a new static metrics collector
 func NewStaticCollector() *StaticCollector {
 	return &StaticCollector{
-		networkInfo: static.NewNetworkInfoCollector(),
+		networkInfo:  static.NewNetworkInfoCollector(config.GetPublicIPURLs()),
+		packages:     config.IsPackageInventoryEnabled(),
+		sysctls:      config.GetSysctls(),
+		disks:        diskFilter(),
+		cloud:        config.IsCloudMetadataEnabled(),
+		cloudTags:    config.IsCloudMetadataEnabled() && config.IsCloudTagsEnabled(),
+		configLabels: config.GetLabels(),
+		timeout:      config.GetCollectorTimeout(),
+		baseline:     loadInventory(config.InventoryFile),
+		baselinePath: inventoryPath(),
 	}
 }
 
-// Collect gathers all static metrics in parallel
+// inventoryPath returns where the inventory baseline is persisted, empty
+// during a dry run so the changes are still reported once the agent runs
+func inventoryPath() string {
+	if config.IsDryRun() {
+		return ""
+	}
+	return config.InventoryFile
+}
+
+// Collect gathers all static metrics in parallel. Each collector has its
+// own deadline; those that miss it are left out of the result.
 func (s *StaticCollector) Collect(ctx context.Context) (*models.StaticMetrics, error) {
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+	group := newCollectGroup(ctx, s.timeout, &s.health)
+	group.observe = s.observe
 	result := &models.StaticMetrics{}
 
 	// System info
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if info, err := static.CollectSystemInfo(ctx); err == nil {
-			mu.Lock()
+	group.Go("system_info", func(ctx context.Context) (func(), error) {
+		info, err := static.CollectSystemInfo(ctx)
+		return func() {
 			result.Platform = info.Platform
 			result.PlatformFamily = info.PlatformFamily
 			result.PlatformVersion = info.PlatformVersion
@@ -47,63 +80,137 @@ func (s *StaticCollector) Collect(ctx context.Context) (*models.StaticMetrics, e
 			result.KernelArch = info.KernelArch
 			result.Virtualization = info.Virtualization
 			result.HostID = info.HostID
-			mu.Unlock()
-		}
-	}()
+		}, err
+	})
+
+	// Virtualization layers (local files only)
+	virt := static.DetectVirtualization()
+	group.store(func() {
+		result.Hypervisor = virt.Hypervisor
+		result.ContainerRuntime = virt.ContainerRuntime
+		result.VirtualizationStack = virt.Stack
+	})
 
 	// Hardware info
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if info, err := static.CollectHardwareInfo(ctx); err == nil {
-			mu.Lock()
+	group.Go("hardware", func(ctx context.Context) (func(), error) {
+		info, err := static.CollectHardwareInfo(ctx)
+		return func() {
 			result.CPUModel = info.CPUModel
 			result.CPUCores = info.CPUCores
 			result.CPUThreads = info.CPUThreads
 			result.TotalMemory = info.TotalMemory
-			mu.Unlock()
-		}
-	}()
+			result.SystemVendor = info.SystemVendor
+			result.ProductName = info.ProductName
+			result.ProductSerial = info.ProductSerial
+			result.BIOSVendor = info.BIOSVendor
+			result.BIOSVersion = info.BIOSVersion
+		}, err
+	})
 
 	// Network info (uses cached collector for public IP)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if info, err := s.networkInfo.Collect(ctx); err == nil {
-			mu.Lock()
+	group.Go("network_info", func(ctx context.Context) (func(), error) {
+		info, err := s.networkInfo.Collect(ctx)
+		return func() {
 			result.InternalIPs = info.InternalIPs
 			result.PublicIP = info.PublicIP
+			result.PublicIPv6 = info.PublicIPv6
 			result.Hostname = info.Hostname
 			result.FQDN = info.FQDN
 			result.Timezone = info.Timezone
-			mu.Unlock()
-		}
-	}()
-
-	// Cloud info
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if info, err := static.DetectCloudProvider(ctx); err == nil {
-			mu.Lock()
-			result.Region = info.Region
-			result.InstanceType = info.InstanceType
-			mu.Unlock()
-		}
-	}()
+		}, err
+	})
+
+	// Default gateway (local files only)
+	gateway := static.DetectDefaultGateway()
+	group.store(func() {
+		result.DefaultGateway = gateway.IPv4
+		result.DefaultGatewayInterface = gateway.IPv4Interface
+		result.DefaultGatewayIPv6 = gateway.IPv6
+	})
+
+	// Cloud info (unless metadata services must not be contacted)
+	if s.cloud {
+		group.Go("cloud", func(ctx context.Context) (func(), error) {
+			info, err := static.DetectCloudProvider(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			// Instance tags become payload labels
+			if s.cloudTags && info.Provider != "" {
+				tags, err := static.FetchCloudTags(ctx, info.Provider)
+				s.health.record("cloud_tags", err)
+				if err == nil {
+					s.mu.Lock()
+					s.labels = tags
+					s.mu.Unlock()
+				}
+			}
+
+			return func() {
+				result.CloudProvider = info.Provider
+				result.Region = info.Region
+				result.InstanceType = info.InstanceType
+			}, nil
+		})
+	}
 
 	// Disk inventory
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if disks, err := static.CollectDiskInventory(ctx); err == nil {
-			mu.Lock()
-			result.Disks = disks
-			mu.Unlock()
-		}
-	}()
+	group.Go("disks", func(ctx context.Context) (func(), error) {
+		disks, err := static.CollectDiskInventory(ctx, s.disks)
+		return func() { result.Disks = disks }, err
+	})
+
+	// Network interface inventory
+	group.Go("interfaces", func(ctx context.Context) (func(), error) {
+		nics, err := static.CollectInterfaceInventory(ctx)
+		return func() { result.Interfaces = nics }, err
+	})
+
+	// Kernel parameters
+	if len(s.sysctls) > 0 {
+		sysctls := static.CollectSysctls(s.sysctls)
+		group.store(func() { result.Sysctls = sysctls })
+	}
+
+	// Installed packages (opt-in, data only sent on change)
+	if s.packages {
+		group.Go("packages", func(ctx context.Context) (func(), error) {
+			inventory, err := static.CollectPackages(ctx)
+			if err != nil {
+				return nil, err
+			}
 
-	wg.Wait()
+			s.mu.RLock()
+			if inventory.Checksum == s.packagesSent {
+				inventory.Data = ""
+			}
+			s.mu.RUnlock()
+
+			return func() { result.Packages = inventory }, nil
+		})
+	}
+
+	group.Wait()
+
+	// Report what changed since the previous refresh
+	s.mu.Lock()
+	result.Changes = diffInventory(s.baseline, result)
+	s.baseline = result
+	path := s.baselinePath
+	s.mu.Unlock()
+	if path != "" {
+		if err := saveInventory(path, result); err != nil {
+			// Changes are still reported until the next restart
+			log.Printf("WARN: Failed to persist inventory, continuing in memory: %v", err)
+			s.mu.Lock()
+			s.baselinePath = ""
+			s.mu.Unlock()
+		}
+	}
+	for _, change := range result.Changes {
+		log.Printf("INFO: Inventory changed [field=%s action=%s item=%s old=%s new=%s]", change.Field, change.Action, change.Item, change.Old, change.New)
+	}
 
 	// Update cache
 	s.mu.Lock()
@@ -127,6 +234,59 @@ func (s *StaticCollector) ShouldRefresh() bool {
 	return time.Since(s.lastRefresh) >= staticRefreshInterval
 }
 
+// MarkSent records static metrics as delivered so unchanged data can be omitted
+func (s *StaticCollector) MarkSent(metrics *models.StaticMetrics) {
+	if metrics == nil || metrics.Packages == nil || metrics.Packages.Data == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.packagesSent = metrics.Packages.Checksum
+}
+
+// Labels returns the labels attached to every payload: cloud instance tags
+// and configured labels
+func (s *StaticCollector) Labels() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.configLabels) == 0 {
+		return s.labels
+	}
+	labels := make(map[string]string, len(s.labels)+len(s.configLabels))
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+	for k, v := range s.configLabels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// Health returns the outcome of each static collector's last run
+func (s *StaticCollector) Health() []models.CollectorHealth {
+	return s.health.snapshot()
+}
+
+// Collectors returns the names of the enabled static collectors
+func (s *StaticCollector) Collectors() []string {
+	names := []string{"system_info", "hardware", "network_info", "disks", "interfaces"}
+	if s.cloud {
+		names = append(names, "cloud")
+	}
+	if s.cloudTags {
+		names = append(names, "cloud_tags")
+	}
+	if len(s.sysctls) > 0 {
+		names = append(names, "sysctls")
+	}
+	if s.packages {
+		names = append(names, "packages")
+	}
+	return names
+}
+
 // GetCached returns cached static metrics
 func (s *StaticCollector) GetCached() *models.StaticMetrics {
 	s.mu.RLock()
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/metrics/dynamic"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// accessWrite is W_OK for access(2)
+const accessWrite = 2
+
+// detectPermissions probes what the agent can collect with the privileges it
+// runs with, so an unprivileged agent reports exactly what it is missing
+// instead of collectors failing one by one
+func detectPermissions() *models.AgentCapabilities {
+	result := &models.AgentCapabilities{
+		Root:         os.Geteuid() == 0,
+		Capabilities: effectiveCapabilities(),
+	}
+	disabled := config.GetDisabledCollectors()
+	unavailable := func(feature, reason string) {
+		result.Unavailable = append(result.Unavailable, models.UnavailableFeature{Feature: feature, Reason: reason})
+	}
+
+	// Collected data
+	if f, err := os.Open("/sys/class/dmi/id/product_serial"); err == nil {
+		f.Close()
+	} else if os.IsPermission(err) {
+		unavailable("static_info.product_serial", "DMI serial number is readable by root only")
+	}
+	if !disabled["gateway"] && !dynamic.ICMPPermitted() {
+		unavailable("gateway.icmp", "ICMP sockets need CAP_NET_RAW or net.ipv4.ping_group_range; gateway reachability falls back to the ARP table")
+	}
+	if !disabled["managed_processes"] && !dynamic.SupervisorPermitted() {
+		unavailable("managed_processes.supervisord", "supervisord control socket is not writable")
+	}
+	if _, err := os.ReadDir("/proc/1/fd"); os.IsPermission(err) {
+		unavailable("port_report.listening.process", "owners of other users' sockets need CAP_SYS_PTRACE")
+	}
+
+	// Local state
+	if !writable(filepath.Dir(config.SequenceFile)) {
+		unavailable("state", "state directory is not writable; the payload sequence and inventory baseline are kept in memory only")
+	}
+
+	// Server commands
+	if _, err := os.Stat(config.EnvFilePath); err == nil && !writable(config.EnvFilePath) && !writable(config.AgentSettings) {
+		unavailable("command:update_config", "neither the configuration file nor the state directory is writable")
+	}
+	if path := config.GetCommandAuditLog(); path != "" && !writable(path) {
+		unavailable("commands", "command audit log is not writable; server commands are refused")
+	}
+	if !result.Root {
+		unavailable("command:uninstall", "requires root")
+	}
+
+	return result
+}
+
+// writable reports whether the agent may write path, or create it when it
+// does not exist yet
+func writable(path string) bool {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return syscall.Access(path, accessWrite) == nil
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		path = parent
+	}
+}
+
+// effectiveCapabilities returns the capabilities from capabilityNumbers that
+// are in the agent's effective set
+func effectiveCapabilities() []string {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var mask uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "CapEff:"); ok {
+			mask, _ = strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+			break
+		}
+	}
+
+	var names []string
+	for name, number := range capabilityNumbers {
+		if mask&(1<<number) != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/ports"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// startPortScan handles the scan_ports command: the listening ports, and the
+// open ports of an optional loopback range given by the "from" and "to"
+// params, are reported in the next payload. Only one scan runs at a time.
+func (a *Agent) startPortScan(ctx context.Context, params map[string]any) {
+	requestID, _ := params["id"].(string)
+	from, hasFrom := params["from"].(float64)
+	to, hasTo := params["to"].(float64)
+
+	if hasFrom != hasTo || (hasFrom && (from < 1 || to > 65535 || from > to || int(to-from)+1 > config.PortScanMaxPorts)) {
+		log.Printf("WARN: Refusing port scan: invalid range, at most %d ports between 1 and 65535 [from=%v to=%v]",
+			config.PortScanMaxPorts, params["from"], params["to"])
+		return
+	}
+	if !a.scanningPorts.CompareAndSwap(false, true) {
+		log.Printf("WARN: Port scan already running, ignoring command [request_id=%s]", requestID)
+		return
+	}
+
+	log.Printf("INFO: Scanning ports [request_id=%s]", requestID)
+	go func() {
+		defer a.scanningPorts.Store(false)
+
+		report := &models.PortReport{RequestID: requestID, Timestamp: time.Now()}
+		listening, err := ports.Listening()
+		if err != nil {
+			report.Error = err.Error()
+		}
+		report.Listening = listening
+
+		if hasFrom {
+			start := time.Now()
+			report.Scan = &models.PortScan{
+				Host: "127.0.0.1",
+				From: int(from),
+				To:   int(to),
+				Open: ports.Scan(ctx, "127.0.0.1", int(from), int(to)),
+			}
+			report.Scan.DurationMs = float64(time.Since(start).Milliseconds())
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		a.mu.Lock()
+		a.portReport = report
+		a.mu.Unlock()
+		log.Printf("INFO: Port scan finished, reporting in next payload [request_id=%s listening=%d]", requestID, len(listening))
+	}()
+}
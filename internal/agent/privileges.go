@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/spool"
+)
+
+// Capability numbers from linux/capability.h
+var capabilityNumbers = map[string]uint{
+	"dac_read_search":  2,
+	"net_bind_service": 10,
+	"net_raw":          13,
+	"sys_ptrace":       19,
+}
+
+// Linux capability interface
+const (
+	capabilityVersion3 = 0x20080522
+	prSetKeepCaps      = 8
+)
+
+// capHeader and capData mirror the capset(2) structures
+type capHeader struct {
+	version uint32
+	pid     int32
+}
+
+type capData struct {
+	effective   uint32
+	permitted   uint32
+	inheritable uint32
+}
+
+// dropPrivileges switches the agent from root to the configured user once the
+// privileged startup work is done, keeping only the configured capabilities.
+// The state directories are handed over to the user and the config file is
+// made readable by its group so reloads keep working.
+func dropPrivileges() error {
+	name := config.GetRunAsUser()
+	if name == "" {
+		return nil
+	}
+
+	account, err := user.Lookup(name)
+	if err != nil {
+		return fmt.Errorf("failed to look up user %s: %w", name, err)
+	}
+	uid, err := strconv.Atoi(account.Uid)
+	if err != nil {
+		return fmt.Errorf("invalid uid of user %s: %w", name, err)
+	}
+	gid, err := strconv.Atoi(account.Gid)
+	if err != nil {
+		return fmt.Errorf("invalid gid of user %s: %w", name, err)
+	}
+	switch euid := os.Geteuid(); {
+	case euid == uid:
+		return nil // Already running as the user, e.g. after a re-exec
+	case euid != 0:
+		return fmt.Errorf("switching to user %s requires starting as root", name)
+	}
+
+	var mask uint32
+	capabilities := config.GetCapabilities()
+	for _, capability := range capabilities {
+		number, ok := capabilityNumbers[strings.TrimPrefix(strings.ToLower(capability), "cap_")]
+		if !ok {
+			return fmt.Errorf("unsupported capability %s", capability)
+		}
+		mask |= 1 << number
+	}
+
+	var groups []int
+	if ids, err := account.GroupIds(); err == nil {
+		for _, id := range ids {
+			if n, err := strconv.Atoi(id); err == nil {
+				groups = append(groups, n)
+			}
+		}
+	}
+
+	handOver(uid, gid)
+
+	// Capabilities survive the switch only with keep-caps set; like the
+	// uid switch itself, it has to be applied to every thread
+	if mask != 0 {
+		if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, prSetKeepCaps, 1, 0); errno == syscall.ENOTSUP {
+			return fmt.Errorf("keeping capabilities requires a build without cgo (CGO_ENABLED=0)")
+		} else if errno != 0 {
+			return fmt.Errorf("failed to keep capabilities: %w", errno)
+		}
+	}
+	if err := syscall.Setgroups(groups); err != nil {
+		return fmt.Errorf("failed to set groups: %w", err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("failed to switch group: %w", err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("failed to switch user: %w", err)
+	}
+	if mask != 0 {
+		header := capHeader{version: capabilityVersion3}
+		data := [2]capData{{effective: mask, permitted: mask}}
+		if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+			return fmt.Errorf("failed to set capabilities: %w", errno)
+		}
+		syscall.AllThreadsSyscall(syscall.SYS_PRCTL, prSetKeepCaps, 0, 0)
+	}
+
+	log.Printf("INFO: Dropped root privileges [user=%s uid=%d capabilities=%s]", name, uid, strings.Join(capabilities, ","))
+	return nil
+}
+
+// handOver gives the user the state and logs it writes and read access to
+// the config files. Paths the operator configured elsewhere are not
+// re-permissioned: only files the agent itself creates there are handed
+// over. Failures are logged: the affected feature fails later on its own.
+func handOver(uid, gid int) {
+	ownedDirs := []string{filepath.Dir(config.SequenceFile)}
+	if path := config.GetCommandAuditLog(); path == config.CommandAuditLog {
+		ownedDirs = append(ownedDirs, filepath.Dir(path))
+	} else if path != "" {
+		handOverFile(path, uid, gid)
+	}
+	for _, dir := range ownedDirs {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			log.Printf("WARN: %v - %s", err, "Failed to create state directory")
+			continue
+		}
+		err := filepath.WalkDir(dir, func(path string, _ fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Lchown(path, uid, gid)
+		})
+		if err != nil {
+			log.Printf("WARN: %v - %s", err, "Failed to hand state directory over to agent user")
+		}
+	}
+	handOverSpool(uid, gid)
+
+	// Only the system layout is ours to re-permission; a config file given
+	// with --config or MONIFY_CONFIG_PATH may live in /tmp or a home directory
+	if config.EnvFilePath != config.SystemEnvFile {
+		return
+	}
+	configFiles := map[string]os.FileMode{
+		filepath.Dir(config.SystemEnvFile): 0750,
+		config.SystemEnvFile:               0640,
+		config.ConfigFilePath:              0640,
+	}
+	for path, mode := range configFiles {
+		if err := os.Chown(path, 0, gid); err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("WARN: %v - %s", err, "Failed to make configuration readable by agent user")
+			}
+			continue
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			log.Printf("WARN: %v - %s", err, "Failed to make configuration readable by agent user")
+		}
+	}
+}
+
+// handOverSpool gives the user a spool directory outside the state
+// directory. The spool's files are handed over, and the directory itself
+// only when it holds nothing else, so a shared directory is left alone.
+func handOverSpool(uid, gid int) {
+	dir := config.GetSpoolDir()
+	if config.GetSpoolMaxBytes() == 0 || strings.HasPrefix(dir, filepath.Dir(config.SequenceFile)+"/") {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("WARN: %v - %s", err, "Failed to hand spool over to agent user")
+		return
+	}
+	dedicated := true
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !spool.IsFile(e.Name()) {
+			dedicated = false
+			continue
+		}
+		if err := os.Lchown(filepath.Join(dir, e.Name()), uid, gid); err != nil {
+			log.Printf("WARN: %v - %s", err, "Failed to hand spool over to agent user")
+		}
+	}
+	if !dedicated {
+		log.Printf("WARN: Spool directory holds other files and is left as is; make it writable by the agent user [dir=%s]", dir)
+		return
+	}
+	if err := os.Chown(dir, uid, gid); err != nil {
+		log.Printf("WARN: %v - %s", err, "Failed to hand spool directory over to agent user")
+	}
+}
+
+// handOverFile gives the user a single file, creating it if needed
+func handOverFile(path string, uid, gid int) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err == nil {
+		err = f.Chown(uid, gid)
+		f.Close()
+	}
+	if err != nil {
+		log.Printf("WARN: %v - %s [path=%s]", err, "Failed to hand file over to agent user", path)
+	}
+}
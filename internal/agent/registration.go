@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/sender"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// payloadFeatures are the optional payload sections and formats this
+// version of the agent can send
+var payloadFeatures = []string{"agent", "alerts", "anomalies", "batch", "chunks", "maintenance", "port_report"}
+
+// register announces the agent to the server and applies the configuration
+// it returns, before the first payload is sent. Failures are logged and do
+// not stop the agent; servers without the endpoint are skipped.
+func (a *Agent) register(ctx context.Context) {
+	if a.dryRun || !config.IsRegistrationEnabled() || config.GetMQTTBrokerURL() != "" || config.GetRelaySocket() != "" {
+		return
+	}
+
+	a.mu.RLock()
+	serverURL, token, tokens := a.serverURL, a.token, a.tokens
+	a.mu.RUnlock()
+
+	registrationURL := config.GetRegistrationURL()
+	if registrationURL == "" {
+		registrationURL = agentEndpoint(serverURL, "register")
+	}
+	registrar, err := sender.NewHTTPSender(registrationURL, token)
+	if err != nil {
+		log.Printf("WARN: %v - %s", err, "Failed to register with server")
+		return
+	}
+	defer registrar.Close()
+	if tokens != nil {
+		registrar.SetTokenSource(tokens)
+	}
+
+	registration := &models.Registration{
+		Hostname:     a.hostname,
+		Version:      config.Version,
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		Labels:       a.staticCollector.Labels(),
+		Capabilities: a.capabilities(),
+		Collectors:   append(a.staticCollector.Collectors(), a.dynamicCollector.Collectors()...),
+		Timestamp:    time.Now(),
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, config.GetTimeout())
+	resp, err := registrar.Register(sendCtx, registration)
+	cancel()
+	switch {
+	case errors.Is(err, sender.ErrNotFound):
+		log.Printf("INFO: %s", "Server does not support registration, skipping")
+		return
+	case errors.Is(err, sender.ErrUnauthorized):
+		a.authRejected(err)
+		return
+	case err != nil:
+		log.Printf("WARN: Registration failed, continuing without it [error=%v]", err)
+		return
+	}
+	a.authAccepted()
+
+	a.mu.Lock()
+	a.serverFeatures = resp.Capabilities
+	if len(resp.Scopes) > 0 {
+		a.tokenScopes = resp.Scopes
+	}
+	a.mu.Unlock()
+	log.Printf("INFO: Registered with server [capabilities=%s]", strings.Join(resp.Capabilities, ","))
+
+	if len(resp.Config) > 0 {
+		a.applyRegistrationConfig(ctx, resp.Config)
+	}
+	if len(resp.Commands) > 0 {
+		a.processServerCommands(ctx, resp.Commands)
+	}
+}
+
+// applyRegistrationConfig applies the settings of a registration response
+// right away. They are authorized like an update_config command, so
+// read-only mode, the allowlist and required signatures still apply.
+func (a *Agent) applyRegistrationConfig(ctx context.Context, settings map[string]any) {
+	cmd := models.ServerCommand{Command: "update_config", Params: settings}
+
+	a.mu.RLock()
+	policy, hostname := a.commandPolicy, a.hostname
+	a.mu.RUnlock()
+
+	err := policy.authorize(cmd, hostname)
+	if !a.commandsAllowed() {
+		err = fmt.Errorf("read-only mode")
+	}
+	if err != nil {
+		log.Printf("WARN: Refusing configuration from registration: %v", err)
+		auditRefusal(cmd, err)
+		return
+	}
+	if err := auditCommand(cmd, nil); err != nil {
+		log.Printf("ERROR: Refusing configuration from registration that cannot be audited: %v", err)
+		return
+	}
+
+	if saveRemoteSettings(settings) {
+		a.reload(ctx, config.GetCollectionInterval())
+	}
+}
+
+// capabilities lists the optional payload sections and the server commands
+// this agent accepts
+func (a *Agent) capabilities() []string {
+	a.mu.RLock()
+	policy := a.commandPolicy
+	a.mu.RUnlock()
+
+	capabilities := append([]string(nil), payloadFeatures...)
+	if a.commandsAllowed() {
+		for command := range policy.allowed {
+			if command == "uninstall" && os.Geteuid() != 0 {
+				continue // Needs root, see processServerCommands
+			}
+			capabilities = append(capabilities, "command:"+command)
+		}
+		if policy.publicKey != nil {
+			capabilities = append(capabilities, "signed_commands")
+		}
+	}
+	sort.Strings(capabilities)
+	return capabilities
+}
@@ -0,0 +1,259 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/sender"
+)
+
+// senderSet holds everything built from the server settings, so a
+// configuration reload can swap it in one step
+type senderSet struct {
+	sender        sender.Sender
+	primary       sender.Sender           // Resends spooled payloads to the primary destination only
+	composite     *sender.CompositeSender // nil without secondary destinations
+	commandStream *sender.CommandStream   // nil unless commands are pushed over a persistent stream
+	breaker       *sender.CircuitBreaker  // nil when the circuit breaker is disabled
+	heartbeat     *sender.HTTPSender      // nil when heartbeats are disabled
+	tokens        *sender.TokenSource     // nil when the static token is used
+}
+
+// newSenderSet creates the senders for serverURL from the current configuration
+func newSenderSet(serverURL, token string) (*senderSet, error) {
+	tokens, err := newTokenSource(serverURL, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize sender
+	var metricSender sender.Sender
+	if socket := config.GetRelaySocket(); socket != "" {
+		metricSender, err = sender.NewUnixSocketSender(socket)
+	} else if brokerURL := config.GetMQTTBrokerURL(); brokerURL != "" {
+		metricSender, err = sender.NewMQTTSender(brokerURL, config.GetMQTTTopic(), token)
+	} else {
+		metricSender, err = newServerSender(serverURL, token, tokens)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Stop hammering the server during outages
+	var breaker *sender.CircuitBreaker
+	if threshold := config.GetCircuitBreakerThreshold(); threshold > 0 {
+		breaker = sender.NewCircuitBreaker(metricSender, threshold, config.CircuitBreakerOpenInterval, config.CircuitBreakerMaxInterval)
+		metricSender = breaker
+	}
+
+	// Secondary destinations: other organizations, Prometheus remote_write and Graphite
+	primarySender := metricSender
+	var secondaries []sender.Destination
+	for _, tenant := range config.GetTenants() {
+		// The server's CA, client certificate and HMAC secret are only sent to
+		// the server itself, never to another organization's URL
+		var tenantSender *sender.HTTPSender
+		if tenant.URL == "" || tenant.URL == serverURL || tenant.URL == config.GetFallbackServerURL() {
+			tenantURL := tenant.URL
+			if tenantURL == "" {
+				tenantURL = serverURL
+			}
+			tenantSender, err = sender.NewHTTPSender(tenantURL, tenant.Token)
+		} else {
+			tenantSender, err = sender.NewTenantHTTPSender(tenant.URL, tenant.Token)
+		}
+		if err != nil {
+			return nil, err
+		}
+		secondaries = append(secondaries, sender.Destination{Name: "tenant:" + tenant.Name, Sender: tenantSender})
+	}
+	if endpoint := config.GetRemoteWriteURL(); endpoint != "" {
+		remoteWrite, err := sender.NewRemoteWriteSender(endpoint, config.GetRemoteWriteToken())
+		if err != nil {
+			return nil, err
+		}
+		secondaries = append(secondaries, sender.Destination{Name: "remote_write", Sender: remoteWrite})
+	}
+	if address := config.GetGraphiteAddress(); address != "" {
+		graphite, err := sender.NewGraphiteSender(address, config.GetGraphitePrefix(), config.GetGraphiteInterval())
+		if err != nil {
+			return nil, err
+		}
+		secondaries = append(secondaries, sender.Destination{Name: "graphite", Sender: graphite})
+	}
+	var composite *sender.CompositeSender
+	if len(secondaries) > 0 {
+		composite = sender.NewCompositeSender(sender.Destination{Name: "monify", Sender: primarySender}, secondaries...)
+		metricSender = composite
+	}
+
+	// Persistent command stream (HTTPS mode only)
+	var commandStream *sender.CommandStream
+	if config.IsCommandStreamEnabled() && config.GetMQTTBrokerURL() == "" && config.GetRelaySocket() == "" {
+		streamURL := config.GetCommandStreamURL()
+		if streamURL == "" {
+			streamURL = agentEndpoint(serverURL, "commands/stream")
+		}
+		commandStream, err = sender.NewCommandStream(streamURL, token)
+		if err != nil {
+			return nil, err
+		}
+		if tokens != nil {
+			commandStream.SetTokenSource(tokens)
+		}
+	}
+
+	// Lightweight heartbeat while metrics sends fail (HTTPS mode only)
+	var heartbeat *sender.HTTPSender
+	if config.IsHeartbeatEnabled() && config.GetMQTTBrokerURL() == "" && config.GetRelaySocket() == "" {
+		heartbeatURL := config.GetHeartbeatURL()
+		if heartbeatURL == "" {
+			heartbeatURL = agentEndpoint(serverURL, "heartbeat")
+		}
+		heartbeat, err = sender.NewHTTPSender(heartbeatURL, token)
+		if err != nil {
+			return nil, err
+		}
+		if tokens != nil {
+			heartbeat.SetTokenSource(tokens)
+		}
+	}
+
+	return &senderSet{
+		sender:        metricSender,
+		primary:       primarySender,
+		composite:     composite,
+		commandStream: commandStream,
+		breaker:       breaker,
+		heartbeat:     heartbeat,
+		tokens:        tokens,
+	}, nil
+}
+
+// newTokenSource returns the source of short-lived access tokens, exchanged
+// for the configured refresh token, or nil when the static token is used
+func newTokenSource(serverURL, token string) (*sender.TokenSource, error) {
+	refreshToken := config.GetRefreshToken()
+	if refreshToken == "" {
+		return nil, nil
+	}
+	tokenURL := config.GetTokenURL()
+	if tokenURL == "" {
+		tokenURL = agentEndpoint(serverURL, "token")
+	}
+	return sender.NewTokenSource(tokenURL, token, refreshToken)
+}
+
+// startBackground starts the command stream and heartbeat of the current
+// sender set. They run until stopBackground is called or ctx is cancelled.
+func (a *Agent) startBackground(ctx context.Context) {
+	bgCtx, cancel := context.WithCancel(ctx)
+	a.stopBackground = cancel
+	if a.dryRun {
+		return // Nothing talks to the server
+	}
+
+	if a.commandStream != nil {
+		go a.runCommandStream(bgCtx, a.commandStream)
+	}
+	if a.heartbeat != nil {
+		go a.runHeartbeat(bgCtx, a.heartbeat)
+	}
+}
+
+// reload re-reads the env file and applies the server URL, token, senders,
+// collector toggles and collection interval without restarting. On error the
+// running configuration is kept. It returns the collection interval to use.
+func (a *Agent) reload(ctx context.Context, interval time.Duration) time.Duration {
+	changed, err := config.ReloadEnvFile()
+	if err != nil {
+		log.Printf("ERROR: %v - %s", err, "Failed to reload configuration, keeping current settings")
+		return interval
+	}
+	if len(changed) == 0 {
+		log.Printf("INFO: %s", "Configuration unchanged")
+		return interval
+	}
+
+	// Keep the server URL and token the agent was created with unless the
+	// file changed them. The token is optional with a client certificate or
+	// a refresh token.
+	a.mu.RLock()
+	serverURL, token := a.serverURL, a.token
+	a.mu.RUnlock()
+	if slices.Contains(changed, "MONIFY_SERVER_URL") {
+		serverURL = config.GetServerURL()
+	}
+	if slices.Contains(changed, "MONIFY_TOKEN") || slices.Contains(changed, "MONIFY_TOKEN_CREDENTIAL") {
+		current := token
+		var err error
+		if token, err = config.GetToken(); err != nil && config.GetTokenCredential() != "" {
+			// systemd only decrypts the sealed token when the service starts
+			log.Printf("WARN: %v - %s", err, "Failed to read the sealed token, keeping the current one until the agent restarts")
+			token = current
+		}
+	}
+
+	a.mu.RLock()
+	seen := a.commandPolicy.seen
+	a.mu.RUnlock()
+	policy, err := newCommandPolicy(seen)
+	if err != nil {
+		log.Printf("ERROR: %v - %s", err, "Failed to apply reloaded configuration, keeping current settings")
+		return interval
+	}
+
+	senders, err := newSenderSet(serverURL, token)
+	if err != nil {
+		log.Printf("ERROR: %v - %s", err, "Failed to apply reloaded configuration, keeping current settings")
+		return interval
+	}
+
+	staticCollector := NewStaticCollector()
+	dynamicCollector := NewDynamicCollector()
+	dynamicCollector.Start()
+
+	a.stopBackground()
+
+	a.mu.Lock()
+	old, oldDynamic := a.senderSet, a.dynamicCollector
+	if token != a.token {
+		// A new token deserves a fresh chance and reports its own scopes
+		a.authFailed = false
+		a.authFailures = 0
+		a.authGaveUp = false
+		a.tokenScopes = nil
+	}
+	a.serverURL = serverURL
+	a.token = token
+	a.senderSet = senders
+	a.staticCollector = staticCollector
+	a.dynamicCollector = dynamicCollector
+	a.readOnly = config.IsReadOnlyMode()
+	a.commandPolicy = policy
+	a.alerts = newAlertEvaluator(a.alerts)
+	a.budget = newResourceBudget(a.budget)
+	a.batchSize = config.GetBatchIntervals()
+	if maxItems := config.GetMaxSectionItems(); maxItems != a.chunker.maxItems {
+		a.chunker = newPayloadChunker(maxItems)
+	}
+	a.mu.Unlock()
+
+	oldDynamic.Stop()
+	if err := old.sender.Close(); err != nil {
+		log.Printf("ERROR: %v - %s", err, "Failed to close sender")
+	}
+	if old.heartbeat != nil {
+		old.heartbeat.Close()
+	}
+
+	a.startBackground(ctx)
+
+	interval = config.GetCollectionInterval()
+	log.Printf("INFO: Configuration reloaded [changed=%s server=%s interval=%s]", strings.Join(changed, ","), serverURL, interval)
+	return interval
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/monify-labs/agent/internal/config"
+)
+
+// updateConfig handles the update_config command: the settings in params
+// are persisted to the env file and applied by a configuration reload.
+func (a *Agent) updateConfig(params map[string]any) {
+	if !saveRemoteSettings(params) {
+		return
+	}
+
+	select {
+	case a.reloadChan <- struct{}{}:
+	default: // Reload already pending
+	}
+}
+
+// saveRemoteSettings persists server-provided settings to the env file and
+// reports whether any were saved. Nothing is written if any setting is
+// unknown or invalid.
+func saveRemoteSettings(params map[string]any) bool {
+	vars := make(map[string]string, len(params))
+	for name, value := range params {
+		key, ok := config.RemoteSettings[name]
+		if !ok {
+			log.Printf("WARN: Refusing config update: setting not allowed [setting=%s]", name)
+			return false
+		}
+		str, err := settingValue(value)
+		if err != nil {
+			log.Printf("WARN: Refusing config update: %v [setting=%s]", err, name)
+			return false
+		}
+		vars[key] = str
+	}
+	if len(vars) == 0 {
+		return false
+	}
+
+	if err := config.SaveEnvFile(vars); err != nil {
+		log.Printf("ERROR: %v - %s", err, "Failed to save config update")
+		return false
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	log.Printf("INFO: Config update saved [settings=%s]", strings.Join(names, ","))
+	return true
+}
+
+// settingValue converts a JSON parameter to its env file form: lists are
+// comma-separated and objects become comma-separated key=value pairs
+func settingValue(value any) (string, error) {
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case bool:
+		str = strconv.FormatBool(v)
+	case float64:
+		str = strconv.FormatFloat(v, 'f', -1, 64)
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok || strings.Contains(s, ",") {
+				return "", fmt.Errorf("list items must be strings without commas")
+			}
+			items = append(items, s)
+		}
+		str = strings.Join(items, ",")
+	case map[string]any:
+		pairs := make([]string, 0, len(v))
+		for key, item := range v {
+			s, ok := item.(string)
+			if !ok || strings.ContainsAny(key, ",=") || strings.Contains(s, ",") {
+				return "", fmt.Errorf("labels must be strings without commas")
+			}
+			pairs = append(pairs, key+"="+s)
+		}
+		sort.Strings(pairs)
+		str = strings.Join(pairs, ",")
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+
+	// One variable per line in the env file
+	if strings.ContainsAny(str, "\r\n") {
+		return "", fmt.Errorf("value contains a line break")
+	}
+	return str, nil
+}
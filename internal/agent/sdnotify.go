@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sdNotify sends a state such as "READY=1" to systemd when the agent runs as
+// a Type=notify service. It does nothing when NOTIFY_SOCKET is not set.
+func sdNotify(state ...string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:] // Abstract namespace
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(strings.Join(state, "\n")))
+	return err
+}
+
+// watchdogInterval returns how often systemd expects WATCHDOG=1, half the
+// configured WatchdogSec, or 0 when the watchdog is not enabled for us
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// notifyStatus is the STATUS= line shown by systemctl status
+func (a *Agent) notifyStatus() string {
+	a.mu.RLock()
+	lastSend, sendFailing := a.lastSend, a.sendFailing
+	a.mu.RUnlock()
+
+	if lastSend.IsZero() {
+		return "STATUS=No metrics sent yet"
+	}
+	status := fmt.Sprintf("STATUS=Last send %s ago", time.Since(lastSend).Round(time.Second))
+	if sendFailing {
+		status += ", sends failing"
+	}
+	return status
+}
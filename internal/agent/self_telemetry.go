@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// selfTelemetry measures the agent's own resource usage between payloads
+type selfTelemetry struct {
+	mu           sync.Mutex
+	lastAt       time.Time
+	lastCPU      time.Duration // User and system CPU time at lastAt
+	lastNumGC    uint32
+	lastPauseNs  uint64
+	payloadBytes int
+	sendLatency  time.Duration
+}
+
+// collect returns the agent's resource usage since the previous call
+func (t *selfTelemetry) collect() *models.AgentMetrics {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	now := time.Now()
+	cpu := processCPUTime()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	metrics := &models.AgentMetrics{
+		RSSBytes:      residentBytes(),
+		HeapBytes:     mem.HeapAlloc,
+		Goroutines:    runtime.NumGoroutine(),
+		GCCount:       mem.NumGC - t.lastNumGC,
+		GCPauseMs:     float64(mem.PauseTotalNs-t.lastPauseNs) / 1e6,
+		PayloadBytes:  t.payloadBytes,
+		SendLatencyMs: float64(t.sendLatency.Microseconds()) / 1000,
+	}
+	if !t.lastAt.IsZero() {
+		if elapsed := now.Sub(t.lastAt); elapsed > 0 {
+			metrics.CPUPercent = float64(cpu-t.lastCPU) / float64(elapsed) * 100
+		}
+	}
+
+	t.lastAt, t.lastCPU = now, cpu
+	t.lastNumGC, t.lastPauseNs = mem.NumGC, mem.PauseTotalNs
+	return metrics
+}
+
+// recordPayload remembers the encoded size of an assembled payload
+func (t *selfTelemetry) recordPayload(payload *models.MetricPayload) {
+	counter := &countingWriter{}
+	if err := json.NewEncoder(counter).Encode(payload); err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.payloadBytes = counter.n - 1 // Without the encoder's trailing newline
+	t.mu.Unlock()
+}
+
+// recordSend remembers how long a successful send took
+func (t *selfTelemetry) recordSend(latency time.Duration) {
+	t.mu.Lock()
+	t.sendLatency = latency
+	t.mu.Unlock()
+}
+
+// countingWriter discards what is written and counts the bytes
+type countingWriter struct {
+	n int
+}
+
+// Write counts p
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
+
+// processCPUTime returns the user and system CPU time used by the process
+func processCPUTime() time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+// residentBytes returns the resident set size of the process, 0 if unknown
+func residentBytes() uint64 {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	// "size resident shared text lib data dt", in pages
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * uint64(os.Getpagesize())
+}
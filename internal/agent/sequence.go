@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sequencer numbers payloads so the server can deduplicate resent payloads and
+// detect gaps. The last number is persisted so the sequence keeps increasing
+// across restarts.
+type sequencer struct {
+	path string // Empty when the sequence is kept in memory only
+
+	mu   sync.Mutex
+	last uint64
+}
+
+// newSequencer loads the last sequence number from path
+func newSequencer(path string) *sequencer {
+	s := &sequencer{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("WARN: Failed to read payload sequence, starting over [path=%s error=%v]", path, err)
+		}
+		return s
+	}
+
+	if last, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err == nil {
+		s.last = last
+	}
+	return s
+}
+
+// next returns the next sequence number and persists it
+func (s *sequencer) next() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.last++
+	if s.path != "" {
+		if err := s.save(); err != nil {
+			// Keep numbering in memory; the sequence restarts after a restart
+			log.Printf("WARN: Failed to persist payload sequence, continuing in memory: %v", err)
+			s.path = ""
+		}
+	}
+	return s.last
+}
+
+// save writes the last sequence number atomically. Caller must hold s.mu.
+func (s *sequencer) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(s.last, 10)+"\n"), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// newPayloadID returns a random (version 4) UUID
+func newPayloadID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
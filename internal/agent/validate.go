@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/user"
+	"slices"
+	"strings"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/metrics/dynamic"
+	"github.com/monify-labs/agent/internal/mountfilter"
+	"github.com/monify-labs/agent/internal/proxy"
+)
+
+// ValidateConfig checks the env file and what the agent makes of the loaded
+// configuration: collector names, alert rules, probe targets, keys and the
+// user to switch to. It only reads local files and runs no collectors.
+func ValidateConfig() ([]config.Problem, error) {
+	problems, err := config.CheckEnvFile()
+	if err != nil {
+		return nil, err
+	}
+	add := func(severity, setting, format string, args ...any) {
+		problems = append(problems, config.Problem{Severity: severity, Setting: setting, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Collectors
+	known := KnownCollectors()
+	unknown := func(setting, name string) {
+		if match := config.ClosestMatch(name, known); match != "" {
+			add(config.ProblemError, setting, "unknown collector %q, did you mean %q?", name, match)
+		} else {
+			add(config.ProblemError, setting, "unknown collector %q", name)
+		}
+	}
+	disabled := config.GetDisabledCollectors()
+	for _, name := range slices.Sorted(maps.Keys(disabled)) {
+		if !isKnownCollector(name) {
+			unknown("MONIFY_DISABLE_COLLECTORS", name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(config.GetCollectorIntervals())) {
+		switch {
+		case !isKnownCollector(name):
+			unknown("MONIFY_COLLECTOR_INTERVALS", name)
+		case name == "directories" || name == "plugins" || name == "anomalies":
+			add(config.ProblemWarning, "MONIFY_COLLECTOR_INTERVALS", "collector %q has its own schedule and is not affected", name)
+		case disabled[name]:
+			add(config.ProblemWarning, "MONIFY_COLLECTOR_INTERVALS", "collector %q is disabled", name)
+		}
+	}
+
+	// Settings of collectors that are disabled, and their targets
+	if tags := os.Getenv("MONIFY_CLOUD_TAGS"); !config.IsCloudMetadataEnabled() && (tags == "true" || tags == "1") {
+		add(config.ProblemWarning, "MONIFY_CLOUD_TAGS", "has no effect: MONIFY_CLOUD_METADATA is false")
+	}
+	if probes := config.GetProbes(); len(probes) > 0 {
+		if disabled["probes"] {
+			add(config.ProblemWarning, "MONIFY_PROBES", "has no effect: the probes collector is disabled")
+		}
+		for _, target := range probes {
+			if err := dynamic.CheckProbeTarget(target); err != nil {
+				add(config.ProblemError, "MONIFY_PROBES", "%s: %v", target, err)
+			}
+		}
+	}
+	if dirs := config.GetWatchDirs(); len(dirs) > 0 {
+		if disabled["directories"] {
+			add(config.ProblemWarning, "MONIFY_WATCH_DIRS", "has no effect: the directories collector is disabled")
+		}
+		for _, dir := range dirs {
+			if _, err := os.Stat(dir); err != nil {
+				add(config.ProblemWarning, "MONIFY_WATCH_DIRS", "%v", err)
+			}
+		}
+	}
+	disks := diskFilterOptions()
+	for _, setting := range []struct {
+		name    string
+		options mountfilter.Options
+	}{
+		{"MONIFY_DISK_INCLUDE", mountfilter.Options{Include: disks.Include}},
+		{"MONIFY_DISK_EXCLUDE", mountfilter.Options{Exclude: disks.Exclude}},
+		{"MONIFY_DISK_SKIP_FSTYPES", mountfilter.Options{SkipTypes: disks.SkipTypes}},
+		{"MONIFY_DISK_KEEP_FSTYPES", mountfilter.Options{KeepTypes: disks.KeepTypes}},
+	} {
+		if _, err := mountfilter.Parse(setting.options); err != nil {
+			add(config.ProblemError, setting.name, "%v", err)
+		}
+	}
+
+	// Alert rules
+	for _, text := range config.GetAlertRules() {
+		if _, err := parseAlertRule(text); err != nil {
+			add(config.ProblemError, "MONIFY_ALERT_RULES", "%q: %v", text, err)
+		}
+	}
+
+	// Keys and proxy
+	if value := config.GetCommandPublicKey(); value != "" {
+		if _, err := parsePublicKey(value); err != nil {
+			add(config.ProblemError, "MONIFY_COMMAND_PUBLIC_KEY", "%v", err)
+		}
+	} else if !config.IsUnsignedCommandsAllowed() && !config.IsReadOnlyMode() {
+		add(config.ProblemWarning, "MONIFY_COMMAND_PUBLIC_KEY", "no key is set, so every server command will be refused")
+	}
+	if err := proxy.Err(); err != nil {
+		add(config.ProblemError, "MONIFY_SOCKS5_PROXY", "%v", err)
+	}
+
+	// Privileges
+	if name := config.GetRunAsUser(); name != "" {
+		if _, err := user.Lookup(name); err != nil {
+			add(config.ProblemError, "MONIFY_USER", "%v; create it with: useradd --system --no-create-home %s", err, name)
+		}
+		for _, capability := range config.GetCapabilities() {
+			if _, ok := capabilityNumbers[strings.TrimPrefix(strings.ToLower(capability), "cap_")]; !ok {
+				add(config.ProblemError, "MONIFY_CAPABILITIES", "unsupported capability %q", capability)
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// isKnownCollector reports whether name is a dynamic collector
+func isKnownCollector(name string) bool {
+	return slices.Contains(dynamicCollectors, name) || slices.Contains(optionalCollectors, name)
+}
@@ -3,7 +3,13 @@ package config
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -12,30 +18,372 @@ const (
 	ServerURL = "https://api.monify.cloud/v1/agent/metrics"
 	Timeout   = 10 * time.Second
 
+	// Connection settings
+	DNSRefreshInterval = 5 * time.Minute        // Pooled connections are dropped so the server name is resolved again
+	HappyEyeballsDelay = 300 * time.Millisecond // Head start of IPv6 before IPv4 is dialed in parallel
+	MQTTTopic          = "monify/metrics"       // Default MQTT topic prefix
+
+	// Authentication failure settings
+	AuthFailureThreshold = 5                // Consecutive token rejections before giving up
+	AuthFailureWindow    = 10 * time.Minute // Rejections must last at least this long before giving up
+
+	// Token refresh settings
+	TokenRefreshMargin = 1 * time.Minute // Access tokens are refreshed this long before they expire
+
+	// Failover settings
+	FailoverThreshold     = 3               // Consecutive primary failures before failing over
+	FailoverProbeInterval = 5 * time.Minute // How often the primary is retried while failed over
+
+	// Command stream settings
+	CommandStreamIdleTimeout = 90 * time.Second // Reconnect when no event or keepalive arrives for this long
+	CommandStreamMinBackoff  = 5 * time.Second
+	CommandStreamMaxBackoff  = 5 * time.Minute
+
+	// Server command settings
+	RestartMinUptime = 1 * time.Minute // Restart commands are ignored until the agent has run this long
+	PortScanMaxPorts = 1024            // Ports one scan_ports command may probe
+
+	// High-resolution mode settings (high_resolution server command)
+	HighResolutionInterval    = 1 * time.Second  // Default collection interval
+	HighResolutionDuration    = 10 * time.Minute // Default duration before reverting
+	HighResolutionMaxDuration = 1 * time.Hour
+
+	// Command security settings
+	CommandAuditLog    = "/var/log/monify/commands.log" // Every server command and its outcome
+	CommandAuditMaxMB  = 10                             // The audit log is rotated to .1 beyond this size
+	CommandMaxValidity = 1 * time.Hour                  // Signed commands may not expire later than this
+
+	// Heartbeat settings
+	HeartbeatInterval = 5 * time.Second // Heartbeats are only sent while metrics sends fail or are disabled
+
+	// Circuit breaker settings
+	CircuitBreakerThreshold    = 5                // Consecutive failures before the circuit opens
+	CircuitBreakerOpenInterval = 1 * time.Minute  // Wait before the first probe
+	CircuitBreakerMaxInterval  = 10 * time.Minute // Upper bound as failed probes double the wait
+
+	// Exporter settings
+	GraphitePrefix = "monify" // Default Graphite path prefix
+
+	// Local API settings (loopback only)
+	StatusAddress = "127.0.0.1:9123" // Local status endpoint
+
 	// Collection settings
 	CollectionInterval    = 15 * time.Second
+	CollectionJitter      = 5 * time.Second // Upper bound of the random collection phase offset
 	StaticRefreshInterval = 1 * time.Hour
 
+	// Plugin settings
+	PluginDir      = "/etc/monify/plugins.d"
+	PluginInterval = 60 * time.Second // How often plugins are run
+	PluginTimeout  = 10 * time.Second // Plugins still running after this are killed
+
+	// Anomaly detection settings
+	AnomalyThreshold = 4.0              // Samples this many standard deviations from the baseline are anomalous
+	AnomalyWindow    = 10 * time.Minute // Span of the rolling baselines
+	AnomalyWarmup    = 5 * time.Minute  // Samples needed before a baseline is trusted
+
+	// Resource limit settings
+	ResourceLimitResume = 0.8 // Shed collectors come back below this fraction of the budget
+
+	// Collector timeout settings
+	CollectorTimeout = 8 * time.Second // Collectors still running after this are left out of the payload
+
+	// Collector interval settings
+	CollectorIntervalSlack = 1 * time.Second // Collectors with their own interval run this much early to absorb ticker jitter
+
+	// One-shot collection settings
+	CollectOnceSampling = 3 * time.Second // Background samples taken before "monify collect" reads the collectors
+
+	// Collector quarantine settings
+	CollectorQuarantineFailures = 5               // Consecutive failures before a collector is quarantined
+	CollectorRetryInterval      = 5 * time.Minute // How often a quarantined collector is retried
+
+	// Payload settings
+	MaxSectionItems = 500 // List sections larger than this are chunked across payloads
+
+	// State settings
+	SequenceFile    = "/var/lib/monify/sequence"    // Last payload sequence number
+	MaintenanceFile = "/var/lib/monify/maintenance" // End of the current maintenance window
+	InventoryFile   = "/var/lib/monify/inventory"   // Static inventory of the last refresh, changes are reported against it
+	AgentSettings   = "/var/lib/monify/settings"    // Settings saved by the agent after dropping root, see SaveEnvFile
+
+	// Maintenance settings
+	MaintenanceMaxDuration = 7 * 24 * time.Hour // Longest maintenance window
+
+	// Alert settings
+	AlertHookTimeout = 30 * time.Second // Alert hooks still running after this are killed
+
+	// Offline spool settings
+	SpoolDir            = "/var/lib/monify/spool"
+	SpoolMaxMB          = 100 // Oldest payloads are dropped beyond this size
+	SpoolReplayBatch    = 20  // Spooled payloads per replay request
+	SpoolReplayRequests = 5   // Replay requests per collection cycle
+
+	// Update settings
+	UpdateURL     = "https://github.com/monify-labs/agent/releases" // Release downloads, or a mirror with the same layout
+	UpdateMaxMB   = 100                                             // Larger downloads are refused
+	UpdateTimeout = 5 * time.Minute
+
 	// Agent info (injected at build time via ldflags)
 	Version   = "1.1.1"
 	Commit    = "unknown"
 	BuildDate = "unknown"
+)
+
+// SystemEnvFile is the env file of the system-wide installation
+const SystemEnvFile = "/etc/monify/env"
+
+// EnvFilePath is the environment file settings are read from and saved to
+// (monify --config overrides it)
+var EnvFilePath = SystemEnvFile
+
+// ResolvePaths selects the settings files before command-line flags are
+// parsed: the env file in MONIFY_CONFIG_PATH if set, otherwise the system
+// files. Users other than root who cannot read the system env file use
+// env and config.yaml in ~/.config/monify instead, which allows rootless
+// operation and separate agents per user.
+func ResolvePaths() {
+	if path := os.Getenv("MONIFY_CONFIG_PATH"); path != "" {
+		EnvFilePath = path
+		return
+	}
+	if os.Geteuid() == 0 || syscall.Access(EnvFilePath, accessRead) == nil {
+		return
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return
+	}
+	EnvFilePath = filepath.Join(dir, "monify", "env")
+	ConfigFilePath = filepath.Join(dir, "monify", "config.yaml")
+}
+
+// EnvFileWritable reports whether the caller may write the env file, or
+// create it when it does not exist yet
+func EnvFileWritable() bool {
+	path := EnvFilePath
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return syscall.Access(path, accessWrite) == nil
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		path = parent
+	}
+}
+
+// accessRead and accessWrite are R_OK and W_OK for access(2)
+const (
+	accessRead  = 4
+	accessWrite = 2
+)
+
+// ReleasePublicKey is the base64 Ed25519 key release checksums are signed
+// with (injected at build time via ldflags). Updates are refused without one.
+var ReleasePublicKey = ""
 
-	// Environment file path
-	EnvFilePath = "/etc/monify/env"
+// CommandPublicKey is the base64 Ed25519 key server commands are signed with
+// (injected at build time via ldflags). Without one, and without
+// MONIFY_COMMAND_PUBLIC_KEY, server commands are refused unless unsigned
+// commands are explicitly allowed.
+var CommandPublicKey = ""
+
+// RemoteSettings maps the settings the server may change with the
+// update_config command to their environment variables. Connection, token
+// and plugin settings are deliberately absent: the server must not be able
+// to redirect the agent or make it run code.
+var RemoteSettings = map[string]string{
+	"interval":         "MONIFY_INTERVAL",
+	"labels":           "MONIFY_LABELS",
+	"collect_packages": "MONIFY_COLLECT_PACKAGES",
+	"cloud_tags":       "MONIFY_CLOUD_TAGS",
+	"sysctls":          "MONIFY_SYSCTLS",
+	"watch_dirs":       "MONIFY_WATCH_DIRS",
+	"probes":           "MONIFY_PROBES",
+}
+
+// fileEnv holds the variables applied from the env file, so a reload can
+// update or remove them without touching variables set by the environment
+var (
+	fileEnvMu sync.Mutex
+	fileEnv   = map[string]string{}
 )
 
-// LoadEnvFile loads environment variables from /etc/monify/env
+// LoadEnvFile loads environment variables from /etc/monify/env and the
+// settings in config.yaml. Settings without errors are applied also when
+// config.yaml has errors.
+//
+// Settings are resolved in this order, the first one set wins: command-line
+// flags (see Override), the process environment, the env file, config.yaml,
+// and the defaults of the getters. A variable set to an empty value in the
+// process environment counts as set.
 func LoadEnvFile() error {
+	vars, err := readSettings()
+	if vars == nil {
+		return err
+	}
+
+	fileEnvMu.Lock()
+	defer fileEnvMu.Unlock()
+
+	for key, value := range vars {
+		// Only set if not already set in environment
+		if _, ok := os.LookupEnv(key); !ok {
+			os.Setenv(key, value)
+			fileEnv[key] = value
+		}
+	}
+
+	return err
+}
+
+// ReloadEnvFile re-reads /etc/monify/env and config.yaml and returns the
+// names of the variables that changed. Variables set by the process
+// environment keep precedence; variables removed from the files are unset.
+func ReloadEnvFile() ([]string, error) {
+	vars, err := readSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	fileEnvMu.Lock()
+	defer fileEnvMu.Unlock()
+
+	var changed []string
+	for key, value := range vars {
+		if old, fromFile := fileEnv[key]; fromFile {
+			if old == value {
+				continue
+			}
+		} else if _, ok := os.LookupEnv(key); ok {
+			continue // Set by the environment or a flag
+		}
+		os.Setenv(key, value)
+		fileEnv[key] = value
+		changed = append(changed, key)
+	}
+
+	for key := range fileEnv {
+		if _, ok := vars[key]; !ok {
+			os.Unsetenv(key)
+			delete(fileEnv, key)
+			changed = append(changed, key)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// Override sets a variable from a command-line flag. It takes precedence over
+// the process environment and the settings files, also across reloads.
+func Override(key, value string) {
+	fileEnvMu.Lock()
+	defer fileEnvMu.Unlock()
+
+	os.Setenv(key, value)
+	delete(fileEnv, key)
+}
+
+// EnvFileValue returns the value of key in /etc/monify/env as currently on
+// disk, whether or not it has been applied yet
+func EnvFileValue(key string) string {
+	vars, err := readEnvFile()
+	if err != nil {
+		return ""
+	}
+	return vars[key]
+}
+
+// ProcessEnviron returns the environment the process was started with,
+// without the variables applied from the env file
+func ProcessEnviron() []string {
+	fileEnvMu.Lock()
+	defer fileEnvMu.Unlock()
+
+	var environ []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, fromFile := fileEnv[key]; !fromFile {
+			environ = append(environ, kv)
+		}
+	}
+	return environ
+}
+
+// readSettings returns the settings of config.yaml overridden by those of
+// the env file. With an error in config.yaml, the settings read so far are
+// returned along with the error.
+func readSettings() (map[string]string, error) {
+	vars, err := readEnvFile()
+	if err != nil {
+		return nil, err
+	}
+	settings, err := readConfigYAML()
+	if settings == nil {
+		return vars, err
+	}
+	for key, value := range vars {
+		settings[key] = value
+	}
+	return settings, err
+}
+
+// readEnvFile parses /etc/monify/env as KEY=VALUE lines. Settings the
+// agent saved in AgentSettings take precedence over the env file.
+func readEnvFile() (map[string]string, error) {
 	data, err := os.ReadFile(EnvFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	agent := readAgentSettings()
+	if err != nil && agent == nil {
+		return nil, nil // File doesn't exist is not an error
+	}
+
+	vars := parseEnvLines(data)
+	for key, value := range agent {
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// readAgentSettings returns the settings the agent saved in AgentSettings.
+// The file is writable by the unprivileged agent user, so only the settings
+// it may save are taken from it.
+func readAgentSettings() map[string]string {
+	data, err := os.ReadFile(AgentSettings)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // File doesn't exist is not an error
+		return nil
+	}
+	vars := parseEnvLines(data)
+	for key := range vars {
+		if !agentSetting(key) {
+			delete(vars, key)
 		}
-		return err
 	}
+	return vars
+}
+
+// agentSetting reports whether the agent may save key in AgentSettings: the
+// settings the server may change and the rotated refresh token
+func agentSetting(key string) bool {
+	if key == "MONIFY_REFRESH_TOKEN" {
+		return true
+	}
+	for _, name := range RemoteSettings {
+		if name == key {
+			return true
+		}
+	}
+	return false
+}
 
-	// Parse each line as KEY=VALUE
+// parseEnvLines parses KEY=VALUE lines, skipping blank lines and comments
+func parseEnvLines(data []byte) map[string]string {
+	vars := make(map[string]string)
 	lines := strings.Split(string(data), "\n")
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
@@ -45,58 +393,52 @@ func LoadEnvFile() error {
 
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) == 2 {
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-			// Only set if not already set in environment
-			if os.Getenv(key) == "" {
-				os.Setenv(key, value)
-			}
+			vars[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
 		}
 	}
-
-	return nil
+	return vars
 }
 
-// SaveEnvFile saves environment variables to /etc/monify/env
+// SaveEnvFile saves environment variables to /etc/monify/env, keeping the
+// other settings and comments in it. An agent that dropped root cannot write
+// the env file; it saves remote settings and rotated refresh tokens in
+// AgentSettings instead, which takes precedence until the same settings are
+// saved to the env file again.
 func SaveEnvFile(vars map[string]string) error {
-	// Read existing file
-	existing := make(map[string]string)
-	if data, err := os.ReadFile(EnvFilePath); err == nil {
-		lines := strings.Split(string(data), "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line == "" || strings.HasPrefix(line, "#") {
-				continue
-			}
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				existing[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
-			}
+	if !EnvFileWritable() {
+		agentOnly := true
+		for key := range vars {
+			agentOnly = agentOnly && agentSetting(key)
+		}
+		if agentOnly {
+			return editSettingsFile(AgentSettings, vars, nil)
 		}
 	}
 
-	// Merge with new vars
-	for k, v := range vars {
-		existing[k] = v
+	if err := editSettingsFile(EnvFilePath, vars, nil); err != nil {
+		return err
 	}
-
-	// Create directory
-	dir := "/etc/monify"
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	names := make([]string, 0, len(vars))
+	for key := range vars {
+		names = append(names, key)
 	}
+	return forgetAgentSettings(names)
+}
 
-	// Write file
-	var content strings.Builder
-	for k, v := range existing {
-		content.WriteString(fmt.Sprintf("%s=%s\n", k, v))
+// forgetAgentSettings removes names from AgentSettings after they were
+// written to the env file, so the env file's values apply again
+func forgetAgentSettings(names []string) error {
+	saved := readAgentSettings()
+	var stale []string
+	for _, name := range names {
+		if _, ok := saved[name]; ok {
+			stale = append(stale, name)
+		}
 	}
-
-	if err := os.WriteFile(EnvFilePath, []byte(content.String()), 0600); err != nil {
-		return fmt.Errorf("failed to write env file: %w", err)
+	if len(stale) == 0 {
+		return nil
 	}
-
-	return nil
+	return editSettingsFile(AgentSettings, nil, stale)
 }
 
 // GetServerURL returns server URL from env or default
@@ -107,17 +449,818 @@ func GetServerURL() string {
 	return ServerURL
 }
 
-// GetToken returns token from environment variable
+// GetTimeout returns how long a request to the server or another destination
+// may take (MONIFY_TIMEOUT seconds)
+func GetTimeout() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("MONIFY_TIMEOUT")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return Timeout
+}
+
+// IsCommandStreamEnabled checks if server commands are received over a persistent stream
+func IsCommandStreamEnabled() bool {
+	enabled := os.Getenv("MONIFY_COMMAND_STREAM")
+	return enabled == "true" || enabled == "1"
+}
+
+// GetCommandStreamURL returns the command stream URL override; by default it is derived from the server URL
+func GetCommandStreamURL() string {
+	return os.Getenv("MONIFY_COMMAND_STREAM_URL")
+}
+
+// IsHeartbeatEnabled checks if heartbeats are sent while metrics sends fail or are disabled
+func IsHeartbeatEnabled() bool {
+	enabled := os.Getenv("MONIFY_HEARTBEAT")
+	return enabled == "true" || enabled == "1"
+}
+
+// GetHeartbeatURL returns the heartbeat URL override; by default it is derived from the server URL
+func GetHeartbeatURL() string {
+	return os.Getenv("MONIFY_HEARTBEAT_URL")
+}
+
+// GetRegistrationURL returns the registration URL override; by default it is derived from the server URL
+func GetRegistrationURL() string {
+	return os.Getenv("MONIFY_REGISTRATION_URL")
+}
+
+// IsRegistrationEnabled checks if the agent registers with the server on startup (MONIFY_REGISTRATION, default true)
+func IsRegistrationEnabled() bool {
+	enabled := os.Getenv("MONIFY_REGISTRATION")
+	return enabled != "false" && enabled != "0"
+}
+
+// GetDiagnosticsURL returns the diagnostics upload URL override; by default it is derived from the server URL
+func GetDiagnosticsURL() string {
+	return os.Getenv("MONIFY_DIAGNOSTICS_URL")
+}
+
+// GetMQTTBrokerURL returns the MQTT broker URL; when set, payloads are published there instead of sent over HTTPS
+func GetMQTTBrokerURL() string {
+	return os.Getenv("MONIFY_MQTT_URL")
+}
+
+// GetRelaySocket returns the Unix socket of a local relay; when set, payloads are handed to it instead of sent over HTTPS
+func GetRelaySocket() string {
+	return os.Getenv("MONIFY_RELAY_SOCKET")
+}
+
+// GetMQTTTopic returns the MQTT topic prefix; the hostname is appended per host
+func GetMQTTTopic() string {
+	if topic := os.Getenv("MONIFY_MQTT_TOPIC"); topic != "" {
+		return topic
+	}
+	return MQTTTopic
+}
+
+// Tenant is an additional Monify organization that receives the same metrics
+type Tenant struct {
+	Name  string
+	Token string
+	URL   string // Empty to use the main server URL
+}
+
+// GetTenants returns the additional organizations metrics are sent to
+// (MONIFY_TENANTS, comma-separated name=token or name=token@url)
+func GetTenants() []Tenant {
+	var tenants []Tenant
+	for _, entry := range strings.Split(os.Getenv("MONIFY_TENANTS"), ",") {
+		name, credentials, ok := strings.Cut(entry, "=")
+		if name = strings.TrimSpace(name); !ok || name == "" {
+			continue
+		}
+		token, url, _ := strings.Cut(strings.TrimSpace(credentials), "@")
+		if token == "" {
+			continue
+		}
+		tenants = append(tenants, Tenant{Name: name, Token: token, URL: url})
+	}
+	return tenants
+}
+
+// GetRemoteWriteURL returns the Prometheus remote_write endpoint metrics are mirrored to
+func GetRemoteWriteURL() string {
+	return os.Getenv("MONIFY_REMOTE_WRITE_URL")
+}
+
+// GetRemoteWriteToken returns the bearer token for the remote_write endpoint
+func GetRemoteWriteToken() string {
+	return os.Getenv("MONIFY_REMOTE_WRITE_TOKEN")
+}
+
+// GetGraphiteAddress returns the carbon plaintext address (host:port) metrics are mirrored to
+func GetGraphiteAddress() string {
+	return os.Getenv("MONIFY_GRAPHITE_ADDR")
+}
+
+// GetStatusAddress returns the local status API address (MONIFY_STATUS_ADDR, empty disables)
+func GetStatusAddress() string {
+	if addr, ok := os.LookupEnv("MONIFY_STATUS_ADDR"); ok {
+		return addr
+	}
+	return StatusAddress
+}
+
+// GetPrometheusAddress returns the local Prometheus endpoint address (MONIFY_PROMETHEUS_ADDR), empty when disabled
+func GetPrometheusAddress() string {
+	return os.Getenv("MONIFY_PROMETHEUS_ADDR")
+}
+
+// GetGraphitePrefix returns the Graphite path prefix
+func GetGraphitePrefix() string {
+	if prefix, ok := os.LookupEnv("MONIFY_GRAPHITE_PREFIX"); ok {
+		return prefix
+	}
+	return GraphitePrefix
+}
+
+// GetGraphiteInterval returns the interval Graphite timestamps are aligned to (0 sends every collection)
+func GetGraphiteInterval() time.Duration {
+	if value := os.Getenv("MONIFY_GRAPHITE_INTERVAL"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return 0
+}
+
+// GetFallbackServerURL returns the server URL used when the primary keeps failing
+func GetFallbackServerURL() string {
+	return os.Getenv("MONIFY_SERVER_URL_FALLBACK")
+}
+
+// GetFailoverThreshold returns how many consecutive failures trigger failover
+func GetFailoverThreshold() int {
+	if value := os.Getenv("MONIFY_FAILOVER_THRESHOLD"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 1 {
+			return n
+		}
+	}
+	return FailoverThreshold
+}
+
+// GetSOCKS5Proxy returns the SOCKS5 proxy (socks5://[user:pass@]host:port) outbound connections are tunneled through
+func GetSOCKS5Proxy() string {
+	return os.Getenv("MONIFY_SOCKS5_PROXY")
+}
+
+// GetCircuitBreakerThreshold returns how many consecutive failures open the circuit (0 disables)
+func GetCircuitBreakerThreshold() int {
+	if value := os.Getenv("MONIFY_CIRCUIT_BREAKER_THRESHOLD"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return CircuitBreakerThreshold
+}
+
+// GetIPFamily returns the address family for server connections: auto (default), ipv4 or ipv6
+func GetIPFamily() string {
+	switch family := strings.ToLower(os.Getenv("MONIFY_IP_FAMILY")); family {
+	case "ipv4", "ipv6":
+		return family
+	default:
+		return "auto"
+	}
+}
+
+// GetDNSRefreshInterval returns how often the server name is resolved again (MONIFY_DNS_REFRESH seconds, 0 disables)
+func GetDNSRefreshInterval() time.Duration {
+	if value := os.Getenv("MONIFY_DNS_REFRESH"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return DNSRefreshInterval
+}
+
+// GetCACertPath returns the path of a custom CA bundle (PEM), if configured
+func GetCACertPath() string {
+	return os.Getenv("MONIFY_CA_CERT")
+}
+
+// GetTLSServerName returns the name used to verify the server certificate instead of the URL host
+func GetTLSServerName() string {
+	return os.Getenv("MONIFY_TLS_SERVER_NAME")
+}
+
+// IsTLSInsecureSkipVerify checks if server certificate verification is disabled
+func IsTLSInsecureSkipVerify() bool {
+	skip := os.Getenv("MONIFY_TLS_INSECURE_SKIP_VERIFY")
+	return skip == "true" || skip == "1"
+}
+
+// GetClientCertPath returns the client certificate (PEM) presented for mutual TLS, if configured
+func GetClientCertPath() string {
+	return os.Getenv("MONIFY_CLIENT_CERT")
+}
+
+// GetClientKeyPath returns the client private key (PEM); defaults to the certificate file
+func GetClientKeyPath() string {
+	if path := os.Getenv("MONIFY_CLIENT_KEY"); path != "" {
+		return path
+	}
+	return GetClientCertPath()
+}
+
+// GetRefreshToken returns the refresh token exchanged for short-lived access tokens, if configured
+func GetRefreshToken() string {
+	return os.Getenv("MONIFY_REFRESH_TOKEN")
+}
+
+// GetTokenURL returns the token endpoint override; by default it is derived from the server URL
+func GetTokenURL() string {
+	return os.Getenv("MONIFY_TOKEN_URL")
+}
+
+// GetAuthFailureThreshold returns how many consecutive token rejections it
+// takes to give up (MONIFY_AUTH_FAILURES)
+func GetAuthFailureThreshold() int {
+	if n, err := strconv.Atoi(os.Getenv("MONIFY_AUTH_FAILURES")); err == nil && n > 0 {
+		return n
+	}
+	return AuthFailureThreshold
+}
+
+// GetAuthFailureWindow returns how long token rejections must last before
+// giving up (MONIFY_AUTH_FAILURE_WINDOW seconds)
+func GetAuthFailureWindow() time.Duration {
+	if n, err := strconv.Atoi(os.Getenv("MONIFY_AUTH_FAILURE_WINDOW")); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second
+	}
+	return AuthFailureWindow
+}
+
+// IsAuthFailureExit reports whether the agent exits once it gives up on a
+// rejected token (MONIFY_AUTH_FAILURE_ACTION "exit", the default) rather than
+// retrying forever ("retry")
+func IsAuthFailureExit() bool {
+	return os.Getenv("MONIFY_AUTH_FAILURE_ACTION") != "retry"
+}
+
+// GetHMACSecret returns the shared secret request bodies are signed with, if configured
+func GetHMACSecret() string {
+	return os.Getenv("MONIFY_HMAC_SECRET")
+}
+
+// GetToken returns the token from the environment, or decrypted from the
+// credential in MONIFY_TOKEN_CREDENTIAL
 func GetToken() (string, error) {
 	token := os.Getenv("MONIFY_TOKEN")
 	if token == "" {
+		if path := GetTokenCredential(); path != "" {
+			return readTokenCredential(path)
+		}
 		return "", fmt.Errorf("MONIFY_TOKEN environment variable not set")
 	}
 	return token, nil
 }
 
+// TokenCredential is the name of the systemd credential the token is sealed
+// in by monify login --encrypt
+const TokenCredential = "monify-token"
+
+// GetTokenCredential returns the encrypted credential file holding the token
+// when it is not stored in plain text (MONIFY_TOKEN_CREDENTIAL)
+func GetTokenCredential() string {
+	return os.Getenv("MONIFY_TOKEN_CREDENTIAL")
+}
+
+// readTokenCredential returns the token sealed in the credential at path.
+// The service gets it decrypted from systemd (LoadCredentialEncrypted);
+// commands run outside the service decrypt it with systemd-creds as root.
+func readTokenCredential(path string) (string, error) {
+	if dir := os.Getenv("CREDENTIALS_DIRECTORY"); dir != "" {
+		if data, err := os.ReadFile(filepath.Join(dir, TokenCredential)); err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+	out, err := exec.Command("systemd-creds", "decrypt", "--name="+TokenCredential, path, "-").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the token in %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 // IsDebugMode checks if debug mode is enabled
 func IsDebugMode() bool {
 	debug := os.Getenv("MONIFY_DEBUG")
 	return debug == "true" || debug == "1"
 }
+
+// GetCollectionInterval returns the collection interval (MONIFY_INTERVAL seconds, minimum 5)
+func GetCollectionInterval() time.Duration {
+	if value := os.Getenv("MONIFY_INTERVAL"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 5 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return CollectionInterval
+}
+
+// GetCollectionJitter returns the upper bound of the random start delay (MONIFY_COLLECTION_JITTER seconds, 0 disables)
+func GetCollectionJitter() time.Duration {
+	if value := os.Getenv("MONIFY_COLLECTION_JITTER"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return min(time.Duration(n)*time.Second, GetCollectionInterval())
+		}
+	}
+	return CollectionJitter
+}
+
+// GetMaxSectionItems returns the maximum list entries per payload section (0 disables chunking)
+func GetMaxSectionItems() int {
+	if value := os.Getenv("MONIFY_MAX_SECTION_ITEMS"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return MaxSectionItems
+}
+
+// GetCompression returns the request body encoding: gzip, zstd, or auto (default)
+// to use zstd once the server advertises support for it
+func GetCompression() string {
+	if value := os.Getenv("MONIFY_COMPRESSION"); value != "" {
+		return strings.ToLower(value)
+	}
+	return "auto"
+}
+
+// GetCompressionLevel returns the compression level (0 uses the codec default)
+func GetCompressionLevel() int {
+	if value := os.Getenv("MONIFY_COMPRESSION_LEVEL"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// GetBatchIntervals returns how many collection intervals are sent per request (1 disables batching)
+func GetBatchIntervals() int {
+	if value := os.Getenv("MONIFY_BATCH_INTERVALS"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 1 {
+			return n
+		}
+	}
+	return 1
+}
+
+// GetSpoolDir returns the directory for payloads that could not be sent
+func GetSpoolDir() string {
+	if dir := os.Getenv("MONIFY_SPOOL_DIR"); dir != "" {
+		return dir
+	}
+	return SpoolDir
+}
+
+// GetNice returns the scheduling priority the agent lowers itself to
+// (MONIFY_NICE, 0 to 19, 0 leaves it unchanged)
+func GetNice() int {
+	if n, err := strconv.Atoi(os.Getenv("MONIFY_NICE")); err == nil && n >= 0 && n <= 19 {
+		return n
+	}
+	return 0
+}
+
+// GetIONiceClass returns the I/O scheduling class of the agent (MONIFY_IONICE:
+// "idle" or "best-effort", empty leaves it unchanged)
+func GetIONiceClass() string {
+	return os.Getenv("MONIFY_IONICE")
+}
+
+// GetCPULimit returns the CPU usage, in percent of one core, above which
+// optional collectors are shed (MONIFY_CPU_LIMIT, 0 disables)
+func GetCPULimit() float64 {
+	if f, err := strconv.ParseFloat(os.Getenv("MONIFY_CPU_LIMIT"), 64); err == nil && f > 0 {
+		return f
+	}
+	return 0
+}
+
+// GetMemoryLimitBytes returns the resident memory above which optional
+// collectors are shed and garbage collection gets more aggressive
+// (MONIFY_MEMORY_LIMIT_MB, 0 disables)
+func GetMemoryLimitBytes() uint64 {
+	if n, err := strconv.Atoi(os.Getenv("MONIFY_MEMORY_LIMIT_MB")); err == nil && n > 0 {
+		return uint64(n) * 1024 * 1024
+	}
+	return 0
+}
+
+// GetRunAsUser returns the user the agent switches to once startup is done
+// (MONIFY_USER, empty keeps running as the starting user)
+func GetRunAsUser() string {
+	return os.Getenv("MONIFY_USER")
+}
+
+// GetCapabilities returns the capabilities kept after switching user
+// (comma-separated MONIFY_CAPABILITIES, empty keeps none)
+func GetCapabilities() []string {
+	value, ok := os.LookupEnv("MONIFY_CAPABILITIES")
+	if !ok {
+		return DefaultCapabilities
+	}
+
+	var capabilities []string
+	for _, capability := range strings.Split(value, ",") {
+		if capability = strings.TrimSpace(capability); capability != "" {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	return capabilities
+}
+
+// GetSpoolMaxBytes returns the size limit of the offline spool (0 disables spooling)
+func GetSpoolMaxBytes() int64 {
+	if value := os.Getenv("MONIFY_SPOOL_MAX_MB"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return int64(n) * 1024 * 1024
+		}
+	}
+	return SpoolMaxMB * 1024 * 1024
+}
+
+// DefaultCapabilities are the capabilities kept after switching user when
+// MONIFY_CAPABILITIES is not set: reading root-only files such as the DMI
+// serial, inspecting other users' processes, and ICMP gateway checks
+var DefaultCapabilities = []string{"dac_read_search", "sys_ptrace", "net_raw"}
+
+// DefaultCommandAllowlist are the server commands executed when
+// MONIFY_COMMAND_ALLOWLIST is not set. Only commands that neither change the
+// host nor the agent's configuration are allowed by default; restart,
+// uninstall and update_config must be allowed explicitly.
+var DefaultCommandAllowlist = []string{
+	"diagnostics",
+	"high_resolution",
+	"pause",
+	"resume",
+	"scan_ports",
+}
+
+// DefaultSysctls are the kernel parameters reported when MONIFY_SYSCTLS is not set
+var DefaultSysctls = []string{
+	"vm.swappiness",
+	"vm.overcommit_memory",
+	"vm.max_map_count",
+	"net.core.somaxconn",
+	"net.ipv4.ip_forward",
+	"net.ipv4.tcp_max_syn_backlog",
+	"fs.file-max",
+	"kernel.pid_max",
+}
+
+// GetSysctls returns the kernel parameters to report (comma-separated MONIFY_SYSCTLS)
+func GetSysctls() []string {
+	value, ok := os.LookupEnv("MONIFY_SYSCTLS")
+	if !ok {
+		return DefaultSysctls
+	}
+
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// DefaultPublicIPURLs are the echo services asked for the public IP
+// addresses when MONIFY_PUBLIC_IP_URLS is not set
+var DefaultPublicIPURLs = []string{
+	"https://api64.ipify.org",
+	"https://icanhazip.com",
+	"https://ifconfig.me/ip",
+}
+
+// GetPublicIPURLs returns the echo services asked for the public IP addresses,
+// tried in order (comma-separated MONIFY_PUBLIC_IP_URLS, empty disables the lookup)
+func GetPublicIPURLs() []string {
+	value, ok := os.LookupEnv("MONIFY_PUBLIC_IP_URLS")
+	if !ok {
+		return DefaultPublicIPURLs
+	}
+
+	var urls []string
+	for _, url := range strings.Split(value, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// GetWatchDirs returns directories whose size and file count are tracked (comma-separated MONIFY_WATCH_DIRS)
+func GetWatchDirs() []string {
+	var dirs []string
+	for _, dir := range strings.Split(os.Getenv("MONIFY_WATCH_DIRS"), ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// GetDiskInclude returns the mountpoint and device patterns the disk
+// collectors are limited to (comma-separated MONIFY_DISK_INCLUDE)
+func GetDiskInclude() []string {
+	var patterns []string
+	for _, pattern := range strings.Split(os.Getenv("MONIFY_DISK_INCLUDE"), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// GetDiskExclude returns the mountpoint and device patterns the disk
+// collectors skip (comma-separated MONIFY_DISK_EXCLUDE)
+func GetDiskExclude() []string {
+	var patterns []string
+	for _, pattern := range strings.Split(os.Getenv("MONIFY_DISK_EXCLUDE"), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// GetDiskSkipFSTypes returns the filesystem types the disk collectors skip in addition to the
+// pseudo filesystems skipped by default
+// (comma-separated MONIFY_DISK_SKIP_FSTYPES)
+func GetDiskSkipFSTypes() []string {
+	var types []string
+	for _, fstype := range strings.Split(os.Getenv("MONIFY_DISK_SKIP_FSTYPES"), ",") {
+		if fstype = strings.TrimSpace(fstype); fstype != "" {
+			types = append(types, fstype)
+		}
+	}
+	return types
+}
+
+// GetDiskKeepFSTypes returns the filesystem types the disk collectors report although
+// they are skipped by default
+// (comma-separated MONIFY_DISK_KEEP_FSTYPES)
+func GetDiskKeepFSTypes() []string {
+	var types []string
+	for _, fstype := range strings.Split(os.Getenv("MONIFY_DISK_KEEP_FSTYPES"), ",") {
+		if fstype = strings.TrimSpace(fstype); fstype != "" {
+			types = append(types, fstype)
+		}
+	}
+	return types
+}
+
+// GetProbes returns the service check target URLs from MONIFY_PROBES
+func GetProbes() []string {
+	var targets []string
+	for _, target := range strings.Split(os.Getenv("MONIFY_PROBES"), ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// GetAlertRules returns the local alert rules (MONIFY_ALERT_RULES, separated by semicolons)
+func GetAlertRules() []string {
+	var rules []string
+	for _, rule := range strings.Split(os.Getenv("MONIFY_ALERT_RULES"), ";") {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// GetAlertHook returns the executable run when a local alert fires or resolves (MONIFY_ALERT_HOOK)
+func GetAlertHook() string {
+	return os.Getenv("MONIFY_ALERT_HOOK")
+}
+
+// GetAlertHookTimeout returns how long the alert hook may run before it is killed (MONIFY_ALERT_HOOK_TIMEOUT seconds)
+func GetAlertHookTimeout() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("MONIFY_ALERT_HOOK_TIMEOUT")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return AlertHookTimeout
+}
+
+// GetAnomalyThreshold returns how many standard deviations from its baseline
+// a sample must be to be anomalous (MONIFY_ANOMALY_THRESHOLD, 0 disables)
+func GetAnomalyThreshold() float64 {
+	if value, ok := os.LookupEnv("MONIFY_ANOMALY_THRESHOLD"); ok {
+		if f, err := strconv.ParseFloat(value, 64); err == nil && f >= 0 {
+			return f
+		}
+	}
+	return AnomalyThreshold
+}
+
+// GetCollectorTimeout returns how long each collector may run per
+// collection (MONIFY_COLLECTOR_TIMEOUT seconds)
+func GetCollectorTimeout() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("MONIFY_COLLECTOR_TIMEOUT")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return CollectorTimeout
+}
+
+// GetCollectorQuarantineFailures returns after how many consecutive failures a
+// collector is quarantined (MONIFY_COLLECTOR_QUARANTINE, 0 disables)
+func GetCollectorQuarantineFailures() int {
+	if n, err := strconv.Atoi(os.Getenv("MONIFY_COLLECTOR_QUARANTINE")); err == nil && n >= 0 {
+		return n
+	}
+	return CollectorQuarantineFailures
+}
+
+// GetDisabledCollectors returns the dynamic collectors that are turned off
+// (comma-separated MONIFY_DISABLE_COLLECTORS, "network" turns off all
+// network traffic collectors)
+func GetDisabledCollectors() map[string]bool {
+	disabled := make(map[string]bool)
+	for _, name := range strings.Split(os.Getenv("MONIFY_DISABLE_COLLECTORS"), ",") {
+		for _, collector := range CollectorNames(strings.TrimSpace(name)) {
+			disabled[collector] = true
+		}
+	}
+	return disabled
+}
+
+// GetCollectorIntervals returns how often individual dynamic collectors run,
+// when less often than every collection (MONIFY_COLLECTOR_INTERVALS,
+// comma-separated name=seconds)
+func GetCollectorIntervals() map[string]time.Duration {
+	intervals := make(map[string]time.Duration)
+	for _, pair := range strings.Split(os.Getenv("MONIFY_COLLECTOR_INTERVALS"), ",") {
+		name, value, _ := strings.Cut(pair, "=")
+		seconds, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || seconds <= 0 {
+			continue
+		}
+		for _, collector := range CollectorNames(strings.TrimSpace(name)) {
+			intervals[collector] = time.Duration(seconds) * time.Second
+		}
+	}
+	return intervals
+}
+
+// CollectorNames expands a configured collector name; "network" stands for
+// all network traffic collectors
+func CollectorNames(name string) []string {
+	switch name {
+	case "":
+		return nil
+	case "network":
+		return []string{"network_public", "network_private", "network_health"}
+	default:
+		return []string{name}
+	}
+}
+
+// GetPluginDir returns the directory of exec plugins (MONIFY_PLUGIN_DIR, empty disables)
+func GetPluginDir() string {
+	if dir, ok := os.LookupEnv("MONIFY_PLUGIN_DIR"); ok {
+		return dir
+	}
+	return PluginDir
+}
+
+// GetPluginInterval returns how often plugins are run (MONIFY_PLUGIN_INTERVAL seconds)
+func GetPluginInterval() time.Duration {
+	if value := os.Getenv("MONIFY_PLUGIN_INTERVAL"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return PluginInterval
+}
+
+// GetPluginTimeout returns how long a plugin may run before it is killed (MONIFY_PLUGIN_TIMEOUT seconds)
+func GetPluginTimeout() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("MONIFY_PLUGIN_TIMEOUT")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return PluginTimeout
+}
+
+// IsPackageInventoryEnabled checks if the installed package inventory is enabled
+func IsPackageInventoryEnabled() bool {
+	enabled := os.Getenv("MONIFY_COLLECT_PACKAGES")
+	return enabled == "true" || enabled == "1"
+}
+
+// GetLabels returns the labels attached to every payload (MONIFY_LABELS, comma-separated key=value pairs)
+func GetLabels() map[string]string {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv("MONIFY_LABELS"), ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); ok && key != "" {
+			labels[key] = strings.TrimSpace(value)
+		}
+	}
+	return labels
+}
+
+// IsCloudTagsEnabled checks if cloud instance tags should be attached as labels (default: enabled)
+func IsCloudTagsEnabled() bool {
+	enabled := os.Getenv("MONIFY_CLOUD_TAGS")
+	return enabled != "false" && enabled != "0"
+}
+
+// IsCloudMetadataEnabled checks if cloud metadata services are probed for the provider, region and instance type (default: enabled)
+func IsCloudMetadataEnabled() bool {
+	enabled := os.Getenv("MONIFY_CLOUD_METADATA")
+	return enabled != "false" && enabled != "0"
+}
+
+// GetCommandAllowlist returns the server commands the agent may execute
+// (comma-separated MONIFY_COMMAND_ALLOWLIST, empty refuses all)
+func GetCommandAllowlist() []string {
+	value, ok := os.LookupEnv("MONIFY_COMMAND_ALLOWLIST")
+	if !ok {
+		return DefaultCommandAllowlist
+	}
+
+	var commands []string
+	for _, command := range strings.Split(value, ",") {
+		if command = strings.TrimSpace(command); command != "" {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// GetCommandPublicKey returns the Ed25519 key server commands must be signed
+// with, base64-encoded or as the path of a PEM file. MONIFY_COMMAND_PUBLIC_KEY
+// overrides the key built into the agent.
+func GetCommandPublicKey() string {
+	if key := os.Getenv("MONIFY_COMMAND_PUBLIC_KEY"); key != "" {
+		return key
+	}
+	return CommandPublicKey
+}
+
+// IsUnsignedCommandsAllowed checks if server commands run without a signature
+// (MONIFY_ALLOW_UNSIGNED_COMMANDS). This is an explicit opt-out: by default
+// unsigned commands are refused.
+func IsUnsignedCommandsAllowed() bool {
+	allowed := os.Getenv("MONIFY_ALLOW_UNSIGNED_COMMANDS")
+	return allowed == "true" || allowed == "1"
+}
+
+// GetCommandAuditLog returns the file server commands are recorded in (empty disables)
+func GetCommandAuditLog() string {
+	if path, ok := os.LookupEnv("MONIFY_COMMAND_AUDIT_LOG"); ok {
+		return path
+	}
+	return CommandAuditLog
+}
+
+// GetUpdateURL returns the base URL release binaries are downloaded from
+func GetUpdateURL() string {
+	if url := os.Getenv("MONIFY_UPDATE_URL"); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	return UpdateURL
+}
+
+// GetUpdateTimeout returns how long monify update may take to download a release (MONIFY_UPDATE_TIMEOUT seconds)
+func GetUpdateTimeout() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("MONIFY_UPDATE_TIMEOUT")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return UpdateTimeout
+}
+
+// GetUpdatePublicKey returns the key release checksums must be signed with
+func GetUpdatePublicKey() string {
+	if key := os.Getenv("MONIFY_UPDATE_PUBLIC_KEY"); key != "" {
+		return key
+	}
+	return ReleasePublicKey
+}
+
+// IsUpdateCheckEnabled checks if monify status and version look up the latest
+// release (MONIFY_UPDATE_CHECK, default true)
+func IsUpdateCheckEnabled() bool {
+	enabled := os.Getenv("MONIFY_UPDATE_CHECK")
+	return enabled != "false" && enabled != "0"
+}
+
+// IsDryRun checks if payloads are printed to stdout instead of being sent (MONIFY_DRY_RUN)
+func IsDryRun() bool {
+	dryRun := os.Getenv("MONIFY_DRY_RUN")
+	return dryRun == "true" || dryRun == "1"
+}
+
+// IsReadOnlyMode checks if execution of server commands is disabled locally.
+// Read-only mode cannot be overridden by the server.
+func IsReadOnlyMode() bool {
+	readOnly := os.Getenv("MONIFY_READ_ONLY")
+	return readOnly == "true" || readOnly == "1"
+}
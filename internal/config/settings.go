@@ -0,0 +1,366 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// settingValidators maps every setting the agent reads to a check of its
+// value; nil accepts any value
+var settingValidators = map[string]func(string) error{
+	// Server and authentication
+	"MONIFY_TOKEN":               nil,
+	"MONIFY_TOKEN_CREDENTIAL":    validPath,
+	"MONIFY_SERVER_URL":          validURL,
+	"MONIFY_SERVER_URL_FALLBACK": validURL,
+	"MONIFY_REFRESH_TOKEN":       nil,
+	"MONIFY_TOKEN_URL":           validURL,
+	"MONIFY_TENANTS":             validPairs,
+	"MONIFY_HMAC_SECRET":         nil,
+	"MONIFY_AUTH_FAILURES":       validRange(1, 0),
+	"MONIFY_AUTH_FAILURE_WINDOW": validCount,
+	"MONIFY_AUTH_FAILURE_ACTION": validChoice("exit", "retry"),
+	"MONIFY_TIMEOUT":             validRange(1, 0),
+
+	// Transport
+	"MONIFY_COMMAND_STREAM":            validBool,
+	"MONIFY_COMMAND_STREAM_URL":        validURL,
+	"MONIFY_HEARTBEAT":                 validBool,
+	"MONIFY_HEARTBEAT_URL":             validURL,
+	"MONIFY_REGISTRATION":              validBool,
+	"MONIFY_REGISTRATION_URL":          validURL,
+	"MONIFY_DIAGNOSTICS_URL":           validURL,
+	"MONIFY_MQTT_URL":                  validURL,
+	"MONIFY_MQTT_TOPIC":                validMQTTTopic,
+	"MONIFY_RELAY_SOCKET":              validPath,
+	"MONIFY_REMOTE_WRITE_URL":          validURL,
+	"MONIFY_REMOTE_WRITE_TOKEN":        nil,
+	"MONIFY_GRAPHITE_ADDR":             nil,
+	"MONIFY_GRAPHITE_PREFIX":           nil,
+	"MONIFY_GRAPHITE_INTERVAL":         validCount,
+	"MONIFY_FAILOVER_THRESHOLD":        validRange(1, 0),
+	"MONIFY_CIRCUIT_BREAKER_THRESHOLD": validCount,
+	"MONIFY_SOCKS5_PROXY":              nil,
+	"MONIFY_IP_FAMILY":                 validChoice("auto", "ipv4", "ipv6"),
+	"MONIFY_DNS_REFRESH":               validCount,
+	"MONIFY_COMPRESSION":               validChoice("auto", "gzip", "zstd"),
+	"MONIFY_COMPRESSION_LEVEL":         validCount,
+	"MONIFY_BATCH_INTERVALS":           validRange(1, 0),
+	"MONIFY_SPOOL_DIR":                 validPath,
+	"MONIFY_SPOOL_MAX_MB":              validCount,
+	"MONIFY_MAX_SECTION_ITEMS":         validCount,
+
+	// TLS
+	"MONIFY_CA_CERT":                  validPath,
+	"MONIFY_TLS_SERVER_NAME":          nil,
+	"MONIFY_TLS_INSECURE_SKIP_VERIFY": validBool,
+	"MONIFY_CLIENT_CERT":              validPath,
+	"MONIFY_CLIENT_KEY":               validPath,
+
+	// Local endpoints
+	"MONIFY_STATUS_ADDR":     nil,
+	"MONIFY_PROMETHEUS_ADDR": nil,
+
+	// Collection
+	"MONIFY_INTERVAL":             validRange(5, 0),
+	"MONIFY_COLLECTION_JITTER":    validCount,
+	"MONIFY_LABELS":               validPairs,
+	"MONIFY_COLLECT_PACKAGES":     validBool,
+	"MONIFY_CLOUD_TAGS":           validBool,
+	"MONIFY_CLOUD_METADATA":       validBool,
+	"MONIFY_PUBLIC_IP_URLS":       validURLs,
+	"MONIFY_SYSCTLS":              nil,
+	"MONIFY_WATCH_DIRS":           nil,
+	"MONIFY_DISK_INCLUDE":         nil,
+	"MONIFY_DISK_EXCLUDE":         nil,
+	"MONIFY_DISK_SKIP_FSTYPES":    nil,
+	"MONIFY_DISK_KEEP_FSTYPES":    nil,
+	"MONIFY_PROBES":               nil,
+	"MONIFY_PLUGIN_DIR":           nil,
+	"MONIFY_PLUGIN_INTERVAL":      validRange(1, 0),
+	"MONIFY_PLUGIN_TIMEOUT":       validRange(1, 0),
+	"MONIFY_DISABLE_COLLECTORS":   nil,
+	"MONIFY_COLLECTOR_INTERVALS":  validPairs,
+	"MONIFY_COLLECTOR_TIMEOUT":    validRange(1, 0),
+	"MONIFY_COLLECTOR_QUARANTINE": validCount,
+	"MONIFY_ANOMALY_THRESHOLD":    validNumber,
+	"MONIFY_ALERT_RULES":          nil,
+	"MONIFY_ALERT_HOOK":           validPath,
+	"MONIFY_ALERT_HOOK_TIMEOUT":   validRange(1, 0),
+
+	// Resources and privileges
+	"MONIFY_NICE":            validRange(0, 19),
+	"MONIFY_IONICE":          validChoice("idle", "best-effort"),
+	"MONIFY_CPU_LIMIT":       validNumber,
+	"MONIFY_MEMORY_LIMIT_MB": validCount,
+	"MONIFY_USER":            nil,
+	"MONIFY_CAPABILITIES":    nil,
+
+	// Commands and updates
+	"MONIFY_READ_ONLY":               validBool,
+	"MONIFY_COMMAND_ALLOWLIST":       nil,
+	"MONIFY_COMMAND_PUBLIC_KEY":      nil,
+	"MONIFY_ALLOW_UNSIGNED_COMMANDS": validBool,
+	"MONIFY_COMMAND_AUDIT_LOG":       nil,
+	"MONIFY_UPDATE_URL":              validURL,
+	"MONIFY_UPDATE_PUBLIC_KEY":       nil,
+	"MONIFY_UPDATE_CHECK":            validBool,
+	"MONIFY_UPDATE_TIMEOUT":          validRange(1, 0),
+	"MONIFY_DEBUG":                   validBool,
+	"MONIFY_DRY_RUN":                 validBool,
+}
+
+// secretSettings hold credentials, masked when settings are listed
+var secretSettings = map[string]bool{
+	"MONIFY_TOKEN":              true,
+	"MONIFY_REFRESH_TOKEN":      true,
+	"MONIFY_TENANTS":            true,
+	"MONIFY_HMAC_SECRET":        true,
+	"MONIFY_REMOTE_WRITE_TOKEN": true,
+	"MONIFY_SOCKS5_PROXY":       true, // May carry a password
+}
+
+// SettingName normalizes a setting name given on the command line, e.g.
+// server_url to MONIFY_SERVER_URL
+func SettingName(name string) string {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, "MONIFY_") {
+		name = "MONIFY_" + name
+	}
+	return name
+}
+
+// Settings returns the names of all settings, sorted
+func Settings() []string {
+	names := make([]string, 0, len(settingValidators))
+	for name := range settingValidators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsSecretSetting reports whether the value of a setting is a credential
+func IsSecretSetting(name string) bool {
+	return secretSettings[name]
+}
+
+// ValidateSetting checks that name is a known setting and value is valid for
+// it. Empty values are valid: they select the default or disable a feature.
+func ValidateSetting(name, value string) error {
+	validate, ok := settingValidators[name]
+	if !ok {
+		return fmt.Errorf("unknown setting %s", name)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%s must be a single line", name)
+	}
+	if value == "" || validate == nil {
+		return nil
+	}
+	if err := validate(value); err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return nil
+}
+
+// validBool accepts the values boolean settings are compared with
+func validBool(value string) error {
+	switch value {
+	case "true", "false", "1", "0":
+		return nil
+	}
+	return fmt.Errorf("expected true or false")
+}
+
+// validCount accepts non-negative integers
+func validCount(value string) error {
+	if n, err := strconv.Atoi(value); err != nil || n < 0 {
+		return fmt.Errorf("expected a non-negative integer")
+	}
+	return nil
+}
+
+// validRange accepts integers from min to max; max 0 means no upper bound
+func validRange(min, max int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		switch {
+		case err != nil:
+			return fmt.Errorf("expected an integer")
+		case n < min:
+			return fmt.Errorf("must be at least %d", min)
+		case max > 0 && n > max:
+			return fmt.Errorf("must be at most %d", max)
+		}
+		return nil
+	}
+}
+
+// validNumber accepts non-negative numbers
+func validNumber(value string) error {
+	if f, err := strconv.ParseFloat(value, 64); err != nil || f < 0 {
+		return fmt.Errorf("expected a non-negative number")
+	}
+	return nil
+}
+
+// validURL accepts absolute URLs with a host
+func validURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("expected an absolute URL, e.g. https://host/path")
+	}
+	return nil
+}
+
+// validURLs accepts comma-separated absolute URLs
+func validURLs(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			if err := validURL(item); err != nil {
+				return fmt.Errorf("%s: %w", item, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validMQTTTopic accepts MQTT topic names without wildcards
+func validMQTTTopic(value string) error {
+	if strings.ContainsAny(value, "+#\x00") {
+		return fmt.Errorf("must not contain MQTT wildcards (+ or #)")
+	}
+	return nil
+}
+
+// validPath accepts absolute paths
+func validPath(value string) error {
+	if !filepath.IsAbs(value) {
+		return fmt.Errorf("expected an absolute path")
+	}
+	return nil
+}
+
+// validPairs accepts comma-separated key=value pairs
+func validPairs(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		if key, _, ok := strings.Cut(strings.TrimSpace(pair), "="); !ok || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("expected comma-separated key=value pairs")
+		}
+	}
+	return nil
+}
+
+// validChoice accepts one of choices, ignoring case
+func validChoice(choices ...string) func(string) error {
+	return func(value string) error {
+		for _, choice := range choices {
+			if strings.EqualFold(value, choice) {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected one of %s", strings.Join(choices, ", "))
+	}
+}
+
+// ReadEnvFile returns the settings stored in the env file, nil if it does
+// not exist
+func ReadEnvFile() (map[string]string, error) {
+	return readEnvFile()
+}
+
+// UnsetEnvFile removes settings from the env file, and any value the agent
+// saved for them
+func UnsetEnvFile(names ...string) error {
+	if err := editSettingsFile(EnvFilePath, nil, names); err != nil {
+		return err
+	}
+	return forgetAgentSettings(names)
+}
+
+// editSettingsFile sets and removes settings in an env file in place,
+// keeping comments, order, mode and ownership. The file is replaced
+// atomically so the agent never reads a partial file.
+func editSettingsFile(path string, set map[string]string, unset []string) error {
+	var lines []string
+	mode := os.FileMode(0600)
+	uid, gid := -1, -1
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+			if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+				uid, gid = int(stat.Uid), int(stat.Gid)
+			}
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	remove := make(map[string]bool, len(unset))
+	for _, name := range unset {
+		remove[name] = true
+	}
+	written := make(map[string]bool, len(set))
+	var content strings.Builder
+	for _, line := range lines {
+		key, _, ok := strings.Cut(strings.TrimSpace(line), "=")
+		key = strings.TrimSpace(key)
+		if ok && !strings.HasPrefix(key, "#") {
+			if remove[key] || written[key] {
+				continue // Removed, or a duplicate of a line already replaced
+			}
+			if value, ok := set[key]; ok {
+				line = key + "=" + value
+				written[key] = true
+			}
+		}
+		content.WriteString(line + "\n")
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		if !written[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		content.WriteString(name + "=" + set[name] + "\n")
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".env-*")
+	if err != nil {
+		return fmt.Errorf("failed to write env file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content.String()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write env file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write env file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write env file: %w", err)
+	}
+	if uid >= 0 {
+		os.Chown(tmp.Name(), uid, gid) // Best effort: only root may give files away
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write env file: %w", err)
+	}
+	return nil
+}
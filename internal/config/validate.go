@@ -0,0 +1,212 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Problem severities
+const (
+	ProblemError   = "error"   // The agent ignores or rejects the setting
+	ProblemWarning = "warning" // Valid, but probably not what was meant
+)
+
+// Problem is an issue found in the configuration
+type Problem struct {
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"` // Set for config.yaml, empty for the env file
+	Line     int    `json:"line,omitempty"` // Line in the file, 0 if not tied to one
+	Setting  string `json:"setting,omitempty"`
+	Message  string `json:"message"`
+}
+
+// proxySettings are the standard proxy variables, which the agent honors when
+// they are set in the env file
+var proxySettings = map[string]bool{
+	"HTTP_PROXY": true, "HTTPS_PROXY": true, "NO_PROXY": true,
+	"http_proxy": true, "https_proxy": true, "no_proxy": true,
+}
+
+// settingDependencies maps settings to the setting they only apply with
+var settingDependencies = map[string]string{
+	"MONIFY_TOKEN_URL":           "MONIFY_REFRESH_TOKEN",
+	"MONIFY_CLIENT_KEY":          "MONIFY_CLIENT_CERT",
+	"MONIFY_FAILOVER_THRESHOLD":  "MONIFY_SERVER_URL_FALLBACK",
+	"MONIFY_MQTT_TOPIC":          "MONIFY_MQTT_URL",
+	"MONIFY_REMOTE_WRITE_TOKEN":  "MONIFY_REMOTE_WRITE_URL",
+	"MONIFY_GRAPHITE_PREFIX":     "MONIFY_GRAPHITE_ADDR",
+	"MONIFY_GRAPHITE_INTERVAL":   "MONIFY_GRAPHITE_ADDR",
+	"MONIFY_CAPABILITIES":        "MONIFY_USER",
+	"MONIFY_ALERT_HOOK":          "MONIFY_ALERT_RULES",
+	"MONIFY_AUTH_FAILURE_WINDOW": "MONIFY_AUTH_FAILURES",
+}
+
+// CheckEnvFile checks the syntax of the env file and config.yaml and every
+// setting in them: unknown names, values of the wrong type or out of range,
+// files that do not exist and settings without effect. The error is only
+// set if a file cannot be read.
+func CheckEnvFile() ([]Problem, error) {
+	data, err := os.ReadFile(EnvFilePath)
+	if os.IsNotExist(err) {
+		return []Problem{{Severity: ProblemError, Message: fmt.Sprintf("%s does not exist", EnvFilePath)}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	add := func(severity string, line int, setting, format string, args ...any) {
+		problems = append(problems, Problem{Severity: severity, Line: line, Setting: setting, Message: fmt.Sprintf(format, args...)})
+	}
+
+	settings := make(map[string]string)
+	lines := make(map[string]int)
+	for i, line := range strings.Split(string(data), "\n") {
+		number := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") {
+			add(ProblemError, number, "", "remove \"export\": lines are read as KEY=VALUE")
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" {
+			add(ProblemError, number, "", "expected KEY=VALUE")
+			continue
+		}
+		if previous, ok := lines[key]; ok {
+			add(ProblemWarning, number, key, "also set on line %d; this value wins", previous)
+		}
+		settings[key], lines[key] = value, number
+
+		if _, known := settingValidators[key]; !known {
+			switch {
+			case proxySettings[key]:
+			case !strings.HasPrefix(key, "MONIFY_"):
+				add(ProblemWarning, number, key, "not a Monify setting; it is only passed to the agent's environment")
+			default:
+				if match := ClosestMatch(key, Settings()); match != "" {
+					add(ProblemError, number, key, "unknown setting, did you mean %s?", match)
+				} else {
+					add(ProblemError, number, key, "unknown setting")
+				}
+			}
+			continue
+		}
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			add(ProblemWarning, number, key, "quotes are kept as part of the value; remove them")
+		}
+		if validate := settingValidators[key]; value != "" && validate != nil {
+			if err := validate(value); err != nil {
+				add(ProblemError, number, key, "invalid value: %v", err)
+			}
+		}
+	}
+
+	// config.yaml, where the env file wins
+	yamlSettings, yamlLines, yamlProblems, err := checkConfigYAML(true)
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, yamlProblems...)
+	for key, value := range yamlSettings {
+		if line, ok := lines[key]; ok {
+			problems = append(problems, Problem{Severity: ProblemWarning, File: ConfigFilePath, Line: yamlLines[key], Setting: key,
+				Message: fmt.Sprintf("overridden by line %d of %s", line, EnvFilePath)})
+			continue
+		}
+		settings[key] = value
+	}
+	// addAt adds a problem at the line key is set on, in either file
+	addAt := func(severity, key, format string, args ...any) {
+		problem := Problem{Severity: severity, Line: lines[key], Setting: key, Message: fmt.Sprintf(format, args...)}
+		if line, ok := yamlLines[key]; ok && problem.Line == 0 {
+			problem.File, problem.Line = ConfigFilePath, line
+		}
+		problems = append(problems, problem)
+	}
+
+	// Credentials
+	if settings["MONIFY_TOKEN"] == "" && settings["MONIFY_TOKEN_CREDENTIAL"] == "" && settings["MONIFY_REFRESH_TOKEN"] == "" && settings["MONIFY_CLIENT_CERT"] == "" &&
+		settings["MONIFY_TENANTS"] == "" && settings["MONIFY_RELAY_SOCKET"] == "" && os.Getenv("MONIFY_TOKEN") == "" {
+		add(ProblemError, 0, "MONIFY_TOKEN", "no credentials: set MONIFY_TOKEN (sudo monify login), a refresh token or a client certificate")
+	}
+	if v := settings["MONIFY_TLS_INSECURE_SKIP_VERIFY"]; v == "true" || v == "1" {
+		addAt(ProblemWarning, "MONIFY_TLS_INSECURE_SKIP_VERIFY", "server certificates are not verified")
+	}
+
+	// Files the agent reads
+	for _, key := range []string{"MONIFY_TOKEN_CREDENTIAL", "MONIFY_CA_CERT", "MONIFY_CLIENT_CERT", "MONIFY_CLIENT_KEY", "MONIFY_ALERT_HOOK", "MONIFY_PLUGIN_DIR"} {
+		path := settings[key]
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			addAt(ProblemError, key, "%v", err)
+		case key == "MONIFY_PLUGIN_DIR" && !info.IsDir():
+			addAt(ProblemError, key, "%s is not a directory", path)
+		case key == "MONIFY_ALERT_HOOK" && info.Mode()&0111 == 0:
+			addAt(ProblemError, key, "%s is not executable", path)
+		}
+	}
+
+	// Settings without effect
+	for key, requires := range settingDependencies {
+		if settings[key] != "" && settings[requires] == "" {
+			addAt(ProblemWarning, key, "has no effect without %s", requires)
+		}
+	}
+
+	// Problems of single lines first, the env file's before config.yaml's
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		if (a.Line == 0) != (b.Line == 0) {
+			return b.Line == 0
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return problems, nil
+}
+
+// ClosestMatch returns the candidate most similar to name, "" if none is
+// close enough to be a likely typo
+func ClosestMatch(name string, candidates []string) string {
+	best, bestDistance := "", len(name)/3+1
+	for _, candidate := range candidates {
+		if d := editDistance(strings.ToUpper(name), strings.ToUpper(candidate)); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance of a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlError is a syntax error in a YAML document
+type yamlError struct {
+	line    int
+	message string
+}
+
+func (e *yamlError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.message)
+}
+
+// yamlErrorLine extracts the line number yaml.v3 puts in its messages
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): `)
+
+// parseYAML parses a document whose root is a mapping; an empty document
+// yields an empty mapping. Aliases are resolved by the walk over the nodes.
+func parseYAML(data []byte) (*yaml.Node, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return &yaml.Node{Kind: yaml.MappingNode, Line: 1}, nil
+		}
+		return nil, newYAMLError(err)
+	}
+	var next yaml.Node
+	if err := dec.Decode(&next); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, newYAMLError(err)
+		}
+		return nil, &yamlError{next.Line, "multiple documents are not supported"}
+	}
+
+	root := &doc
+	if root.Kind == yaml.DocumentNode {
+		if len(root.Content) == 0 {
+			return &yaml.Node{Kind: yaml.MappingNode, Line: 1}, nil
+		}
+		root = root.Content[0]
+	}
+	if root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+		return &yaml.Node{Kind: yaml.MappingNode, Line: root.Line}, nil
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, &yamlError{root.Line, "expected key: value at the top level"}
+	}
+	return root, nil
+}
+
+// newYAMLError converts a yaml.v3 error into a yamlError with its line
+func newYAMLError(err error) *yamlError {
+	message := err.Error()
+	if m := yamlErrorLine.FindStringSubmatch(message); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return &yamlError{line, strings.TrimPrefix(message, m[0])}
+	}
+	return &yamlError{0, strings.TrimPrefix(message, "yaml: ")}
+}
+
+// resolveYAML follows aliases to the node they refer to
+func resolveYAML(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// yamlScalar returns the value of a scalar node; null is empty
+func yamlScalar(node *yaml.Node) string {
+	if node.Tag == "!!null" {
+		return ""
+	}
+	return node.Value
+}
+
+// ConfigFilePath is the optional YAML configuration file. It holds the same
+// settings as the env file, grouped by the parts of their names, e.g.
+// MONIFY_SERVER_URL as server: {url: ...}. The env file wins for settings
+// set in both, and secrets are only accepted in the env file.
+var ConfigFilePath = "/etc/monify/config.yaml"
+
+// pairSettings are the settings holding comma-separated key=value pairs,
+// written as mappings in config.yaml
+var pairSettings = map[string]bool{
+	"MONIFY_LABELS":              true,
+	"MONIFY_COLLECTOR_INTERVALS": true,
+}
+
+// listSeparator returns what separates the entries of a list setting
+func listSeparator(name string) string {
+	if name == "MONIFY_ALERT_RULES" {
+		return ";"
+	}
+	return ","
+}
+
+// readConfigYAML returns the settings in config.yaml, nil if it does not
+// exist. The settings without errors are returned along with the error.
+func readConfigYAML() (map[string]string, error) {
+	vars, _, problems, err := checkConfigYAML(false)
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	for _, problem := range problems {
+		if problem.Severity != ProblemError {
+			continue
+		}
+		message := problem.Message
+		if problem.Setting != "" {
+			message = problem.Setting + ": " + message
+		}
+		messages = append(messages, fmt.Sprintf("line %d: %s", problem.Line, message))
+	}
+	if len(messages) > 0 {
+		return vars, fmt.Errorf("%s: %s", ConfigFilePath, strings.Join(messages, "; "))
+	}
+	return vars, nil
+}
+
+// checkConfigYAML reads config.yaml and maps it to settings, returning the
+// line each setting is on and the problems found. Values are only checked
+// with validate set. The error is only set if the file cannot be read.
+func checkConfigYAML(validate bool) (map[string]string, map[string]int, []Problem, error) {
+	data, err := os.ReadFile(ConfigFilePath)
+	if os.IsNotExist(err) {
+		return nil, nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	root, err := parseYAML(data)
+	if err != nil {
+		line := 0
+		if yerr, ok := err.(*yamlError); ok {
+			line, err = yerr.line, fmt.Errorf("%s", yerr.message)
+		}
+		return nil, nil, []Problem{{Severity: ProblemError, File: ConfigFilePath, Line: line, Message: err.Error()}}, nil
+	}
+
+	vars := make(map[string]string)
+	lines := make(map[string]int)
+	var problems []Problem
+	add := func(line int, path, format string, args ...any) {
+		problems = append(problems, Problem{Severity: ProblemError, File: ConfigFilePath, Line: line, Setting: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var walk func(node *yaml.Node, prefix, path string)
+	walk = func(node *yaml.Node, prefix, path string) {
+		seen := make(map[string]bool, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, child := resolveYAML(node.Content[i]), resolveYAML(node.Content[i+1])
+			key := keyNode.Value
+			name := prefix + "_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+			keyPath := strings.TrimPrefix(path+"."+key, ".")
+			_, known := settingValidators[name]
+
+			if keyNode.Kind != yaml.ScalarNode || key == "" {
+				add(keyNode.Line, path, "keys must be plain values")
+				continue
+			}
+			if seen[key] {
+				add(keyNode.Line, keyPath, "%s is set twice", key)
+				continue
+			}
+			seen[key] = true
+
+			var value string
+			switch {
+			case known && secretSettings[name]:
+				add(keyNode.Line, keyPath, "secrets are only read from %s, which only root can read", EnvFilePath)
+				continue
+			case known && child.Kind == yaml.MappingNode && pairSettings[name]:
+				pairs := make([]string, 0, len(child.Content)/2)
+				nested := false
+				for j := 0; j+1 < len(child.Content); j += 2 {
+					k, v := resolveYAML(child.Content[j]), resolveYAML(child.Content[j+1])
+					if k.Kind != yaml.ScalarNode || v.Kind != yaml.ScalarNode {
+						nested = true
+						break
+					}
+					pairs = append(pairs, k.Value+"="+yamlScalar(v))
+				}
+				if nested {
+					add(keyNode.Line, keyPath, "expected a mapping of values")
+					continue
+				}
+				value = strings.Join(pairs, ",")
+				if strings.Count(value, ",") != len(pairs)-1 || strings.Count(value, "=") != len(pairs) {
+					add(keyNode.Line, keyPath, "keys and values must not contain commas, and keys no =")
+					continue
+				}
+			case known && child.Kind == yaml.MappingNode:
+				add(keyNode.Line, keyPath, "expected a value or a list, not a mapping")
+				continue
+			case known && child.Kind == yaml.SequenceNode:
+				items := make([]string, 0, len(child.Content))
+				nested := false
+				for _, item := range child.Content {
+					item = resolveYAML(item)
+					if item.Kind != yaml.ScalarNode {
+						nested = true
+						break
+					}
+					items = append(items, yamlScalar(item))
+				}
+				if nested {
+					add(keyNode.Line, keyPath, "expected a list of values, not of lists or mappings")
+					continue
+				}
+				value = strings.Join(items, listSeparator(name))
+			case known:
+				value = yamlScalar(child)
+			case child.Kind == yaml.MappingNode && hasSettingPrefix(name+"_"):
+				walk(child, name, keyPath)
+				continue
+			default:
+				candidates := make([]string, 0, len(settingValidators))
+				for _, setting := range Settings() {
+					candidates = append(candidates, strings.ToLower(strings.TrimPrefix(setting, "MONIFY_")))
+				}
+				if match := ClosestMatch(strings.ReplaceAll(keyPath, ".", "_"), candidates); match != "" {
+					add(keyNode.Line, keyPath, "unknown setting, did you mean %s?", match)
+				} else {
+					add(keyNode.Line, keyPath, "unknown setting")
+				}
+				continue
+			}
+
+			if validate {
+				if check := settingValidators[name]; value != "" && check != nil {
+					if err := check(value); err != nil {
+						add(keyNode.Line, keyPath, "invalid value: %v", err)
+						continue
+					}
+				}
+			}
+			vars[name], lines[name] = value, keyNode.Line
+		}
+	}
+	walk(root, "MONIFY", "")
+	return vars, lines, problems, nil
+}
+
+// hasSettingPrefix reports whether the name of a setting starts with prefix
+func hasSettingPrefix(prefix string) bool {
+	for name := range settingValidators {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
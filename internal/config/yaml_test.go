@@ -0,0 +1,124 @@
+package config
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useConfigYAML points ConfigFilePath at a file holding content
+func useConfigYAML(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	saved := ConfigFilePath
+	ConfigFilePath = path
+	t.Cleanup(func() { ConfigFilePath = saved })
+}
+
+func TestCheckConfigYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		want     map[string]string
+		wantLine int
+		wantErr  string
+	}{
+		{"empty", "", map[string]string{}, 0, ""},
+		{"comments only", "# nothing here\n", map[string]string{}, 0, ""},
+		{"nested groups", "server:\n  url: https://a.example.com\n  url_fallback: https://b.example.com\n",
+			map[string]string{"MONIFY_SERVER_URL": "https://a.example.com", "MONIFY_SERVER_URL_FALLBACK": "https://b.example.com"}, 0, ""},
+		{"flat name", "server_url: https://a.example.com # comment\n",
+			map[string]string{"MONIFY_SERVER_URL": "https://a.example.com"}, 0, ""},
+		{"escaped single quote", "tls_server_name: 'it''s'\n", map[string]string{"MONIFY_TLS_SERVER_NAME": "it's"}, 0, ""},
+		{"double quotes", `tls_server_name: "a\tb: c"` + "\n", map[string]string{"MONIFY_TLS_SERVER_NAME": "a\tb: c"}, 0, ""},
+		{"block list", "probes:\n  - smtp://mail.example.com:587\n  - imaps://mail.example.com\n",
+			map[string]string{"MONIFY_PROBES": "smtp://mail.example.com:587,imaps://mail.example.com"}, 0, ""},
+		{"flow list", "sysctls: [vm.swappiness, fs.file-max]\n", map[string]string{"MONIFY_SYSCTLS": "vm.swappiness,fs.file-max"}, 0, ""},
+		{"alert rules", "alert_rules:\n  - load1 > 4 for 5m\n  - disk_used_percent > 95\n",
+			map[string]string{"MONIFY_ALERT_RULES": "load1 > 4 for 5m;disk_used_percent > 95"}, 0, ""},
+		{"labels", "labels:\n  env: prod\n  team: web\n", map[string]string{"MONIFY_LABELS": "env=prod,team=web"}, 0, ""},
+		{"flow intervals", "collector:\n  intervals: {disk_space: 60, network: 30}\n",
+			map[string]string{"MONIFY_COLLECTOR_INTERVALS": "disk_space=60,network=30"}, 0, ""},
+		{"multi-line string", "tls_server_name: >-\n  a\n  b\n", map[string]string{"MONIFY_TLS_SERVER_NAME": "a b"}, 0, ""},
+		{"anchor", "server:\n  url: &url https://a.example.com\n  url_fallback: *url\n",
+			map[string]string{"MONIFY_SERVER_URL": "https://a.example.com", "MONIFY_SERVER_URL_FALLBACK": "https://a.example.com"}, 0, ""},
+		{"null", "tls_server_name:\n", map[string]string{"MONIFY_TLS_SERVER_NAME": ""}, 0, ""},
+
+		{"nested mapping value", "interval: 30\ntls_server_name: b: c\n", nil, 2, "mapping values are not allowed"},
+		{"unterminated quote", "tls_server_name: 'abc\n", nil, 2, "found unexpected end of stream"},
+		{"top-level list", "- a\n", nil, 1, "expected key: value at the top level"},
+		{"multiple documents", "interval: 30\n---\ninterval: 60\n", nil, 2, "multiple documents are not supported"},
+		{"mapping in list", "interval: 30\nprobes:\n  - url: smtp://mail.example.com\n",
+			map[string]string{"MONIFY_INTERVAL": "30"}, 2, "probes: expected a list of values"},
+		{"mapping for a value", "interval:\n  seconds: 30\n", map[string]string{}, 1, "interval: expected a value or a list"},
+		{"duplicate", "interval: 30\ninterval: 60\n", map[string]string{"MONIFY_INTERVAL": "30"}, 2, "interval: interval is set twice"},
+		{"secret", "token: abc\n", map[string]string{}, 1, "token: secrets are only read from"},
+		{"unknown setting", "server:\n  urll: https://a.example.com\n", map[string]string{}, 2, "server.urll: unknown setting, did you mean server_url?"},
+		{"comma in label", "labels:\n  env: a,b\n", map[string]string{}, 1, "labels: keys and values must not contain commas"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfigYAML(t, tt.yaml)
+
+			vars, lines, problems, err := checkConfigYAML(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != nil && !maps.Equal(vars, tt.want) {
+				t.Errorf("vars = %v, want %v", vars, tt.want)
+			}
+			if tt.wantErr == "" {
+				if len(problems) > 0 {
+					t.Fatalf("problems = %+v", problems)
+				}
+				for name := range vars {
+					if lines[name] == 0 {
+						t.Errorf("no line for %s", name)
+					}
+				}
+				return
+			}
+			if len(problems) != 1 {
+				t.Fatalf("problems = %+v, want one", problems)
+			}
+			message := problems[0].Message
+			if problems[0].Setting != "" {
+				message = problems[0].Setting + ": " + message
+			}
+			if !strings.Contains(message, tt.wantErr) || problems[0].Line != tt.wantLine {
+				t.Fatalf("problem = line %d: %s, want line %d: %s", problems[0].Line, message, tt.wantLine, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckConfigYAMLValidates(t *testing.T) {
+	useConfigYAML(t, "server:\n  url: not a url\ninterval: 30\n")
+
+	vars, _, problems, err := checkConfigYAML(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Setting != "server.url" || problems[0].Line != 2 {
+		t.Fatalf("problems = %+v, want invalid server.url on line 2", problems)
+	}
+	if _, ok := vars["MONIFY_SERVER_URL"]; ok || vars["MONIFY_INTERVAL"] != "30" {
+		t.Fatalf("vars = %v, want only the valid setting", vars)
+	}
+}
+
+func TestReadConfigYAMLMissing(t *testing.T) {
+	saved := ConfigFilePath
+	ConfigFilePath = filepath.Join(t.TempDir(), "missing.yaml")
+	t.Cleanup(func() { ConfigFilePath = saved })
+
+	vars, err := readConfigYAML()
+	if vars != nil || err != nil {
+		t.Fatalf("readConfigYAML = %v, %v; want nil, nil", vars, err)
+	}
+}
@@ -0,0 +1,344 @@
+// Package diagnostics implements the agent's self-check suite: server
+// connectivity, configuration and local permissions.
+package diagnostics
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/proxy"
+	"github.com/monify-labs/agent/internal/sender"
+	"github.com/monify-labs/agent/internal/uninstall"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// Thresholds for warnings
+const (
+	certExpiryWarning = 14 * 24 * time.Hour // Server certificate expires soon
+	maxClockSkew      = 1 * time.Minute     // Local clock differs from the server's
+)
+
+// hints tell the user how to fix a check that did not pass
+var hints = map[string]string{
+	"credentials":  "run 'sudo monify login'",
+	"root":         "run the agent as root, or keep capabilities with MONIFY_CAPABILITIES",
+	"config_dir":   "run 'sudo monify login' to create it; it must be owned by root and not writable by others",
+	"env_file":     "run 'sudo monify login' to create it, and make it readable by root only (chmod 600)",
+	"state_dir":    "create the directory and make it writable by the agent's user",
+	"spool_dir":    "create the directory and make it writable by the agent's user, or set MONIFY_SPOOL_DIR",
+	"procfs":       "mount /proc, or run the agent outside a sandbox that hides it",
+	"systemd_unit": "reinstall with the install script to run the agent as a service",
+	"server_url":   "set MONIFY_SERVER_URL to the full metrics URL, e.g. " + config.ServerURL,
+	"proxy":        "set MONIFY_SOCKS5_PROXY to socks5://[user:pass@]host:port; HTTP(S)_PROXY is not used for metrics",
+	"dns":          "check /etc/resolv.conf and the host name in MONIFY_SERVER_URL",
+	"tcp":          "allow outbound connections to the server in the firewall, or configure MONIFY_SOCKS5_PROXY",
+	"tls":          "check MONIFY_CA_CERT and MONIFY_TLS_SERVER_NAME, and that no proxy intercepts TLS",
+	"http":         "check MONIFY_SERVER_URL and the server status",
+	"clock":        "enable time synchronization, e.g. 'sudo timedatectl set-ntp true'",
+}
+
+// Run runs all checks against serverURL and returns their results in order.
+// Connectivity checks stop at the first failure since later ones depend on it.
+func Run(ctx context.Context, serverURL string) []models.DiagnosticCheck {
+	var checks []models.DiagnosticCheck
+	add := func(name string, check func() (string, string)) string {
+		start := time.Now()
+		status, detail := check()
+		result := models.DiagnosticCheck{
+			Name:       name,
+			Status:     status,
+			Detail:     detail,
+			DurationMs: float64(time.Since(start).Milliseconds()),
+		}
+		if status != models.DiagnosticOK {
+			result.Hint = hints[name]
+		}
+		checks = append(checks, result)
+		return status
+	}
+
+	add("credentials", checkCredentials)
+	add("root", checkRoot)
+	add("config_dir", checkConfigDir)
+	add("env_file", checkEnvFile)
+	add("state_dir", func() (string, string) { return checkWritable(filepath.Dir(config.SequenceFile)) })
+	if config.GetSpoolMaxBytes() > 0 {
+		add("spool_dir", func() (string, string) { return checkWritable(config.GetSpoolDir()) })
+	}
+	add("procfs", checkProcfs)
+	add("systemd_unit", checkSystemdUnit)
+
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Host == "" {
+		add("server_url", func() (string, string) { return models.DiagnosticFail, fmt.Sprintf("invalid server URL %q", serverURL) })
+		return checks
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		port := "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	if add("proxy", checkProxy) == models.DiagnosticFail {
+		return checks
+	}
+	if add("dns", func() (string, string) { return checkDNS(ctx, u.Hostname()) }) == models.DiagnosticFail {
+		return checks
+	}
+	if add("tcp", func() (string, string) { return checkTCP(ctx, addr) }) == models.DiagnosticFail {
+		return checks
+	}
+	if u.Scheme == "https" {
+		if add("tls", func() (string, string) { return checkTLS(ctx, addr, u.Hostname()) }) == models.DiagnosticFail {
+			return checks
+		}
+	}
+
+	var serverDate time.Time
+	add("http", func() (string, string) {
+		status, detail, date := checkHTTP(ctx, serverURL)
+		serverDate = date
+		return status, detail
+	})
+	if !serverDate.IsZero() {
+		add("clock", func() (string, string) { return checkClock(serverDate) })
+	}
+
+	return checks
+}
+
+// checkCredentials verifies that some form of authentication is configured
+func checkCredentials() (string, string) {
+	var configured []string
+	if _, err := config.GetToken(); err == nil {
+		configured = append(configured, "token")
+	}
+	if config.GetRefreshToken() != "" {
+		configured = append(configured, "refresh token")
+	}
+	if config.GetClientCertPath() != "" {
+		configured = append(configured, "client certificate")
+	}
+	if len(configured) == 0 {
+		return models.DiagnosticFail, "no token, refresh token or client certificate configured"
+	}
+	return models.DiagnosticOK, strings.Join(configured, ", ")
+}
+
+// checkRoot warns when the agent cannot read root-only metrics
+func checkRoot() (string, string) {
+	if os.Geteuid() != 0 {
+		return models.DiagnosticWarn, fmt.Sprintf("running as uid %d, some metrics are unavailable", os.Geteuid())
+	}
+	return models.DiagnosticOK, "running as root"
+}
+
+// checkConfigDir fails when others may write the configuration directory,
+// which would let them replace the token or plugins
+func checkConfigDir() (string, string) {
+	dir := uninstall.ConfigDir
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return models.DiagnosticWarn, dir + " does not exist"
+	}
+	if err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+	perm := info.Mode().Perm()
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Uid != 0 {
+		return models.DiagnosticFail, fmt.Sprintf("%s is owned by uid %d, expected root", dir, stat.Uid)
+	}
+	if perm&0o022 != 0 {
+		return models.DiagnosticFail, fmt.Sprintf("%s has mode %04o, writable by others", dir, perm)
+	}
+	return models.DiagnosticOK, fmt.Sprintf("%s has mode %04o", dir, perm)
+}
+
+// checkEnvFile warns when the env file holding the token is readable by others
+func checkEnvFile() (string, string) {
+	info, err := os.Stat(config.EnvFilePath)
+	if os.IsNotExist(err) {
+		return models.DiagnosticWarn, config.EnvFilePath + " does not exist"
+	}
+	if err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return models.DiagnosticWarn, fmt.Sprintf("%s has mode %04o, expected 0600", config.EnvFilePath, perm)
+	}
+	return models.DiagnosticOK, config.EnvFilePath
+}
+
+// checkWritable verifies that the agent can create files in dir
+func checkWritable(dir string) (string, string) {
+	f, err := os.CreateTemp(dir, ".monify-check-*")
+	if err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return models.DiagnosticOK, dir
+}
+
+// checkProcfs verifies that /proc is readable, which most collectors need
+func checkProcfs() (string, string) {
+	if _, err := os.ReadFile("/proc/stat"); err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+	return models.DiagnosticOK, "/proc readable"
+}
+
+// checkSystemdUnit warns when the agent is not installed as a systemd service
+func checkSystemdUnit() (string, string) {
+	if _, err := os.Stat(uninstall.ServiceFile); err != nil {
+		if os.IsNotExist(err) {
+			return models.DiagnosticWarn, uninstall.ServiceFile + " does not exist"
+		}
+		return models.DiagnosticFail, err.Error()
+	}
+	return models.DiagnosticOK, uninstall.ServiceFile
+}
+
+// checkProxy validates the SOCKS5 proxy setting. HTTP(S)_PROXY only applies
+// to update downloads, so relying on it for metrics is a warning.
+func checkProxy() (string, string) {
+	if err := proxy.Err(); err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+	if addr := proxy.Address(); addr != "" {
+		return models.DiagnosticOK, "SOCKS5 proxy " + addr
+	}
+	for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		if os.Getenv(name) != "" {
+			return models.DiagnosticWarn, name + " is set but not used for metrics"
+		}
+	}
+	return models.DiagnosticOK, "direct connection"
+}
+
+// checkDNS resolves the server name. With a SOCKS5 proxy the proxy resolves it.
+func checkDNS(ctx context.Context, host string) (string, string) {
+	if proxy.Address() != "" {
+		return models.DiagnosticOK, "resolved by SOCKS5 proxy " + proxy.Address()
+	}
+	if net.ParseIP(host) != nil {
+		return models.DiagnosticOK, host
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.GetTimeout())
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+	return models.DiagnosticOK, strings.Join(addrs, ", ")
+}
+
+// checkTCP connects to the server the way senders do (address family, proxy)
+func checkTCP(ctx context.Context, addr string) (string, string) {
+	ctx, cancel := context.WithTimeout(ctx, config.GetTimeout())
+	defer cancel()
+	conn, err := sender.ServerDialFunc()(ctx, "tcp", addr)
+	if err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+	defer conn.Close()
+	return models.DiagnosticOK, "connected to " + conn.RemoteAddr().String()
+}
+
+// checkTLS performs a handshake with the sender TLS settings and reports the
+// server certificate's expiry
+func checkTLS(ctx context.Context, addr, host string) (string, string) {
+	tlsConfig, err := sender.ServerTLSConfig()
+	if err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.GetTimeout())
+	defer cancel()
+	conn, err := sender.ServerDialFunc()(ctx, "tcp", addr)
+	if err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+	defer conn.Close()
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+
+	state := tlsConn.ConnectionState()
+	detail := tls.VersionName(state.Version)
+	if len(state.PeerCertificates) > 0 {
+		expiry := state.PeerCertificates[0].NotAfter
+		detail += ", certificate expires " + expiry.Format(time.RFC3339)
+		if time.Until(expiry) < certExpiryWarning {
+			return models.DiagnosticWarn, detail
+		}
+	}
+	if tlsConfig.InsecureSkipVerify {
+		return models.DiagnosticWarn, detail + ", certificate verification disabled"
+	}
+	return models.DiagnosticOK, detail
+}
+
+// checkHTTP sends an unauthenticated request to the server URL. Any HTTP
+// answer proves the path works; the Date header is returned for the clock check.
+func checkHTTP(ctx context.Context, serverURL string) (string, string, time.Time) {
+	tlsConfig, err := sender.ServerTLSConfig()
+	if err != nil {
+		return models.DiagnosticFail, err.Error(), time.Time{}
+	}
+	client := &http.Client{
+		Timeout: config.GetTimeout(),
+		Transport: &http.Transport{
+			DialContext:     sender.ServerDialFunc(),
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", serverURL, nil)
+	if err != nil {
+		return models.DiagnosticFail, err.Error(), time.Time{}
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("monify/%s", config.Version))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.DiagnosticFail, err.Error(), time.Time{}
+	}
+	resp.Body.Close()
+
+	date, _ := http.ParseTime(resp.Header.Get("Date"))
+	if resp.StatusCode >= 500 {
+		return models.DiagnosticWarn, resp.Status, date
+	}
+	return models.DiagnosticOK, resp.Status, date
+}
+
+// checkClock compares the local clock with the server's Date header
+func checkClock(serverDate time.Time) (string, string) {
+	skew := time.Since(serverDate).Round(time.Second)
+	detail := fmt.Sprintf("local clock differs from the server by %s", skew)
+	if skew.Abs() > maxClockSkew {
+		return models.DiagnosticWarn, detail
+	}
+	return models.DiagnosticOK, detail
+}
@@ -0,0 +1,20 @@
+// Package exitcode defines the exit statuses of the monify commands and the
+// agent, so that systemd units and scripts can tell failures apart. The
+// values are part of the CLI interface: existing ones must not change.
+package exitcode
+
+// Exit statuses
+const (
+	OK            = 0   // Success
+	Failure       = 1   // Any failure without a more specific status
+	Usage         = 2   // Invalid command line
+	AuthFailed    = 3   // The server rejected the credentials; systemd does not restart the agent
+	NotConfigured = 4   // No credentials are configured, run monify login
+	InvalidConfig = 5   // The configuration has errors, see monify validate
+	NoPermission  = 6   // The command requires root
+	Unreachable   = 7   // The server cannot be reached: DNS, proxy, firewall or TLS
+	CollectFailed = 8   // Metrics could not be collected
+	NotInstalled  = 9   // systemd or the agent service is not installed
+	ServiceFailed = 10  // The service did not start or is not running
+	Interrupted   = 130 // Interrupted by SIGINT (128 + 2)
+)
@@ -0,0 +1,261 @@
+// Package install sets the agent up as a systemd service natively, the same
+// way scripts/install.sh does, for hosts that already have the binary.
+package install
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/exitcode"
+	"github.com/monify-labs/agent/internal/uninstall"
+)
+
+// Unit is the systemd unit of the agent, kept in sync with scripts/install.sh
+const Unit = `[Unit]
+Description=Monify Monitoring Agent
+Documentation=https://docs.monify.cloud
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+WatchdogSec=2min
+ExecStart=/usr/local/bin/monify run
+ExecReload=/bin/kill -HUP $MAINPID
+Restart=always
+RestartSec=5
+RestartPreventExitStatus=3
+StandardOutput=journal
+StandardError=journal
+SyslogIdentifier=monify
+
+# Security settings
+NoNewPrivileges=yes
+ProtectSystem=strict
+ProtectHome=yes
+PrivateTmp=yes
+ReadWritePaths=/etc/monify /var/log/monify
+StateDirectory=monify
+StateDirectoryMode=0700
+ProtectKernelTunables=yes
+ProtectKernelModules=yes
+ProtectControlGroups=yes
+RestrictSUIDSGID=yes
+RestrictRealtime=yes
+LockPersonality=yes
+
+# Resource limits
+MemoryMax=64M
+CPUQuota=5%
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// ErrNoSystemd is returned by Run on hosts without systemd
+var ErrNoSystemd = errors.New("systemd is not running")
+
+// ErrAuthFailed is returned by Start when the agent exited because the
+// server rejected its token
+var ErrAuthFailed = errors.New("authentication failed - invalid token")
+
+// Options controls what Run sets up
+type Options struct {
+	User string // Account created for the agent to switch to, empty for none
+}
+
+// Run installs the running binary, creates the service user and the
+// configuration, log and state directories, and writes the unit. The
+// configuration itself is left to the caller.
+func Run(opts Options) error {
+	if !hasSystemd() {
+		return ErrNoSystemd
+	}
+	if err := installBinary(); err != nil {
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+	if opts.User != "" {
+		if err := createUser(opts.User); err != nil {
+			return fmt.Errorf("failed to create user %s: %w", opts.User, err)
+		}
+	}
+	for dir, mode := range map[string]os.FileMode{uninstall.ConfigDir: 0700, uninstall.LogDir: 0755, uninstall.StateDir: 0700} {
+		if err := os.MkdirAll(dir, mode); err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(uninstall.ServiceFile, []byte(Unit), 0644); err != nil {
+		return fmt.Errorf("failed to write unit: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(uninstall.ServiceFile, 0644); err != nil {
+		return err
+	}
+	return systemctl("daemon-reload")
+}
+
+// Start enables and (re)starts the service, then waits for it to settle.
+// ErrAuthFailed is returned if the agent exited because of its token.
+func Start() error {
+	if err := systemctl("enable", uninstall.ServiceName); err != nil {
+		return err
+	}
+	if err := systemctl("restart", uninstall.ServiceName); err != nil {
+		return err
+	}
+
+	time.Sleep(3 * time.Second)
+	if exec.Command("systemctl", "is-active", "--quiet", uninstall.ServiceName).Run() == nil {
+		return nil
+	}
+	out, _ := exec.Command("systemctl", "show", uninstall.ServiceName, "--property=ExecMainStatus", "--value").Output()
+	if strings.TrimSpace(string(out)) == strconv.Itoa(exitcode.AuthFailed) {
+		return ErrAuthFailed
+	}
+	return fmt.Errorf("service is not running")
+}
+
+// ErrNoSystemdCreds is returned by SealToken on hosts without systemd-creds
+// (systemd 250 or later)
+var ErrNoSystemdCreds = errors.New("systemd-creds is not available")
+
+// credentialDropIn passes the decrypted token to the service
+var credentialDropIn = filepath.Join(uninstall.DropInDir, "token-credential.conf")
+
+// TokenCredentialPath returns where SealToken stores the encrypted token,
+// next to the env file
+func TokenCredentialPath() string {
+	return filepath.Join(filepath.Dir(config.EnvFilePath), "token.cred")
+}
+
+// SealToken encrypts token with systemd-creds, bound to the TPM2 chip if the
+// host has one and to the host key in /var/lib/systemd otherwise, and has
+// systemd decrypt it for the service. The token itself is never written to
+// disk in plain text.
+func SealToken(token string) error {
+	if _, err := exec.LookPath("systemd-creds"); err != nil {
+		return ErrNoSystemdCreds
+	}
+	if err := os.MkdirAll(filepath.Dir(TokenCredentialPath()), 0755); err != nil {
+		return err
+	}
+	cmd := exec.Command("systemd-creds", "encrypt", "--name="+config.TokenCredential, "--with-key=auto", "-", TokenCredentialPath())
+	cmd.Stdin = strings.NewReader(token)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("systemd-creds encrypt: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	if err := os.Chmod(TokenCredentialPath(), 0600); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(uninstall.DropInDir, 0755); err != nil {
+		return err
+	}
+	dropIn := fmt.Sprintf("[Service]\nLoadCredentialEncrypted=%s:%s\n", config.TokenCredential, TokenCredentialPath())
+	if err := os.WriteFile(credentialDropIn, []byte(dropIn), 0644); err != nil {
+		return fmt.Errorf("failed to write unit drop-in: %w", err)
+	}
+	if !hasSystemd() {
+		return nil
+	}
+	return systemctl("daemon-reload")
+}
+
+// RemoveTokenCredential deletes the encrypted token and its drop-in, if any
+func RemoveTokenCredential() error {
+	for _, path := range []string{TokenCredentialPath(), credentialDropIn} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	os.Remove(uninstall.DropInDir) // Only if no other drop-ins are left
+	if !hasSystemd() {
+		return nil
+	}
+	return systemctl("daemon-reload")
+}
+
+// installBinary copies the running executable to uninstall.BinaryPath,
+// unless it is running from there
+func installBinary() error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if self, err = filepath.EvalSymlinks(self); err != nil {
+		return err
+	}
+	if target, err := filepath.EvalSymlinks(uninstall.BinaryPath); err == nil && target == self {
+		return nil
+	}
+
+	src, err := os.Open(self)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Written next to the target and renamed, so a running agent keeps its
+	// binary until it restarts
+	tmp, err := os.CreateTemp(filepath.Dir(uninstall.BinaryPath), ".monify-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), uninstall.BinaryPath)
+}
+
+// createUser creates a system account without home directory and login
+// shell, unless it exists
+func createUser(name string) error {
+	if _, err := user.Lookup(name); err == nil {
+		return nil
+	}
+	shell := "/usr/sbin/nologin"
+	if _, err := os.Stat(shell); err != nil {
+		shell = "/bin/false"
+	}
+	out, err := exec.Command("useradd", "--system", "--no-create-home", "--shell", shell, name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("useradd: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// hasSystemd reports whether the host is running systemd
+func hasSystemd() bool {
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return false
+	}
+	_, err := exec.LookPath("systemctl")
+	return err == nil
+}
+
+// systemctl runs systemctl with args
+func systemctl(args ...string) error {
+	if out, err := exec.Command("systemctl", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("systemctl %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
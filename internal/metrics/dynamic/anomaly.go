@@ -0,0 +1,120 @@
+package dynamic
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// anomalyFloors is the smallest standard deviation assumed for each metric,
+// so that small changes of a very steady metric are not flagged
+var anomalyFloors = map[string]float64{
+	"cpu_usage_percent":             2,
+	"memory_used_percent":           1,
+	"disk_read_bytes_per_second":    1 << 20,
+	"disk_write_bytes_per_second":   1 << 20,
+	"network_recv_bytes_per_second": 1 << 20,
+	"network_sent_bytes_per_second": 1 << 20,
+}
+
+// baseline is the exponentially weighted mean and variance of a metric
+type baseline struct {
+	mean     float64
+	variance float64
+	samples  int
+}
+
+// AnomalyDetector keeps rolling baselines of the 1-second samples of key
+// metrics and records runs of samples that deviate strongly from them
+type AnomalyDetector struct {
+	alpha     float64 // EWMA weight of a new sample
+	warmup    int     // Samples before a baseline is trusted
+	threshold float64 // Deviation in standard deviations
+
+	mu        sync.Mutex
+	baselines map[string]*baseline
+	open      map[string]*models.Anomaly // Runs still in progress
+	done      []models.Anomaly
+}
+
+// NewAnomalyDetector creates a detector with baselines spanning window that
+// flags samples more than threshold standard deviations from the mean
+func NewAnomalyDetector(window, warmup time.Duration, threshold float64) *AnomalyDetector {
+	return &AnomalyDetector{
+		alpha:     2 / (window.Seconds() + 1),
+		warmup:    int(warmup.Seconds()),
+		threshold: threshold,
+		baselines: make(map[string]*baseline),
+		open:      make(map[string]*models.Anomaly),
+	}
+}
+
+// Observe adds a sample of metric to its baseline. A nil detector ignores it.
+func (d *AnomalyDetector) Observe(metric string, value float64, at time.Time) {
+	if d == nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	b := d.baselines[metric]
+	if b == nil {
+		b = &baseline{mean: value}
+		d.baselines[metric] = b
+	}
+
+	// Score against the baseline before the sample is folded into it
+	stddev := math.Max(math.Sqrt(b.variance), anomalyFloors[metric])
+	anomalous := false
+	var score float64
+	if b.samples >= d.warmup && stddev > 0 {
+		score = math.Abs(value-b.mean) / stddev
+		anomalous = score >= d.threshold
+	}
+
+	if anomalous {
+		run := d.open[metric]
+		if run == nil {
+			run = &models.Anomaly{Metric: metric, Start: at, Baseline: b.mean, StdDev: stddev}
+			d.open[metric] = run
+		}
+		run.End = at
+		run.Samples++
+		if score > run.Score {
+			run.Score, run.Peak = score, value
+		}
+	} else if run := d.open[metric]; run != nil {
+		d.done = append(d.done, *run)
+		delete(d.open, metric)
+	}
+
+	// Anomalous samples still update the baseline, so a lasting change of
+	// level becomes the new normal instead of being flagged forever
+	diff := value - b.mean
+	increment := d.alpha * diff
+	b.mean += increment
+	b.variance = (1 - d.alpha) * (b.variance + diff*increment)
+	b.samples++
+}
+
+// Collect returns the runs recorded since the last call. Runs still in
+// progress are included and continue as new runs in the next call.
+func (d *AnomalyDetector) Collect() []models.Anomaly {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	anomalies := d.done
+	d.done = nil
+	for metric, run := range d.open {
+		anomalies = append(anomalies, *run)
+		delete(d.open, metric)
+	}
+	return anomalies
+}
@@ -24,6 +24,8 @@ type CPUCollector struct {
 	samples []cpuSample
 	ctx     context.Context
 	cancel  context.CancelFunc
+
+	anomalies *AnomalyDetector
 }
 
 // NewCPUCollector creates a new CPU collector
@@ -52,6 +54,11 @@ func (c *CPUCollector) Start() {
 	}()
 }
 
+// DetectAnomalies feeds every sample to detector. Call it before Start.
+func (c *CPUCollector) DetectAnomalies(detector *AnomalyDetector) {
+	c.anomalies = detector
+}
+
 // Stop halts background sampling
 func (c *CPUCollector) Stop() {
 	if c.cancel != nil {
@@ -71,6 +78,7 @@ func (c *CPUCollector) sample() {
 		usagePercent: percentages[0],
 		timestamp:    time.Now(),
 	}
+	c.anomalies.Observe("cpu_usage_percent", sample.usagePercent, sample.timestamp)
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
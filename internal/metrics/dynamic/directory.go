@@ -0,0 +1,162 @@
+package dynamic
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// directoryScanInterval is how often watched directories are re-scanned.
+// Walking large trees is expensive, so results are cached between scans.
+const directoryScanInterval = 5 * time.Minute
+
+// DirectoryCollector tracks total size and file count of configured directories
+type DirectoryCollector struct {
+	paths []string
+
+	mu      sync.Mutex
+	results map[string]models.DirectoryMetrics
+	ctx     context.Context
+	cancel  context.CancelFunc
+	paused  atomic.Bool // Scheduled runs are skipped while set
+}
+
+// NewDirectoryCollector creates a collector watching the given directories
+func NewDirectoryCollector(paths []string) *DirectoryCollector {
+	return &DirectoryCollector{
+		paths:   paths,
+		results: make(map[string]models.DirectoryMetrics),
+	}
+}
+
+// Start begins background scanning
+func (d *DirectoryCollector) Start() {
+	if len(d.paths) == 0 {
+		return
+	}
+
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	go func() {
+		ticker := time.NewTicker(directoryScanInterval)
+		defer ticker.Stop()
+
+		d.scanAll()
+		for {
+			select {
+			case <-d.ctx.Done():
+				return
+			case <-ticker.C:
+				if !d.paused.Load() {
+					d.scanAll()
+				}
+			}
+		}
+	}()
+}
+
+// SetPaused skips scheduled scans while paused is true
+func (d *DirectoryCollector) SetPaused(paused bool) {
+	d.paused.Store(paused)
+}
+
+// Stop halts background scanning
+func (d *DirectoryCollector) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+}
+
+// scanAll scans every watched directory sequentially to limit I/O load
+func (d *DirectoryCollector) scanAll() {
+	for _, path := range d.paths {
+		if d.ctx.Err() != nil {
+			return
+		}
+
+		metrics := scanDirectory(d.ctx, path)
+
+		d.mu.Lock()
+		d.results[path] = metrics
+		d.mu.Unlock()
+	}
+}
+
+// Collect returns the latest scan results. Returns nil if no directories are watched.
+func (d *DirectoryCollector) Collect(ctx context.Context) ([]models.DirectoryMetrics, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var result []models.DirectoryMetrics
+	for _, path := range d.paths {
+		if metrics, ok := d.results[path]; ok {
+			result = append(result, metrics)
+		}
+	}
+
+	return result, nil
+}
+
+// scanDirectory walks a directory tree without crossing filesystem boundaries
+func scanDirectory(ctx context.Context, root string) models.DirectoryMetrics {
+	start := time.Now()
+	metrics := models.DirectoryMetrics{Path: root}
+
+	rootDev, hasDev := deviceOf(root)
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			// Skip unreadable entries but keep walking
+			if entry != nil && entry.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+
+		if entry.IsDir() {
+			// Don't descend into other mounts (e.g., NFS below /var/lib)
+			if dev, ok := deviceOf(path); hasDev && ok && dev != rootDev {
+				return filepath.SkipDir
+			}
+			metrics.Dirs++
+			return nil
+		}
+
+		if info.Mode().IsRegular() {
+			metrics.Files++
+			metrics.Size += uint64(info.Size())
+		}
+		return nil
+	})
+	if err != nil {
+		metrics.Error = err.Error()
+	}
+
+	metrics.ScanDurationMs = float64(time.Since(start).Milliseconds())
+	metrics.ScannedAt = start
+
+	return metrics
+}
+
+// deviceOf returns the device ID of the filesystem containing path
+func deviceOf(path string) (uint64, bool) {
+	var stat syscall.Stat_t
+	if err := syscall.Lstat(path, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Dev), true
+}
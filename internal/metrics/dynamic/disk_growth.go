@@ -0,0 +1,196 @@
+package dynamic
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/monify-labs/agent/internal/mountfilter"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+const (
+	growthWindow          = 30 * time.Minute // Regression window for growth rates
+	growthMinSamples      = 60               // Samples required before estimating
+	growthPartitionsEvery = 1 * time.Minute  // How often the mount list is refreshed
+)
+
+// growthSample is a single used/free measurement of a filesystem
+type growthSample struct {
+	used      uint64
+	free      uint64
+	timestamp time.Time
+}
+
+// DiskGrowthCollector samples used space of each local filesystem every second
+// and estimates time until full from a least-squares fit over a rolling window.
+type DiskGrowthCollector struct {
+	mu       sync.Mutex
+	samples  map[string][]growthSample // mountpoint -> samples within the window
+	mounts   []string
+	mountsAt time.Time
+	filter   *mountfilter.Filter
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewDiskGrowthCollector creates a new disk growth collector for the
+// filesystems the filter allows
+func NewDiskGrowthCollector(filter *mountfilter.Filter) *DiskGrowthCollector {
+	return &DiskGrowthCollector{
+		samples: make(map[string][]growthSample),
+		filter:  filter,
+	}
+}
+
+// Start begins background sampling
+func (d *DiskGrowthCollector) Start() {
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	go func() {
+		ticker := time.NewTicker(1 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-d.ctx.Done():
+				return
+			case <-ticker.C:
+				d.sample()
+			}
+		}
+	}()
+}
+
+// Stop halts background sampling
+func (d *DiskGrowthCollector) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+}
+
+// sample measures every local filesystem once
+func (d *DiskGrowthCollector) sample() {
+	now := time.Now()
+
+	// Mount list changes rarely; avoid re-reading it every second
+	if d.mounts == nil || now.Sub(d.mountsAt) >= growthPartitionsEvery {
+		d.mounts = localMountpoints(d.ctx, d.filter)
+		d.mountsAt = now
+	}
+
+	measured := make(map[string]growthSample, len(d.mounts))
+	for _, mount := range d.mounts {
+		var stat syscall.Statfs_t
+		if err := syscall.Statfs(mount, &stat); err != nil {
+			continue
+		}
+		total := stat.Blocks * uint64(stat.Bsize)
+		free := stat.Bavail * uint64(stat.Bsize)
+		measured[mount] = growthSample{
+			used:      total - stat.Bfree*uint64(stat.Bsize),
+			free:      free,
+			timestamp: now,
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cutoff := now.Add(-growthWindow)
+	for mount := range d.samples {
+		if _, ok := measured[mount]; !ok {
+			delete(d.samples, mount) // Unmounted
+		}
+	}
+	for mount, s := range measured {
+		samples := append(d.samples[mount], s)
+		drop := 0
+		for drop < len(samples) && samples[drop].timestamp.Before(cutoff) {
+			drop++
+		}
+		d.samples[mount] = samples[drop:]
+	}
+}
+
+// localMountpoints lists mountpoints of local, non-virtual filesystems the
+// filter allows. Network mounts are excluded since statfs on a dead server
+// can block.
+func localMountpoints(ctx context.Context, filter *mountfilter.Filter) []string {
+	partitions, err := filter.Partitions(ctx)
+	if err != nil {
+		return []string{}
+	}
+
+	seen := make(map[string]bool)
+	mounts := []string{}
+	for _, partition := range partitions {
+		if networkFSTypes[partition.Fstype] || seen[partition.Mountpoint] {
+			continue
+		}
+		seen[partition.Mountpoint] = true
+		mounts = append(mounts, partition.Mountpoint)
+	}
+	return mounts
+}
+
+// Collect estimates growth rate and time until full for each filesystem.
+// Samples are kept across collections so the window spans many intervals.
+func (d *DiskGrowthCollector) Collect(ctx context.Context) ([]models.DiskGrowthMetrics, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var result []models.DiskGrowthMetrics
+	for mount, samples := range d.samples {
+		if len(samples) < growthMinSamples {
+			continue
+		}
+
+		last := samples[len(samples)-1]
+		slope := usedSlope(samples) // bytes per second
+		metric := models.DiskGrowthMetrics{
+			Mount:              mount,
+			Used:               last.used,
+			Free:               last.free,
+			GrowthBytesPerHour: slope * 3600,
+			WindowSeconds:      int(last.timestamp.Sub(samples[0].timestamp).Seconds()),
+		}
+
+		// Only a growing filesystem has a time to exhaustion
+		if slope > 0 {
+			hours := float64(last.free) / slope / 3600
+			metric.HoursUntilFull = &hours
+		}
+
+		result = append(result, metric)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Mount < result[j].Mount })
+	return result, nil
+}
+
+// usedSlope fits used space against time with least squares and returns bytes/second
+func usedSlope(samples []growthSample) float64 {
+	origin := samples[0].timestamp
+	base := float64(samples[0].used)
+	n := float64(len(samples))
+
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		// Offsets keep the sums small enough for float64 precision
+		x := s.timestamp.Sub(origin).Seconds()
+		y := float64(s.used) - base
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
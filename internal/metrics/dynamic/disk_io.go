@@ -29,6 +29,9 @@ type DiskIOCollector struct {
 	samples []diskIOSample
 	ctx     context.Context
 	cancel  context.CancelFunc
+
+	anomalies *AnomalyDetector
+	last      *diskIOSample // Previous sample, for the per-second rates of the detector
 }
 
 // NewDiskIOCollector creates a new disk I/O collector
@@ -57,6 +60,11 @@ func (d *DiskIOCollector) Start() {
 	}()
 }
 
+// DetectAnomalies feeds every sample to detector. Call it before Start.
+func (d *DiskIOCollector) DetectAnomalies(detector *AnomalyDetector) {
+	d.anomalies = detector
+}
+
 // Stop halts background sampling
 func (d *DiskIOCollector) Stop() {
 	if d.cancel != nil {
@@ -85,6 +93,22 @@ func (d *DiskIOCollector) sample() {
 		devices:   devices,
 		timestamp: time.Now(),
 	}
+	if d.anomalies != nil {
+		if d.last != nil {
+			if seconds := sample.timestamp.Sub(d.last.timestamp).Seconds(); seconds > 0 {
+				var readBytes, writeBytes uint64
+				for device, curr := range sample.devices {
+					if prev, ok := d.last.devices[device]; ok && curr.readBytes >= prev.readBytes && curr.writeBytes >= prev.writeBytes {
+						readBytes += curr.readBytes - prev.readBytes
+						writeBytes += curr.writeBytes - prev.writeBytes
+					}
+				}
+				d.anomalies.Observe("disk_read_bytes_per_second", float64(readBytes)/seconds, sample.timestamp)
+				d.anomalies.Observe("disk_write_bytes_per_second", float64(writeBytes)/seconds, sample.timestamp)
+			}
+		}
+		d.last = &sample
+	}
 
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -2,23 +2,27 @@ package dynamic
 
 import (
 	"context"
+	"strings"
 
+	"github.com/monify-labs/agent/internal/mountfilter"
 	"github.com/monify-labs/agent/pkg/models"
 	"github.com/shirou/gopsutil/v4/disk"
 )
 
-// CollectDiskSpace aggregates disk space usage across all partitions (no sampling needed)
-func CollectDiskSpace(ctx context.Context) (*models.DiskSpaceMetrics, error) {
-	partitions, err := disk.PartitionsWithContext(ctx, false)
+// CollectDiskSpace aggregates disk space usage across all partitions the
+// filter allows (no sampling needed). A block device mounted more than once,
+// e.g. by bind mounts, is counted once.
+func CollectDiskSpace(ctx context.Context, filter *mountfilter.Filter) (*models.DiskSpaceMetrics, error) {
+	partitions, err := filter.Partitions(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	var totalSpace, usedSpace, freeSpace uint64
+	seen := make(map[string]bool) // Block devices counted so far
 
 	for _, partition := range partitions {
-		// Skip special filesystems
-		if shouldSkipFilesystem(partition.Fstype) {
+		if seen[partition.Device] {
 			continue
 		}
 
@@ -27,6 +31,9 @@ func CollectDiskSpace(ctx context.Context) (*models.DiskSpaceMetrics, error) {
 			continue
 		}
 
+		if strings.HasPrefix(partition.Device, "/") {
+			seen[partition.Device] = true // tmpfs, overlay etc. are named by type
+		}
 		totalSpace += usage.Total
 		usedSpace += usage.Used
 		freeSpace += usage.Free
@@ -45,22 +52,3 @@ func CollectDiskSpace(ctx context.Context) (*models.DiskSpaceMetrics, error) {
 		UsedPercent: usedPercent,
 	}, nil
 }
-
-// shouldSkipFilesystem determines if a filesystem type should be skipped
-func shouldSkipFilesystem(fstype string) bool {
-	skipTypes := map[string]bool{
-		"tmpfs":    true,
-		"devtmpfs": true,
-		"devfs":    true,
-		"proc":     true,
-		"sysfs":    true,
-		"cgroup":   true,
-		"cgroup2":  true,
-		"nsfs":     true,
-		"overlay":  true,
-		"squashfs": true,
-		"iso9660":  true,
-	}
-
-	return skipTypes[fstype]
-}
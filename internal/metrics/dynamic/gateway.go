@@ -0,0 +1,169 @@
+package dynamic
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/monify-labs/agent/internal/metrics/static"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// gatewayPingTimeout bounds a single gateway reachability check
+const gatewayPingTimeout = 1 * time.Second
+
+// CollectGateway checks reachability of the default IPv4 gateway. ICMP echo
+// is used when permitted (root or ping_group_range); otherwise the gateway's
+// ARP entry state is reported.
+func CollectGateway(ctx context.Context) (*models.GatewayMetrics, error) {
+	gateway, iface := defaultGateway()
+	if gateway == nil {
+		return nil, fmt.Errorf("no default IPv4 gateway")
+	}
+
+	metrics := &models.GatewayMetrics{
+		Gateway:   gateway.String(),
+		Interface: iface,
+	}
+
+	latency, err := pingICMP(ctx, gateway)
+	if err == nil {
+		metrics.Reachable = true
+		metrics.LatencyMs = float64(latency.Microseconds()) / 1000
+		metrics.Method = "icmp"
+		return metrics, nil
+	}
+
+	// ICMP not permitted: fall back to the neighbor state
+	if errors.Is(err, os.ErrPermission) {
+		metrics.Reachable = arpComplete(gateway)
+		metrics.Method = "arp"
+		return metrics, nil
+	}
+
+	metrics.Method = "icmp"
+	return metrics, nil
+}
+
+// defaultGateway returns the default IPv4 gateway, using the same route
+// selection as the reported static gateway
+func defaultGateway() (net.IP, string) {
+	gateway, iface := static.DefaultIPv4Route()
+	return net.ParseIP(gateway).To4(), iface
+}
+
+// arpComplete checks whether the ARP entry for ip is resolved (flag 0x2)
+func arpComplete(ip net.IP) bool {
+	data, err := os.ReadFile("/proc/net/arp")
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == ip.String() {
+			return fields[2] == "0x2"
+		}
+	}
+
+	return false
+}
+
+// pingICMP sends one ICMP echo request and waits for the reply
+func pingICMP(ctx context.Context, ip net.IP) (time.Duration, error) {
+	conn, raw, err := openICMP()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(gatewayPingTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	id := uint16(os.Getpid() & 0xffff)
+	seq := uint16(time.Now().UnixNano() & 0xffff)
+
+	// Echo request: type 8, code 0, checksum, identifier, sequence
+	msg := []byte{8, 0, 0, 0, byte(id >> 8), byte(id), byte(seq >> 8), byte(seq)}
+	checksum := icmpChecksum(msg)
+	msg[2], msg[3] = byte(checksum>>8), byte(checksum)
+
+	var dst net.Addr = &net.IPAddr{IP: ip}
+	if !raw {
+		dst = &net.UDPAddr{IP: ip}
+	}
+
+	start := time.Now()
+	if _, err := conn.WriteTo(msg, dst); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return 0, err
+		}
+
+		// Echo reply with our sequence (datagram sockets rewrite the identifier)
+		if n >= 8 && buf[0] == 0 && binary.BigEndian.Uint16(buf[6:8]) == seq &&
+			(!raw || binary.BigEndian.Uint16(buf[4:6]) == id) {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// ICMPPermitted reports whether the agent may send ICMP echo requests, over
+// a raw or an unprivileged datagram socket
+func ICMPPermitted() bool {
+	conn, _, err := openICMP()
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// openICMP opens a raw ICMP socket (root), or an unprivileged datagram ICMP socket
+func openICMP() (net.PacketConn, bool, error) {
+	if conn, err := net.ListenPacket("ip4:icmp", "0.0.0.0"); err == nil {
+		return conn, true, nil
+	}
+
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, syscall.IPPROTO_ICMP)
+	if err != nil {
+		return nil, false, err
+	}
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close()
+
+	conn, err := net.FilePacketConn(f)
+	if err != nil {
+		return nil, false, err
+	}
+	return conn, false, nil
+}
+
+// icmpChecksum computes the Internet checksum of an ICMP message
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,213 @@
+package dynamic
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// mailConn is a line-oriented connection to a mail server
+type mailConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dialMail connects to the target, using implicit TLS for smtps/imaps
+func dialMail(ctx context.Context, target *url.URL) (*mailConn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", target.Host)
+	if err != nil {
+		return nil, fmt.Errorf("connect failed: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	m := &mailConn{conn: conn, reader: bufio.NewReader(conn)}
+	if strings.HasSuffix(target.Scheme, "s") {
+		if err := m.startTLS(target); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// startTLS upgrades the connection, verifying the certificate against the target host
+func (m *mailConn) startTLS(target *url.URL) error {
+	tlsConn := tls.Client(m.conn, &tls.Config{
+		ServerName:         target.Hostname(),
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: target.Query().Has("insecure"),
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("TLS handshake failed: %w", err)
+	}
+
+	m.conn = tlsConn
+	m.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// tlsExpiry returns the leaf certificate expiry if the connection uses TLS
+func (m *mailConn) tlsExpiry() *time.Time {
+	tlsConn, ok := m.conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+	expiry := certs[0].NotAfter
+	return &expiry
+}
+
+// send writes a single command line
+func (m *mailConn) send(line string) error {
+	_, err := fmt.Fprintf(m.conn, "%s\r\n", line)
+	return err
+}
+
+// readLine reads one CRLF-terminated line
+func (m *mailConn) readLine() (string, error) {
+	line, err := m.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// probeSMTP reads the 220 greeting, sends EHLO and optionally STARTTLS
+func probeSMTP(ctx context.Context, target *url.URL, result *models.ProbeMetrics) error {
+	m, err := dialMail(ctx, target)
+	if err != nil {
+		return err
+	}
+	defer m.conn.Close()
+
+	code, banner, err := readSMTPReply(m)
+	if err != nil {
+		return fmt.Errorf("no banner: %w", err)
+	}
+	result.Banner = firstLine(banner)
+	if code != 220 {
+		return fmt.Errorf("unexpected greeting %d", code)
+	}
+
+	if err := m.send("EHLO monify-agent"); err != nil {
+		return err
+	}
+	code, extensions, err := readSMTPReply(m)
+	if err != nil || code != 250 {
+		return fmt.Errorf("EHLO rejected")
+	}
+
+	if target.Query().Has("starttls") {
+		if !strings.Contains(strings.ToUpper(extensions), "STARTTLS") {
+			return fmt.Errorf("STARTTLS not offered")
+		}
+		if err := m.send("STARTTLS"); err != nil {
+			return err
+		}
+		if code, _, err := readSMTPReply(m); err != nil || code != 220 {
+			return fmt.Errorf("STARTTLS rejected")
+		}
+		if err := m.startTLS(target); err != nil {
+			return err
+		}
+	}
+
+	result.CertExpiry = m.tlsExpiry()
+	result.TLS = result.CertExpiry != nil
+
+	m.send("QUIT")
+	return nil
+}
+
+// readSMTPReply reads a possibly multi-line SMTP reply
+func readSMTPReply(m *mailConn) (int, string, error) {
+	var lines []string
+	for {
+		line, err := m.readLine()
+		if err != nil {
+			return 0, "", err
+		}
+		if len(line) < 3 {
+			return 0, "", fmt.Errorf("short response %q", line)
+		}
+		lines = append(lines, line)
+
+		// "250-" continues, "250 " ends the reply
+		if len(line) == 3 || line[3] != '-' {
+			var code int
+			fmt.Sscanf(line[:3], "%d", &code)
+			return code, strings.Join(lines, "\n"), nil
+		}
+	}
+}
+
+// probeIMAP reads the untagged greeting and optionally runs STARTTLS
+func probeIMAP(ctx context.Context, target *url.URL, result *models.ProbeMetrics) error {
+	m, err := dialMail(ctx, target)
+	if err != nil {
+		return err
+	}
+	defer m.conn.Close()
+
+	banner, err := m.readLine()
+	if err != nil {
+		return fmt.Errorf("no banner: %w", err)
+	}
+	result.Banner = banner
+	if !strings.HasPrefix(banner, "* OK") && !strings.HasPrefix(banner, "* PREAUTH") {
+		return fmt.Errorf("unexpected greeting")
+	}
+
+	if target.Query().Has("starttls") {
+		if err := m.send("a1 STARTTLS"); err != nil {
+			return err
+		}
+		if err := readIMAPTagged(m, "a1"); err != nil {
+			return fmt.Errorf("STARTTLS rejected: %w", err)
+		}
+		if err := m.startTLS(target); err != nil {
+			return err
+		}
+	}
+
+	result.CertExpiry = m.tlsExpiry()
+	result.TLS = result.CertExpiry != nil
+
+	m.send("a2 LOGOUT")
+	return nil
+}
+
+// readIMAPTagged skips untagged lines until the tagged completion and checks it is OK
+func readIMAPTagged(m *mailConn, tag string) error {
+	for {
+		line, err := m.readLine()
+		if err != nil {
+			return err
+		}
+		if status, ok := strings.CutPrefix(line, tag+" "); ok {
+			if !strings.HasPrefix(status, "OK") {
+				return fmt.Errorf("%s", status)
+			}
+			return nil
+		}
+	}
+}
+
+// firstLine returns the first line of a multi-line reply
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,297 @@
+package dynamic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// supervisorSockets are the default supervisord control socket locations
+var supervisorSockets = []string{
+	"/var/run/supervisor.sock",
+	"/run/supervisor.sock",
+	"/var/run/supervisor/supervisor.sock",
+	"/tmp/supervisor.sock",
+}
+
+// ManagedProcessCollector reports programs managed by supervisord and pm2.
+// supervisord does not expose a restart counter, so restarts are counted
+// by watching each program's start time change between collections.
+type ManagedProcessCollector struct {
+	mu                 sync.Mutex
+	supervisorStarts   map[string]int64  // program -> last seen start time
+	supervisorRestarts map[string]uint64 // program -> restarts observed
+}
+
+// NewManagedProcessCollector creates a new managed-process collector
+func NewManagedProcessCollector() *ManagedProcessCollector {
+	return &ManagedProcessCollector{
+		supervisorStarts:   make(map[string]int64),
+		supervisorRestarts: make(map[string]uint64),
+	}
+}
+
+// Collect queries all available process managers. Returns nil if none are present.
+func (m *ManagedProcessCollector) Collect(ctx context.Context) ([]models.ManagedProcessMetrics, error) {
+	var result []models.ManagedProcessMetrics
+
+	if procs, err := m.collectSupervisor(ctx); err == nil {
+		result = append(result, procs...)
+	}
+
+	if procs, err := collectPM2(ctx); err == nil {
+		result = append(result, procs...)
+	}
+
+	return result, nil
+}
+
+// xmlrpcValue is a single XML-RPC scalar value
+type xmlrpcValue struct {
+	String string `xml:"string"`
+	Int    string `xml:"int"`
+	I4     string `xml:"i4"`
+	Text   string `xml:",chardata"`
+}
+
+// str returns the value as a string regardless of its XML-RPC type
+func (v xmlrpcValue) str() string {
+	switch {
+	case v.String != "":
+		return v.String
+	case v.Int != "":
+		return v.Int
+	case v.I4 != "":
+		return v.I4
+	default:
+		return strings.TrimSpace(v.Text)
+	}
+}
+
+// num returns the value as an integer, or 0 if not numeric
+func (v xmlrpcValue) num() int64 {
+	n, _ := strconv.ParseInt(v.str(), 10, 64)
+	return n
+}
+
+// supervisorResponse is the XML-RPC response of supervisor.getAllProcessInfo
+type supervisorResponse struct {
+	Processes []struct {
+		Members []struct {
+			Name  string      `xml:"name"`
+			Value xmlrpcValue `xml:"value"`
+		} `xml:"struct>member"`
+	} `xml:"params>param>value>array>data>value"`
+	Fault *struct{} `xml:"fault"`
+}
+
+// SupervisorPermitted reports false when a supervisord control socket exists
+// but the agent may not connect to it
+func SupervisorPermitted() bool {
+	for _, path := range supervisorSockets {
+		if _, err := os.Stat(path); err == nil {
+			return syscall.Access(path, 2) == nil // W_OK, needed to connect
+		}
+	}
+	return true
+}
+
+// collectSupervisor queries supervisord over its unix control socket
+func (m *ManagedProcessCollector) collectSupervisor(ctx context.Context) ([]models.ManagedProcessMetrics, error) {
+	socket := ""
+	for _, path := range supervisorSockets {
+		if _, err := os.Stat(path); err == nil {
+			socket = path
+			break
+		}
+	}
+	if socket == "" {
+		return nil, fmt.Errorf("supervisord socket not found")
+	}
+
+	client := &http.Client{
+		Timeout: 2 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	body := `<?xml version="1.0"?><methodCall><methodName>supervisor.getAllProcessInfo</methodName><params/></methodCall>`
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://localhost/RPC2", strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed supervisorResponse
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+	if parsed.Fault != nil {
+		return nil, fmt.Errorf("supervisord returned a fault")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var result []models.ManagedProcessMetrics
+	for _, proc := range parsed.Processes {
+		fields := make(map[string]xmlrpcValue)
+		for _, member := range proc.Members {
+			fields[member.Name] = member.Value
+		}
+
+		name := fields["name"].str()
+		if group := fields["group"].str(); group != "" && group != name {
+			name = group + ":" + name
+		}
+
+		// Count restarts from start time changes
+		start := fields["start"].num()
+		if prev, ok := m.supervisorStarts[name]; ok && start != 0 && start != prev {
+			m.supervisorRestarts[name]++
+		}
+		m.supervisorStarts[name] = start
+
+		uptime := uint64(0)
+		if now := fields["now"].num(); start > 0 && now > start && fields["statename"].str() == "RUNNING" {
+			uptime = uint64(now - start)
+		}
+
+		result = append(result, models.ManagedProcessMetrics{
+			Manager:  "supervisord",
+			Name:     name,
+			State:    fields["statename"].str(),
+			PID:      int(fields["pid"].num()),
+			Restarts: m.supervisorRestarts[name],
+			Uptime:   uptime,
+		})
+	}
+
+	return result, nil
+}
+
+// pm2Process is the subset of `pm2 jlist` output we use
+type pm2Process struct {
+	Name   string `json:"name"`
+	PID    int    `json:"pid"`
+	PM2Env struct {
+		Status      string `json:"status"`
+		RestartTime uint64 `json:"restart_time"`
+		PMUptime    int64  `json:"pm_uptime"` // Unix milliseconds
+	} `json:"pm2_env"`
+}
+
+// pm2Home returns the pm2 state directory, honouring PM2_HOME like pm2 does
+func pm2Home() string {
+	if home := os.Getenv("PM2_HOME"); home != "" {
+		return home
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".pm2")
+}
+
+// pm2DaemonRunning reports whether a pm2 daemon is already running for home.
+// `pm2 jlist` spawns a daemon when none exists, so it must only be run when
+// one does: the RPC socket has to exist and the pid file has to name a live
+// process (a crashed daemon leaves both behind).
+func pm2DaemonRunning(home string) bool {
+	if home == "" {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(home, "rpc.sock")); err != nil {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(home, "pm2.pid"))
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return false
+	}
+	err = syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// collectPM2 queries pm2 via `pm2 jlist`, but only when a pm2 daemon is
+// already running so collection never starts one
+func collectPM2(ctx context.Context) ([]models.ManagedProcessMetrics, error) {
+	home := pm2Home()
+	if !pm2DaemonRunning(home) {
+		return nil, fmt.Errorf("pm2 daemon not running")
+	}
+
+	path, err := exec.LookPath("pm2")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, path, "jlist")
+	cmd.Env = append(os.Environ(), "PM2_HOME="+home)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	// pm2 may print banners before the JSON array
+	if idx := bytes.IndexByte(output, '['); idx > 0 {
+		output = output[idx:]
+	}
+
+	var procs []pm2Process
+	if err := json.Unmarshal(output, &procs); err != nil {
+		return nil, err
+	}
+
+	var result []models.ManagedProcessMetrics
+	for _, proc := range procs {
+		uptime := uint64(0)
+		if proc.PM2Env.Status == "online" && proc.PM2Env.PMUptime > 0 {
+			uptime = uint64(time.Since(time.UnixMilli(proc.PM2Env.PMUptime)).Seconds())
+		}
+
+		result = append(result, models.ManagedProcessMetrics{
+			Manager:  "pm2",
+			Name:     proc.Name,
+			State:    proc.PM2Env.Status,
+			PID:      proc.PID,
+			Restarts: proc.PM2Env.RestartTime,
+			Uptime:   uptime,
+		})
+	}
+
+	return result, nil
+}
@@ -27,6 +27,8 @@ type MemoryCollector struct {
 	samples []memorySample
 	ctx     context.Context
 	cancel  context.CancelFunc
+
+	anomalies *AnomalyDetector
 }
 
 // NewMemoryCollector creates a new memory collector
@@ -55,6 +57,11 @@ func (m *MemoryCollector) Start() {
 	}()
 }
 
+// DetectAnomalies feeds every sample to detector. Call it before Start.
+func (m *MemoryCollector) DetectAnomalies(detector *AnomalyDetector) {
+	m.anomalies = detector
+}
+
 // Stop halts background sampling
 func (m *MemoryCollector) Stop() {
 	if m.cancel != nil {
@@ -79,6 +86,7 @@ func (m *MemoryCollector) sample() {
 		buffers:     vmem.Buffers,
 		timestamp:   time.Now(),
 	}
+	m.anomalies.Observe("memory_used_percent", sample.usedPercent, sample.timestamp)
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -0,0 +1,91 @@
+package dynamic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// CollectNeighborTable reports ARP (IPv4) and NDP (IPv6) neighbor table
+// usage against the kernel's gc_thresh limits (no sampling needed)
+func CollectNeighborTable(ctx context.Context) (*models.NeighborTableMetrics, error) {
+	ipv4, err := readNeighborStats("/proc/net/stat/arp_cache", "/proc/sys/net/ipv4/neigh/default")
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &models.NeighborTableMetrics{IPv4: ipv4}
+
+	// IPv6 may be disabled
+	if ipv6, err := readNeighborStats("/proc/net/stat/ndisc_cache", "/proc/sys/net/ipv6/neigh/default"); err == nil {
+		metrics.IPv6 = ipv6
+	}
+
+	return metrics, nil
+}
+
+// readNeighborStats parses a neighbor cache stat file and its gc_thresh settings
+func readNeighborStats(statPath, sysctlDir string) (*models.NeighborTableStats, error) {
+	data, err := os.ReadFile(statPath)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("unexpected format in %s", statPath)
+	}
+
+	// Columns are named in the header; values are hex, one line per CPU
+	header := strings.Fields(lines[0])
+	column := make(map[string]int, len(header))
+	for i, name := range header {
+		column[name] = i
+	}
+
+	stats := &models.NeighborTableStats{}
+	for lineNum, line := range lines[1:] {
+		fields := strings.Fields(line)
+		value := func(name string) uint64 {
+			idx, ok := column[name]
+			if !ok || idx >= len(fields) {
+				return 0
+			}
+			v, _ := strconv.ParseUint(fields[idx], 16, 64)
+			return v
+		}
+
+		// entries is global (repeated on every line); counters are per CPU
+		if lineNum == 0 {
+			stats.Entries = value("entries")
+		}
+		stats.TableFulls += value("table_fulls")
+		stats.ForcedGCRuns += value("forced_gc_runs")
+	}
+
+	stats.GCThresh1 = readSysctlUint(filepath.Join(sysctlDir, "gc_thresh1"))
+	stats.GCThresh2 = readSysctlUint(filepath.Join(sysctlDir, "gc_thresh2"))
+	stats.GCThresh3 = readSysctlUint(filepath.Join(sysctlDir, "gc_thresh3"))
+
+	// gc_thresh3 is the hard limit
+	if stats.GCThresh3 > 0 {
+		stats.UsedPercent = float64(stats.Entries) / float64(stats.GCThresh3) * 100
+	}
+
+	return stats, nil
+}
+
+// readSysctlUint reads an unsigned integer sysctl value, returning 0 on error
+func readSysctlUint(path string) uint64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	v, _ := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	return v
+}
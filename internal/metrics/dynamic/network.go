@@ -33,6 +33,9 @@ type NetworkCollector struct {
 	interfaceTypes map[string]string // cache: interface -> "public" or "private"
 	ctx            context.Context
 	cancel         context.CancelFunc
+
+	anomalies *AnomalyDetector
+	last      *networkSample // Previous sample, for the per-second rates of the detector
 }
 
 // NewNetworkCollector creates a new network collector
@@ -62,6 +65,11 @@ func (n *NetworkCollector) Start() {
 	}()
 }
 
+// DetectAnomalies feeds every sample to detector. Call it before Start.
+func (n *NetworkCollector) DetectAnomalies(detector *AnomalyDetector) {
+	n.anomalies = detector
+}
+
 // Stop halts background sampling
 func (n *NetworkCollector) Stop() {
 	if n.cancel != nil {
@@ -99,6 +107,22 @@ func (n *NetworkCollector) sample() {
 		interfaces: interfaces,
 		timestamp:  time.Now(),
 	}
+	if n.anomalies != nil {
+		if n.last != nil {
+			if seconds := sample.timestamp.Sub(n.last.timestamp).Seconds(); seconds > 0 {
+				var recv, sent uint64
+				for name, curr := range sample.interfaces {
+					if prev, ok := n.last.interfaces[name]; ok && name != "lo" && curr.bytesRecv >= prev.bytesRecv && curr.bytesSent >= prev.bytesSent {
+						recv += curr.bytesRecv - prev.bytesRecv
+						sent += curr.bytesSent - prev.bytesSent
+					}
+				}
+				n.anomalies.Observe("network_recv_bytes_per_second", float64(recv)/seconds, sample.timestamp)
+				n.anomalies.Observe("network_sent_bytes_per_second", float64(sent)/seconds, sample.timestamp)
+			}
+		}
+		n.last = &sample
+	}
 
 	n.mu.Lock()
 	defer n.mu.Unlock()
@@ -0,0 +1,135 @@
+package dynamic
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/internal/mountfilter"
+	"github.com/monify-labs/agent/pkg/models"
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// networkFSTypes are the filesystem types reported as network mounts
+var networkFSTypes = map[string]bool{
+	"nfs":   true,
+	"nfs4":  true,
+	"cifs":  true,
+	"smb3":  true,
+	"smbfs": true,
+}
+
+// statfsTimeout bounds how long a single mount may take to answer
+const statfsTimeout = 2 * time.Second
+
+// statfsResult is the outcome of a statfs call on a network mount
+type statfsResult struct {
+	usage   *disk.UsageStat
+	err     error
+	latency time.Duration
+}
+
+// NetworkMountCollector checks NFS/CIFS mounts with per-mount timeouts.
+// A statfs against a dead server can block in the kernel indefinitely, so
+// each mount has at most one call in flight; while it is stuck the mount is
+// reported unavailable without starting another.
+type NetworkMountCollector struct {
+	mu       sync.Mutex
+	inflight map[string]chan statfsResult // mountpoint -> pending statfs
+	filter   *mountfilter.Filter
+}
+
+// NewNetworkMountCollector creates a new network mount collector for the
+// mounts the filter allows
+func NewNetworkMountCollector(filter *mountfilter.Filter) *NetworkMountCollector {
+	return &NetworkMountCollector{
+		inflight: make(map[string]chan statfsResult),
+		filter:   filter,
+	}
+}
+
+// Collect reports availability, statfs latency, and usage of network mounts.
+// Returns nil if there are no network mounts.
+func (n *NetworkMountCollector) Collect(ctx context.Context) ([]models.NetworkMountMetrics, error) {
+	partitions, err := disk.PartitionsWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var result []models.NetworkMountMetrics
+
+	for _, partition := range partitions {
+		if !networkFSTypes[partition.Fstype] || !n.filter.Allows(partition.Mountpoint, partition.Device, partition.Fstype) {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			metrics := n.check(ctx, partition)
+			mu.Lock()
+			result = append(result, metrics)
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	return result, nil
+}
+
+// check runs (or waits for) a statfs on a single mount
+func (n *NetworkMountCollector) check(ctx context.Context, partition disk.PartitionStat) models.NetworkMountMetrics {
+	metrics := models.NetworkMountMetrics{
+		Mount:  partition.Mountpoint,
+		Device: partition.Device,
+		FSType: partition.Fstype,
+	}
+
+	n.mu.Lock()
+	pending, stuck := n.inflight[partition.Mountpoint]
+	if !stuck {
+		pending = make(chan statfsResult, 1)
+		n.inflight[partition.Mountpoint] = pending
+		go func() {
+			start := time.Now()
+			usage, err := disk.Usage(partition.Mountpoint)
+			pending <- statfsResult{usage: usage, err: err, latency: time.Since(start)}
+		}()
+	}
+	n.mu.Unlock()
+
+	timer := time.NewTimer(statfsTimeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-pending:
+		n.mu.Lock()
+		delete(n.inflight, partition.Mountpoint)
+		n.mu.Unlock()
+
+		metrics.LatencyMs = float64(res.latency.Microseconds()) / 1000
+		if res.err != nil {
+			metrics.Error = res.err.Error()
+			return metrics
+		}
+		metrics.Available = true
+		metrics.Total = res.usage.Total
+		metrics.Used = res.usage.Used
+		metrics.Free = res.usage.Free
+		metrics.UsedPercent = res.usage.UsedPercent
+
+	case <-timer.C:
+		metrics.Error = fmt.Sprintf("statfs timed out after %s", statfsTimeout)
+		if stuck {
+			metrics.Error = "statfs still blocked from a previous check"
+		}
+
+	case <-ctx.Done():
+		metrics.Error = ctx.Err().Error()
+	}
+
+	return metrics
+}
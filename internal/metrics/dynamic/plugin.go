@@ -0,0 +1,259 @@
+package dynamic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// Plugin output limits
+const (
+	pluginMaxOutput  = 1 << 20 // Bytes read from stdout
+	pluginMaxMetrics = 1000    // Metrics kept per plugin
+)
+
+// pluginEnv is the whole environment of a plugin: the agent's own
+// environment holds the server token and must not leak to plugins
+var pluginEnv = []string{
+	"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+	"LANG=C",
+}
+
+// pluginNamePattern matches valid metric and label names
+var pluginNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// PluginCollector runs the executables in a plugin directory on a schedule.
+// Each must print a PluginOutput JSON document on stdout; the latest result
+// of every plugin is merged into the payload.
+type PluginCollector struct {
+	dir      string
+	interval time.Duration
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	results map[string]models.PluginMetrics
+	ctx     context.Context
+	cancel  context.CancelFunc
+	paused  atomic.Bool // Scheduled runs are skipped while set
+}
+
+// NewPluginCollector creates a collector for the plugins in dir
+func NewPluginCollector(dir string, interval, timeout time.Duration) *PluginCollector {
+	return &PluginCollector{
+		dir:      dir,
+		interval: interval,
+		timeout:  timeout,
+		results:  make(map[string]models.PluginMetrics),
+	}
+}
+
+// Start begins running plugins in the background
+func (p *PluginCollector) Start() {
+	if p.dir == "" {
+		return
+	}
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		p.runAll()
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-ticker.C:
+				if !p.paused.Load() {
+					p.runAll()
+				}
+			}
+		}
+	}()
+}
+
+// SetPaused skips scheduled plugin runs while paused is true
+func (p *PluginCollector) SetPaused(paused bool) {
+	p.paused.Store(paused)
+}
+
+// Stop halts running plugins
+func (p *PluginCollector) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+// runAll runs every plugin in parallel. The directory is listed on each run
+// so plugins can be added or removed without restarting the agent.
+func (p *PluginCollector) runAll() {
+	plugins := p.discover()
+
+	var wg sync.WaitGroup
+	results := make(map[string]models.PluginMetrics, len(plugins))
+	var mu sync.Mutex
+	for _, path := range plugins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := p.run(path)
+			mu.Lock()
+			results[result.Name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if p.ctx.Err() != nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.results = results
+	p.mu.Unlock()
+}
+
+// discover lists the plugins that may be run: regular executable files that
+// only their owner (root, or the agent's user) can modify. Hidden files and
+// editor backups are skipped.
+func (p *PluginCollector) discover() []string {
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		return nil
+	}
+
+	uid := uint32(os.Geteuid())
+	var plugins []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+			continue
+		}
+
+		path := filepath.Join(p.dir, name)
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok || (stat.Uid != 0 && stat.Uid != uid) || info.Mode().Perm()&0o022 != 0 {
+			continue // Writable by others: running it would hand them our privileges
+		}
+		plugins = append(plugins, path)
+	}
+	return plugins
+}
+
+// run executes one plugin and parses its output
+func (p *PluginCollector) run(path string) models.PluginMetrics {
+	start := time.Now()
+	result := models.PluginMetrics{Name: filepath.Base(path), RunAt: start}
+
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Dir = p.dir
+	cmd.Env = pluginEnv
+	cmd.Stdout = &limitedBuffer{buf: &stdout, remaining: pluginMaxOutput}
+	cmd.Stderr = &limitedBuffer{buf: &stderr, remaining: 4096}
+
+	// Kill the whole process group on timeout, not just the plugin itself
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	result.DurationMs = float64(time.Since(start).Milliseconds())
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.Error = fmt.Sprintf("timed out after %s", p.timeout)
+		return result
+	case err != nil:
+		result.Error = err.Error()
+		if msg := strings.TrimSpace(firstLine(stderr.String())); msg != "" {
+			result.Error += ": " + msg
+		}
+		return result
+	}
+
+	var output models.PluginOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		result.Error = fmt.Sprintf("invalid output: %v", err)
+		return result
+	}
+
+	result.Metrics, result.Error = validPluginMetrics(output.Metrics)
+	return result
+}
+
+// validPluginMetrics drops metrics with invalid names or labels and
+// describes what was dropped
+func validPluginMetrics(metrics []models.PluginMetric) ([]models.PluginMetric, string) {
+	var valid []models.PluginMetric
+	dropped := 0
+	for _, metric := range metrics {
+		ok := pluginNamePattern.MatchString(metric.Name) && len(valid) < pluginMaxMetrics
+		for name := range metric.Labels {
+			ok = ok && pluginNamePattern.MatchString(name)
+		}
+		if !ok {
+			dropped++
+			continue
+		}
+		valid = append(valid, metric)
+	}
+
+	if dropped > 0 {
+		return valid, fmt.Sprintf("dropped %d invalid or excess metrics", dropped)
+	}
+	return valid, ""
+}
+
+// Collect returns the latest result of every plugin, sorted by name.
+// Returns nil if the plugin directory is empty or missing.
+func (p *PluginCollector) Collect(ctx context.Context) ([]models.PluginMetrics, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var result []models.PluginMetrics
+	for _, metrics := range p.results {
+		result = append(result, metrics)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
+
+// limitedBuffer keeps at most remaining bytes and discards the rest, so a
+// chatty plugin cannot exhaust the agent's memory
+type limitedBuffer struct {
+	buf       *bytes.Buffer
+	remaining int
+}
+
+// Write implements io.Writer
+func (l *limitedBuffer) Write(b []byte) (int, error) {
+	n := min(len(b), l.remaining)
+	l.buf.Write(b[:n])
+	l.remaining -= n
+	return len(b), nil
+}
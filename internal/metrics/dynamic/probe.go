@@ -0,0 +1,105 @@
+package dynamic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// probeTimeout bounds a single probe including connect, banner and TLS handshake
+const probeTimeout = 5 * time.Second
+
+// probeFunc checks a single target and fills in the result
+type probeFunc func(ctx context.Context, target *url.URL, result *models.ProbeMetrics) error
+
+// probeProtocols maps target URL schemes to their default port and check
+var probeProtocols = map[string]struct {
+	port  string
+	probe probeFunc
+}{
+	"smtp":  {"25", probeSMTP},
+	"smtps": {"465", probeSMTP},
+	"imap":  {"143", probeIMAP},
+	"imaps": {"993", probeIMAP},
+}
+
+// ProbeCollector runs service checks against configured target URLs
+// (e.g., smtp://mail.example.com:587?starttls, imaps://mail.example.com)
+type ProbeCollector struct {
+	targets []string
+}
+
+// NewProbeCollector creates a new probe collector
+func NewProbeCollector(targets []string) *ProbeCollector {
+	return &ProbeCollector{targets: targets}
+}
+
+// Collect runs all probes concurrently. Returns nil if none are configured.
+func (p *ProbeCollector) Collect(ctx context.Context) ([]models.ProbeMetrics, error) {
+	if len(p.targets) == 0 {
+		return nil, nil
+	}
+
+	result := make([]models.ProbeMetrics, len(p.targets))
+	var wg sync.WaitGroup
+	for i, target := range p.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result[i] = runProbe(ctx, target)
+		}()
+	}
+	wg.Wait()
+
+	return result, nil
+}
+
+// CheckProbeTarget returns an error if target is not a URL a probe can check
+func CheckProbeTarget(target string) error {
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("invalid target URL")
+	}
+	if _, ok := probeProtocols[u.Scheme]; !ok {
+		return fmt.Errorf("unsupported protocol %q", u.Scheme)
+	}
+	return nil
+}
+
+// runProbe checks one target URL
+func runProbe(ctx context.Context, target string) models.ProbeMetrics {
+	result := models.ProbeMetrics{Target: target}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		result.Error = "invalid target URL"
+		return result
+	}
+	protocol, ok := probeProtocols[u.Scheme]
+	if !ok {
+		result.Error = fmt.Sprintf("unsupported protocol %q", u.Scheme)
+		return result
+	}
+	result.Protocol = u.Scheme
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), protocol.port)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := protocol.probe(ctx, u, &result); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Up = true
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	return result
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/monify-labs/agent/pkg/models"
 	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/load"
 	"github.com/shirou/gopsutil/v4/process"
 )
 
@@ -23,9 +24,17 @@ func CollectSystemDynamic(ctx context.Context) (*models.SystemMetrics, error) {
 		processCount = uint64(len(processes))
 	}
 
-	return &models.SystemMetrics{
+	metrics := &models.SystemMetrics{
 		Uptime:       info.Uptime,
 		BootTime:     info.BootTime,
 		ProcessCount: processCount,
-	}, nil
+	}
+
+	// Get run queue and blocked processes (procs_running/procs_blocked in /proc/stat)
+	if misc, err := load.MiscWithContext(ctx); err == nil {
+		metrics.ProcsRunning = uint64(misc.ProcsRunning)
+		metrics.ProcsBlocked = uint64(misc.ProcsBlocked)
+	}
+
+	return metrics, nil
 }
@@ -0,0 +1,123 @@
+package static
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// blockDeviceInfo contains hardware details of a physical block device
+type blockDeviceInfo struct {
+	Name       string // Kernel name (e.g., sda, nvme0n1)
+	Model      string
+	Serial     string
+	Size       uint64 // Bytes
+	Rotational *bool  // nil if unknown
+}
+
+// sysBlockPath is the sysfs directory containing block devices
+const sysBlockPath = "/sys/class/block"
+
+// lookupBlockDevice resolves a partition or mapper device path (e.g., /dev/sda1,
+// /dev/mapper/vg-root) to its underlying physical disk and reads its details from sysfs
+func lookupBlockDevice(device string) (*blockDeviceInfo, bool) {
+	name := physicalDiskName(device)
+	if name == "" {
+		return nil, false
+	}
+
+	base := filepath.Join(sysBlockPath, name)
+	info := &blockDeviceInfo{
+		Name:   name,
+		Model:  readSysfsString(filepath.Join(base, "device", "model")),
+		Serial: readSysfsString(filepath.Join(base, "device", "serial")),
+	}
+
+	// Size is reported in 512-byte sectors regardless of the logical block size
+	if sectors, err := strconv.ParseUint(readSysfsString(filepath.Join(base, "size")), 10, 64); err == nil {
+		info.Size = sectors * 512
+	}
+
+	switch readSysfsString(filepath.Join(base, "queue", "rotational")) {
+	case "0":
+		rotational := false
+		info.Rotational = &rotational
+	case "1":
+		rotational := true
+		info.Rotational = &rotational
+	}
+
+	// SATA/SAS disks expose the serial via udev rather than sysfs
+	if info.Serial == "" {
+		info.Serial = udevProperty(readSysfsString(filepath.Join(base, "dev")), "ID_SERIAL_SHORT")
+	}
+
+	return info, true
+}
+
+// physicalDiskName maps a device path to the kernel name of its whole disk
+func physicalDiskName(device string) string {
+	if !strings.HasPrefix(device, "/dev/") {
+		return ""
+	}
+
+	// Resolve /dev/mapper/* and /dev/disk/by-* symlinks to dm-N / sdX
+	if resolved, err := filepath.EvalSymlinks(device); err == nil {
+		device = resolved
+	}
+	name := filepath.Base(device)
+
+	// Follow device-mapper (LVM, LUKS) to its first backing device
+	for i := 0; i < 4; i++ {
+		slaves, err := os.ReadDir(filepath.Join(sysBlockPath, name, "slaves"))
+		if err != nil || len(slaves) == 0 {
+			break
+		}
+		name = slaves[0].Name()
+	}
+
+	// Partitions live under their parent disk in sysfs
+	sysPath, err := filepath.EvalSymlinks(filepath.Join(sysBlockPath, name))
+	if err != nil {
+		return ""
+	}
+	if _, err := os.Stat(filepath.Join(sysPath, "partition")); err == nil {
+		name = filepath.Base(filepath.Dir(sysPath))
+	}
+
+	return name
+}
+
+// udevProperty reads a property from the udev database for a "major:minor" device
+func udevProperty(majorMinor, key string) string {
+	if majorMinor == "" {
+		return ""
+	}
+
+	f, err := os.Open(filepath.Join("/run/udev/data", "b"+majorMinor))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	prefix := "E:" + key + "="
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), prefix); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return ""
+}
+
+// readSysfsString reads a sysfs attribute, returning "" on error
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -2,30 +2,64 @@ package static
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/monify-labs/agent/internal/proxy"
 )
 
 // CloudInfo contains cloud provider metadata
 type CloudInfo struct {
+	Provider     string
 	Region       string
 	InstanceType string
 }
 
+// cloudDetector probes a single provider's metadata service
+type cloudDetector struct {
+	provider string
+	detect   func(context.Context) (*CloudInfo, error)
+}
+
 // DetectCloudProvider attempts to detect cloud provider and retrieve metadata
 func DetectCloudProvider(ctx context.Context) (*CloudInfo, error) {
-	// Try providers in order of popularity
-	providers := []func(context.Context) (*CloudInfo, error){
-		detectAWS,
-		detectGCP,
-		detectAzure,
-		detectDigitalOcean,
+	// Providers in order of precedence when several metadata services answer
+	detectors := []cloudDetector{
+		{"aws", detectAWS},
+		{"gcp", detectGCP},
+		{"azure", detectAzure},
+		{"digitalocean", detectDigitalOcean},
+		{"hetzner", detectHetzner},
+		{"linode", detectLinode},
+		{"vultr", detectVultr},
+		{"oci", detectOCI},
+		{"scaleway", detectScaleway},
+		{"alibaba", detectAlibaba},
 	}
 
-	for _, detector := range providers {
-		if info, err := detector(ctx); err == nil && (info.Region != "" || info.InstanceType != "") {
+	// Probe all providers concurrently so bare metal hosts wait for one timeout, not ten
+	results := make([]*CloudInfo, len(detectors))
+	done := make(chan struct{}, len(detectors))
+	for i, detector := range detectors {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			if info, err := detector.detect(ctx); err == nil && (info.Region != "" || info.InstanceType != "") {
+				info.Provider = detector.provider
+				results[i] = info
+			}
+		}()
+	}
+	for range detectors {
+		<-done
+	}
+
+	for _, info := range results {
+		if info != nil {
 			return info, nil
 		}
 	}
@@ -34,15 +68,33 @@ func DetectCloudProvider(ctx context.Context) (*CloudInfo, error) {
 	return &CloudInfo{}, nil
 }
 
+// awsMetadataURL is the EC2 instance metadata service root
+const awsMetadataURL = "http://169.254.169.254/latest"
+
+// awsMetadataHeaders returns the headers for EC2 metadata requests. IMDSv2
+// is preferred, and required on instances launched from recent AMIs; when
+// the token request fails, the headers are empty and requests use IMDSv1.
+func awsMetadataHeaders(ctx context.Context, client *http.Client) map[string]string {
+	headers := map[string]string{}
+	req, err := http.NewRequestWithContext(ctx, "PUT", awsMetadataURL+"/api/token", nil)
+	if err != nil {
+		return headers
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	if token, err := doMetadataRequest(client, req); err == nil {
+		headers["X-aws-ec2-metadata-token"] = token
+	}
+	return headers
+}
+
 // detectAWS detects AWS EC2 instance metadata
 func detectAWS(ctx context.Context) (*CloudInfo, error) {
-	client := &http.Client{Timeout: 2 * time.Second}
-
-	// AWS metadata endpoint
-	baseURL := "http://169.254.169.254/latest/meta-data"
+	client := newMetadataClient()
+	headers := awsMetadataHeaders(ctx, client)
+	baseURL := awsMetadataURL + "/meta-data"
 
 	// Get region
-	region, err := fetchMetadata(ctx, client, baseURL+"/placement/availability-zone")
+	region, err := fetchMetadataWithHeaders(ctx, client, baseURL+"/placement/availability-zone", headers)
 	if err != nil {
 		return nil, err
 	}
@@ -52,7 +104,7 @@ func detectAWS(ctx context.Context) (*CloudInfo, error) {
 	}
 
 	// Get instance type
-	instanceType, _ := fetchMetadata(ctx, client, baseURL+"/instance-type")
+	instanceType, _ := fetchMetadataWithHeaders(ctx, client, baseURL+"/instance-type", headers)
 
 	return &CloudInfo{
 		Region:       region,
@@ -62,7 +114,7 @@ func detectAWS(ctx context.Context) (*CloudInfo, error) {
 
 // detectGCP detects Google Cloud Platform instance metadata
 func detectGCP(ctx context.Context) (*CloudInfo, error) {
-	client := &http.Client{Timeout: 2 * time.Second}
+	client := newMetadataClient()
 
 	// GCP requires this header
 	baseURL := "http://metadata.google.internal/computeMetadata/v1/instance"
@@ -78,6 +130,9 @@ func detectGCP(ctx context.Context) (*CloudInfo, error) {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata returned status %d", resp.StatusCode)
+	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -118,7 +173,7 @@ func detectGCP(ctx context.Context) (*CloudInfo, error) {
 
 // detectAzure detects Azure instance metadata
 func detectAzure(ctx context.Context) (*CloudInfo, error) {
-	client := &http.Client{Timeout: 2 * time.Second}
+	client := newMetadataClient()
 
 	baseURL := "http://169.254.169.254/metadata/instance/compute"
 
@@ -133,6 +188,9 @@ func detectAzure(ctx context.Context) (*CloudInfo, error) {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata returned status %d", resp.StatusCode)
+	}
 
 	region, _ := io.ReadAll(resp.Body)
 
@@ -155,7 +213,7 @@ func detectAzure(ctx context.Context) (*CloudInfo, error) {
 
 // detectDigitalOcean detects DigitalOcean droplet metadata
 func detectDigitalOcean(ctx context.Context) (*CloudInfo, error) {
-	client := &http.Client{Timeout: 2 * time.Second}
+	client := newMetadataClient()
 
 	baseURL := "http://169.254.169.254/metadata/v1"
 
@@ -171,19 +229,194 @@ func detectDigitalOcean(ctx context.Context) (*CloudInfo, error) {
 	}, nil
 }
 
+// detectHetzner detects Hetzner Cloud server metadata
+func detectHetzner(ctx context.Context) (*CloudInfo, error) {
+	client := newMetadataClient()
+
+	baseURL := "http://169.254.169.254/hetzner/v1/metadata"
+
+	region, err := fetchMetadata(ctx, client, baseURL+"/region")
+	if err != nil {
+		return nil, err
+	}
+
+	// Hetzner doesn't expose the server type via metadata
+	return &CloudInfo{
+		Region: region,
+	}, nil
+}
+
+// detectLinode detects Linode (Akamai) instance metadata
+func detectLinode(ctx context.Context) (*CloudInfo, error) {
+	client := newMetadataClient()
+
+	baseURL := "http://169.254.169.254/v1"
+
+	// Linode requires a short-lived metadata token
+	req, err := http.NewRequestWithContext(ctx, "PUT", baseURL+"/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Token-Expiry-Seconds", "60")
+
+	token, err := doMetadataRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var instance struct {
+		Region string `json:"region"`
+		Type   string `json:"type"`
+	}
+	err = fetchMetadataJSON(ctx, client, baseURL+"/instance", map[string]string{
+		"Metadata-Token": token,
+		"Accept":         "application/json",
+	}, &instance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CloudInfo{
+		Region:       instance.Region,
+		InstanceType: instance.Type,
+	}, nil
+}
+
+// detectVultr detects Vultr instance metadata
+func detectVultr(ctx context.Context) (*CloudInfo, error) {
+	client := newMetadataClient()
+
+	var metadata struct {
+		Region struct {
+			RegionCode string `json:"regioncode"`
+		} `json:"region"`
+	}
+	if err := fetchMetadataJSON(ctx, client, "http://169.254.169.254/v1.json", nil, &metadata); err != nil {
+		return nil, err
+	}
+
+	// Vultr doesn't expose the plan via metadata
+	return &CloudInfo{
+		Region: strings.ToLower(metadata.Region.RegionCode),
+	}, nil
+}
+
+// detectOCI detects Oracle Cloud Infrastructure instance metadata
+func detectOCI(ctx context.Context) (*CloudInfo, error) {
+	client := newMetadataClient()
+
+	var instance struct {
+		CanonicalRegionName string `json:"canonicalRegionName"`
+		Shape               string `json:"shape"`
+	}
+	err := fetchMetadataJSON(ctx, client, "http://169.254.169.254/opc/v2/instance/", map[string]string{
+		"Authorization": "Bearer Oracle",
+	}, &instance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CloudInfo{
+		Region:       instance.CanonicalRegionName,
+		InstanceType: instance.Shape,
+	}, nil
+}
+
+// detectScaleway detects Scaleway instance metadata
+func detectScaleway(ctx context.Context) (*CloudInfo, error) {
+	client := newMetadataClient()
+
+	var conf struct {
+		CommercialType string `json:"commercial_type"`
+		Location       struct {
+			ZoneID string `json:"zone_id"`
+		} `json:"location"`
+	}
+	if err := fetchMetadataJSON(ctx, client, "http://169.254.42.42/conf?format=json", nil, &conf); err != nil {
+		return nil, err
+	}
+
+	// Convert zone to region (e.g., fr-par-1 -> fr-par)
+	region := conf.Location.ZoneID
+	if idx := strings.LastIndex(region, "-"); idx != -1 {
+		region = region[:idx]
+	}
+
+	return &CloudInfo{
+		Region:       region,
+		InstanceType: conf.CommercialType,
+	}, nil
+}
+
+// detectAlibaba detects Alibaba Cloud ECS instance metadata
+func detectAlibaba(ctx context.Context) (*CloudInfo, error) {
+	client := newMetadataClient()
+
+	baseURL := "http://100.100.100.200/latest/meta-data"
+
+	region, err := fetchMetadata(ctx, client, baseURL+"/region-id")
+	if err != nil {
+		return nil, err
+	}
+
+	instanceType, _ := fetchMetadata(ctx, client, baseURL+"/instance/instance-type")
+
+	return &CloudInfo{
+		Region:       region,
+		InstanceType: instanceType,
+	}, nil
+}
+
 // fetchMetadata is a helper to fetch metadata from a URL
+// newMetadataClient creates an HTTP client for metadata services, routed through
+// the SOCKS5 proxy when one is configured (link-local endpoints are dialed directly)
+func newMetadataClient() *http.Client {
+	return &http.Client{
+		Timeout:   2 * time.Second,
+		Transport: &http.Transport{DialContext: proxy.DialFunc(&net.Dialer{Timeout: 2 * time.Second})},
+	}
+}
+
 func fetchMetadata(ctx context.Context, client *http.Client, url string) (string, error) {
+	return fetchMetadataWithHeaders(ctx, client, url, nil)
+}
+
+// fetchMetadataWithHeaders fetches a plain-text metadata value with optional headers
+func fetchMetadataWithHeaders(ctx context.Context, client *http.Client, url string, headers map[string]string) (string, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return "", err
 	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 
+	return doMetadataRequest(client, req)
+}
+
+// fetchMetadataJSON fetches a JSON metadata document with optional headers
+func fetchMetadataJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, v any) error {
+	body, err := fetchMetadataWithHeaders(ctx, client, url, headers)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(body), v)
+}
+
+// doMetadataRequest executes a metadata request and returns the trimmed body.
+// Non-200 responses are errors so another provider's 404 page is never taken as data.
+func doMetadataRequest(client *http.Client, req *http.Request) (string, error) {
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata returned status %d", resp.StatusCode)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
@@ -0,0 +1,90 @@
+package static
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FetchCloudTags retrieves instance tags/labels from the provider's metadata
+// service. Providers or instances that don't expose tags return an empty map.
+func FetchCloudTags(ctx context.Context, provider string) (map[string]string, error) {
+	switch provider {
+	case "aws":
+		return fetchAWSTags(ctx)
+	case "gcp":
+		return fetchGCPTags(ctx)
+	case "azure":
+		return fetchAzureTags(ctx)
+	default:
+		return map[string]string{}, nil
+	}
+}
+
+// fetchAWSTags reads EC2 instance tags. Requires "Allow tags in instance
+// metadata" to be enabled on the instance; otherwise the listing returns 404.
+func fetchAWSTags(ctx context.Context) (map[string]string, error) {
+	client := newMetadataClient()
+	headers := awsMetadataHeaders(ctx, client)
+
+	keys, err := fetchMetadataWithHeaders(ctx, client, awsMetadataURL+"/meta-data/tags/instance", headers)
+	if err != nil {
+		return nil, fmt.Errorf("instance tags not available in metadata: %w", err)
+	}
+
+	tags := make(map[string]string)
+	for _, key := range strings.Split(keys, "\n") {
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		if value, err := fetchMetadataWithHeaders(ctx, client, awsMetadataURL+"/meta-data/tags/instance/"+key, headers); err == nil {
+			tags[key] = value
+		}
+	}
+
+	return tags, nil
+}
+
+// fetchGCPTags reads GCE network tags. GCE labels are not exposed by the
+// metadata server, so network tags are reported as labels with empty values.
+func fetchGCPTags(ctx context.Context) (map[string]string, error) {
+	client := newMetadataClient()
+
+	var networkTags []string
+	err := fetchMetadataJSON(ctx, client, "http://metadata.google.internal/computeMetadata/v1/instance/tags", map[string]string{
+		"Metadata-Flavor": "Google",
+	}, &networkTags)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(networkTags))
+	for _, tag := range networkTags {
+		tags[tag] = ""
+	}
+
+	return tags, nil
+}
+
+// fetchAzureTags reads Azure VM tags
+func fetchAzureTags(ctx context.Context) (map[string]string, error) {
+	client := newMetadataClient()
+
+	var tagsList []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	err := fetchMetadataJSON(ctx, client, "http://169.254.169.254/metadata/instance/compute/tagsList?api-version=2021-02-01", map[string]string{
+		"Metadata": "true",
+	}, &tagsList)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(tagsList))
+	for _, tag := range tagsList {
+		tags[tag.Name] = tag.Value
+	}
+
+	return tags, nil
+}
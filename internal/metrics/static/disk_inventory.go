@@ -3,13 +3,15 @@ package static
 import (
 	"context"
 
+	"github.com/monify-labs/agent/internal/mountfilter"
 	"github.com/monify-labs/agent/pkg/models"
 	"github.com/shirou/gopsutil/v4/disk"
 )
 
-// CollectDiskInventory gathers static disk/filesystem information
-func CollectDiskInventory(ctx context.Context) ([]models.DiskInventoryMetrics, error) {
-	partitions, err := disk.PartitionsWithContext(ctx, false)
+// CollectDiskInventory gathers static disk/filesystem information of the
+// filesystems the filter allows
+func CollectDiskInventory(ctx context.Context, filter *mountfilter.Filter) ([]models.DiskInventoryMetrics, error) {
+	partitions, err := filter.Partitions(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -17,43 +19,30 @@ func CollectDiskInventory(ctx context.Context) ([]models.DiskInventoryMetrics, e
 	var disks []models.DiskInventoryMetrics
 
 	for _, partition := range partitions {
-		// Skip special filesystems
-		if shouldSkipFilesystem(partition.Fstype) {
-			continue
-		}
-
 		usage, err := disk.UsageWithContext(ctx, partition.Mountpoint)
 		if err != nil {
 			continue
 		}
 
-		disks = append(disks, models.DiskInventoryMetrics{
+		inventory := models.DiskInventoryMetrics{
 			Device:      partition.Device,
 			MountPoint:  partition.Mountpoint,
 			FSType:      partition.Fstype,
 			Total:       usage.Total,
 			InodesTotal: usage.InodesTotal,
-		})
-	}
+		}
 
-	return disks, nil
-}
+		// Enrich with the underlying physical disk (best effort)
+		if blockDev, ok := lookupBlockDevice(partition.Device); ok {
+			inventory.PhysicalDevice = blockDev.Name
+			inventory.Model = blockDev.Model
+			inventory.Serial = blockDev.Serial
+			inventory.DiskSize = blockDev.Size
+			inventory.Rotational = blockDev.Rotational
+		}
 
-// shouldSkipFilesystem determines if a filesystem type should be skipped
-func shouldSkipFilesystem(fstype string) bool {
-	skipTypes := map[string]bool{
-		"tmpfs":    true,
-		"devtmpfs": true,
-		"devfs":    true,
-		"proc":     true,
-		"sysfs":    true,
-		"cgroup":   true,
-		"cgroup2":  true,
-		"nsfs":     true,
-		"overlay":  true,
-		"squashfs": true,
-		"iso9660":  true,
+		disks = append(disks, inventory)
 	}
 
-	return skipTypes[fstype]
+	return disks, nil
 }
@@ -0,0 +1,136 @@
+package static
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+)
+
+// resolveFQDN determines the fully qualified domain name with `hostname -f`
+// semantics: /etc/hosts canonical name, then search domains and canonical DNS
+// name (forward-confirmed), then forward-confirmed reverse DNS. Falls back to
+// the short hostname if nothing qualifies.
+func resolveFQDN(ctx context.Context, hostname string) string {
+	resolver := net.DefaultResolver
+
+	// Hostname is already qualified
+	if strings.Contains(hostname, ".") {
+		return strings.TrimSuffix(hostname, ".")
+	}
+
+	// /etc/hosts canonical name (first name on the matching line)
+	if fqdn := hostsCanonicalName("/etc/hosts", hostname); fqdn != "" {
+		return fqdn
+	}
+
+	// hostname + search domain, confirmed by a forward lookup
+	for _, domain := range searchDomains("/etc/resolv.conf") {
+		candidate := hostname + "." + domain
+		if addrs, err := resolver.LookupHost(ctx, candidate); err == nil && len(addrs) > 0 {
+			return candidate
+		}
+	}
+
+	// Canonical DNS name of the hostname
+	if cname, err := resolver.LookupCNAME(ctx, hostname); err == nil {
+		if cname = strings.TrimSuffix(cname, "."); strings.Contains(cname, ".") {
+			return cname
+		}
+	}
+
+	// Forward-confirmed reverse DNS: PTR name must resolve back to the same IP
+	if addrs, err := resolver.LookupHost(ctx, hostname); err == nil {
+		for _, addr := range addrs {
+			if ip := net.ParseIP(addr); ip == nil || ip.IsLoopback() {
+				continue
+			}
+			names, err := resolver.LookupAddr(ctx, addr)
+			if err != nil {
+				continue
+			}
+			for _, name := range names {
+				name = strings.TrimSuffix(name, ".")
+				if strings.Contains(name, ".") && resolvesTo(ctx, resolver, name, addr) {
+					return name
+				}
+			}
+		}
+	}
+
+	return hostname
+}
+
+// hostsCanonicalName returns the canonical name of hostname from a hosts file,
+// if it is fully qualified
+func hostsCanonicalName(path, hostname string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx != -1 {
+			line = line[:idx]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		for _, name := range fields[1:] {
+			if name == hostname && strings.Contains(fields[1], ".") {
+				return fields[1]
+			}
+		}
+	}
+
+	return ""
+}
+
+// searchDomains returns the domain/search entries from resolv.conf
+func searchDomains(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "domain", "search":
+			for _, domain := range fields[1:] {
+				if domain = strings.TrimSuffix(domain, "."); domain != "" {
+					domains = append(domains, domain)
+				}
+			}
+		}
+	}
+
+	return domains
+}
+
+// resolvesTo checks whether name has addr among its forward DNS records
+func resolvesTo(ctx context.Context, resolver *net.Resolver, name, addr string) bool {
+	addrs, err := resolver.LookupHost(ctx, name)
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package static
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GatewayInfo contains the default routes of the host
+type GatewayInfo struct {
+	IPv4          string
+	IPv4Interface string
+	IPv6          string
+	IPv6Interface string
+}
+
+// DetectDefaultGateway reads the default IPv4 and IPv6 routes from procfs.
+// When several default routes exist, the one with the lowest metric wins.
+func DetectDefaultGateway() *GatewayInfo {
+	info := &GatewayInfo{}
+	info.IPv4, info.IPv4Interface = DefaultIPv4Route()
+	info.IPv6, info.IPv6Interface = defaultIPv6Route("/proc/net/ipv6_route")
+	return info
+}
+
+// DefaultIPv4Route returns the lowest-metric default IPv4 gateway and its
+// interface, or empty strings when there is none
+func DefaultIPv4Route() (string, string) {
+	return defaultIPv4Route("/proc/net/route")
+}
+
+// defaultIPv4Route parses /proc/net/route (little-endian hex addresses)
+func defaultIPv4Route(path string) (string, string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	gateway, iface := "", ""
+	bestMetric := uint64(1<<64 - 1)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Skip header
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		metric, _ := strconv.ParseUint(fields[6], 10, 64)
+		if metric >= bestMetric {
+			continue
+		}
+
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+		gateway, iface, bestMetric = ip.String(), fields[0], metric
+	}
+
+	return gateway, iface
+}
+
+// defaultIPv6Route parses /proc/net/ipv6_route (big-endian hex addresses)
+func defaultIPv6Route(path string) (string, string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	gateway, iface := "", ""
+	bestMetric := uint64(1<<64 - 1)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// dest dest_len src src_len next_hop metric refcnt use flags iface
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[1] != "00" || strings.Trim(fields[0], "0") != "" {
+			continue
+		}
+
+		raw, err := hex.DecodeString(fields[4])
+		if err != nil || len(raw) != 16 || net.IP(raw).IsUnspecified() {
+			continue
+		}
+		metric, _ := strconv.ParseUint(fields[5], 16, 64)
+		if metric >= bestMetric {
+			continue
+		}
+
+		gateway, iface, bestMetric = net.IP(raw).String(), fields[9], metric
+	}
+
+	return gateway, iface
+}
@@ -2,6 +2,7 @@ package static
 
 import (
 	"context"
+	"path/filepath"
 
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/mem"
@@ -13,8 +14,18 @@ type HardwareInfo struct {
 	CPUCores    int
 	CPUThreads  int
 	TotalMemory uint64
+
+	// DMI identity (empty when not exposed, e.g., some VMs and ARM boards)
+	SystemVendor  string
+	ProductName   string
+	ProductSerial string // Only readable by root
+	BIOSVendor    string
+	BIOSVersion   string
 }
 
+// dmiPath is the sysfs directory exposing DMI/SMBIOS identity
+const dmiPath = "/sys/class/dmi/id"
+
 // CollectHardwareInfo gathers CPU and memory hardware specifications
 func CollectHardwareInfo(ctx context.Context) (*HardwareInfo, error) {
 	// Get CPU info
@@ -47,9 +58,14 @@ func CollectHardwareInfo(ctx context.Context) (*HardwareInfo, error) {
 	}
 
 	return &HardwareInfo{
-		CPUModel:    cpuModel,
-		CPUCores:    physicalCores,
-		CPUThreads:  logicalCores,
-		TotalMemory: memInfo.Total,
+		CPUModel:      cpuModel,
+		CPUCores:      physicalCores,
+		CPUThreads:    logicalCores,
+		TotalMemory:   memInfo.Total,
+		SystemVendor:  readSysfsString(filepath.Join(dmiPath, "sys_vendor")),
+		ProductName:   readSysfsString(filepath.Join(dmiPath, "product_name")),
+		ProductSerial: readSysfsString(filepath.Join(dmiPath, "product_serial")),
+		BIOSVendor:    readSysfsString(filepath.Join(dmiPath, "bios_vendor")),
+		BIOSVersion:   readSysfsString(filepath.Join(dmiPath, "bios_version")),
 	}, nil
 }
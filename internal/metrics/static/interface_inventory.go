@@ -0,0 +1,72 @@
+package static
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/monify-labs/agent/pkg/models"
+	gopsutilNet "github.com/shirou/gopsutil/v4/net"
+)
+
+// sysNetPath is the sysfs directory containing network interfaces
+const sysNetPath = "/sys/class/net"
+
+// CollectInterfaceInventory gathers static network interface information
+func CollectInterfaceInventory(ctx context.Context) ([]models.NetworkInterfaceMetrics, error) {
+	interfaces, err := gopsutilNet.InterfacesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var nics []models.NetworkInterfaceMetrics
+
+	for _, iface := range interfaces {
+		// Skip loopback
+		if hasFlag(iface.Flags, "loopback") {
+			continue
+		}
+
+		nic := models.NetworkInterfaceMetrics{
+			Name:   iface.Name,
+			MAC:    iface.HardwareAddr,
+			MTU:    iface.MTU,
+			Up:     hasFlag(iface.Flags, "up"),
+			Duplex: readSysfsString(filepath.Join(sysNetPath, iface.Name, "duplex")),
+			Driver: interfaceDriver(iface.Name),
+		}
+
+		// Speed is -1 (or unreadable) when the link is down or virtual
+		if speed, err := strconv.Atoi(readSysfsString(filepath.Join(sysNetPath, iface.Name, "speed"))); err == nil && speed > 0 {
+			nic.SpeedMbps = speed
+		}
+
+		for _, addr := range iface.Addrs {
+			nic.Addresses = append(nic.Addresses, addr.Addr)
+		}
+
+		nics = append(nics, nic)
+	}
+
+	return nics, nil
+}
+
+// interfaceDriver returns the kernel driver bound to an interface, if any
+func interfaceDriver(name string) string {
+	target, err := os.Readlink(filepath.Join(sysNetPath, name, "device", "driver"))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+// hasFlag checks if an interface flag is set
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/monify-labs/agent/internal/proxy"
 	gopsutilNet "github.com/shirou/gopsutil/v4/net"
 )
 
@@ -17,6 +18,7 @@ import (
 type NetworkInfo struct {
 	InternalIPs []string
 	PublicIP    string
+	PublicIPv6  string
 	Hostname    string
 	FQDN        string
 	Timezone    string
@@ -24,16 +26,20 @@ type NetworkInfo struct {
 
 // NetworkInfoCollector handles network information collection with public IP caching
 type NetworkInfoCollector struct {
-	mu            sync.RWMutex
-	publicIPCache string
-	cacheTime     time.Time
-	cacheDuration time.Duration
+	mu              sync.RWMutex
+	publicIPCache   string
+	publicIPv6Cache string
+	cacheTime       time.Time
+	cacheDuration   time.Duration
+	endpoints       []string // Echo services returning the caller's IP, empty disables the lookup
 }
 
-// NewNetworkInfoCollector creates a new NetworkInfoCollector with 5-minute cache
-func NewNetworkInfoCollector() *NetworkInfoCollector {
+// NewNetworkInfoCollector creates a new NetworkInfoCollector with 5-minute
+// cache that asks the echo services at endpoints for the public IPs
+func NewNetworkInfoCollector(endpoints []string) *NetworkInfoCollector {
 	return &NetworkInfoCollector{
 		cacheDuration: 5 * time.Minute,
+		endpoints:     endpoints,
 	}
 }
 
@@ -51,22 +57,23 @@ func (n *NetworkInfoCollector) Collect(ctx context.Context) (*NetworkInfo, error
 	// Get internal IPs
 	internalIPs := n.getInternalIPs(ctx)
 
-	// Get public IP (with caching)
-	publicIP := n.getPublicIP(ctx)
+	// Get public IPv4/IPv6 (with caching)
+	publicIP, publicIPv6 := n.getPublicIP(ctx)
 
 	// Get FQDN (best effort)
-	fqdn := n.getFQDN(hostname)
+	fqdn := resolveFQDN(ctx, hostname)
 
 	return &NetworkInfo{
 		InternalIPs: internalIPs,
 		PublicIP:    publicIP,
+		PublicIPv6:  publicIPv6,
 		Hostname:    hostname,
 		FQDN:        fqdn,
 		Timezone:    timezone,
 	}, nil
 }
 
-// getInternalIPs retrieves all internal IP addresses
+// getInternalIPs retrieves all internal IP addresses, including global IPv6 addresses
 func (n *NetworkInfoCollector) getInternalIPs(ctx context.Context) []string {
 	var ips []string
 
@@ -85,8 +92,8 @@ func (n *NetworkInfoCollector) getInternalIPs(ctx context.Context) []string {
 			}
 
 			if ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() {
-				// Only include private IPs
-				if isPrivateIP(ip) {
+				// Include private IPs and globally routed IPv6 (not NATed)
+				if isPrivateIP(ip) || isGlobalIPv6(ip) {
 					ips = append(ips, ip.String())
 				}
 			}
@@ -96,42 +103,51 @@ func (n *NetworkInfoCollector) getInternalIPs(ctx context.Context) []string {
 	return ips
 }
 
-// getPublicIP retrieves the public IP address with caching
-func (n *NetworkInfoCollector) getPublicIP(ctx context.Context) string {
+// getPublicIP retrieves the public IPv4 and IPv6 addresses with caching
+func (n *NetworkInfoCollector) getPublicIP(ctx context.Context) (string, string) {
+	if len(n.endpoints) == 0 {
+		return "", "" // Lookup disabled
+	}
+
 	// Check cache first
 	n.mu.RLock()
-	if time.Since(n.cacheTime) < n.cacheDuration && n.publicIPCache != "" {
-		cachedIP := n.publicIPCache
+	if time.Since(n.cacheTime) < n.cacheDuration && (n.publicIPCache != "" || n.publicIPv6Cache != "") {
+		cachedIP, cachedIPv6 := n.publicIPCache, n.publicIPv6Cache
 		n.mu.RUnlock()
-		return cachedIP
+		return cachedIP, cachedIPv6
 	}
 	n.mu.RUnlock()
 
-	// Fetch new public IP
-	publicIP := n.fetchPublicIP(ctx)
+	// Fetch new public IPs (forcing each address family)
+	publicIP := n.fetchPublicIP(ctx, "tcp4")
+	publicIPv6 := n.fetchPublicIP(ctx, "tcp6")
 
 	// Update cache
 	n.mu.Lock()
 	n.publicIPCache = publicIP
+	n.publicIPv6Cache = publicIPv6
 	n.cacheTime = time.Now()
 	n.mu.Unlock()
 
-	return publicIP
+	return publicIP, publicIPv6
 }
 
-// fetchPublicIP queries external service for public IP
-func (n *NetworkInfoCollector) fetchPublicIP(ctx context.Context) string {
-	endpoints := []string{
-		"https://api.ipify.org",
-		"https://icanhazip.com",
-		"https://ifconfig.me",
-	}
-
+// fetchPublicIP queries the echo services for the public IP over the given network (tcp4 or tcp6)
+func (n *NetworkInfoCollector) fetchPublicIP(ctx context.Context, network string) string {
+	// Pin the address family so dual-stack endpoints answer with the matching IP.
+	// Behind a SOCKS5 proxy the endpoints see, and report, the proxy's egress address.
+	dialer := &net.Dialer{Timeout: 3 * time.Second}
 	client := &http.Client{
 		Timeout: 3 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return proxy.DialContext(ctx, dialer, network, addr)
+			},
+		},
 	}
+	defer client.CloseIdleConnections()
 
-	for _, endpoint := range endpoints {
+	for _, endpoint := range n.endpoints {
 		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 		if err != nil {
 			continue
@@ -149,33 +165,29 @@ func (n *NetworkInfoCollector) fetchPublicIP(ctx context.Context) string {
 			continue
 		}
 
-		ip := strings.TrimSpace(string(body))
-		if net.ParseIP(ip) != nil {
-			return ip
+		ip := net.ParseIP(strings.TrimSpace(string(body)))
+		if ip == nil {
+			continue
+		}
+		if isIPv4 := ip.To4() != nil; isIPv4 == (network == "tcp4") {
+			return ip.String()
 		}
 	}
 
 	return ""
 }
 
-// getFQDN attempts to get fully qualified domain name
-func (n *NetworkInfoCollector) getFQDN(hostname string) string {
-	// Try reverse DNS lookup
-	addrs, err := net.LookupAddr(hostname)
-	if err == nil && len(addrs) > 0 {
-		return strings.TrimSuffix(addrs[0], ".")
-	}
-
-	// Fallback to hostname
-	return hostname
-}
-
 // getTimezone returns the system timezone
 func getTimezone() string {
 	zone, _ := time.Now().Zone()
 	return zone
 }
 
+// isGlobalIPv6 checks if an IP is a globally routable IPv6 address (2000::/3)
+func isGlobalIPv6(ip net.IP) bool {
+	return ip.To4() == nil && ip.IsGlobalUnicast() && !isPrivateIP(ip)
+}
+
 // isPrivateIP checks if an IP is in private address space
 func isPrivateIP(ip net.IP) bool {
 	privateBlocks := []string{
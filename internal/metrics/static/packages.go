@@ -0,0 +1,98 @@
+package static
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// packageManagers lists supported package managers and their query commands.
+// Each command prints one "name<TAB>version<TAB>arch" line per package.
+var packageManagers = []struct {
+	name string
+	args []string
+}{
+	{"dpkg", []string{"dpkg-query", "-W", "-f", "${Package}\t${Version}\t${Architecture}\n"}},
+	{"rpm", []string{"rpm", "-qa", "--qf", "%{NAME}\t%{VERSION}-%{RELEASE}\t%{ARCH}\n"}},
+}
+
+// CollectPackages inventories installed packages using the first available package manager
+func CollectPackages(ctx context.Context) (*models.PackageInventory, error) {
+	for _, pm := range packageManagers {
+		path, err := exec.LookPath(pm.args[0])
+		if err != nil {
+			continue
+		}
+
+		output, err := exec.CommandContext(ctx, path, pm.args[1:]...).Output()
+		if err != nil {
+			return nil, fmt.Errorf("%s query failed: %w", pm.name, err)
+		}
+
+		return buildPackageInventory(pm.name, parsePackageList(output))
+	}
+
+	return nil, fmt.Errorf("no supported package manager found")
+}
+
+// parsePackageList parses tab-separated package lines, sorted by name
+func parsePackageList(output []byte) []models.PackageInfo {
+	var packages []models.PackageInfo
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+
+		pkg := models.PackageInfo{Name: fields[0], Version: fields[1]}
+		if len(fields) > 2 {
+			pkg.Arch = fields[2]
+		}
+		packages = append(packages, pkg)
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].Name != packages[j].Name {
+			return packages[i].Name < packages[j].Name
+		}
+		return packages[i].Arch < packages[j].Arch
+	})
+
+	return packages
+}
+
+// buildPackageInventory checksums the package list and compresses it for transport
+func buildPackageInventory(manager string, packages []models.PackageInfo) (*models.PackageInventory, error) {
+	data, err := json.Marshal(packages)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	if _, err := gzipWriter.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return &models.PackageInventory{
+		Manager:  manager,
+		Count:    len(packages),
+		Checksum: hex.EncodeToString(sum[:]),
+		Data:     base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
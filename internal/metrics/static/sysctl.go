@@ -0,0 +1,27 @@
+package static
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CollectSysctls reads the given kernel parameters from /proc/sys.
+// Parameters that do not exist on this kernel are omitted.
+func CollectSysctls(names []string) map[string]string {
+	values := make(map[string]string)
+
+	for _, name := range names {
+		// vm.swappiness -> /proc/sys/vm/swappiness
+		path := filepath.Join("/proc/sys", strings.ReplaceAll(name, ".", "/"))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		// Multi-value parameters (e.g., net.ipv4.tcp_rmem) are tab-separated
+		values[name] = strings.Join(strings.Fields(string(data)), " ")
+	}
+
+	return values
+}
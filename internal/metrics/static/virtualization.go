@@ -0,0 +1,150 @@
+package static
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VirtualizationInfo separates the hypervisor layer from the container layer
+type VirtualizationInfo struct {
+	Hypervisor       string   // kvm, vmware, hyperv, xen, virtualbox, etc. ("" on bare metal)
+	ContainerRuntime string   // docker, podman, lxc, containerd, kubernetes, etc. ("" if not containerized)
+	Subsystem        string   // wsl1, wsl2 ("" if none)
+	Stack            []string // All detected layers, outermost first (e.g., [hyperv wsl2 docker])
+}
+
+// DetectVirtualization inspects DMI, cpuinfo, kernel release, and PID 1 to
+// determine each virtualization layer the agent is running under
+func DetectVirtualization() *VirtualizationInfo {
+	info := &VirtualizationInfo{
+		Hypervisor:       detectHypervisor(),
+		Subsystem:        detectWSL(),
+		ContainerRuntime: detectContainerRuntime(),
+	}
+
+	// WSL2 runs in a Hyper-V utility VM that hides DMI
+	if info.Subsystem == "wsl2" && info.Hypervisor == "" {
+		info.Hypervisor = "hyperv"
+	}
+
+	for _, layer := range []string{info.Hypervisor, info.Subsystem, info.ContainerRuntime} {
+		if layer != "" {
+			info.Stack = append(info.Stack, layer)
+		}
+	}
+
+	return info
+}
+
+// hypervisorVendors maps DMI vendor/product substrings to hypervisor names
+var hypervisorVendors = []struct {
+	match      string
+	hypervisor string
+}{
+	{"qemu", "kvm"},
+	{"kvm", "kvm"},
+	{"amazon ec2", "kvm"},
+	{"google compute engine", "kvm"},
+	{"openstack", "kvm"},
+	{"digitalocean", "kvm"},
+	{"hetzner", "kvm"},
+	{"vmware", "vmware"},
+	{"virtualbox", "virtualbox"},
+	{"innotek", "virtualbox"},
+	{"xen", "xen"},
+	{"parallels", "parallels"},
+	{"bochs", "bochs"},
+	{"bhyve", "bhyve"},
+	{"microsoft corporation virtual machine", "hyperv"},
+}
+
+// detectHypervisor identifies the hypervisor from DMI, falling back to Xen
+// sysfs and the cpuinfo hypervisor flag
+func detectHypervisor() string {
+	dmi := strings.ToLower(strings.Join([]string{
+		readSysfsString(filepath.Join(dmiPath, "sys_vendor")),
+		readSysfsString(filepath.Join(dmiPath, "product_name")),
+		readSysfsString(filepath.Join(dmiPath, "bios_vendor")),
+	}, " "))
+
+	for _, vendor := range hypervisorVendors {
+		if strings.Contains(dmi, vendor.match) {
+			return vendor.hypervisor
+		}
+	}
+
+	if readSysfsString("/sys/hypervisor/type") == "xen" {
+		return "xen"
+	}
+
+	// The hypervisor CPU flag is set by every x86 hypervisor
+	if data, err := os.ReadFile("/proc/cpuinfo"); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "flags") {
+				if strings.Contains(line, " hypervisor") {
+					return "unknown"
+				}
+				break
+			}
+		}
+	}
+
+	return ""
+}
+
+// detectWSL identifies Windows Subsystem for Linux from the kernel release
+func detectWSL() string {
+	release := strings.ToLower(readSysfsString("/proc/sys/kernel/osrelease"))
+	switch {
+	case strings.Contains(release, "microsoft-standard"), strings.Contains(release, "wsl2"):
+		return "wsl2"
+	case strings.Contains(release, "microsoft"):
+		return "wsl1"
+	default:
+		return ""
+	}
+}
+
+// detectContainerRuntime identifies the container runtime the agent runs under
+func detectContainerRuntime() string {
+	// Kubernetes pods, whatever the underlying runtime
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return "kubernetes"
+	}
+
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return "docker"
+	}
+	if _, err := os.Stat("/run/.containerenv"); err == nil {
+		return "podman"
+	}
+
+	// systemd-nspawn, lxc, and podman set container= in PID 1's environment
+	if data, err := os.ReadFile("/proc/1/environ"); err == nil {
+		for _, env := range strings.Split(string(data), "\x00") {
+			if value, ok := strings.CutPrefix(env, "container="); ok && value != "" {
+				return value
+			}
+		}
+	}
+
+	// Fall back to cgroup paths of PID 1
+	if data, err := os.ReadFile("/proc/1/cgroup"); err == nil {
+		cgroup := string(data)
+		switch {
+		case strings.Contains(cgroup, "kubepods"):
+			return "kubernetes"
+		case strings.Contains(cgroup, "docker"):
+			return "docker"
+		case strings.Contains(cgroup, "libpod"):
+			return "podman"
+		case strings.Contains(cgroup, "containerd"):
+			return "containerd"
+		case strings.Contains(cgroup, "lxc"):
+			return "lxc"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,174 @@
+package mountfilter
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// regexPrefix marks a pattern as a regular expression instead of a glob
+const regexPrefix = "re:"
+
+// DefaultSkipTypes are the pseudo and image filesystems never reported
+// unless kept with MONIFY_DISK_KEEP_FSTYPES
+var DefaultSkipTypes = []string{
+	"tmpfs",
+	"devtmpfs",
+	"devfs",
+	"proc",
+	"sysfs",
+	"cgroup",
+	"cgroup2",
+	"nsfs",
+	"overlay",
+	"squashfs",
+	"iso9660",
+}
+
+// defaultFilter applies DefaultSkipTypes only, used for a nil Filter
+var defaultFilter, _ = Parse(Options{})
+
+// pattern matches a mountpoint or device by glob or regular expression
+type pattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+// match reports whether the pattern matches any of the names
+func (p pattern) match(names ...string) bool {
+	for _, name := range names {
+		if p.re != nil {
+			if p.re.MatchString(name) {
+				return true
+			}
+		} else if ok, _ := path.Match(p.glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Options are the filter settings
+type Options struct {
+	Include   []string // Mountpoint or device patterns; when set, only matching filesystems are reported
+	Exclude   []string // Mountpoint or device patterns never reported
+	SkipTypes []string // Filesystem type globs skipped in addition to DefaultSkipTypes
+	KeepTypes []string // Filesystem type globs reported despite DefaultSkipTypes
+}
+
+// Filter selects the filesystems the disk collectors report, by mountpoint,
+// device and filesystem type. A nil Filter only skips DefaultSkipTypes.
+type Filter struct {
+	include   []pattern
+	exclude   []pattern
+	skipTypes []pattern
+	keepTypes []pattern
+}
+
+// Parse builds a filter from its options. Mountpoint and device patterns are
+// globs (/snap/*, /dev/loop*) or, prefixed with re:, regular expressions
+// matched anywhere in the name; filesystem types are globs (fuse.*).
+func Parse(options Options) (*Filter, error) {
+	f := &Filter{}
+	var err error
+	if f.include, err = compile(options.Include, true); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = compile(options.Exclude, true); err != nil {
+		return nil, err
+	}
+	if f.skipTypes, err = compile(append(append([]string(nil), DefaultSkipTypes...), options.SkipTypes...), false); err != nil {
+		return nil, err
+	}
+	if f.keepTypes, err = compile(options.KeepTypes, false); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// compile parses each pattern of a list, accepting regular expressions if
+// regex is set
+func compile(values []string, regex bool) ([]pattern, error) {
+	var patterns []pattern
+	for _, value := range values {
+		if expr, ok := strings.CutPrefix(value, regexPrefix); ok && regex {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", value, err)
+			}
+			patterns = append(patterns, pattern{re: re})
+			continue
+		}
+		if _, err := path.Match(value, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", value, err)
+		}
+		patterns = append(patterns, pattern{glob: value})
+	}
+	return patterns, nil
+}
+
+// Allows reports whether a filesystem is reported: its type must not be
+// skipped, it must match an include pattern, if there are any, and no
+// exclude pattern
+func (f *Filter) Allows(mountpoint, device, fstype string) bool {
+	if f == nil {
+		f = defaultFilter
+	}
+	if matchAny(f.skipTypes, fstype) && !matchAny(f.keepTypes, fstype) {
+		return false
+	}
+	if len(f.include) > 0 && !matchAny(f.include, mountpoint, device) {
+		return false
+	}
+	return !matchAny(f.exclude, mountpoint, device)
+}
+
+// Partitions lists the mounted filesystems the filter allows. Filesystems
+// without a block device (tmpfs, overlay, network mounts) are only listed
+// when their type is kept.
+func (f *Filter) Partitions(ctx context.Context) ([]disk.PartitionStat, error) {
+	if f == nil {
+		f = defaultFilter
+	}
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.keepTypes) > 0 {
+		all, err := disk.PartitionsWithContext(ctx, true)
+		if err != nil {
+			return nil, err
+		}
+		listed := make(map[string]bool)
+		for _, partition := range partitions {
+			listed[partition.Mountpoint] = true
+		}
+		for _, partition := range all {
+			if !listed[partition.Mountpoint] && matchAny(f.keepTypes, partition.Fstype) {
+				partitions = append(partitions, partition)
+			}
+		}
+	}
+
+	allowed := partitions[:0]
+	for _, partition := range partitions {
+		if f.Allows(partition.Mountpoint, partition.Device, partition.Fstype) {
+			allowed = append(allowed, partition)
+		}
+	}
+	return allowed, nil
+}
+
+// matchAny reports whether any of the patterns matches any of the names
+func matchAny(patterns []pattern, names ...string) bool {
+	for _, p := range patterns {
+		if p.match(names...) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+// Package ports reports the host's listening sockets and scans local port
+// ranges for the scan_ports server command.
+package ports
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// Scan settings
+const (
+	scanWorkers = 64                     // Concurrent connection attempts
+	scanTimeout = 500 * time.Millisecond // Per port
+)
+
+// Socket states in /proc/net: LISTEN for TCP, UNCONN (bound) for UDP
+const (
+	tcpListen = "0A"
+	udpUnconn = "07"
+)
+
+// Listening returns the listening TCP and bound UDP sockets from procfs, with
+// the owning process where it can be resolved (other users' processes need root)
+func Listening() ([]models.ListeningPort, error) {
+	var sockets []socket
+	var firstErr error
+	for _, table := range []struct{ protocol, state string }{
+		{"tcp", tcpListen}, {"tcp6", tcpListen}, {"udp", udpUnconn}, {"udp6", udpUnconn},
+	} {
+		found, err := readSockets(filepath.Join("/proc/net", table.protocol), table.protocol, table.state)
+		if err != nil {
+			if firstErr == nil && !os.IsNotExist(err) {
+				firstErr = err
+			}
+			continue
+		}
+		sockets = append(sockets, found...)
+	}
+	if sockets == nil {
+		return nil, firstErr
+	}
+
+	owners := socketOwners()
+	result := make([]models.ListeningPort, len(sockets))
+	for i, s := range sockets {
+		result[i] = s.port
+		if owner, ok := owners[s.inode]; ok {
+			result[i].PID = owner.pid
+			result[i].Process = owner.name
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Protocol != result[j].Protocol {
+			return result[i].Protocol < result[j].Protocol
+		}
+		if result[i].Port != result[j].Port {
+			return result[i].Port < result[j].Port
+		}
+		return result[i].Address < result[j].Address
+	})
+	return result, nil
+}
+
+// socket is a listening socket and the inode identifying it in /proc/<pid>/fd
+type socket struct {
+	port  models.ListeningPort
+	inode uint64
+}
+
+// readSockets parses one /proc/net socket table, keeping entries in state
+func readSockets(path, protocol, state string) ([]socket, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sockets []socket
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Skip header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != state {
+			continue
+		}
+		host, port, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		ip := parseProcIP(host)
+		portNum, err := strconv.ParseUint(port, 16, 16)
+		if ip == nil || err != nil {
+			continue
+		}
+		inode, _ := strconv.ParseUint(fields[9], 10, 64)
+		sockets = append(sockets, socket{
+			port:  models.ListeningPort{Protocol: protocol, Address: ip.String(), Port: int(portNum)},
+			inode: inode,
+		})
+	}
+	return sockets, scanner.Err()
+}
+
+// parseProcIP decodes an address from /proc/net, stored as 32-bit words in
+// host (little-endian) byte order
+func parseProcIP(s string) net.IP {
+	raw, err := hex.DecodeString(s)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.LittleEndian.Uint32(raw[i:]))
+	}
+	return ip
+}
+
+// socketOwner identifies the process holding a socket
+type socketOwner struct {
+	pid  int32
+	name string
+}
+
+// socketOwners maps socket inodes to processes by walking /proc/<pid>/fd
+func socketOwners() map[uint64]socketOwner {
+	owners := make(map[uint64]socketOwner)
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return owners
+	}
+
+	for _, proc := range procs {
+		pid, err := strconv.ParseInt(proc.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join("/proc", proc.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue // Exited, or owned by another user
+		}
+
+		var name string
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, ok := owners[inode]; ok {
+				continue // Shared after fork: keep the first (lowest) PID
+			}
+			if name == "" {
+				comm, _ := os.ReadFile(filepath.Join("/proc", proc.Name(), "comm"))
+				name = strings.TrimSpace(string(comm))
+			}
+			owners[inode] = socketOwner{pid: int32(pid), name: name}
+		}
+	}
+	return owners
+}
+
+// Scan attempts TCP connections to host on every port from first to last
+// and returns the open ones in ascending order
+func Scan(ctx context.Context, host string, first, last int) []int {
+	ports := make(chan int)
+	var mu sync.Mutex
+	var open []int
+
+	var wg sync.WaitGroup
+	for range min(scanWorkers, last-first+1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dialer := net.Dialer{Timeout: scanTimeout}
+			for port := range ports {
+				conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+				if err != nil {
+					continue
+				}
+				conn.Close()
+				mu.Lock()
+				open = append(open, port)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for port := first; port <= last && ctx.Err() == nil; port++ {
+		ports <- port
+	}
+	close(ports)
+	wg.Wait()
+
+	sort.Ints(open)
+	return open
+}
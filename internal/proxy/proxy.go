@@ -0,0 +1,280 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+)
+
+// Cloud metadata endpoints outside link-local space that must never be proxied
+var directHosts = map[string]bool{
+	"metadata.google.internal": true,
+	"100.100.100.200":          true,
+}
+
+// socks5 holds the parsed MONIFY_SOCKS5_PROXY setting
+type socks5 struct {
+	address  string
+	username string
+	password string
+}
+
+var (
+	loadOnce sync.Once
+	current  *socks5
+	loadErr  error
+)
+
+// configured returns the SOCKS5 proxy from the environment, or nil when unset
+func configured() (*socks5, error) {
+	loadOnce.Do(func() {
+		current, loadErr = parse(config.GetSOCKS5Proxy())
+	})
+	return current, loadErr
+}
+
+// parse accepts socks5://[user:pass@]host:port or a bare host:port
+func parse(value string) (*socks5, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if !strings.Contains(value, "://") {
+		value = "socks5://" + value
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SOCKS5 proxy: %w", err)
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("SOCKS5 proxy address missing")
+	}
+
+	p := &socks5{address: u.Host}
+	if u.Port() == "" {
+		p.address = net.JoinHostPort(u.Hostname(), "1080")
+	}
+	if u.User != nil {
+		p.username = u.User.Username()
+		p.password, _ = u.User.Password()
+	}
+	return p, nil
+}
+
+// Address returns the configured proxy address (host:port), or "" when disabled
+func Address() string {
+	if p, err := configured(); err == nil && p != nil {
+		return p.address
+	}
+	return ""
+}
+
+// Err returns the error of an invalid proxy setting, nil when it is valid or unset
+func Err() error {
+	_, err := configured()
+	return err
+}
+
+// DialFunc returns a DialContext function for http.Transport and other
+// clients that routes connections through the SOCKS5 proxy when one is set
+func DialFunc(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return DialContext(ctx, dialer, network, addr)
+	}
+}
+
+// DialContext connects to addr through the SOCKS5 proxy, or directly with
+// dialer when no proxy is configured or addr is a local/metadata address
+func DialContext(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	p, err := configured()
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || bypass(addr) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	// The proxy's own address family decides the egress; pin only the leg to the proxy
+	conn, err := dialer.DialContext(ctx, "tcp", p.address)
+	if err != nil {
+		return nil, fmt.Errorf("SOCKS5 proxy %s: %w", p.address, err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else if dialer.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(dialer.Timeout))
+	}
+
+	// Report handshake failures as network errors so senders treat them as retryable
+	if err := p.connect(conn, addr); err != nil {
+		conn.Close()
+		return nil, &net.OpError{Op: "socks5", Net: network, Addr: conn.RemoteAddr(), Err: err}
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// bypass reports whether addr must be dialed directly: loopback, link-local
+// (including 169.254.169.254 instance metadata) and known metadata hosts
+func bypass(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if host == "localhost" || directHosts[host] {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsLinkLocalUnicast())
+}
+
+// connect performs the SOCKS5 handshake (RFC 1928) and CONNECT request on conn
+func (p *socks5) connect(conn net.Conn, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+
+	// Greeting: offer no-auth, plus username/password when credentials are set
+	methods := []byte{0x00}
+	if p.username != "" {
+		methods = append(methods, 0x02)
+	}
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != 0x05 {
+		return fmt.Errorf("unexpected SOCKS version %d", reply[0])
+	}
+	switch reply[1] {
+	case 0x00:
+	case 0x02:
+		if err := p.authenticate(conn); err != nil {
+			return err
+		}
+	default:
+		return errors.New("no acceptable authentication method")
+	}
+
+	// CONNECT request; hostnames are resolved by the proxy
+	req := []byte{0x05, 0x01, 0x00}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("hostname too long")
+		}
+		req = append(req, 0x03, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, 0x01)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, 0x04)
+		req = append(req, ip.To16()...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// Reply: VER REP RSV ATYP BND.ADDR BND.PORT
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return err
+	}
+	if head[1] != 0x00 {
+		return fmt.Errorf("connect to %s failed: %s", addr, replyMessage(head[1]))
+	}
+
+	var skip int
+	switch head[3] {
+	case 0x01:
+		skip = net.IPv4len
+	case 0x04:
+		skip = net.IPv6len
+	case 0x03:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return err
+		}
+		skip = int(n[0])
+	default:
+		return fmt.Errorf("unexpected address type %d", head[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
+
+// authenticate performs username/password authentication (RFC 1929)
+func (p *socks5) authenticate(conn net.Conn) error {
+	if p.username == "" {
+		return errors.New("proxy requires authentication")
+	}
+	if len(p.username) > 255 || len(p.password) > 255 {
+		return errors.New("proxy credentials too long")
+	}
+
+	req := []byte{0x01, byte(len(p.username))}
+	req = append(req, p.username...)
+	req = append(req, byte(len(p.password)))
+	req = append(req, p.password...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0x00 {
+		return errors.New("proxy authentication failed")
+	}
+	return nil
+}
+
+// replyMessage describes a SOCKS5 reply code
+func replyMessage(code byte) string {
+	switch code {
+	case 0x01:
+		return "general failure"
+	case 0x02:
+		return "connection not allowed by ruleset"
+	case 0x03:
+		return "network unreachable"
+	case 0x04:
+		return "host unreachable"
+	case 0x05:
+		return "connection refused"
+	case 0x06:
+		return "TTL expired"
+	case 0x07:
+		return "command not supported"
+	case 0x08:
+		return "address type not supported"
+	default:
+		return fmt.Sprintf("reply code %d", code)
+	}
+}
@@ -0,0 +1,140 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// ErrCircuitOpen is returned without contacting the server while the circuit
+// breaker is open. It wraps ErrServerUnavailable, so it is retryable.
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker open", ErrServerUnavailable)
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"    // Sends go through
+	CircuitOpen     = "open"      // Sends fail fast until the next probe
+	CircuitHalfOpen = "half-open" // A single probe send is in flight
+)
+
+// CircuitBreaker stops sending after threshold consecutive transient failures.
+// While open, sends fail fast with ErrCircuitOpen; after the open interval one
+// probe send is let through. A successful probe closes the circuit, a failed
+// one reopens it with the interval doubled up to maxInterval.
+type CircuitBreaker struct {
+	sender       Sender
+	threshold    int
+	openInterval time.Duration
+	maxInterval  time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int           // Consecutive transient failures while closed
+	interval time.Duration // Current open interval
+	openedAt time.Time
+}
+
+// NewCircuitBreaker wraps s with a circuit breaker
+func NewCircuitBreaker(s Sender, threshold int, openInterval, maxInterval time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		sender:       s,
+		threshold:    threshold,
+		openInterval: openInterval,
+		maxInterval:  max(maxInterval, openInterval),
+		state:        CircuitClosed,
+		interval:     openInterval,
+	}
+}
+
+// Send sends a single metric payload
+func (c *CircuitBreaker) Send(ctx context.Context, payload *models.MetricPayload) (*models.ServerResponse, error) {
+	return c.do(func() (*models.ServerResponse, error) {
+		return c.sender.Send(ctx, payload)
+	})
+}
+
+// SendBatch sends several payloads in a single request
+func (c *CircuitBreaker) SendBatch(ctx context.Context, payloads []*models.MetricPayload) (*models.ServerResponse, error) {
+	return c.do(func() (*models.ServerResponse, error) {
+		return c.sender.SendBatch(ctx, payloads)
+	})
+}
+
+// do runs send unless the circuit is open, and records the outcome
+func (c *CircuitBreaker) do(send func() (*models.ServerResponse, error)) (*models.ServerResponse, error) {
+	c.mu.Lock()
+	switch c.state {
+	case CircuitHalfOpen:
+		c.mu.Unlock()
+		return nil, ErrCircuitOpen
+	case CircuitOpen:
+		if time.Since(c.openedAt) < c.interval {
+			c.mu.Unlock()
+			return nil, ErrCircuitOpen
+		}
+		c.state = CircuitHalfOpen
+		log.Printf("INFO: Circuit breaker half-open, probing server")
+	}
+	probing := c.state == CircuitHalfOpen
+	c.mu.Unlock()
+
+	resp, err := send()
+
+	// Rate limiting means the server is up; only outages trip the breaker
+	var throttled *ThrottledError
+	failed := err != nil && IsRetryable(err) && !errors.As(err, &throttled)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !failed {
+		if probing {
+			log.Printf("INFO: Circuit breaker closed, server reachable again")
+		}
+		c.state = CircuitClosed
+		c.failures = 0
+		c.interval = c.openInterval
+		return resp, err
+	}
+
+	if probing {
+		c.interval = min(c.interval*2, c.maxInterval)
+		c.open()
+		log.Printf("WARN: Circuit breaker probe failed, reopening [retry_in=%s error=%v]", c.interval, err)
+		return nil, err
+	}
+
+	c.failures++
+	if failures := c.failures; failures >= c.threshold {
+		c.open()
+		log.Printf("WARN: Circuit breaker open after %d consecutive failures [retry_in=%s error=%v]", failures, c.interval, err)
+	}
+	return nil, err
+}
+
+// open moves the circuit to the open state. Caller must hold c.mu.
+func (c *CircuitBreaker) open() {
+	c.state = CircuitOpen
+	c.openedAt = time.Now()
+	c.failures = 0
+}
+
+// State returns the current circuit state
+func (c *CircuitBreaker) State() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Close closes the wrapped sender
+func (c *CircuitBreaker) Close() error {
+	return c.sender.Close()
+}
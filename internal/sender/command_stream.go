@@ -0,0 +1,209 @@
+package sender
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// ErrStreamUnsupported is returned when the server does not offer a command stream
+var ErrStreamUnsupported = errors.New("command stream not supported by server")
+
+// CommandStream keeps a long-lived Server-Sent Events connection over which
+// the server pushes commands, so they apply immediately instead of waiting
+// for the next metrics response
+type CommandStream struct {
+	streamURL string
+	token     string
+	tokens    *TokenSource // nil when the static token is used
+	client    *http.Client
+}
+
+// NewCommandStream creates a command stream for the given URL
+func NewCommandStream(streamURL, token string) (*CommandStream, error) {
+	tlsConfig, err := ServerTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// No overall timeout: the response body stays open for as long as the
+	// connection lives. Dead connections are caught by the idle timeout.
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext:           ServerDialFunc(),
+			TLSClientConfig:       tlsConfig,
+			ResponseHeaderTimeout: config.GetTimeout(),
+		},
+	}
+
+	return &CommandStream{
+		streamURL: streamURL,
+		token:     token,
+		client:    client,
+	}, nil
+}
+
+// SetTokenSource switches from the static token to short-lived access tokens
+func (c *CommandStream) SetTokenSource(tokens *TokenSource) {
+	c.tokens = tokens
+}
+
+// Run connects and delivers pushed commands to handle until ctx is cancelled,
+// reconnecting with backoff. It returns early with ErrUnauthorized or
+// ErrStreamUnsupported when reconnecting cannot help.
+func (c *CommandStream) Run(ctx context.Context, handle func([]models.ServerCommand)) error {
+	backoff := config.CommandStreamMinBackoff
+	for {
+		connected, err := c.connect(ctx, handle)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrStreamUnsupported) {
+			return err
+		}
+
+		if connected {
+			backoff = config.CommandStreamMinBackoff
+		}
+		log.Printf("WARN: Command stream disconnected, reconnecting [retry_in=%s error=%v]", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, config.CommandStreamMaxBackoff)
+	}
+}
+
+// connect opens one stream connection and reads events until it ends.
+// connected reports whether the server accepted the stream.
+func (c *CommandStream) connect(ctx context.Context, handle func([]models.ServerCommand)) (connected bool, err error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(streamCtx, "GET", c.streamURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("User-Agent", fmt.Sprintf("monify/%s", config.Version))
+	req.Header.Set("X-Agent-Version", config.Version)
+	token := c.token
+	if c.tokens != nil {
+		if token, err = c.tokens.Token(ctx); err != nil {
+			return false, err
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, classifyRequestError(err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized && c.tokens != nil:
+		// Reconnect with a fresh access token
+		if _, err := c.tokens.Refresh(ctx, token); err != nil {
+			return false, err
+		}
+		return false, fmt.Errorf("access token expired")
+	case resp.StatusCode == http.StatusUnauthorized:
+		return false, ErrUnauthorized
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed:
+		return false, ErrStreamUnsupported
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("%w: status code %d", ErrServerUnavailable, resp.StatusCode)
+	}
+
+	log.Printf("INFO: Command stream connected [url=%s]", c.streamURL)
+
+	// Drop the connection when the server stops sending keepalives
+	idle := time.AfterFunc(config.CommandStreamIdleTimeout, cancel)
+	defer idle.Stop()
+
+	var event string
+	var data strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		idle.Reset(config.CommandStreamIdleTimeout)
+		line := scanner.Text()
+
+		// A blank line dispatches the buffered event
+		if line == "" {
+			if data.Len() > 0 && (event == "" || event == "message" || event == "command") {
+				c.dispatch(data.String(), handle)
+			}
+			event = ""
+			data.Reset()
+			continue
+		}
+
+		// Comment lines are keepalives
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+
+	if err := scanner.Err(); err != nil && streamCtx.Err() == nil {
+		return true, fmt.Errorf("%w: %w", ErrNetwork, err)
+	}
+	if ctx.Err() == nil && streamCtx.Err() != nil {
+		return true, fmt.Errorf("%w: no data for %s", ErrNetwork, config.CommandStreamIdleTimeout)
+	}
+	return true, fmt.Errorf("%w: stream closed by server", ErrNetwork)
+}
+
+// dispatch decodes an event payload holding one command or an array of commands
+func (c *CommandStream) dispatch(data string, handle func([]models.ServerCommand)) {
+	var commands []models.ServerCommand
+	if strings.HasPrefix(strings.TrimSpace(data), "[") {
+		if err := json.Unmarshal([]byte(data), &commands); err != nil {
+			log.Printf("WARN: Ignoring malformed command event: %v", err)
+			return
+		}
+	} else {
+		var cmd models.ServerCommand
+		if err := json.Unmarshal([]byte(data), &cmd); err != nil {
+			log.Printf("WARN: Ignoring malformed command event: %v", err)
+			return
+		}
+		commands = append(commands, cmd)
+	}
+
+	if len(commands) > 0 {
+		handle(commands)
+	}
+}
+
+// Close releases idle connections
+func (c *CommandStream) Close() {
+	c.client.CloseIdleConnections()
+}
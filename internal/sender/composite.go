@@ -0,0 +1,149 @@
+package sender
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// destinationQueueSize is how many payloads a secondary destination may fall behind
+const destinationQueueSize = 64
+
+// Destination is a named sender
+type Destination struct {
+	Name   string
+	Sender Sender
+}
+
+// CompositeSender sends each payload to several destinations. The primary
+// destination is sent to synchronously and its result is returned, so
+// spooling and server commands behave as with a single sender. Every
+// secondary destination has its own queue and worker: a slow or failing
+// destination never delays the primary or the other destinations.
+// Secondary sends are best effort; payloads are dropped when a queue is full.
+type CompositeSender struct {
+	primary     Destination
+	secondaries []*secondaryDestination
+	wg          sync.WaitGroup
+}
+
+// secondaryDestination is a secondary destination with its own queue
+type secondaryDestination struct {
+	Destination
+	queue   chan []*models.MetricPayload
+	ctx     context.Context
+	cancel  context.CancelFunc
+	failing bool // Only touched by the worker
+}
+
+// NewCompositeSender creates a sender that mirrors payloads to secondary destinations
+func NewCompositeSender(primary Destination, secondaries ...Destination) *CompositeSender {
+	c := &CompositeSender{primary: primary}
+
+	for _, dest := range secondaries {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := &secondaryDestination{
+			Destination: dest,
+			queue:       make(chan []*models.MetricPayload, destinationQueueSize),
+			ctx:         ctx,
+			cancel:      cancel,
+		}
+		c.secondaries = append(c.secondaries, s)
+
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			s.run()
+		}()
+	}
+
+	return c
+}
+
+// Primary returns the primary destination's sender, for resending spooled
+// payloads that secondary destinations have already received
+func (c *CompositeSender) Primary() Sender {
+	return c.primary.Sender
+}
+
+// Send queues the payload for secondary destinations and sends it to the primary
+func (c *CompositeSender) Send(ctx context.Context, payload *models.MetricPayload) (*models.ServerResponse, error) {
+	if payload == nil {
+		return nil, nil
+	}
+	c.Mirror([]*models.MetricPayload{payload})
+	return c.primary.Sender.Send(ctx, payload)
+}
+
+// SendBatch queues the payloads for secondary destinations and sends them to the primary
+func (c *CompositeSender) SendBatch(ctx context.Context, payloads []*models.MetricPayload) (*models.ServerResponse, error) {
+	if len(payloads) == 0 {
+		return nil, nil
+	}
+	c.Mirror(payloads)
+	return c.primary.Sender.SendBatch(ctx, payloads)
+}
+
+// Mirror enqueues payloads on every secondary destination without blocking.
+// Used directly for payloads that go to the spool instead of the primary.
+func (c *CompositeSender) Mirror(payloads []*models.MetricPayload) {
+	for _, s := range c.secondaries {
+		select {
+		case s.queue <- payloads:
+		default:
+			log.Printf("WARN: Destination queue full, dropping payload [destination=%s]", s.Name)
+		}
+	}
+}
+
+// Close stops the secondary workers and closes all destinations
+func (c *CompositeSender) Close() error {
+	for _, s := range c.secondaries {
+		s.cancel()
+	}
+	c.wg.Wait()
+
+	for _, s := range c.secondaries {
+		if err := s.Sender.Close(); err != nil {
+			log.Printf("ERROR: Failed to close destination [destination=%s error=%v]", s.Name, err)
+		}
+	}
+	return c.primary.Sender.Close()
+}
+
+// run sends queued payloads until the destination is closed
+func (s *secondaryDestination) run() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case payloads := <-s.queue:
+			s.send(payloads)
+		}
+	}
+}
+
+// send delivers payloads to the destination, logging only state changes
+func (s *secondaryDestination) send(payloads []*models.MetricPayload) {
+	ctx, cancel := context.WithTimeout(s.ctx, config.GetTimeout())
+	defer cancel()
+
+	var err error
+	if len(payloads) == 1 {
+		_, err = s.Sender.Send(ctx, payloads[0])
+	} else {
+		_, err = s.Sender.SendBatch(ctx, payloads)
+	}
+
+	switch {
+	case err != nil && !s.failing:
+		s.failing = true
+		log.Printf("WARN: Destination failing, other destinations are unaffected [destination=%s error=%v]", s.Name, err)
+	case err == nil && s.failing:
+		s.failing = false
+		log.Printf("INFO: Destination recovered [destination=%s]", s.Name)
+	}
+}
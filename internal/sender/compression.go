@@ -0,0 +1,130 @@
+package sender
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings for request bodies
+const (
+	encodingGzip = "gzip"
+	encodingZstd = "zstd"
+	encodingAuto = "auto" // gzip until the server advertises zstd
+)
+
+// compressor encodes request bodies with the configured or negotiated encoding
+type compressor struct {
+	mode      string
+	gzipLevel int
+	zstd      *zstd.Encoder
+
+	mu           sync.Mutex
+	zstdAccepted bool // Auto mode: server advertised zstd in Accept-Encoding
+}
+
+// newCompressor creates a compressor. level 0 uses each codec's default;
+// gzip accepts 1-9 and zstd 1-22.
+func newCompressor(mode string, level int) (*compressor, error) {
+	c := &compressor{mode: mode, gzipLevel: gzip.DefaultCompression}
+
+	switch mode {
+	case encodingGzip, encodingZstd, encodingAuto:
+	default:
+		return nil, fmt.Errorf("unsupported compression %q (use gzip, zstd or auto)", mode)
+	}
+
+	if level != 0 && mode == encodingGzip {
+		if level < gzip.BestSpeed || level > gzip.BestCompression {
+			return nil, fmt.Errorf("gzip compression level must be 1-9, got %d", level)
+		}
+		c.gzipLevel = level
+	}
+
+	if mode != encodingGzip {
+		zstdLevel := zstd.SpeedDefault
+		if level != 0 {
+			if level < 1 || level > 22 {
+				return nil, fmt.Errorf("zstd compression level must be 1-22, got %d", level)
+			}
+			zstdLevel = zstd.EncoderLevelFromZstd(level)
+		}
+
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstdLevel), zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+		c.zstd = encoder
+	}
+
+	return c, nil
+}
+
+// encoding returns the content encoding for the next request
+func (c *compressor) encoding() string {
+	switch c.mode {
+	case encodingZstd:
+		return encodingZstd
+	case encodingAuto:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.zstdAccepted {
+			return encodingZstd
+		}
+	}
+	return encodingGzip
+}
+
+// compress encodes data with the given content encoding
+func (c *compressor) compress(encoding string, data []byte) ([]byte, error) {
+	if encoding == encodingZstd {
+		return c.zstd.EncodeAll(data, make([]byte, 0, len(data)/4)), nil
+	}
+
+	var buf bytes.Buffer
+	gzipWriter, err := gzip.NewWriterLevel(&buf, c.gzipLevel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+	}
+	if _, err := gzipWriter.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress data: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// observe updates negotiation state from the server's Accept-Encoding header
+func (c *compressor) observe(resp *http.Response) {
+	if c.mode != encodingAuto {
+		return
+	}
+
+	accepted := resp.Header.Get("Accept-Encoding")
+	if accepted == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.zstdAccepted = strings.Contains(accepted, encodingZstd)
+}
+
+// reject handles a 415 response for the given encoding. It returns true if the
+// request should be retried with gzip.
+func (c *compressor) reject(encoding string) bool {
+	if c.mode != encodingAuto || encoding != encodingZstd {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.zstdAccepted = false
+	return true
+}
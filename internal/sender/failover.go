@@ -0,0 +1,121 @@
+package sender
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// failoverProbeTimeout bounds a send to the primary while failed over, so a
+// still-dead primary does not eat the time budget of the fallback send
+const failoverProbeTimeout = 5 * time.Second
+
+// FailoverSender sends to a primary destination and switches to a fallback
+// after threshold consecutive transient failures. While failed over, the
+// primary is retried every probeInterval and used again once it succeeds.
+type FailoverSender struct {
+	primary       Destination
+	fallback      Destination
+	threshold     int
+	probeInterval time.Duration
+
+	mu         sync.Mutex
+	failures   int // Consecutive transient failures of the primary
+	failedOver bool
+	lastProbe  time.Time
+}
+
+// NewFailoverSender creates a sender with a fallback destination
+func NewFailoverSender(primary, fallback Destination, threshold int, probeInterval time.Duration) *FailoverSender {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &FailoverSender{
+		primary:       primary,
+		fallback:      fallback,
+		threshold:     threshold,
+		probeInterval: probeInterval,
+	}
+}
+
+// Send sends a single metric payload
+func (f *FailoverSender) Send(ctx context.Context, payload *models.MetricPayload) (*models.ServerResponse, error) {
+	return f.do(ctx, func(ctx context.Context, s Sender) (*models.ServerResponse, error) {
+		return s.Send(ctx, payload)
+	})
+}
+
+// SendBatch sends several payloads in a single request
+func (f *FailoverSender) SendBatch(ctx context.Context, payloads []*models.MetricPayload) (*models.ServerResponse, error) {
+	return f.do(ctx, func(ctx context.Context, s Sender) (*models.ServerResponse, error) {
+		return s.SendBatch(ctx, payloads)
+	})
+}
+
+// do runs send against the active destination, failing over or back as needed
+func (f *FailoverSender) do(ctx context.Context, send func(context.Context, Sender) (*models.ServerResponse, error)) (*models.ServerResponse, error) {
+	f.mu.Lock()
+	failedOver := f.failedOver
+	probe := failedOver && time.Since(f.lastProbe) >= f.probeInterval
+	if probe {
+		f.lastProbe = time.Now()
+	}
+	f.mu.Unlock()
+
+	// Failed over: periodically try the primary again
+	if probe {
+		probeCtx, cancel := context.WithTimeout(ctx, failoverProbeTimeout)
+		resp, err := send(probeCtx, f.primary.Sender)
+		cancel()
+		if err == nil {
+			f.mu.Lock()
+			f.failedOver = false
+			f.failures = 0
+			f.mu.Unlock()
+			log.Printf("INFO: Primary server reachable again, failing back [server=%s]", f.primary.Name)
+			return resp, nil
+		}
+	}
+
+	if failedOver {
+		return send(ctx, f.fallback.Sender)
+	}
+
+	resp, err := send(ctx, f.primary.Sender)
+	if err == nil || !IsRetryable(err) {
+		f.mu.Lock()
+		f.failures = 0
+		f.mu.Unlock()
+		return resp, err
+	}
+
+	f.mu.Lock()
+	f.failures++
+	switchOver := f.failures >= f.threshold
+	if switchOver {
+		f.failedOver = true
+		f.lastProbe = time.Now()
+	}
+	failures := f.failures
+	f.mu.Unlock()
+
+	if !switchOver {
+		return nil, err
+	}
+
+	log.Printf("WARN: Primary server failed %d times, failing over [primary=%s fallback=%s error=%v]",
+		failures, f.primary.Name, f.fallback.Name, err)
+	return send(ctx, f.fallback.Sender)
+}
+
+// Close closes both destinations
+func (f *FailoverSender) Close() error {
+	err := f.fallback.Sender.Close()
+	if primaryErr := f.primary.Sender.Close(); primaryErr != nil {
+		return primaryErr
+	}
+	return err
+}
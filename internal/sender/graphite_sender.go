@@ -0,0 +1,136 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/proxy"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// graphitePathReplacer makes hostnames and label values safe as path nodes
+var graphitePathReplacer = strings.NewReplacer(".", "_", " ", "_", "/", "_")
+
+// GraphiteSender writes dynamic metrics to Graphite (carbon) using the
+// plaintext protocol: "<prefix>.<host>.<metric>[.<label values>] <value> <timestamp>".
+//
+// When interval is set, timestamps are aligned down to it and only the first
+// payload of each interval is written, so data points line up with the
+// retention of the matching storage-schemas.conf entry.
+type GraphiteSender struct {
+	address  string
+	prefix   string
+	interval time.Duration
+
+	mu         sync.Mutex
+	conn       net.Conn
+	lastBucket int64
+}
+
+// NewGraphiteSender creates a new Graphite plaintext sender. The address
+// defaults to port 2003 when none is given.
+func NewGraphiteSender(address, prefix string, interval time.Duration) (*GraphiteSender, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "2003")
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("invalid Graphite address %q", address)
+	}
+
+	return &GraphiteSender{
+		address:  address,
+		prefix:   strings.Trim(prefix, "."),
+		interval: interval,
+	}, nil
+}
+
+// Send writes the metrics of a single payload
+func (g *GraphiteSender) Send(ctx context.Context, payload *models.MetricPayload) (*models.ServerResponse, error) {
+	if payload == nil {
+		return nil, nil
+	}
+	return g.SendBatch(ctx, []*models.MetricPayload{payload})
+}
+
+// SendBatch writes the metrics of several payloads over one connection
+func (g *GraphiteSender) SendBatch(ctx context.Context, payloads []*models.MetricPayload) (*models.ServerResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var buf bytes.Buffer
+	for _, payload := range payloads {
+		timestamp := payload.Timestamp.Unix()
+		if g.interval > 0 {
+			step := int64(g.interval.Seconds())
+			timestamp -= timestamp % step
+			if timestamp == g.lastBucket {
+				continue
+			}
+			g.lastBucket = timestamp
+		}
+
+		host := graphitePathReplacer.Replace(payload.Hostname)
+		for _, point := range metricPoints(payload.DynamicMetrics) {
+			path := point.name
+			for _, label := range point.labels {
+				node := strings.Trim(label[1], "/")
+				if node == "" {
+					node = "root" // Mountpoint "/"
+				}
+				path += "." + graphitePathReplacer.Replace(node)
+			}
+			if g.prefix != "" {
+				fmt.Fprintf(&buf, "%s.", g.prefix)
+			}
+			fmt.Fprintf(&buf, "%s.%s %s %d\n", host, path,
+				strconv.FormatFloat(point.value, 'f', -1, 64), timestamp)
+		}
+	}
+	if buf.Len() == 0 {
+		return &models.ServerResponse{Status: "success"}, nil
+	}
+
+	if g.conn == nil {
+		dialer := &net.Dialer{Timeout: config.GetTimeout()}
+		conn, err := proxy.DialContext(ctx, dialer, "tcp", g.address)
+		if err != nil {
+			return nil, classifyRequestError(err)
+		}
+		g.conn = conn
+	}
+
+	deadline := time.Now().Add(config.GetTimeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	g.conn.SetWriteDeadline(deadline)
+
+	if _, err := g.conn.Write(buf.Bytes()); err != nil {
+		// Carbon does not acknowledge writes; reconnect next time
+		g.conn.Close()
+		g.conn = nil
+		return nil, fmt.Errorf("%w: Graphite write failed: %w", ErrNetwork, err)
+	}
+
+	return &models.ServerResponse{Status: "success"}, nil
+}
+
+// Close closes the connection to carbon
+func (g *GraphiteSender) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.conn != nil {
+		err := g.conn.Close()
+		g.conn = nil
+		return err
+	}
+	return nil
+}
@@ -2,99 +2,341 @@ package sender
 
 import (
 	"bytes"
-	"compress/gzip"
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/proxy"
 	"github.com/monify-labs/agent/pkg/models"
 )
 
 // ErrUnauthorized is returned when authentication fails (401)
 var ErrUnauthorized = errors.New("authentication failed: invalid or expired token")
 
+// ErrCertificate is returned when the server certificate cannot be verified
+var ErrCertificate = errors.New("TLS certificate verification failed")
+
+// ErrNetwork is returned when the server cannot be reached
+var ErrNetwork = errors.New("network error")
+
+// ErrServerUnavailable is returned when the server is overloaded or failing (429, 5xx)
+var ErrServerUnavailable = errors.New("server unavailable")
+
+// ErrNotFound is returned when the server does not offer an endpoint (404)
+var ErrNotFound = errors.New("endpoint not found")
+
+// maxRetryAfter caps how long a Retry-After header can pause sending
+const maxRetryAfter = time.Hour
+
+// ThrottledError is returned when the server asks the agent to back off (429
+// or 503 with Retry-After). It wraps ErrServerUnavailable, so it is retryable.
+type ThrottledError struct {
+	RetryAfter time.Duration
+	StatusCode int
+}
+
+func (e *ThrottledError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%v: rate limited (status %d, retry after %s)", ErrServerUnavailable, e.StatusCode, e.RetryAfter)
+	}
+	return fmt.Sprintf("%v: rate limited (status %d)", ErrServerUnavailable, e.StatusCode)
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrServerUnavailable
+}
+
+// RetryAfter returns how long the server asked the agent to wait, if err carries a Retry-After
+func RetryAfter(err error) (time.Duration, bool) {
+	var throttled *ThrottledError
+	if errors.As(err, &throttled) && throttled.RetryAfter > 0 {
+		return throttled.RetryAfter, true
+	}
+	return 0, false
+}
+
+// parseRetryAfter reads a Retry-After header given as seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = date.Sub(now)
+	}
+
+	if wait < 0 {
+		return 0
+	}
+	return min(wait, maxRetryAfter)
+}
+
+// IsRetryable reports whether a send error is transient, so the payload may
+// succeed if sent again later
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrNetwork) || errors.Is(err, ErrCertificate) || errors.Is(err, ErrServerUnavailable)
+}
+
 // HTTPSender sends metrics via HTTP/HTTPS
 type HTTPSender struct {
-	serverURL string
-	token     string
-	client    *http.Client
+	serverURL  string
+	token      string
+	ca         *caBundle    // nil when only system roots are trusted
+	cert       *clientCert  // nil when mutual TLS is not configured
+	signer     *signer      // nil when request signing is disabled
+	tokens     *TokenSource // nil when the static token is used
+	compressor *compressor
+
+	mu           sync.Mutex
+	client       *http.Client
+	dnsRefreshed time.Time // Last time pooled connections were dropped to re-resolve the server
 }
 
 // NewHTTPSender creates a new HTTP sender
-func NewHTTPSender(serverURL, token string) *HTTPSender {
-	// Create HTTP client with connection pooling
-	client := &http.Client{
-		Timeout: config.Timeout,
+func NewHTTPSender(serverURL, token string) (*HTTPSender, error) {
+	h := &HTTPSender{
+		serverURL: serverURL,
+		token:     token,
+		signer:    newSigner(config.GetHMACSecret()),
+	}
+
+	// Request body compression
+	c, err := newCompressor(config.GetCompression(), config.GetCompressionLevel())
+	if err != nil {
+		return nil, err
+	}
+	h.compressor = c
+
+	// Load custom CA bundle if configured
+	var rootCAs *x509.CertPool
+	if path := config.GetCACertPath(); path != "" {
+		h.ca = newCABundle(path)
+		pool, err := h.ca.load()
+		if err != nil {
+			return nil, err
+		}
+		rootCAs = pool
+	}
+
+	// Load client certificate for mutual TLS if configured
+	if h.cert, err = configuredClientCert(); err != nil {
+		return nil, err
+	}
+
+	h.client = newHTTPClient(applyServerTLSOptions(newTLSConfig(rootCAs, h.cert)))
+
+	return h, nil
+}
+
+// NewTenantHTTPSender creates a sender for another organization's server. It
+// trusts the system roots only and sends none of the main server's
+// credentials: no request signature, client certificate or TLS overrides.
+func NewTenantHTTPSender(serverURL, token string) (*HTTPSender, error) {
+	c, err := newCompressor(config.GetCompression(), config.GetCompressionLevel())
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPSender{
+		serverURL:  serverURL,
+		token:      token,
+		compressor: c,
+		client:     newHTTPClient(newTLSConfig(nil, nil)),
+	}, nil
+}
+
+// SetTokenSource switches from the static token to short-lived access tokens
+func (h *HTTPSender) SetTokenSource(tokens *TokenSource) {
+	h.tokens = tokens
+}
+
+// authToken returns the bearer token for the next request
+func (h *HTTPSender) authToken(ctx context.Context) (string, error) {
+	if h.tokens == nil {
+		return h.token, nil
+	}
+	return h.tokens.Token(ctx)
+}
+
+// newHTTPClient creates an HTTP client with connection pooling
+func newHTTPClient(tlsConfig *tls.Config) *http.Client {
+	return &http.Client{
+		Timeout: config.GetTimeout(),
 		Transport: &http.Transport{
+			DialContext:         ServerDialFunc(),
+			TLSClientConfig:     tlsConfig,
 			MaxIdleConns:        100,
 			MaxIdleConnsPerHost: 10,
 			IdleConnTimeout:     90 * time.Second,
 		},
 	}
+}
 
-	return &HTTPSender{
-		serverURL: serverURL,
-		token:     token,
-		client:    client,
+// ServerDialFunc returns the dial function for server connections. It pins
+// the configured address family; otherwise both families are raced (Happy
+// Eyeballs) so a broken IPv6 path does not stall sends.
+func ServerDialFunc() func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: config.GetTimeout(), FallbackDelay: config.HappyEyeballsDelay}
+	family := ""
+	switch config.GetIPFamily() {
+	case "ipv4":
+		family = "tcp4"
+	case "ipv6":
+		family = "tcp6"
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if family != "" {
+			network = family
+		}
+		return proxy.DialContext(ctx, dialer, network, addr)
 	}
 }
 
+// getClient returns the current HTTP client, reloading the CA bundle if it
+// changed and periodically dropping pooled connections so the server name is
+// resolved again (the server may have moved to new IPs)
+func (h *HTTPSender) getClient() *http.Client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if interval := config.GetDNSRefreshInterval(); interval > 0 && time.Since(h.dnsRefreshed) >= interval {
+		if !h.dnsRefreshed.IsZero() {
+			h.client.CloseIdleConnections()
+		}
+		h.dnsRefreshed = time.Now()
+	}
+
+	if h.ca == nil || !h.ca.changed() {
+		return h.client
+	}
+
+	pool, err := h.ca.load()
+	if err != nil {
+		// Keep using the last good bundle
+		log.Printf("WARN: Failed to reload CA bundle, keeping previous one: %v", err)
+		return h.client
+	}
+
+	log.Printf("INFO: Reloaded CA bundle [path=%s]", h.ca.path)
+	old := h.client
+	h.client = newHTTPClient(applyServerTLSOptions(newTLSConfig(pool, h.cert)))
+	old.CloseIdleConnections()
+
+	return h.client
+}
+
+// classifyRequestError distinguishes certificate problems from network problems
+func classifyRequestError(err error) error {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCert x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalidCert) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &verifyErr) {
+		return fmt.Errorf("%w (check MONIFY_CA_CERT, MONIFY_TLS_SERVER_NAME or TLS interception by a proxy): %w", ErrCertificate, err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", ErrNetwork, err)
+	}
+
+	return fmt.Errorf("request failed: %w", err)
+}
+
 // Send sends a single metric payload
 func (h *HTTPSender) Send(ctx context.Context, payload *models.MetricPayload) (*models.ServerResponse, error) {
 	if payload == nil {
 		return nil, nil
 	}
+	return h.post(ctx, payload, 1)
+}
+
+// SendBatch sends several payloads as a JSON array in a single request
+func (h *HTTPSender) SendBatch(ctx context.Context, payloads []*models.MetricPayload) (*models.ServerResponse, error) {
+	if len(payloads) == 0 {
+		return nil, nil
+	}
+	return h.post(ctx, payloads, len(payloads))
+}
 
+// SendHeartbeat posts a heartbeat instead of a metric payload
+func (h *HTTPSender) SendHeartbeat(ctx context.Context, heartbeat *models.Heartbeat) error {
+	_, err := h.post(ctx, heartbeat, 0)
+	return err
+}
+
+// Register posts the agent's registration and returns the server's answer
+func (h *HTTPSender) Register(ctx context.Context, registration *models.Registration) (*models.ServerResponse, error) {
+	return h.post(ctx, registration, 0)
+}
+
+// SendDiagnostics posts a diagnostics report instead of a metric payload
+func (h *HTTPSender) SendDiagnostics(ctx context.Context, report *models.DiagnosticsReport) error {
+	_, err := h.post(ctx, report, 0)
+	return err
+}
+
+// post marshals, compresses and sends a payload or payload array
+func (h *HTTPSender) post(ctx context.Context, body any, count int) (*models.ServerResponse, error) {
 	// Marshal to JSON
-	data, err := json.Marshal(payload)
+	data, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	// Compress with gzip
-	var buf bytes.Buffer
-	gzipWriter := gzip.NewWriter(&buf)
-	if _, err := gzipWriter.Write(data); err != nil {
-		return nil, fmt.Errorf("failed to compress data: %w", err)
-	}
-	if err := gzipWriter.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
-	}
-
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, "POST", h.serverURL, &buf)
+	token, err := h.authToken(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Content-Encoding", "gzip")
-	req.Header.Set("User-Agent", fmt.Sprintf("monify/%s", config.Version))
-	req.Header.Set("X-Agent-Version", config.Version)
+	// One trace ID per send, shared by the retries below, to correlate with server logs
+	traceID := newTraceID()
 
-	// Set authentication if token is configured
-	if h.token != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", h.token))
+	encoding := h.compressor.encoding()
+	statusCode, header, respBody, err := h.do(ctx, data, encoding, token, traceID, count)
+	if err != nil {
+		return nil, traceError(err, traceID, nil)
 	}
 
-	// Send request
-	resp, err := h.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+	// Access token expired or was revoked: refresh once before giving up
+	if statusCode == http.StatusUnauthorized && h.tokens != nil {
+		if token, err = h.tokens.Refresh(ctx, token); err != nil {
+			return nil, err
+		}
+		statusCode, header, respBody, err = h.do(ctx, data, encoding, token, traceID, count)
+		if err != nil {
+			return nil, traceError(err, traceID, nil)
+		}
 	}
-	defer resp.Body.Close()
 
-	// Read response body
-	respBody, _ := io.ReadAll(resp.Body)
+	// Server stopped accepting the negotiated encoding: fall back to gzip
+	if statusCode == http.StatusUnsupportedMediaType && h.compressor.reject(encoding) {
+		log.Printf("WARN: Server rejected %s encoding, falling back to gzip", encoding)
+		statusCode, header, respBody, err = h.do(ctx, data, encodingGzip, token, traceID, count)
+		if err != nil {
+			return nil, traceError(err, traceID, nil)
+		}
+	}
 
 	// Check status code
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+	if statusCode >= 200 && statusCode < 300 {
 		// Parse server response for commands
 		var serverResp models.ServerResponse
 		if err := json.Unmarshal(respBody, &serverResp); err != nil {
@@ -104,21 +346,115 @@ func (h *HTTPSender) Send(ctx context.Context, payload *models.MetricPayload) (*
 		return &serverResp, nil
 	}
 
-	// Handle different error codes
-	switch resp.StatusCode {
+	return nil, traceError(statusError(statusCode, header, respBody), traceID, header)
+}
+
+// statusError maps a non-2xx response to an error
+func statusError(statusCode int, header http.Header, respBody []byte) error {
+	switch statusCode {
 	case http.StatusUnauthorized:
-		return nil, ErrUnauthorized
+		return ErrUnauthorized
 	case http.StatusBadRequest:
-		return nil, fmt.Errorf("bad request: %s", string(respBody))
+		return fmt.Errorf("bad request: %s", string(respBody))
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, string(respBody))
 	case http.StatusTooManyRequests:
-		return nil, fmt.Errorf("rate limited")
+		return &ThrottledError{RetryAfter: parseRetryAfter(header.Get("Retry-After"), time.Now()), StatusCode: statusCode}
+	case http.StatusServiceUnavailable:
+		if retryAfter := parseRetryAfter(header.Get("Retry-After"), time.Now()); retryAfter > 0 {
+			return &ThrottledError{RetryAfter: retryAfter, StatusCode: statusCode}
+		}
+		return fmt.Errorf("%w: status code %d: %s", ErrServerUnavailable, statusCode, string(respBody))
 	default:
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(respBody))
+		if statusCode >= 500 {
+			return fmt.Errorf("%w: status code %d: %s", ErrServerUnavailable, statusCode, string(respBody))
+		}
+		return fmt.Errorf("unexpected status code %d: %s", statusCode, string(respBody))
 	}
 }
 
+// newTraceID returns a random 128-bit trace ID in hex
+func newTraceID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// traceError annotates err with the trace ID sent and the request ID the
+// server returned, if any, so failed sends can be matched with server logs
+func traceError(err error, traceID string, header http.Header) error {
+	if requestID := header.Get("X-Request-Id"); requestID != "" {
+		return fmt.Errorf("%w [trace_id=%s request_id=%s]", err, traceID, requestID)
+	}
+	return fmt.Errorf("%w [trace_id=%s]", err, traceID)
+}
+
+// do compresses data with the given encoding, sends it and returns the status, headers and body
+func (h *HTTPSender) do(ctx context.Context, data []byte, encoding, token, traceID string, count int) (int, http.Header, []byte, error) {
+	req, err := h.newRequest(ctx, data, encoding, token, traceID, count)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	// Send request
+	client := h.getClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		// Pooled connections may point at the same dead IP: re-resolve on the next send
+		client.CloseIdleConnections()
+		return 0, nil, nil, classifyRequestError(err)
+	}
+	defer resp.Body.Close()
+
+	h.compressor.observe(resp)
+
+	// Read response body
+	respBody, _ := io.ReadAll(resp.Body)
+
+	return resp.StatusCode, resp.Header, respBody, nil
+}
+
+// newRequest compresses data with the given encoding and creates the
+// authenticated, signed POST request carrying it
+func (h *HTTPSender) newRequest(ctx context.Context, data []byte, encoding, token, traceID string, count int) (*http.Request, error) {
+	compressed, err := h.compressor.compress(encoding, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, "POST", h.serverURL, bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", encoding)
+	req.Header.Set("User-Agent", fmt.Sprintf("monify/%s", config.Version))
+	req.Header.Set("X-Agent-Version", config.Version)
+	req.Header.Set("X-Payload-Count", strconv.Itoa(count))
+	req.Header.Set("X-Trace-Id", traceID)
+
+	// Set authentication if token is configured
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	// Sign the body if a shared secret is configured
+	if h.signer != nil {
+		if err := h.signer.sign(req, compressed); err != nil {
+			return nil, err
+		}
+	}
+
+	return req, nil
+}
+
 // Close closes the HTTP client
 func (h *HTTPSender) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.client.CloseIdleConnections()
 	return nil
 }
@@ -0,0 +1,123 @@
+package sender
+
+import (
+	"sort"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// metricPoint is a single numeric value derived from dynamic metrics,
+// shared by the exporters that speak other time-series formats
+type metricPoint struct {
+	name   string      // Snake case, without prefix (e.g., cpu_usage_percent)
+	labels [][2]string // Dimensions in order (e.g., mountpoint, path)
+	value  float64
+}
+
+// metricPoints flattens dynamic metrics into numeric points
+func metricPoints(m *models.DynamicMetrics) []metricPoint {
+	if m == nil {
+		return nil
+	}
+
+	var points []metricPoint
+	add := func(name string, value float64, labels ...string) {
+		point := metricPoint{name: name, value: value}
+		for i := 0; i+1 < len(labels); i += 2 {
+			point.labels = append(point.labels, [2]string{labels[i], labels[i+1]})
+		}
+		points = append(points, point)
+	}
+
+	if m.CPU != nil {
+		add("cpu_usage_percent", m.CPU.UsagePercent)
+		add("load1", m.CPU.LoadAvg1m)
+		add("load5", m.CPU.LoadAvg5m)
+		add("load15", m.CPU.LoadAvg15m)
+	}
+	if m.Memory != nil {
+		add("memory_total_bytes", float64(m.Memory.Total))
+		add("memory_used_bytes", float64(m.Memory.Used))
+		add("memory_available_bytes", float64(m.Memory.Available))
+		add("memory_cached_bytes", float64(m.Memory.Cached))
+		add("memory_buffers_bytes", float64(m.Memory.Buffers))
+		add("memory_used_percent", m.Memory.UsedPercent)
+	}
+	if m.Swap != nil {
+		add("swap_total_bytes", float64(m.Swap.Total))
+		add("swap_used_bytes", float64(m.Swap.Used))
+	}
+	if m.DiskSpace != nil {
+		add("disk_total_bytes", float64(m.DiskSpace.Total))
+		add("disk_used_bytes", float64(m.DiskSpace.Used))
+		add("disk_free_bytes", float64(m.DiskSpace.Free))
+		add("disk_used_percent", m.DiskSpace.UsedPercent)
+	}
+	for _, fs := range m.DiskGrowth {
+		add("filesystem_growth_bytes_per_hour", fs.GrowthBytesPerHour, "mountpoint", fs.Mount)
+		if fs.HoursUntilFull != nil {
+			add("filesystem_hours_until_full", *fs.HoursUntilFull, "mountpoint", fs.Mount)
+		}
+	}
+	if m.DiskIO != nil {
+		add("disk_read_mbps", m.DiskIO.ReadMBps)
+		add("disk_write_mbps", m.DiskIO.WriteMBps)
+		add("disk_read_iops", m.DiskIO.ReadIOPS)
+		add("disk_write_iops", m.DiskIO.WriteIOPS)
+	}
+	for scope, traffic := range map[string]*models.NetworkAggregateMetrics{"public": m.NetworkPublic, "private": m.NetworkPrivate} {
+		if traffic != nil {
+			add("network_send_mbps", traffic.SendMbps, "scope", scope)
+			add("network_recv_mbps", traffic.RecvMbps, "scope", scope)
+		}
+	}
+	if m.NetworkHealth != nil {
+		add("network_errors_total", float64(m.NetworkHealth.ErrorsIn), "direction", "in")
+		add("network_errors_total", float64(m.NetworkHealth.ErrorsOut), "direction", "out")
+		add("network_drops_total", float64(m.NetworkHealth.DropsIn), "direction", "in")
+		add("network_drops_total", float64(m.NetworkHealth.DropsOut), "direction", "out")
+	}
+	if m.Gateway != nil {
+		add("gateway_reachable", boolValue(m.Gateway.Reachable), "gateway", m.Gateway.Gateway)
+	}
+	for _, probe := range m.Probes {
+		add("probe_up", boolValue(probe.Up), "target", probe.Target)
+	}
+	for _, mount := range m.NetworkMounts {
+		add("network_mount_available", boolValue(mount.Available), "mountpoint", mount.Mount)
+	}
+	for _, dir := range m.Directories {
+		add("directory_size_bytes", float64(dir.Size), "path", dir.Path)
+		add("directory_files", float64(dir.Files), "path", dir.Path)
+	}
+	for _, plugin := range m.Plugins {
+		for _, metric := range plugin.Metrics {
+			labels := []string{"plugin", plugin.Name}
+			names := make([]string, 0, len(metric.Labels))
+			for name := range metric.Labels {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				labels = append(labels, name, metric.Labels[name])
+			}
+			add("plugin_"+metric.Name, metric.Value, labels...)
+		}
+	}
+	if m.System != nil {
+		add("uptime_seconds", float64(m.System.Uptime))
+		add("processes", float64(m.System.ProcessCount))
+		add("procs_running", float64(m.System.ProcsRunning))
+		add("procs_blocked", float64(m.System.ProcsBlocked))
+	}
+
+	return points
+}
+
+// boolValue converts a boolean to a 0/1 value
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
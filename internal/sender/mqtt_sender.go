@@ -0,0 +1,351 @@
+package sender
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// MQTT 3.1.1 control packet types
+const (
+	mqttConnect    = 1
+	mqttConnAck    = 2
+	mqttPublish    = 3
+	mqttPubAck     = 4
+	mqttDisconnect = 14
+)
+
+// MQTTSender publishes payloads to an MQTT broker with QoS 1, one topic per
+// host (<topic>/<hostname>). Payloads are gzip-compressed JSON. The broker is
+// responsible for forwarding them to Monify, so server commands are not
+// received over MQTT.
+type MQTTSender struct {
+	brokerURL *url.URL
+	topic     string
+	token     string
+	clientID  string
+	tlsConfig *tls.Config // nil for plain mqtt://
+	gzip      *compressor
+
+	mu       sync.Mutex
+	conn     net.Conn
+	reader   *bufio.Reader
+	packetID uint16
+}
+
+// NewMQTTSender creates a new MQTT sender for an mqtt:// or mqtts:// broker URL.
+// Credentials in the URL take precedence; otherwise the token is sent as password.
+func NewMQTTSender(brokerURL, topic, token string) (*MQTTSender, error) {
+	u, err := url.Parse(brokerURL)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid MQTT broker URL %q", brokerURL)
+	}
+	if strings.ContainsAny(topic, "+#\x00") {
+		return nil, fmt.Errorf("invalid MQTT topic %q: wildcards are not allowed in published topics", topic)
+	}
+
+	m := &MQTTSender{
+		brokerURL: u,
+		topic:     strings.TrimSuffix(topic, "/"),
+		token:     token,
+	}
+
+	switch u.Scheme {
+	case "mqtt", "tcp":
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), "1883")
+		}
+	case "mqtts", "ssl", "tls":
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), "8883")
+		}
+		var rootCAs *x509.CertPool
+		if path := config.GetCACertPath(); path != "" {
+			if rootCAs, err = newCABundle(path).load(); err != nil {
+				return nil, err
+			}
+		}
+		cert, err := configuredClientCert()
+		if err != nil {
+			return nil, err
+		}
+		m.tlsConfig = newTLSConfig(rootCAs, cert)
+		m.tlsConfig.ServerName = u.Hostname()
+		applyServerTLSOptions(m.tlsConfig)
+	default:
+		return nil, fmt.Errorf("unsupported MQTT scheme %q (use mqtt:// or mqtts://)", u.Scheme)
+	}
+
+	hostname, _ := os.Hostname()
+	m.clientID = "monify-" + hostname
+
+	if m.gzip, err = newCompressor(encodingGzip, 0); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Send publishes a single metric payload
+func (m *MQTTSender) Send(ctx context.Context, payload *models.MetricPayload) (*models.ServerResponse, error) {
+	if payload == nil {
+		return nil, nil
+	}
+	return m.publish(ctx, payload.Hostname, payload)
+}
+
+// SendBatch publishes several payloads as a JSON array in a single message
+func (m *MQTTSender) SendBatch(ctx context.Context, payloads []*models.MetricPayload) (*models.ServerResponse, error) {
+	if len(payloads) == 0 {
+		return nil, nil
+	}
+	return m.publish(ctx, payloads[0].Hostname, payloads)
+}
+
+// publish sends a QoS 1 message and waits for the broker's acknowledgement
+func (m *MQTTSender) publish(ctx context.Context, hostname string, body any) (*models.ServerResponse, error) {
+	level := mqttTopicLevel(hostname)
+	if level == "" {
+		return nil, fmt.Errorf("cannot publish to MQTT: payload has no hostname")
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	compressed, err := m.gzip.compress(encodingGzip, data)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn == nil {
+		if err := m.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	deadline := time.Now().Add(config.GetTimeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	m.conn.SetDeadline(deadline)
+
+	m.packetID++
+	if m.packetID == 0 {
+		m.packetID = 1 // 0 is not a valid packet identifier
+	}
+
+	// Variable header: topic, packet identifier
+	topic := m.topic + "/" + level
+	packet := appendMQTTString(nil, topic)
+	packet = binary.BigEndian.AppendUint16(packet, m.packetID)
+	packet = append(packet, compressed...)
+
+	if err := m.writePacket(mqttPublish<<4|0x02, packet); err != nil {
+		m.closeLocked()
+		return nil, fmt.Errorf("%w: MQTT publish failed: %w", ErrNetwork, err)
+	}
+
+	// Wait for the matching PUBACK, skipping unrelated packets
+	for {
+		packetType, body, err := m.readPacket()
+		if err != nil {
+			m.closeLocked()
+			return nil, fmt.Errorf("%w: no MQTT acknowledgement: %w", ErrNetwork, err)
+		}
+		if packetType == mqttPubAck && len(body) >= 2 && binary.BigEndian.Uint16(body) == m.packetID {
+			return &models.ServerResponse{Status: "success"}, nil
+		}
+	}
+}
+
+// connect opens the connection and performs the MQTT handshake. Caller must hold m.mu.
+func (m *MQTTSender) connect(ctx context.Context) error {
+	conn, err := ServerDialFunc()(ctx, "tcp", m.brokerURL.Host)
+	if err != nil {
+		return classifyRequestError(err)
+	}
+	if m.tlsConfig != nil {
+		tlsConn := tls.Client(conn, m.tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return classifyRequestError(err)
+		}
+		conn = tlsConn
+	}
+	conn.SetDeadline(time.Now().Add(config.GetTimeout()))
+
+	m.conn = conn
+	m.reader = bufio.NewReader(conn)
+
+	username, password := "monify", m.token
+	if user := m.brokerURL.User; user != nil {
+		username = user.Username()
+		password, _ = user.Password()
+	}
+	if username == "" && password != "" {
+		// MQTT 3.1.1 does not allow a password without a username
+		username = "monify"
+	}
+
+	// Variable header: protocol name, level 4 (3.1.1), flags, keep alive disabled.
+	// Dead connections are detected by missing PUBACKs instead of pings.
+	packet := appendMQTTString(nil, "MQTT")
+	packet = append(packet, 4)
+	flags := byte(0x02) // Clean session; the spool handles redelivery
+	if username != "" {
+		flags |= 0x80
+	}
+	if password != "" {
+		flags |= 0x40
+	}
+	packet = append(packet, flags, 0, 0)
+
+	// Payload: client identifier, username, password
+	packet = appendMQTTString(packet, m.clientID)
+	if username != "" {
+		packet = appendMQTTString(packet, username)
+	}
+	if password != "" {
+		packet = appendMQTTString(packet, password)
+	}
+
+	if err := m.writePacket(mqttConnect<<4, packet); err != nil {
+		m.closeLocked()
+		return fmt.Errorf("%w: MQTT connect failed: %w", ErrNetwork, err)
+	}
+
+	packetType, body, err := m.readPacket()
+	if err != nil {
+		m.closeLocked()
+		return fmt.Errorf("%w: no MQTT CONNACK: %w", ErrNetwork, err)
+	}
+	if packetType != mqttConnAck || len(body) < 2 {
+		m.closeLocked()
+		return fmt.Errorf("%w: unexpected MQTT packet type %d", ErrNetwork, packetType)
+	}
+
+	switch body[1] {
+	case 0:
+		return nil
+	case 4, 5: // Bad username or password, not authorized
+		m.closeLocked()
+		return ErrUnauthorized
+	default:
+		m.closeLocked()
+		return fmt.Errorf("%w: MQTT broker refused connection (code %d)", ErrServerUnavailable, body[1])
+	}
+}
+
+// writePacket writes a control packet with the given first header byte
+func (m *MQTTSender) writePacket(header byte, body []byte) error {
+	packet := append([]byte{header}, encodeMQTTLength(len(body))...)
+	packet = append(packet, body...)
+	_, err := m.conn.Write(packet)
+	return err
+}
+
+// readPacket reads a control packet and returns its type and body
+func (m *MQTTSender) readPacket() (byte, []byte, error) {
+	header, err := m.reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Remaining length: up to 4 bytes, 7 bits each
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed MQTT remaining length")
+		}
+		b, err := m.reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(m.reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header >> 4, body, nil
+}
+
+// encodeMQTTLength encodes the remaining length field
+func encodeMQTTLength(n int) []byte {
+	var out []byte
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if n == 0 {
+			return out
+		}
+	}
+}
+
+// mqttTopicLevel turns a hostname into a single topic level: wildcards,
+// level separators and NUL would make the topic invalid or match other hosts
+func mqttTopicLevel(hostname string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '+', '#', '/', 0:
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(hostname))
+}
+
+// appendMQTTString appends a length-prefixed UTF-8 string
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// closeLocked drops the connection so the next send reconnects. Caller must hold m.mu.
+func (m *MQTTSender) closeLocked() {
+	if m.conn != nil {
+		m.conn.Close()
+		m.conn = nil
+		m.reader = nil
+	}
+}
+
+// Close disconnects from the broker
+func (m *MQTTSender) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn != nil {
+		m.conn.SetDeadline(time.Now().Add(time.Second))
+		m.writePacket(mqttDisconnect<<4, nil)
+		m.closeLocked()
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package sender
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http/httptrace"
+	"time"
+)
+
+// PingResult describes a test request to the server
+type PingResult struct {
+	URL        string        `json:"url"`
+	RemoteAddr string        `json:"remote_addr,omitempty"` // Address connected to: the server, or the SOCKS5 proxy
+	StatusCode int           `json:"status_code,omitempty"` // Zero if no response was received
+	Status     string        `json:"status,omitempty"`
+	Latency    time.Duration `json:"-"`
+	LatencyMs  float64       `json:"latency_ms"`
+	TraceID    string        `json:"trace_id"`
+	RequestID  string        `json:"request_id,omitempty"` // From the X-Request-Id response header
+
+	// TLS details, empty for plain HTTP
+	TLSVersion  string    `json:"tls_version,omitempty"`
+	CipherSuite string    `json:"cipher_suite,omitempty"`
+	CertSubject string    `json:"cert_subject,omitempty"`
+	CertIssuer  string    `json:"cert_issuer,omitempty"`
+	CertExpiry  time.Time `json:"cert_expiry,omitzero"`
+}
+
+// Ping posts an empty payload array with the configured authentication, so
+// the server checks the token without storing anything, and reports the
+// response and connection details. A non-2xx answer is returned as the error
+// together with the result.
+func (h *HTTPSender) Ping(ctx context.Context) (*PingResult, error) {
+	result := &PingResult{URL: h.serverURL, TraceID: newTraceID()}
+
+	token, err := h.authToken(ctx)
+	if err != nil {
+		return result, err
+	}
+	req, err := h.newRequest(ctx, []byte("[]"), h.compressor.encoding(), token, result.TraceID, 0)
+	if err != nil {
+		return result, err
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			result.RemoteAddr = info.Conn.RemoteAddr().String()
+		},
+	}))
+
+	start := time.Now()
+	resp, err := h.getClient().Do(req)
+	result.Latency = time.Since(start)
+	result.LatencyMs = float64(result.Latency.Microseconds()) / 1000
+	if err != nil {
+		return result, classifyRequestError(err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+
+	result.StatusCode = resp.StatusCode
+	result.Status = resp.Status
+	result.RequestID = resp.Header.Get("X-Request-Id")
+	if state := resp.TLS; state != nil {
+		result.TLSVersion = tls.VersionName(state.Version)
+		result.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+		if len(state.PeerCertificates) > 0 {
+			cert := state.PeerCertificates[0]
+			result.CertSubject = cert.Subject.String()
+			result.CertIssuer = cert.Issuer.String()
+			result.CertExpiry = cert.NotAfter
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, statusError(resp.StatusCode, resp.Header, respBody)
+	}
+	return result, nil
+}
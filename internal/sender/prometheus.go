@@ -0,0 +1,89 @@
+package sender
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// prometheusEscaper escapes label values in the text exposition format
+var prometheusEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// PrometheusMetric is a single sample in the Prometheus text exposition format
+type PrometheusMetric struct {
+	Name   string
+	Type   string      // "gauge" or "counter"
+	Labels [][2]string // Name/value pairs in output order
+	Value  float64
+}
+
+// PrometheusMetrics converts the dynamic metrics of a payload into gauges
+// prefixed with monify_, labeled with the payload's host labels
+func PrometheusMetrics(payload *models.MetricPayload) []PrometheusMetric {
+	if payload == nil {
+		return nil
+	}
+
+	var hostLabels [][2]string
+	for name, value := range payload.Labels {
+		hostLabels = append(hostLabels, [2]string{invalidLabelChars.ReplaceAllString(name, "_"), value})
+	}
+	sort.Slice(hostLabels, func(i, j int) bool { return hostLabels[i][0] < hostLabels[j][0] })
+
+	var metrics []PrometheusMetric
+	for _, point := range metricPoints(payload.DynamicMetrics) {
+		// Metric dimensions win over host labels of the same name
+		labels := append([][2]string{}, point.labels...)
+		for _, label := range hostLabels {
+			if !slices.ContainsFunc(point.labels, func(l [2]string) bool { return l[0] == label[0] }) {
+				labels = append(labels, label)
+			}
+		}
+		metrics = append(metrics, PrometheusMetric{
+			Name:   "monify_" + point.name,
+			Type:   "gauge",
+			Labels: labels,
+			Value:  point.value,
+		})
+	}
+	return metrics
+}
+
+// WritePrometheus writes metrics in the text exposition format. Samples of
+// the same name are grouped under one TYPE line, in first-seen order.
+func WritePrometheus(w io.Writer, metrics []PrometheusMetric) error {
+	var order []string
+	groups := make(map[string][]PrometheusMetric)
+	for _, metric := range metrics {
+		if _, ok := groups[metric.Name]; !ok {
+			order = append(order, metric.Name)
+		}
+		groups[metric.Name] = append(groups[metric.Name], metric)
+	}
+
+	buf := bufio.NewWriter(w)
+	for _, name := range order {
+		group := groups[name]
+		buf.WriteString("# TYPE " + name + " " + group[0].Type + "\n")
+		for _, metric := range group {
+			buf.WriteString(name)
+			if len(metric.Labels) > 0 {
+				buf.WriteByte('{')
+				for i, label := range metric.Labels {
+					if i > 0 {
+						buf.WriteByte(',')
+					}
+					buf.WriteString(label[0] + `="` + prometheusEscaper.Replace(label[1]) + `"`)
+				}
+				buf.WriteByte('}')
+			}
+			buf.WriteString(" " + strconv.FormatFloat(metric.Value, 'g', -1, 64) + "\n")
+		}
+	}
+	return buf.Flush()
+}
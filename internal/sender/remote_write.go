@@ -0,0 +1,212 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// RemoteWriteSender translates dynamic metrics into Prometheus remote_write
+// (protocol 1.0: snappy-compressed protobuf WriteRequest) samples
+type RemoteWriteSender struct {
+	url    string
+	token  string // Bearer token, optional
+	client *http.Client
+}
+
+// NewRemoteWriteSender creates a new remote_write sender. Basic auth
+// credentials may be given in the URL.
+func NewRemoteWriteSender(endpoint, token string) (*RemoteWriteSender, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid remote_write URL %q", endpoint)
+	}
+
+	return &RemoteWriteSender{
+		url:    endpoint,
+		token:  token,
+		client: newHTTPClient(newTLSConfig(nil, nil)),
+	}, nil
+}
+
+// Send writes the samples of a single payload
+func (r *RemoteWriteSender) Send(ctx context.Context, payload *models.MetricPayload) (*models.ServerResponse, error) {
+	if payload == nil {
+		return nil, nil
+	}
+	return r.SendBatch(ctx, []*models.MetricPayload{payload})
+}
+
+// SendBatch writes the samples of several payloads in one request
+func (r *RemoteWriteSender) SendBatch(ctx context.Context, payloads []*models.MetricPayload) (*models.ServerResponse, error) {
+	series := newSeriesSet()
+	for _, payload := range payloads {
+		appendPayloadSeries(series, payload)
+	}
+	if len(series.order) == 0 {
+		return &models.ServerResponse{Status: "success"}, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.url, bytes.NewReader(s2.EncodeSnappy(nil, series.marshal())))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", fmt.Sprintf("monify/%s", config.Version))
+	if r.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", r.token))
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, classifyRequestError(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return &models.ServerResponse{Status: "success"}, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("remote_write authentication failed: %s", strings.TrimSpace(string(body)))
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, fmt.Errorf("%w: remote_write status code %d: %s", ErrServerUnavailable, resp.StatusCode, strings.TrimSpace(string(body)))
+	default:
+		return nil, fmt.Errorf("remote_write rejected samples (status code %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+}
+
+// Close closes the HTTP client
+func (r *RemoteWriteSender) Close() error {
+	r.client.CloseIdleConnections()
+	return nil
+}
+
+// invalidLabelChars matches characters not allowed in Prometheus label names
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// appendPayloadSeries converts the dynamic metrics of a payload into samples
+func appendPayloadSeries(series *seriesSet, payload *models.MetricPayload) {
+	// Host labels first so the built-in instance/job labels win on conflict
+	base := map[string]string{}
+	for name, value := range payload.Labels {
+		base[invalidLabelChars.ReplaceAllString(name, "_")] = value
+	}
+	base["instance"] = payload.Hostname
+	base["job"] = "monify"
+
+	ts := payload.Timestamp.UnixMilli()
+	for _, point := range metricPoints(payload.DynamicMetrics) {
+		labels := make(map[string]string, len(base)+len(point.labels)+1)
+		for k, v := range base {
+			labels[k] = v
+		}
+		for _, label := range point.labels {
+			labels[label[0]] = label[1]
+		}
+		labels["__name__"] = "monify_" + point.name
+		series.add(labels, ts, point.value)
+	}
+}
+
+// remoteSample is a single timestamped value
+type remoteSample struct {
+	timestamp int64 // Unix milliseconds
+	value     float64
+}
+
+// remoteSeries is a label set and its samples in time order
+type remoteSeries struct {
+	labels  [][2]string // Sorted by name
+	samples []remoteSample
+}
+
+// seriesSet groups samples by label set, keeping first-seen order
+type seriesSet struct {
+	series map[string]*remoteSeries
+	order  []string
+}
+
+// newSeriesSet creates an empty series set
+func newSeriesSet() *seriesSet {
+	return &seriesSet{series: make(map[string]*remoteSeries)}
+}
+
+// add appends a sample to the series identified by labels
+func (s *seriesSet) add(labels map[string]string, timestamp int64, value float64) {
+	sorted := make([][2]string, 0, len(labels))
+	for name, v := range labels {
+		if v != "" {
+			sorted = append(sorted, [2]string{name, v})
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+
+	var key strings.Builder
+	for _, label := range sorted {
+		key.WriteString(label[0] + "\xff" + label[1] + "\xff")
+	}
+
+	existing, ok := s.series[key.String()]
+	if !ok {
+		existing = &remoteSeries{labels: sorted}
+		s.series[key.String()] = existing
+		s.order = append(s.order, key.String())
+	}
+	existing.samples = append(existing.samples, remoteSample{timestamp: timestamp, value: value})
+}
+
+// marshal encodes the set as a prometheus.WriteRequest protobuf message:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func (s *seriesSet) marshal() []byte {
+	var out []byte
+	for _, key := range s.order {
+		series := s.series[key]
+
+		var ts []byte
+		for _, label := range series.labels {
+			var l []byte
+			l = appendProtoBytes(l, 1, []byte(label[0]))
+			l = appendProtoBytes(l, 2, []byte(label[1]))
+			ts = appendProtoBytes(ts, 1, l)
+		}
+		for _, sample := range series.samples {
+			var sm []byte
+			sm = binary.AppendUvarint(sm, 1<<3|1) // Field 1, 64-bit
+			sm = binary.LittleEndian.AppendUint64(sm, math.Float64bits(sample.value))
+			sm = binary.AppendUvarint(sm, 2<<3|0) // Field 2, varint
+			sm = binary.AppendUvarint(sm, uint64(sample.timestamp))
+			ts = appendProtoBytes(ts, 2, sm)
+		}
+
+		out = appendProtoBytes(out, 1, ts)
+	}
+	return out
+}
+
+// appendProtoBytes appends a length-delimited protobuf field
+func appendProtoBytes(b []byte, field int, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
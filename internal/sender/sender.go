@@ -11,6 +11,9 @@ type Sender interface {
 	// Send sends a metric payload to the server
 	Send(ctx context.Context, payload *models.MetricPayload) (*models.ServerResponse, error)
 
+	// SendBatch sends several payloads, oldest first, in a single request
+	SendBatch(ctx context.Context, payloads []*models.MetricPayload) (*models.ServerResponse, error)
+
 	// Close closes the sender and releases resources
 	Close() error
 }
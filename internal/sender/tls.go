@@ -0,0 +1,177 @@
+package sender
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+)
+
+// caBundle tracks a custom CA bundle file so it can be reloaded when it changes
+type caBundle struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// newCABundle creates a CA bundle tracker for the given PEM file
+func newCABundle(path string) *caBundle {
+	return &caBundle{path: path}
+}
+
+// changed reports whether the file on disk differs from the last loaded version
+func (c *caBundle) changed() bool {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		// Keep the last good pool; load() reports the error on the next reload
+		return c.modTime.IsZero()
+	}
+	return !info.ModTime().Equal(c.modTime) || info.Size() != c.size
+}
+
+// load reads the bundle and returns a pool of system roots plus the bundle certificates
+func (c *caBundle) load() (*x509.CertPool, error) {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle %s: %w", c.path, err)
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle %s: %w", c.path, err)
+	}
+
+	// Start from system roots so public endpoints keep working
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA bundle %s contains no valid PEM certificates", c.path)
+	}
+
+	c.modTime = info.ModTime()
+	c.size = info.Size()
+
+	return pool, nil
+}
+
+// clientCert tracks a client certificate and key for mutual TLS so they can be
+// rotated on disk without a restart
+type clientCert struct {
+	certPath string
+	keyPath  string
+
+	mu      sync.Mutex
+	certMod time.Time
+	keyMod  time.Time
+	loaded  *tls.Certificate
+}
+
+// loadClientCert reads the key pair; keyPath may equal certPath for a combined PEM file
+func loadClientCert(certPath, keyPath string) (*clientCert, error) {
+	c := &clientCert{certPath: certPath, keyPath: keyPath}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload reads the key pair from disk. Caller must hold c.mu or own c exclusively.
+func (c *clientCert) reload() error {
+	certInfo, err := os.Stat(c.certPath)
+	if err != nil {
+		return fmt.Errorf("failed to read client certificate %s: %w", c.certPath, err)
+	}
+	keyInfo, err := os.Stat(c.keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read client key %s: %w", c.keyPath, err)
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certPath, c.keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load client certificate %s: %w", c.certPath, err)
+	}
+
+	c.loaded = &cert
+	c.certMod = certInfo.ModTime()
+	c.keyMod = keyInfo.ModTime()
+	return nil
+}
+
+// getClientCertificate returns the key pair for a handshake, reloading it if the files changed
+func (c *clientCert) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	certInfo, certErr := os.Stat(c.certPath)
+	keyInfo, keyErr := os.Stat(c.keyPath)
+	if certErr == nil && keyErr == nil &&
+		(!certInfo.ModTime().Equal(c.certMod) || !keyInfo.ModTime().Equal(c.keyMod)) {
+		if err := c.reload(); err != nil {
+			// Keep presenting the last good pair; a half-written rotation is retried next handshake
+			log.Printf("WARN: Failed to reload client certificate, keeping previous one: %v", err)
+		} else {
+			log.Printf("INFO: Reloaded client certificate [path=%s]", c.certPath)
+		}
+	}
+
+	return c.loaded, nil
+}
+
+// newTLSConfig builds the client TLS configuration; cert enables mutual TLS
+func newTLSConfig(rootCAs *x509.CertPool, cert *clientCert) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    rootCAs, // nil uses system roots
+	}
+	if cert != nil {
+		cfg.GetClientCertificate = cert.getClientCertificate
+	}
+	return cfg
+}
+
+// applyServerTLSOptions applies the server name override and verification
+// setting for connections to the server or broker
+func applyServerTLSOptions(cfg *tls.Config) *tls.Config {
+	if name := config.GetTLSServerName(); name != "" {
+		cfg.ServerName = name
+	}
+	cfg.InsecureSkipVerify = config.IsTLSInsecureSkipVerify()
+	return cfg
+}
+
+// ServerTLSConfig builds the TLS configuration for auxiliary connections to the
+// server (CA bundle loaded once, client certificate and server TLS options)
+func ServerTLSConfig() (*tls.Config, error) {
+	var rootCAs *x509.CertPool
+	if path := config.GetCACertPath(); path != "" {
+		pool, err := newCABundle(path).load()
+		if err != nil {
+			return nil, err
+		}
+		rootCAs = pool
+	}
+
+	cert, err := configuredClientCert()
+	if err != nil {
+		return nil, err
+	}
+
+	return applyServerTLSOptions(newTLSConfig(rootCAs, cert)), nil
+}
+
+// configuredClientCert loads the mutual TLS key pair from the environment, or returns nil when unset
+func configuredClientCert() (*clientCert, error) {
+	certPath := config.GetClientCertPath()
+	if certPath == "" {
+		return nil, nil
+	}
+	return loadClientCert(certPath, config.GetClientKeyPath())
+}
@@ -0,0 +1,144 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+)
+
+// TokenSource supplies short-lived access tokens, exchanging a refresh token
+// at the token endpoint before they expire or after the server rejects one
+type TokenSource struct {
+	tokenURL string
+	client   *http.Client
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	expiry       time.Time // Zero when unknown; the token is then used until rejected
+}
+
+// tokenResponse is the token endpoint reply
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int64  `json:"expires_in"`              // Seconds
+	RefreshToken string `json:"refresh_token,omitempty"` // Set when the server rotates the refresh token
+}
+
+// NewTokenSource creates a token source. accessToken may be empty, in which
+// case one is fetched on first use.
+func NewTokenSource(tokenURL, accessToken, refreshToken string) (*TokenSource, error) {
+	tlsConfig, err := ServerTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenSource{
+		tokenURL: tokenURL,
+		client: &http.Client{
+			Timeout: config.GetTimeout(),
+			Transport: &http.Transport{
+				DialContext:     ServerDialFunc(),
+				TLSClientConfig: tlsConfig,
+			},
+		},
+		accessToken:  accessToken,
+		refreshToken: refreshToken,
+	}, nil
+}
+
+// Token returns a valid access token, refreshing it when it is about to expire
+func (t *TokenSource) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.accessToken != "" && (t.expiry.IsZero() || time.Until(t.expiry) > config.TokenRefreshMargin) {
+		return t.accessToken, nil
+	}
+	if err := t.refreshLocked(ctx); err != nil {
+		return "", err
+	}
+	return t.accessToken, nil
+}
+
+// Refresh fetches a new access token after the server rejected the given one.
+// Concurrent callers holding the same stale token share a single refresh.
+func (t *TokenSource) Refresh(ctx context.Context, rejected string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.accessToken != rejected && t.accessToken != "" {
+		return t.accessToken, nil
+	}
+	if err := t.refreshLocked(ctx); err != nil {
+		return "", err
+	}
+	return t.accessToken, nil
+}
+
+// refreshLocked exchanges the refresh token for a new access token. Caller must hold t.mu.
+func (t *TokenSource) refreshLocked(ctx context.Context) error {
+	body, err := json.Marshal(map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": t.refreshToken,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.tokenURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("monify/%s", config.Version))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("token refresh failed: %w", classifyRequestError(err))
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusBadRequest:
+		// Refresh token revoked or expired: only a new login helps
+		return fmt.Errorf("%w: refresh token rejected", ErrUnauthorized)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("token refresh failed: %w: status code %d", ErrServerUnavailable, resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("token refresh failed: unexpected status code %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(respBody, &token); err != nil || token.AccessToken == "" {
+		return fmt.Errorf("token refresh failed: invalid response")
+	}
+
+	t.accessToken = token.AccessToken
+	t.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		t.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+
+	// Persist a rotated refresh token so the agent can still authenticate after a restart
+	if token.RefreshToken != "" && token.RefreshToken != t.refreshToken {
+		t.refreshToken = token.RefreshToken
+		os.Setenv("MONIFY_REFRESH_TOKEN", token.RefreshToken)
+		if err := config.SaveEnvFile(map[string]string{"MONIFY_REFRESH_TOKEN": token.RefreshToken}); err != nil {
+			log.Printf("WARN: Failed to save rotated refresh token: %v", err)
+		}
+	}
+
+	log.Printf("INFO: Access token refreshed [expires_in=%ds]", token.ExpiresIn)
+	return nil
+}
@@ -0,0 +1,135 @@
+package sender
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// UnixSocketSender hands payloads to a local relay over a Unix domain socket.
+//
+// The protocol is newline-delimited JSON: the agent writes one payload per
+// line and the relay answers each with one line holding a ServerResponse
+// (e.g. {"status":"success"}). A payload counts as delivered once its answer
+// arrives, so unanswered payloads are spooled and resent.
+type UnixSocketSender struct {
+	path string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewUnixSocketSender creates a sender for the relay socket at path
+func NewUnixSocketSender(path string) (*UnixSocketSender, error) {
+	if path == "" {
+		return nil, fmt.Errorf("unix socket path is empty")
+	}
+	return &UnixSocketSender{path: path}, nil
+}
+
+// Send hands a single payload to the relay
+func (u *UnixSocketSender) Send(ctx context.Context, payload *models.MetricPayload) (*models.ServerResponse, error) {
+	if payload == nil {
+		return nil, nil
+	}
+	return u.SendBatch(ctx, []*models.MetricPayload{payload})
+}
+
+// SendBatch hands several payloads to the relay, oldest first
+func (u *UnixSocketSender) SendBatch(ctx context.Context, payloads []*models.MetricPayload) (*models.ServerResponse, error) {
+	if len(payloads) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	for _, payload := range payloads {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.conn == nil {
+		dialer := &net.Dialer{Timeout: config.GetTimeout()}
+		conn, err := dialer.DialContext(ctx, "unix", u.path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: relay socket %s: %w", ErrNetwork, u.path, err)
+		}
+		u.conn = conn
+		u.reader = bufio.NewReader(conn)
+	}
+
+	deadline := time.Now().Add(config.GetTimeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	u.conn.SetDeadline(deadline)
+
+	if _, err := u.conn.Write(buf.Bytes()); err != nil {
+		u.closeLocked()
+		return nil, fmt.Errorf("%w: relay write failed: %w", ErrNetwork, err)
+	}
+
+	// One answer per payload; commands from all answers are merged. All
+	// answers are read even after a rejection to keep the stream in step.
+	result := &models.ServerResponse{Status: "success"}
+	var rejected string
+	for range payloads {
+		line, err := u.reader.ReadBytes('\n')
+		if err != nil {
+			u.closeLocked()
+			return nil, fmt.Errorf("%w: no answer from relay: %w", ErrNetwork, err)
+		}
+
+		var resp models.ServerResponse
+		if err := json.Unmarshal(line, &resp); err != nil {
+			u.closeLocked()
+			return nil, fmt.Errorf("invalid relay answer: %w", err)
+		}
+		if resp.Status == "error" {
+			rejected = resp.Message
+			continue
+		}
+
+		result.Commands = append(result.Commands, resp.Commands...)
+		if len(resp.Scopes) > 0 {
+			result.Scopes = resp.Scopes
+		}
+	}
+
+	if rejected != "" {
+		return nil, fmt.Errorf("relay rejected payload: %s", rejected)
+	}
+	return result, nil
+}
+
+// closeLocked drops the connection so the next send reconnects. Caller must hold u.mu.
+func (u *UnixSocketSender) closeLocked() {
+	if u.conn != nil {
+		u.conn.Close()
+		u.conn = nil
+		u.reader = nil
+	}
+}
+
+// Close closes the relay connection
+func (u *UnixSocketSender) Close() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.closeLocked()
+	return nil
+}
@@ -0,0 +1,260 @@
+// Package spool persists payloads that could not be sent so they can be
+// replayed in order once the server is reachable again.
+package spool
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// fileSuffix is the extension of spooled payload files
+const fileSuffix = ".json.gz"
+
+// IsFile reports whether name is a file the spool creates: a spooled
+// payload, or the temporary file of one being written
+func IsFile(name string) bool {
+	return strings.HasSuffix(name, fileSuffix)
+}
+
+// Purge removes the spool's files from dir, and dir itself if nothing else
+// is left in it. Other files are kept: dir may be any directory the
+// operator configured.
+func Purge(dir string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var errs []error
+	for _, file := range files {
+		if file.Type().IsRegular() && IsFile(file.Name()) {
+			if err := os.Remove(filepath.Join(dir, file.Name())); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err := os.Remove(dir); err != nil && !errors.Is(err, syscall.ENOTEMPTY) && !errors.Is(err, syscall.EEXIST) && !os.IsNotExist(err) {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Spool is a bounded directory of gzip-compressed payloads, oldest first.
+// When the size limit is exceeded, the oldest payloads are dropped.
+type Spool struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries []entry // Sorted oldest first
+	size    int64
+	seq     uint64
+}
+
+// entry is a spooled payload file
+type entry struct {
+	name string
+	size int64
+}
+
+// Open opens or creates the spool directory and indexes existing payloads
+func Open(dir string, maxBytes int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	s := &Spool{dir: dir, maxBytes: maxBytes}
+	for _, file := range files {
+		name := file.Name()
+		if !IsFile(name) {
+			continue
+		}
+		if strings.HasPrefix(name, ".") {
+			// Leftover from an interrupted write
+			os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		s.entries = append(s.entries, entry{name: name, size: info.Size()})
+		s.size += info.Size()
+	}
+
+	// Names start with a fixed-width timestamp, so lexical order is time order
+	sort.Slice(s.entries, func(i, j int) bool { return s.entries[i].name < s.entries[j].name })
+	s.trim()
+
+	return s, nil
+}
+
+// Push appends a payload to the spool
+func (s *Spool) Push(payload *models.MetricPayload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), s.seq%1000000, fileSuffix)
+	tmp := filepath.Join(s.dir, "."+name)
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create spool file: %w", err)
+	}
+
+	gz := gzip.NewWriter(f)
+	err = json.NewEncoder(gz).Encode(payload)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write spool file: %w", err)
+	}
+
+	// Rename last so a crash never leaves a partial payload in the queue
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to commit spool file: %w", err)
+	}
+
+	info, err := os.Stat(filepath.Join(s.dir, name))
+	size := int64(0)
+	if err == nil {
+		size = info.Size()
+	}
+	s.entries = append(s.entries, entry{name: name, size: size})
+	s.size += size
+	s.trim()
+
+	return nil
+}
+
+// Peek returns up to n of the oldest payloads and their IDs without removing
+// them. Corrupt files are discarded and reported in the error. A file that
+// cannot be read, e.g. for lack of permission, is kept: Peek stops before it
+// and returns the error.
+func (s *Spool) Peek(n int) ([]*models.MetricPayload, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var payloads []*models.MetricPayload
+	var ids []string
+	var discarded []string
+	for i := 0; i < len(s.entries) && len(payloads) < n; {
+		name := s.entries[i].name
+		payload, err := s.read(name)
+		var pathErr *os.PathError
+		if errors.Is(err, os.ErrNotExist) {
+			// Removed behind our back
+			s.removeLocked(name)
+			continue
+		}
+		if errors.As(err, &pathErr) {
+			if len(discarded) > 0 {
+				err = fmt.Errorf("%w; discarded corrupt spool files: %s", err, strings.Join(discarded, ", "))
+			}
+			return payloads, ids, err
+		}
+		if err != nil {
+			s.removeLocked(name)
+			discarded = append(discarded, name)
+			continue
+		}
+		payloads = append(payloads, payload)
+		ids = append(ids, name)
+		i++
+	}
+
+	if len(discarded) > 0 {
+		return payloads, ids, fmt.Errorf("discarded corrupt spool files: %s", strings.Join(discarded, ", "))
+	}
+	return payloads, ids, nil
+}
+
+// Remove deletes payloads after they were delivered
+func (s *Spool) Remove(ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.removeLocked(id)
+	}
+}
+
+// Len returns the number of spooled payloads
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// read decodes a spooled payload file. Errors opening or reading the file are
+// *os.PathError; any other error means the file is corrupt.
+func (s *Spool) read(name string) (*models.MetricPayload, error) {
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var payload models.MetricPayload
+	if err := json.NewDecoder(gz).Decode(&payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+// removeLocked deletes a payload file. Caller must hold s.mu.
+func (s *Spool) removeLocked(name string) {
+	for i, e := range s.entries {
+		if e.name == name {
+			os.Remove(filepath.Join(s.dir, name))
+			s.size -= e.size
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// trim drops the oldest payloads until the spool fits its size limit.
+// Caller must hold s.mu (or have exclusive access).
+func (s *Spool) trim() {
+	dropped := 0
+	for s.size > s.maxBytes && len(s.entries) > 1 {
+		s.removeLocked(s.entries[0].name)
+		dropped++
+	}
+	if dropped > 0 {
+		log.Printf("WARN: Spool size limit reached, dropped oldest payloads [dropped=%d]", dropped)
+	}
+}
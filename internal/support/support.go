@@ -0,0 +1,254 @@
+// Package support builds support bundles: a tar.gz with what is needed to
+// troubleshoot an agent, to attach to support tickets. Credentials in the
+// configuration are redacted.
+package support
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/monify-labs/agent/internal/agent"
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/uninstall"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// Limits on what is collected
+const (
+	journalLines   = 2000
+	maxLogBytes    = 1 << 20 // Tail kept of each file in the log directory
+	spooledPayload = 5       // Newest spooled payloads included
+)
+
+// redacted replaces the values of secret settings
+const redacted = "<redacted>"
+
+// bundle writes files into a tar.gz. Parts that cannot be gathered are
+// recorded and written as errors.txt instead of failing the bundle.
+type bundle struct {
+	tw     *tar.Writer
+	now    time.Time
+	errors []string
+}
+
+// Write gathers the support bundle into w. status is the live status of the
+// running agent, nil if it is not reachable.
+func Write(ctx context.Context, w io.Writer, status *models.AgentStatus) error {
+	gz := gzip.NewWriter(w)
+	b := &bundle{tw: tar.NewWriter(gz), now: time.Now()}
+
+	b.add("version.txt", []byte(fmt.Sprintf("Monify Agent v%s\nCommit: %s\nBuild Date: %s\n", config.Version, config.Commit, config.BuildDate)))
+	b.add("system.txt", systemInfo())
+	b.addConfig()
+	if data, err := os.ReadFile(config.ConfigFilePath); err == nil {
+		b.add("config/config.yaml", data) // Secrets are refused there
+	} else if !os.IsNotExist(err) {
+		b.fail("config/config.yaml", err)
+	}
+	if status != nil {
+		b.addJSON("status.json", status)
+	} else {
+		b.fail("status.json", fmt.Errorf("agent not reachable"))
+	}
+	b.addJSON("doctor.json", agent.Doctor(ctx, config.GetServerURL()))
+	if payload, err := agent.CollectOnce(ctx, true); err != nil {
+		b.fail("payload.json", err)
+	} else {
+		b.addJSON("payload.json", payload)
+	}
+	b.addSpool()
+	b.addLogs()
+	b.addService()
+
+	if len(b.errors) > 0 {
+		b.add("errors.txt", []byte(strings.Join(b.errors, "\n")+"\n"))
+	}
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// add writes a file to the bundle
+func (b *bundle) add(name string, data []byte) {
+	header := &tar.Header{
+		Name:    "monify-support/" + name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: b.now,
+	}
+	if err := b.tw.WriteHeader(header); err != nil {
+		b.fail(name, err)
+		return
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		b.fail(name, err)
+	}
+}
+
+// addJSON writes v as indented JSON
+func (b *bundle) addJSON(name string, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		b.fail(name, err)
+		return
+	}
+	b.add(name, append(data, '\n'))
+}
+
+// addCommand writes the output of a command; a missing command is skipped
+func (b *bundle) addCommand(name, command string, args ...string) {
+	if _, err := exec.LookPath(command); err != nil {
+		return
+	}
+	out, err := exec.Command(command, args...).CombinedOutput()
+	if err != nil && len(out) == 0 {
+		b.fail(name, err)
+		return
+	}
+	b.add(name, out)
+}
+
+// fail records a part that could not be gathered
+func (b *bundle) fail(name string, err error) {
+	b.errors = append(b.errors, fmt.Sprintf("%s: %v", name, err))
+}
+
+// addConfig writes the env file with the values of secret settings redacted
+func (b *bundle) addConfig() {
+	f, err := os.Open(config.EnvFilePath)
+	if err != nil {
+		b.fail("config/env", err)
+		return
+	}
+	defer f.Close()
+
+	var content strings.Builder
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if key, _, ok := strings.Cut(line, "="); ok && config.IsSecretSetting(strings.TrimSpace(key)) {
+			line = strings.TrimSpace(key) + "=" + redacted
+		}
+		content.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		b.fail("config/env", err)
+		return
+	}
+	b.add("config/env", []byte(content.String()))
+}
+
+// addSpool writes the newest spooled payloads, as stored
+func (b *bundle) addSpool() {
+	dir := config.GetSpoolDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			b.fail("spool", err)
+		}
+		return
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if name := entry.Name(); !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".json.gz") {
+			names = append(names, name)
+		}
+	}
+	// Names start with a timestamp, so the newest sort last
+	sort.Strings(names)
+	if len(names) > spooledPayload {
+		names = names[len(names)-spooledPayload:]
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			b.fail("spool/"+name, err)
+			continue
+		}
+		b.add("spool/"+name, data)
+	}
+}
+
+// addLogs writes the agent's journal and the tail of each log file
+func (b *bundle) addLogs() {
+	b.addCommand("logs/journal.log", "journalctl", "-u", uninstall.ServiceName, "-n", fmt.Sprint(journalLines), "--no-pager", "-o", "short-iso")
+
+	entries, err := os.ReadDir(uninstall.LogDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			b.fail("logs", err)
+		}
+		return
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := tail(filepath.Join(uninstall.LogDir, entry.Name()), maxLogBytes)
+		if err != nil {
+			b.fail("logs/"+entry.Name(), err)
+			continue
+		}
+		b.add("logs/"+entry.Name(), data)
+	}
+}
+
+// addService writes the unit file and the state systemd reports for it
+func (b *bundle) addService() {
+	if data, err := os.ReadFile(uninstall.ServiceFile); err == nil {
+		b.add("service/monify.service", data)
+	} else if !os.IsNotExist(err) {
+		b.fail("service/monify.service", err)
+	}
+	b.addCommand("service/status.txt", "systemctl", "status", uninstall.ServiceName, "--no-pager", "--lines=0")
+}
+
+// systemInfo describes the host and the agent process
+func systemInfo() []byte {
+	var info strings.Builder
+	hostname, _ := os.Hostname()
+	fmt.Fprintf(&info, "Hostname: %s\n", hostname)
+	fmt.Fprintf(&info, "Architecture: %s\n", runtime.GOARCH)
+	fmt.Fprintf(&info, "Go: %s\n", runtime.Version())
+	fmt.Fprintf(&info, "User: uid=%d gid=%d\n", os.Geteuid(), os.Getegid())
+	for _, path := range []string{"/proc/version", "/proc/uptime", "/etc/os-release"} {
+		if data, err := os.ReadFile(path); err == nil {
+			fmt.Fprintf(&info, "\n# %s\n%s", path, data)
+		}
+	}
+	return []byte(info.String())
+}
+
+// tail returns up to the last max bytes of the file at path
+func tail(path string, max int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > max {
+		if _, err := f.Seek(-max, io.SeekEnd); err != nil {
+			return nil, err
+		}
+	}
+	return io.ReadAll(f)
+}
@@ -0,0 +1,135 @@
+// Package uninstall removes the agent from the host natively, the same way
+// scripts/uninstall.sh does, without downloading and running a script.
+package uninstall
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/spool"
+)
+
+// Installation layout, as created by scripts/install.sh and "monify install"
+const (
+	ServiceName = "monify"
+	ServiceFile = "/etc/systemd/system/monify.service"
+	DropInDir   = "/etc/systemd/system/monify.service.d"
+	BinaryPath  = "/usr/local/bin/monify"
+	ConfigDir   = "/etc/monify"
+	LogDir      = "/var/log/monify"
+	StateDir    = "/var/lib/monify"
+	ServiceUser = "monify"
+)
+
+// FallbackCommand removes the agent with the remote script when the native
+// uninstall fails
+const FallbackCommand = "curl -sSL https://monify.cloud/uninstall.sh | sudo bash"
+
+// Run stops and disables the service and removes the unit file and its
+// drop-ins, binary, configuration, logs, spool and state. Of a spool
+// directory outside the state directory, only the spooled payloads are
+// removed, and the directory itself if it is then empty. With purge the
+// service user created by "monify install" is removed as well. All steps are
+// attempted even if some fail.
+func Run(purge bool) error {
+	var errs []error
+	systemd := hasSystemd()
+
+	if systemd {
+		systemctl("stop", ServiceName)    // Fails if not running
+		systemctl("disable", ServiceName) // Fails if not enabled
+	}
+
+	if err := os.RemoveAll(DropInDir); err != nil {
+		errs = append(errs, err)
+	}
+	if err := removeFile(ServiceFile); err != nil {
+		errs = append(errs, err)
+	} else if systemd {
+		if err := systemctl("daemon-reload"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, path := range []string{BinaryPath, "/var/run/monify.pid", "/var/run/monify.lock"} {
+		if err := removeFile(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, dir := range []string{ConfigDir, LogDir, StateDir} {
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// The spool directory is configurable and may be shared, e.g. /data
+	if err := spool.Purge(config.GetSpoolDir()); err != nil {
+		errs = append(errs, err)
+	}
+
+	if purge {
+		if err := removeUser(ServiceUser); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Detach runs "monify uninstall --yes" as a transient systemd unit and returns
+// immediately. The service cannot uninstall itself: its sandbox makes the
+// binary and unit file read-only, and stopping the service kills its processes.
+func Detach() error {
+	if !hasSystemd() {
+		return fmt.Errorf("systemd is not running")
+	}
+	if _, err := os.Stat(BinaryPath); err != nil {
+		return err
+	}
+	out, err := exec.Command("systemd-run", "--unit=monify-uninstall", "--collect", "--quiet", BinaryPath, "uninstall", "--yes").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemd-run: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// hasSystemd reports whether the host is running systemd
+func hasSystemd() bool {
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return false
+	}
+	_, err := exec.LookPath("systemctl")
+	return err == nil
+}
+
+// systemctl runs systemctl with args
+func systemctl(args ...string) error {
+	if out, err := exec.Command("systemctl", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("systemctl %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// removeUser removes the account called name and its group, ignoring
+// accounts that do not exist
+func removeUser(name string) error {
+	if _, err := user.Lookup(name); err != nil {
+		return nil
+	}
+	if out, err := exec.Command("userdel", name).CombinedOutput(); err != nil {
+		return fmt.Errorf("userdel: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// removeFile removes path, ignoring files that do not exist
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
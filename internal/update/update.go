@@ -0,0 +1,320 @@
+// Package update replaces the agent binary with a release build after
+// verifying its SHA256 checksum and the signature of the checksum file.
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/internal/proxy"
+)
+
+// Release assets next to the binaries: sha256sum output for all binaries,
+// and a base64 Ed25519 signature of that file
+const (
+	checksumsFile = "SHA256SUMS"
+	signatureFile = "SHA256SUMS.sig"
+)
+
+// Result describes the outcome of an update
+type Result struct {
+	Version string // Version reported by the new binary
+	Updated bool   // false if the installed binary already matched the release
+}
+
+// Run downloads the release binary of version ("" for the latest release)
+// for this architecture and atomically replaces the binary at path with it.
+// The latest release is only installed if it is newer than this agent.
+func Run(ctx context.Context, version, path string) (*Result, error) {
+	key, err := publicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	base := config.GetUpdateURL() + "/latest/download"
+	if version != "" {
+		base = config.GetUpdateURL() + "/download/v" + strings.TrimPrefix(version, "v")
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:       http.ProxyFromEnvironment,
+			DialContext: proxy.DialFunc(&net.Dialer{Timeout: config.GetTimeout()}),
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	// The signature covers the checksum file, which covers the binary
+	checksums, err := fetch(ctx, client, base+"/"+checksumsFile, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := fetch(ctx, client, base+"/"+signatureFile, 4096)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(key, checksums, sig) {
+		return nil, fmt.Errorf("invalid signature on %s", checksumsFile)
+	}
+
+	asset := "monify-linux-" + runtime.GOARCH
+	want, err := checksum(checksums, asset)
+	if err != nil {
+		return nil, err
+	}
+
+	if current, err := fileHash(path); err == nil && current == want {
+		return &Result{Version: config.Version}, nil
+	}
+
+	tmp, err := download(ctx, client, base+"/"+asset, filepath.Dir(path), want)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp) // No-op after the rename
+
+	newVersion, err := binaryVersion(ctx, tmp)
+	if err != nil {
+		return nil, fmt.Errorf("downloaded binary does not run: %w", err)
+	}
+	// The signed checksum file does not name a version, so an old release
+	// served as the latest one would otherwise roll the agent back, and one
+	// served under a requested version would be installed as that version
+	if version != "" && strings.TrimPrefix(version, "v") != strings.TrimPrefix(newVersion, "v") {
+		return nil, fmt.Errorf("downloaded binary is version %s, not the requested %s", newVersion, version)
+	}
+	if version == "" && !Newer(newVersion, config.Version) {
+		return nil, fmt.Errorf("latest release %s is not newer than the installed %s; request a version explicitly to downgrade", newVersion, config.Version)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+	return &Result{Version: newVersion, Updated: true}, nil
+}
+
+// Latest returns the version of the latest release. It is read from the
+// redirect of the latest checksum file to its versioned download path, so
+// nothing is downloaded.
+func Latest(ctx context.Context) (string, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:       http.ProxyFromEnvironment,
+			DialContext: proxy.DialFunc(&net.Dialer{Timeout: config.GetTimeout()}),
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	url := config.GetUpdateURL() + "/latest/download/" + checksumsFile
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("monify/%s", config.Version))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	// e.g. .../releases/download/v1.2.3/SHA256SUMS
+	_, rest, found := strings.Cut(resp.Header.Get("Location"), "/download/v")
+	version, _, _ := strings.Cut(rest, "/")
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 || !found || version == "" {
+		return "", fmt.Errorf("%s does not redirect to a versioned release (%s)", url, resp.Status)
+	}
+	return version, nil
+}
+
+// Newer reports whether version a is newer than version b, comparing the
+// dot-separated numbers of both; a pre-release suffix is ignored
+func Newer(a, b string) bool {
+	parse := func(version string) []int {
+		version, _, _ = strings.Cut(strings.TrimPrefix(version, "v"), "-")
+		var numbers []int
+		for _, part := range strings.Split(version, ".") {
+			n, _ := strconv.Atoi(part)
+			numbers = append(numbers, n)
+		}
+		return numbers
+	}
+	x, y := parse(a), parse(b)
+	for i := 0; i < len(x) || i < len(y); i++ {
+		var m, n int
+		if i < len(x) {
+			m = x[i]
+		}
+		if i < len(y) {
+			n = y[i]
+		}
+		if m != n {
+			return m > n
+		}
+	}
+	return false
+}
+
+// publicKey decodes the pinned release signing key
+func publicKey() (ed25519.PublicKey, error) {
+	value := config.GetUpdatePublicKey()
+	if value == "" {
+		return nil, fmt.Errorf("no release signing key built in, set MONIFY_UPDATE_PUBLIC_KEY")
+	}
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid release signing key: expected %d base64-encoded bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// get starts a GET request and fails on non-2xx responses
+func get(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("monify/%s", config.Version))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// fetch downloads a small file completely
+func fetch(ctx context.Context, client *http.Client, url string, limit int64) ([]byte, error) {
+	resp, err := get(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("download %s: larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// checksum finds the hash of name in sha256sum output
+func checksum(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		// "<hash>  <name>", or "<hash> *<name>" in binary mode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s not listed in %s", name, checksumsFile)
+}
+
+// fileHash returns the hex SHA256 of a file
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// download writes url to a temporary executable in dir, so the final rename
+// stays on one filesystem, and checks its hash. It returns the file's path.
+func download(ctx context.Context, client *http.Client, url, dir, want string) (string, error) {
+	resp, err := get(ctx, client, url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	f, err := os.CreateTemp(dir, ".monify-update-*")
+	if err != nil {
+		return "", err
+	}
+	ok := false
+	defer func() {
+		if !ok {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	limit := int64(config.UpdateMaxMB) * 1024 * 1024
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return "", err
+	}
+	if n > limit {
+		return "", fmt.Errorf("download %s: larger than %d MB", url, config.UpdateMaxMB)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return "", fmt.Errorf("checksum mismatch for %s: got %s, want %s", url, got, want)
+	}
+
+	if err := f.Chmod(0o755); err != nil {
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	ok = true
+	return f.Name(), nil
+}
+
+// binaryVersion runs "<path> version" and returns the version it reports
+func binaryVersion(ctx context.Context, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, "version")
+	cmd.Env = append(os.Environ(), "MONIFY_UPDATE_CHECK=false")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	// First line: "Monify Agent v1.2.3"
+	line, _, _ := strings.Cut(string(out), "\n")
+	_, version, found := strings.Cut(line, " v")
+	if !found {
+		return "", fmt.Errorf("unexpected version output %q", line)
+	}
+	return strings.TrimSpace(version), nil
+}
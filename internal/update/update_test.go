@@ -0,0 +1,225 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// release is a fake release served with the GitHub download layout
+type release struct {
+	binary    []byte // Served as monify-linux-<arch>
+	checksums []byte // SHA256SUMS
+	signature []byte // SHA256SUMS.sig
+}
+
+// newRelease builds a release whose binary reports version, with checksums
+// signed by key
+func newRelease(key ed25519.PrivateKey, version string) *release {
+	binary := []byte(fmt.Sprintf("#!/bin/sh\necho 'Monify Agent v%s'\n", version))
+	sum := sha256.Sum256(binary)
+	checksums := []byte(fmt.Sprintf("%s  monify-linux-%s\n", hex.EncodeToString(sum[:]), runtime.GOARCH))
+	return &release{
+		binary:    binary,
+		checksums: checksums,
+		signature: []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, checksums))),
+	}
+}
+
+// serve serves r for every version and as the latest release
+func (r *release) serve(t *testing.T) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch filepath.Base(req.URL.Path) {
+		case checksumsFile:
+			w.Write(r.checksums)
+		case signatureFile:
+			w.Write(r.signature)
+		case "monify-linux-" + runtime.GOARCH:
+			w.Write(r.binary)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("MONIFY_UPDATE_URL", server.URL)
+}
+
+// Release versions around the installed config.Version
+const (
+	newerVersion = "99.0.0"
+	olderVersion = "0.0.1"
+)
+
+// setup pins a fresh release key and returns it with the path of an
+// installed binary
+func setup(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MONIFY_UPDATE_PUBLIC_KEY", base64.StdEncoding.EncodeToString(public))
+
+	path := filepath.Join(t.TempDir(), "monify")
+	if err := os.WriteFile(path, []byte("installed"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return private, path
+}
+
+// assertUnchanged fails if the installed binary at path was replaced
+func assertUnchanged(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "installed" {
+		t.Fatal("installed binary was replaced")
+	}
+}
+
+func TestRunInstallsNewerRelease(t *testing.T) {
+	key, path := setup(t)
+	r := newRelease(key, newerVersion)
+	r.serve(t)
+
+	result, err := Run(context.Background(), "", path)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !result.Updated || result.Version != newerVersion {
+		t.Fatalf("Run = %+v, want update to %s", result, newerVersion)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != string(r.binary) {
+		t.Fatal("installed binary is not the release binary")
+	}
+}
+
+func TestRunRejectsBadSignature(t *testing.T) {
+	key, path := setup(t)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name   string
+		modify func(r *release)
+	}{
+		{"other key", func(r *release) {
+			r.signature = []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, r.checksums)))
+		}},
+		{"modified checksums", func(r *release) {
+			r.checksums = append(r.checksums, "0000  monify-linux-other\n"...)
+		}},
+		{"malformed", func(r *release) { r.signature = []byte("not base64") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRelease(key, newerVersion)
+			tt.modify(r)
+			r.serve(t)
+
+			_, err := Run(context.Background(), "", path)
+			if err == nil || !strings.Contains(err.Error(), "invalid signature") {
+				t.Fatalf("Run error = %v, want invalid signature", err)
+			}
+			assertUnchanged(t, path)
+		})
+	}
+}
+
+func TestRunRejectsChecksumMismatch(t *testing.T) {
+	key, path := setup(t)
+	r := newRelease(key, newerVersion)
+	r.binary = []byte("#!/bin/sh\necho 'Monify Agent v6.6.6'\n") // Not the signed binary
+	r.serve(t)
+
+	_, err := Run(context.Background(), "", path)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Run error = %v, want checksum mismatch", err)
+	}
+	assertUnchanged(t, path)
+}
+
+func TestRunRejectsDowngrade(t *testing.T) {
+	key, path := setup(t)
+	newRelease(key, olderVersion).serve(t)
+
+	_, err := Run(context.Background(), "", path)
+	if err == nil || !strings.Contains(err.Error(), "not newer") {
+		t.Fatalf("Run error = %v, want downgrade refused", err)
+	}
+	assertUnchanged(t, path)
+
+	// An explicitly requested version may be older
+	result, err := Run(context.Background(), olderVersion, path)
+	if err != nil {
+		t.Fatalf("Run with explicit version: %v", err)
+	}
+	if !result.Updated || result.Version != olderVersion {
+		t.Fatalf("Run = %+v, want downgrade to %s", result, olderVersion)
+	}
+}
+
+func TestRunRejectsOtherVersion(t *testing.T) {
+	key, path := setup(t)
+	newRelease(key, olderVersion).serve(t)
+
+	// An old signed release served under the requested version's path
+	_, err := Run(context.Background(), "v"+newerVersion, path)
+	if err == nil || !strings.Contains(err.Error(), "not the requested") {
+		t.Fatalf("Run error = %v, want version mismatch", err)
+	}
+	assertUnchanged(t, path)
+}
+
+func TestChecksum(t *testing.T) {
+	checksums := []byte("AAAA  monify-linux-amd64\nBBBB *monify-linux-arm64\n")
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"monify-linux-amd64", "aaaa", false},
+		{"monify-linux-arm64", "bbbb", false},
+		{"monify-linux-386", "", true},
+	}
+	for _, tt := range tests {
+		got, err := checksum(checksums, tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("checksum(%s) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.2.0", "1.1.9", true},
+		{"v1.10.0", "1.9.0", true},
+		{"1.2", "1.2.0", false},
+		{"1.2.0", "1.2.0", false},
+		{"1.1.0", "1.2.0", false},
+		{"1.2.0-rc1", "1.1.0", true},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.a, tt.b); got != tt.want {
+			t.Errorf("Newer(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
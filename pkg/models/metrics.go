@@ -1,14 +1,91 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // MetricPayload represents the complete payload sent to the server
 // Authentication is done via token in Authorization header
 type MetricPayload struct {
-	Hostname       string          `json:"hostname"`
-	Timestamp      time.Time       `json:"timestamp"`
-	StaticMetrics  *StaticMetrics  `json:"static_info,omitempty"` // Only sent when changed or first time
-	DynamicMetrics *DynamicMetrics `json:"metrics"`               // Always sent
+	ID             string            `json:"id"`  // Random UUID, unchanged when the payload is resent
+	Sequence       uint64            `json:"seq"` // Increases by one per payload, across restarts
+	Hostname       string            `json:"hostname"`
+	Timestamp      time.Time         `json:"timestamp"`
+	Labels         map[string]string `json:"labels,omitempty"`      // Host labels (e.g., cloud instance tags)
+	StaticMetrics  *StaticMetrics    `json:"static_info,omitempty"` // Only sent when changed or first time
+	DynamicMetrics *DynamicMetrics   `json:"metrics"`               // Always sent
+	Chunks         []PayloadChunk    `json:"chunks,omitempty"`      // Parts of sections too large for one payload
+	PortReport     *PortReport       `json:"port_report,omitempty"` // Result of a scan_ports command, sent once
+	Maintenance    *Maintenance      `json:"maintenance,omitempty"` // Set while alerts should be suppressed
+	Alerts         []AlertEvent      `json:"alerts,omitempty"`      // Local alert rules that started or stopped firing
+	Agent          *AgentMetrics     `json:"agent,omitempty"`       // The agent's own resource usage
+}
+
+// AgentMetrics describes the agent's own resource usage. Rates and counts
+// cover the time since the previous payload.
+type AgentMetrics struct {
+	RSSBytes      uint64  `json:"rss_bytes"`   // Resident memory
+	HeapBytes     uint64  `json:"heap_bytes"`  // Go heap in use
+	CPUPercent    float64 `json:"cpu_percent"` // Of one core
+	Goroutines    int     `json:"goroutines"`
+	GCCount       uint32  `json:"gc_count"`           // Garbage collections
+	GCPauseMs     float64 `json:"gc_pause_ms"`        // Total stop-the-world time of those collections
+	PayloadBytes  int     `json:"payload_bytes"`      // Uncompressed JSON size of the previous payload
+	SendLatencyMs float64 `json:"send_latency_ms"`    // Duration of the last successful send
+	Shedding      bool    `json:"shedding,omitempty"` // Optional collectors skipped to stay within the resource budget
+
+	Collectors   []CollectorHealth  `json:"collectors,omitempty"`   // Collectors that are failing or quarantined
+	Capabilities *AgentCapabilities `json:"capabilities,omitempty"` // Sent with static metrics
+}
+
+// AgentCapabilities describes what the agent can collect with the privileges
+// it runs with
+type AgentCapabilities struct {
+	Root         bool                 `json:"root"`
+	Capabilities []string             `json:"capabilities,omitempty"` // Relevant effective Linux capabilities, e.g. net_raw
+	Unavailable  []UnavailableFeature `json:"unavailable,omitempty"`
+}
+
+// UnavailableFeature is data or a command the agent cannot provide without
+// more privileges
+type UnavailableFeature struct {
+	Feature string `json:"feature"` // e.g. static_info.product_serial, gateway.icmp, command:uninstall
+	Reason  string `json:"reason"`
+}
+
+// Alert states
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// AlertEvent reports a local alert rule starting or stopping to fire for one series
+type AlertEvent struct {
+	Rule      string            `json:"rule"`  // Rule as configured, e.g. "disk_used_percent > 95 for 5m"
+	State     string            `json:"state"` // "firing" or "resolved"
+	Labels    map[string]string `json:"labels,omitempty"`
+	Value     float64           `json:"value"`     // Last value of the series
+	Since     time.Time         `json:"since"`     // When the condition started to hold
+	Timestamp time.Time         `json:"timestamp"` // When the state changed
+}
+
+// Maintenance describes a planned maintenance window. Payloads collected
+// during it are still sent, but the server should not alert on them.
+type Maintenance struct {
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// PayloadChunk carries one part of a list section that was split across
+// consecutive payloads. While a section is chunked it is omitted from its
+// normal location; the server reassembles it from chunks sharing a snapshot.
+type PayloadChunk struct {
+	Section  string          `json:"section"`  // e.g. "static_info.disks"
+	Snapshot int64           `json:"snapshot"` // Collection time (Unix ms) identifying the full section
+	Index    int             `json:"index"`    // 0-based chunk index
+	Total    int             `json:"total"`    // Number of chunks in the snapshot
+	Items    json.RawMessage `json:"items"`    // JSON array holding this chunk's entries
 }
 
 // StaticMetrics contains rarely-changing system information
@@ -24,11 +101,22 @@ type StaticMetrics struct {
 	Virtualization  string `json:"virtualization"` // kvm, docker, vmware, etc.
 	HostID          string `json:"host_id"`
 
+	// Virtualization Layers
+	Hypervisor          string   `json:"hypervisor,omitempty"`           // kvm, vmware, hyperv, xen, etc.
+	ContainerRuntime    string   `json:"container_runtime,omitempty"`    // docker, podman, lxc, kubernetes, etc.
+	VirtualizationStack []string `json:"virtualization_stack,omitempty"` // All layers, outermost first (e.g., hyperv, wsl2, docker)
+
 	// Network Info
-	InternalIPs []string `json:"internal_ips"`        // All internal IPs
-	PublicIP    string   `json:"public_ip,omitempty"` // Public facing IP
-	Hostname    string   `json:"hostname"`            // Server hostname
-	FQDN        string   `json:"fqdn,omitempty"`      // Fully qualified domain name
+	InternalIPs []string `json:"internal_ips"`          // Private IPs and global IPv6 addresses
+	PublicIP    string   `json:"public_ip,omitempty"`   // Public facing IPv4
+	PublicIPv6  string   `json:"public_ipv6,omitempty"` // Public facing IPv6
+	Hostname    string   `json:"hostname"`              // Server hostname
+	FQDN        string   `json:"fqdn,omitempty"`        // Fully qualified domain name
+
+	// Default Routes
+	DefaultGateway          string `json:"default_gateway,omitempty"`
+	DefaultGatewayInterface string `json:"default_gateway_interface,omitempty"`
+	DefaultGatewayIPv6      string `json:"default_gateway_ipv6,omitempty"`
 
 	// Hardware Info
 	CPUModel    string `json:"cpu_model"`    // Intel(R) Xeon(R) CPU...
@@ -36,13 +124,38 @@ type StaticMetrics struct {
 	CPUThreads  int    `json:"cpu_threads"`  // Logical processors
 	TotalMemory uint64 `json:"total_memory"` // Total RAM in bytes
 
+	// DMI/BIOS Identity
+	SystemVendor  string `json:"system_vendor,omitempty"`  // Dell Inc., HPE, QEMU, etc.
+	ProductName   string `json:"product_name,omitempty"`   // PowerEdge R640, etc.
+	ProductSerial string `json:"product_serial,omitempty"` // Chassis serial number (requires root)
+	BIOSVendor    string `json:"bios_vendor,omitempty"`
+	BIOSVersion   string `json:"bios_version,omitempty"`
+
 	// Additional Info
-	Timezone     string `json:"timezone,omitempty"`      // Server timezone
-	Region       string `json:"region,omitempty"`        // Cloud region (if detectable)
-	InstanceType string `json:"instance_type,omitempty"` // EC2 type, etc.
+	Timezone      string `json:"timezone,omitempty"`       // Server timezone
+	CloudProvider string `json:"cloud_provider,omitempty"` // aws, gcp, azure, hetzner, etc. (if detectable)
+	Region        string `json:"region,omitempty"`         // Cloud region (if detectable)
+	InstanceType  string `json:"instance_type,omitempty"`  // EC2 type, etc.
 
 	// Inventory
-	Disks []DiskInventoryMetrics `json:"disks,omitempty"` // Disk/filesystem inventory
+	Disks      []DiskInventoryMetrics    `json:"disks,omitempty"`      // Disk/filesystem inventory
+	Interfaces []NetworkInterfaceMetrics `json:"interfaces,omitempty"` // Network interface inventory
+	Packages   *PackageInventory         `json:"packages,omitempty"`   // Installed packages (opt-in)
+
+	// Kernel parameters
+	Sysctls map[string]string `json:"sysctls,omitempty"` // Selected sysctl values (e.g., vm.swappiness)
+
+	// Changes since the previous refresh, also across restarts
+	Changes []InventoryChange `json:"changes,omitempty"`
+}
+
+// InventoryChange describes one change in the static inventory
+type InventoryChange struct {
+	Field  string `json:"field"`          // kernel_version, total_memory, disk, interface, internal_ip, sysctl, etc.
+	Action string `json:"action"`         // added, removed, changed
+	Item   string `json:"item,omitempty"` // Mount point, interface name or sysctl key for per-item fields
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
 }
 
 // DynamicMetrics contains frequently-changing metrics
@@ -51,11 +164,21 @@ type DynamicMetrics struct {
 	Memory         *MemoryMetrics           `json:"memory,omitempty"`
 	Swap           *SwapMetrics             `json:"swap,omitempty"`
 	DiskSpace      *DiskSpaceMetrics        `json:"disk_space,omitempty"`
+	DiskGrowth     []DiskGrowthMetrics      `json:"disk_growth,omitempty"`
+	Probes         []ProbeMetrics           `json:"probes,omitempty"`
 	DiskIO         *DiskIOMetrics           `json:"disk_io,omitempty"`
 	NetworkPublic  *NetworkAggregateMetrics `json:"network_public,omitempty"`
 	NetworkPrivate *NetworkAggregateMetrics `json:"network_private,omitempty"`
 	NetworkHealth  *NetworkHealthMetrics    `json:"network_health,omitempty"`
+	NeighborTable  *NeighborTableMetrics    `json:"neighbor_table,omitempty"`
+	Gateway        *GatewayMetrics          `json:"gateway,omitempty"`
 	System         *SystemMetrics           `json:"system,omitempty"`
+
+	ManagedProcesses []ManagedProcessMetrics `json:"managed_processes,omitempty"`
+	NetworkMounts    []NetworkMountMetrics   `json:"network_mounts,omitempty"`
+	Directories      []DirectoryMetrics      `json:"directories,omitempty"`
+	Plugins          []PluginMetrics         `json:"plugins,omitempty"`
+	Anomalies        []Anomaly               `json:"anomalies,omitempty"`
 }
 
 // SystemMetrics contains frequently-changing system metrics
@@ -63,6 +186,8 @@ type SystemMetrics struct {
 	Uptime       uint64 `json:"uptime"`        // seconds
 	BootTime     uint64 `json:"boot_time"`     // Unix timestamp
 	ProcessCount uint64 `json:"process_count"` // Number of running processes
+	ProcsRunning uint64 `json:"procs_running"` // Processes in the run queue (runnable)
+	ProcsBlocked uint64 `json:"procs_blocked"` // Processes blocked on I/O
 }
 
 // CPUMetrics contains CPU usage information
@@ -98,6 +223,41 @@ type DiskInventoryMetrics struct {
 	FSType      string `json:"fstype"`       // Filesystem type (e.g., ext4, xfs)
 	Total       uint64 `json:"total"`        // Total capacity in bytes
 	InodesTotal uint64 `json:"inodes_total"` // Total inodes
+
+	// Underlying physical disk (empty for virtual/network filesystems)
+	PhysicalDevice string `json:"physical_device,omitempty"` // Kernel name (e.g., sda, nvme0n1)
+	Model          string `json:"model,omitempty"`           // Disk model
+	Serial         string `json:"serial,omitempty"`          // Disk serial number
+	DiskSize       uint64 `json:"disk_size,omitempty"`       // Physical disk size in bytes
+	Rotational     *bool  `json:"rotational,omitempty"`      // true for HDD, false for SSD/NVMe
+}
+
+// NetworkInterfaceMetrics contains static network interface information
+type NetworkInterfaceMetrics struct {
+	Name      string   `json:"name"`                 // Interface name (e.g., eth0)
+	MAC       string   `json:"mac,omitempty"`        // Hardware address
+	SpeedMbps int      `json:"speed_mbps,omitempty"` // Link speed (0 if unknown or down)
+	Duplex    string   `json:"duplex,omitempty"`     // full, half, unknown
+	MTU       int      `json:"mtu"`                  // Maximum transmission unit
+	Driver    string   `json:"driver,omitempty"`     // Kernel driver (e.g., virtio_net, ixgbe)
+	Up        bool     `json:"up"`                   // Administrative state
+	Addresses []string `json:"addresses,omitempty"`  // Assigned addresses in CIDR notation
+}
+
+// PackageInventory contains the installed package list. Data is only
+// included when the checksum differs from the last inventory sent.
+type PackageInventory struct {
+	Manager  string `json:"manager"`        // dpkg, rpm
+	Count    int    `json:"count"`          // Number of installed packages
+	Checksum string `json:"checksum"`       // SHA-256 of the uncompressed package list
+	Data     string `json:"data,omitempty"` // Base64 gzip-compressed JSON array of PackageInfo
+}
+
+// PackageInfo describes a single installed package
+type PackageInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch,omitempty"`
 }
 
 // DiskSpaceMetrics contains aggregated disk space usage across all partitions
@@ -108,6 +268,75 @@ type DiskSpaceMetrics struct {
 	UsedPercent float64 `json:"used_percent"` // Usage percentage
 }
 
+// DiskGrowthMetrics contains the growth trend of a local filesystem
+type DiskGrowthMetrics struct {
+	Mount              string   `json:"mount"`                      // Mount point
+	Used               uint64   `json:"used"`                       // Used space in bytes
+	Free               uint64   `json:"free"`                       // Space available to users in bytes
+	GrowthBytesPerHour float64  `json:"growth_bytes_per_hour"`      // Linear trend, negative when shrinking
+	HoursUntilFull     *float64 `json:"hours_until_full,omitempty"` // Only set while growing
+	WindowSeconds      int      `json:"window_seconds"`             // Time span the trend was fitted over
+}
+
+// NetworkMountMetrics contains health and usage of an NFS/CIFS mount
+type NetworkMountMetrics struct {
+	Mount       string  `json:"mount"`                  // Mount point
+	Device      string  `json:"device"`                 // Remote source (e.g., server:/export)
+	FSType      string  `json:"fstype"`                 // nfs, nfs4, cifs, etc.
+	Available   bool    `json:"available"`              // statfs answered within the timeout
+	LatencyMs   float64 `json:"latency_ms"`             // statfs round-trip time
+	Total       uint64  `json:"total,omitempty"`        // Total capacity in bytes
+	Used        uint64  `json:"used,omitempty"`         // Used space in bytes
+	Free        uint64  `json:"free,omitempty"`         // Free space in bytes
+	UsedPercent float64 `json:"used_percent,omitempty"` // Usage percentage
+	Error       string  `json:"error,omitempty"`        // Reason when unavailable
+}
+
+// PluginOutput is the JSON document an exec plugin writes to stdout
+type PluginOutput struct {
+	Metrics []PluginMetric `json:"metrics"`
+}
+
+// PluginMetric is a single value reported by an exec plugin
+type PluginMetric struct {
+	Name   string            `json:"name"` // [a-zA-Z_][a-zA-Z0-9_]*
+	Value  float64           `json:"value"`
+	Labels map[string]string `json:"labels,omitempty"` // Label names follow the metric name rules
+}
+
+// PluginMetrics holds the result of the last run of one exec plugin
+type PluginMetrics struct {
+	Name       string         `json:"name"` // Executable file name
+	Metrics    []PluginMetric `json:"metrics,omitempty"`
+	RunAt      time.Time      `json:"run_at"`
+	DurationMs float64        `json:"duration_ms"`
+	Error      string         `json:"error,omitempty"` // Reason if the run failed or metrics were dropped
+}
+
+// Anomaly is a run of consecutive 1-second samples of a metric that deviated
+// strongly from its rolling baseline
+type Anomaly struct {
+	Metric   string    `json:"metric"`   // e.g. "cpu_usage_percent"
+	Start    time.Time `json:"start"`    // First deviating sample
+	End      time.Time `json:"end"`      // Last deviating sample
+	Samples  int       `json:"samples"`  // Number of deviating samples
+	Peak     float64   `json:"peak"`     // Most deviating value
+	Baseline float64   `json:"baseline"` // Rolling mean before the run
+	StdDev   float64   `json:"stddev"`   // Rolling standard deviation before the run
+	Score    float64   `json:"score"`    // Largest deviation in standard deviations
+}
+
+// DirectoryMetrics contains the size of a watched directory tree
+type DirectoryMetrics struct {
+	Path           string    `json:"path"`             // Watched directory
+	Size           uint64    `json:"size"`             // Total size of regular files in bytes
+	Files          uint64    `json:"files"`            // Number of regular files
+	Dirs           uint64    `json:"dirs"`             // Number of directories (including the root)
+	ScannedAt      time.Time `json:"scanned_at"`       // When the scan started
+	ScanDurationMs float64   `json:"scan_duration_ms"` // How long the scan took
+	Error          string    `json:"error,omitempty"`  // Reason if the scan failed
+}
+
 // DiskIOMetrics contains aggregated disk I/O metrics across all devices
 type DiskIOMetrics struct {
 	ReadMBps  float64 `json:"read_mbps"`  // Aggregate read bandwidth in MB/s
@@ -132,26 +361,244 @@ type NetworkHealthMetrics struct {
 	DropsOut  uint64 `json:"drops_out"`  // Total outbound drops
 }
 
+// ManagedProcessMetrics contains the state of a program run by a process manager
+type ManagedProcessMetrics struct {
+	Manager  string `json:"manager"`          // supervisord, pm2
+	Name     string `json:"name"`             // Program name (group:name for supervisord)
+	State    string `json:"state"`            // RUNNING, FATAL, online, errored, etc.
+	PID      int    `json:"pid,omitempty"`    // Process ID if running
+	Restarts uint64 `json:"restarts"`         // Restart count
+	Uptime   uint64 `json:"uptime,omitempty"` // Seconds since last start
+}
+
+// NeighborTableMetrics contains ARP/NDP neighbor table usage
+type NeighborTableMetrics struct {
+	IPv4 *NeighborTableStats `json:"ipv4"`
+	IPv6 *NeighborTableStats `json:"ipv6,omitempty"`
+}
+
+// NeighborTableStats contains usage of a single neighbor table
+type NeighborTableStats struct {
+	Entries      uint64  `json:"entries"`        // Current neighbor entries
+	GCThresh1    uint64  `json:"gc_thresh1"`     // No garbage collection below this
+	GCThresh2    uint64  `json:"gc_thresh2"`     // Soft limit
+	GCThresh3    uint64  `json:"gc_thresh3"`     // Hard limit
+	UsedPercent  float64 `json:"used_percent"`   // Entries relative to gc_thresh3
+	TableFulls   uint64  `json:"table_fulls"`    // Cumulative table overflows
+	ForcedGCRuns uint64  `json:"forced_gc_runs"` // Cumulative forced garbage collections
+}
+
 type AgentStatus struct {
-	Hostname       string    `json:"hostname"`
-	Version        string    `json:"version"`
-	Uptime         uint64    `json:"uptime"`
-	LastCollection time.Time `json:"last_collection"`
-	LastSend       time.Time `json:"last_send"`
-	MetricsCount   uint64    `json:"metrics_count"`
-	ErrorCount     uint64    `json:"error_count"`
-	Status         string    `json:"status"` // "running", "stopped", "error"
+	Hostname       string       `json:"hostname"`
+	Version        string       `json:"version"`
+	Uptime         uint64       `json:"uptime"`
+	LastCollection time.Time    `json:"last_collection"`
+	LastSend       time.Time    `json:"last_send"`
+	MetricsCount   uint64       `json:"metrics_count"`
+	ErrorCount     uint64       `json:"error_count"`
+	Status         string       `json:"status"`                          // "running", "stopped", "error"
+	ReadOnly       bool         `json:"read_only"`                       // true if server commands are refused
+	Spooled        int          `json:"spooled"`                         // Payloads waiting in the offline spool
+	ThrottledUntil *time.Time   `json:"throttled_until,omitempty"`       // Set while the server's Retry-After pauses sends
+	Circuit        string       `json:"circuit,omitempty"`               // Circuit breaker state: "closed", "open" or "half-open"
+	HighResUntil   *time.Time   `json:"high_resolution_until,omitempty"` // Set while high-resolution mode is active
+	Maintenance    *Maintenance `json:"maintenance,omitempty"`           // Set during a maintenance window
+	Alerts         []AlertEvent `json:"alerts,omitempty"`                // Local alert rules currently firing
+
+	ServerCapabilities []string `json:"server_capabilities,omitempty"` // Features the server announced at registration
+
+	LastPayload *PayloadSummary   `json:"last_payload,omitempty"` // Last assembled payload
+	Collectors  []CollectorHealth `json:"collectors,omitempty"`   // Outcome of each collector's last run
+}
+
+// PayloadSummary describes an assembled payload without its metrics
+type PayloadSummary struct {
+	ID          string    `json:"id"`
+	Sequence    uint64    `json:"seq"`
+	Timestamp   time.Time `json:"timestamp"`
+	Static      bool      `json:"static"` // true if static metrics were included
+	Chunks      int       `json:"chunks"`
+	CPUUsage    float64   `json:"cpu_usage"`
+	MemoryUsage float64   `json:"memory_usage"`
+}
+
+// CollectorHealth is the outcome of a collector's last run
+type CollectorHealth struct {
+	Name        string    `json:"name"`
+	OK          bool      `json:"ok"`
+	Error       string    `json:"error,omitempty"` // Last error, if the last run failed
+	LastRun     time.Time `json:"last_run"`
+	LastSuccess time.Time `json:"last_success"` // Zero if the collector never succeeded
+
+	Failures         int        `json:"failures,omitempty"`          // Consecutive failed runs
+	QuarantinedUntil *time.Time `json:"quarantined_until,omitempty"` // Skipped until then after repeated failures
+}
+
+// Heartbeat is a minimal liveness report sent while metrics sends fail or are disabled
+type Heartbeat struct {
+	Hostname  string    `json:"hostname"`
+	Version   string    `json:"version"`
+	Status    string    `json:"status"` // "running"
+	Reason    string    `json:"reason"` // "send_failing" or "send_disabled"
+	Timestamp time.Time `json:"timestamp"`
+	LastSend  time.Time `json:"last_send"` // Last successful metrics send (zero if none)
+}
+
+// Registration announces the agent to the server on startup, before the
+// first payload, so both sides can agree on features and configuration
+type Registration struct {
+	Hostname     string            `json:"hostname"`
+	Version      string            `json:"version"`
+	OS           string            `json:"os"`
+	Arch         string            `json:"arch"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Capabilities []string          `json:"capabilities"` // Optional payload sections and server commands the agent supports
+	Collectors   []string          `json:"collectors"`   // Enabled collectors
+	Timestamp    time.Time         `json:"timestamp"`
+}
+
+// Diagnostic check results
+const (
+	DiagnosticOK   = "ok"
+	DiagnosticWarn = "warn"
+	DiagnosticFail = "fail"
+)
+
+// DiagnosticCheck is the result of a single self-check
+type DiagnosticCheck struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"` // "ok", "warn" or "fail"
+	Detail     string  `json:"detail,omitempty"`
+	Hint       string  `json:"hint,omitempty"` // How to fix a check that did not pass
+	DurationMs float64 `json:"duration_ms"`
 }
 
-// ServerCommand represents a command from server to agent
+// DiagnosticsReport is the result of the agent's self-check suite
+type DiagnosticsReport struct {
+	RequestID string            `json:"request_id,omitempty"` // From the diagnostics command, if given
+	Hostname  string            `json:"hostname"`
+	Version   string            `json:"version"`
+	Timestamp time.Time         `json:"timestamp"`
+	Checks    []DiagnosticCheck `json:"checks"`
+	Agent     *AgentStatus      `json:"agent,omitempty"` // Live status, including collector health
+}
+
+// CollectorBenchmark is the measured cost of one collector over the runs of
+// monify benchmark, averaged per run
+type CollectorBenchmark struct {
+	Name       string  `json:"name"`
+	Static     bool    `json:"static,omitempty"` // Static collectors only run hourly
+	Runs       int     `json:"runs"`
+	Failures   int     `json:"failures,omitempty"`
+	Error      string  `json:"error,omitempty"` // Last error, if a run failed
+	MeanMs     float64 `json:"mean_ms"`
+	P95Ms      float64 `json:"p95_ms"`
+	MaxMs      float64 `json:"max_ms"`
+	CPUMs      float64 `json:"cpu_ms"`      // User and system CPU time
+	AllocBytes uint64  `json:"alloc_bytes"` // Heap bytes allocated
+	Allocs     uint64  `json:"allocs"`      // Heap objects allocated
+}
+
+// BenchmarkReport is the result of monify benchmark
+type BenchmarkReport struct {
+	Iterations        int                  `json:"iterations"`
+	Collectors        []CollectorBenchmark `json:"collectors"`
+	SamplerCPUPercent float64              `json:"sampler_cpu_percent"` // Background sampling between collections
+	RSSBytes          uint64               `json:"rss_bytes"`           // Resident memory after the last run
+	HeapBytes         uint64               `json:"heap_bytes"`
+}
+
+// PortReport is the result of a scan_ports command
+type PortReport struct {
+	RequestID string          `json:"request_id,omitempty"` // From the scan_ports command, if given
+	Timestamp time.Time       `json:"timestamp"`
+	Listening []ListeningPort `json:"listening"`
+	Scan      *PortScan       `json:"scan,omitempty"` // Only when a range was requested
+	Error     string          `json:"error,omitempty"`
+}
+
+// ListeningPort is a listening TCP or bound UDP socket
+type ListeningPort struct {
+	Protocol string `json:"protocol"` // "tcp", "tcp6", "udp", "udp6"
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	PID      int32  `json:"pid,omitempty"`
+	Process  string `json:"process,omitempty"`
+}
+
+// PortScan contains the open ports found by connecting to a local range
+type PortScan struct {
+	Host       string  `json:"host"`
+	From       int     `json:"from"`
+	To         int     `json:"to"`
+	Open       []int   `json:"open"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// ServerCommand represents a command from server to agent. Commands carry an
+// Ed25519 signature over the JSON object exactly as the server sent it, with
+// the signature member removed.
 type ServerCommand struct {
-	Command string         `json:"command"` // "update_config", "refresh", "scan_ports", "restart"
-	Params  map[string]any `json:"params,omitempty"`
+	Command   string         `json:"command"`           // "update_config", "refresh", "scan_ports", "restart"
+	Expires   int64          `json:"expires,omitempty"` // Unix time after which a signed command is refused
+	Host      string         `json:"host,omitempty"`    // Hostname the command is meant for, if bound to one
+	ID        string         `json:"id,omitempty"`      // Unique per command; signed commands run at most once
+	Params    map[string]any `json:"params,omitempty"`
+	Signature string         `json:"signature,omitempty"` // Base64 Ed25519 signature
+
+	// Raw is the command as received, which the signature is verified
+	// against. It is empty for commands the agent created itself.
+	Raw json.RawMessage `json:"-"`
 }
 
+// UnmarshalJSON decodes the command and keeps its raw bytes
+func (c *ServerCommand) UnmarshalJSON(data []byte) error {
+	type plain ServerCommand // Without this method
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*c = ServerCommand(decoded)
+	c.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// Token scopes reported by the server
+const (
+	ScopeMetrics  = "metrics"  // Token may only submit metrics
+	ScopeCommands = "commands" // Token allows the server to send commands
+)
+
 // ServerResponse represents the response from server after sending metrics
 type ServerResponse struct {
 	Status   string          `json:"status"` // "success", "error"
 	Message  string          `json:"message,omitempty"`
+	Scopes   []string        `json:"scopes,omitempty"`   // Scopes granted to the token
 	Commands []ServerCommand `json:"commands,omitempty"` // Commands for agent to execute
+
+	// Registration responses only
+	Config       map[string]any `json:"config,omitempty"`       // Settings to apply before the first payload, as with update_config
+	Capabilities []string       `json:"capabilities,omitempty"` // Features the server supports
+}
+
+// GatewayMetrics contains reachability of the default gateway. An unreachable
+// gateway means the host lost its local network, not that the server is down.
+type GatewayMetrics struct {
+	Gateway   string  `json:"gateway"`
+	Interface string  `json:"interface"`
+	Reachable bool    `json:"reachable"`
+	LatencyMs float64 `json:"latency_ms,omitempty"` // ICMP round trip
+	Method    string  `json:"method"`               // icmp or arp
+}
+
+// ProbeMetrics contains the result of a service check
+type ProbeMetrics struct {
+	Target     string     `json:"target"`                // Configured target URL
+	Protocol   string     `json:"protocol,omitempty"`    // smtp, smtps, imap, imaps
+	Up         bool       `json:"up"`                    // Check completed successfully
+	LatencyMs  float64    `json:"latency_ms,omitempty"`  // Time to complete the check
+	Banner     string     `json:"banner,omitempty"`      // Server greeting
+	TLS        bool       `json:"tls"`                   // Session was encrypted (implicit TLS or STARTTLS)
+	CertExpiry *time.Time `json:"cert_expiry,omitempty"` // Server certificate expiry
+	Error      string     `json:"error,omitempty"`       // Reason when down
 }
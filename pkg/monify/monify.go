@@ -0,0 +1,134 @@
+// Package monify lets Go applications embed the Monify agent and receive
+// every assembled metric payload in-process, in addition to or instead of
+// sending it to the Monify server.
+package monify
+
+import (
+	"context"
+	"sync"
+
+	"github.com/monify-labs/agent/internal/agent"
+	"github.com/monify-labs/agent/internal/config"
+	"github.com/monify-labs/agent/pkg/models"
+)
+
+// ErrAuthFailed is returned by Start when the server permanently rejects the token
+var ErrAuthFailed = agent.ErrAuthFailed
+
+// ErrRestartRequested is returned by Start when the server asked the agent to
+// restart. The agent has stopped; the host application decides whether to
+// create and start a new one.
+var ErrRestartRequested = agent.ErrRestartRequested
+
+// Options configures an embedded agent
+type Options struct {
+	ServerURL string // Defaults to the Monify cloud endpoint
+	Token     string // Required unless DisableSend is set
+	Debug     bool
+
+	// DisableSend delivers payloads only to handlers and channels
+	DisableSend bool
+
+	// HandleSignals lets the agent react to SIGINT/SIGTERM/SIGHUP.
+	// Leave false when the host application manages signals.
+	HandleSignals bool
+
+	// StatusAddress serves the agent status as JSON on a loopback address
+	// (e.g. "127.0.0.1:9123"). Empty disables the status API.
+	StatusAddress string
+
+	// PrometheusAddress serves the collected metrics and agent self-metrics
+	// in Prometheus text format on a loopback address. Empty disables it.
+	PrometheusAddress string
+}
+
+// Agent is an embedded monitoring agent
+type Agent struct {
+	agent *agent.Agent
+
+	mu       sync.Mutex
+	channels []*payloadChannel // Open Payloads channels, closed when Start returns
+}
+
+// payloadChannel is a channel returned by Payloads
+type payloadChannel struct {
+	ch     chan *models.MetricPayload
+	closed bool
+}
+
+// New creates an embedded agent
+func New(opts Options) (*Agent, error) {
+	serverURL := opts.ServerURL
+	if serverURL == "" {
+		serverURL = config.ServerURL
+	}
+
+	a, err := agent.NewAgent(serverURL, opts.Token, opts.Debug)
+	if err != nil {
+		return nil, err
+	}
+	a.SetSendEnabled(!opts.DisableSend)
+	a.SetSignalHandling(opts.HandleSignals)
+	a.SetStatusAddress(opts.StatusAddress)
+	a.SetPrometheusAddress(opts.PrometheusAddress)
+
+	return &Agent{agent: a}, nil
+}
+
+// OnPayload registers a callback invoked with every assembled payload.
+// Callbacks run in the collection loop: keep them fast and do not modify the payload.
+func (a *Agent) OnPayload(fn func(payload *models.MetricPayload)) {
+	a.agent.OnPayload(fn)
+}
+
+// Payloads returns a channel receiving every assembled payload. When the
+// channel buffer is full, payloads are dropped rather than blocking collection.
+// The channel is closed when Start returns, so it can be ranged over; call
+// Payloads again before starting the agent a second time.
+func (a *Agent) Payloads(buffer int) <-chan *models.MetricPayload {
+	pc := &payloadChannel{ch: make(chan *models.MetricPayload, buffer)}
+	a.mu.Lock()
+	a.channels = append(a.channels, pc)
+	a.mu.Unlock()
+
+	a.agent.OnPayload(func(payload *models.MetricPayload) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if pc.closed {
+			return
+		}
+		select {
+		case pc.ch <- payload:
+		default:
+		}
+	})
+	return pc.ch
+}
+
+// Start runs the collection loop until ctx is cancelled or Stop is called.
+// Channels returned by Payloads are closed when it returns.
+func (a *Agent) Start(ctx context.Context) error {
+	defer a.closePayloads()
+	return a.agent.Start(ctx)
+}
+
+// closePayloads closes every open Payloads channel
+func (a *Agent) closePayloads() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, pc := range a.channels {
+		close(pc.ch)
+		pc.closed = true
+	}
+	a.channels = nil
+}
+
+// Stop stops the agent
+func (a *Agent) Stop() error {
+	return a.agent.Stop()
+}
+
+// Status returns the current agent status
+func (a *Agent) Status() *models.AgentStatus {
+	return a.agent.GetStatus()
+}
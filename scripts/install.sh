@@ -174,8 +174,11 @@ After=network-online.target
 Wants=network-online.target
 
 [Service]
-Type=simple
+Type=notify
+NotifyAccess=main
+WatchdogSec=2min
 ExecStart=/usr/local/bin/monify run
+ExecReload=/bin/kill -HUP $MAINPID
 Restart=always
 RestartSec=5
 RestartPreventExitStatus=3
@@ -189,8 +192,14 @@ ProtectSystem=strict
 ProtectHome=yes
 PrivateTmp=yes
 ReadWritePaths=/etc/monify /var/log/monify
+StateDirectory=monify
+StateDirectoryMode=0700
 ProtectKernelTunables=yes
+ProtectKernelModules=yes
 ProtectControlGroups=yes
+RestrictSUIDSGID=yes
+RestrictRealtime=yes
+LockPersonality=yes
 
 # Resource limits
 MemoryMax=64M
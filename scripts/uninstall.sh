@@ -15,6 +15,7 @@ set -e
 INSTALL_DIR="/usr/local/bin"
 CONFIG_DIR="/etc/monify"
 LOG_DIR="/var/log/monify"
+STATE_DIR="/var/lib/monify"
 SERVICE_FILE="/etc/systemd/system/monify.service"
 BINARY_NAME="monify"
 
@@ -131,6 +132,9 @@ cleanup() {
     # Remove any lock files
     rm -f /var/run/monify.lock 2>/dev/null || true
     
+    # Remove offline spool
+    rm -rf "$STATE_DIR" 2>/dev/null || true
+    
     print_success "Cleanup complete"
 }
 
@@ -145,6 +149,7 @@ print_complete() {
     echo "  - Binary: ${INSTALL_DIR}/${BINARY_NAME}"
     echo "  - Config: ${CONFIG_DIR}"
     echo "  - Logs: ${LOG_DIR}"
+    echo "  - State: ${STATE_DIR}"
     echo "  - Service: ${SERVICE_FILE}"
     echo ""
     echo "Thank you for using Monify!"
@@ -176,11 +181,22 @@ confirm_uninstall() {
     fi
 }
 
+# Let the agent remove itself; older binaries without "uninstall --purge"
+# fall back to the steps below
+native_uninstall() {
+    if [ -x "${INSTALL_DIR}/${BINARY_NAME}" ] && "${INSTALL_DIR}/${BINARY_NAME}" uninstall --yes --purge; then
+        print_complete
+        exit 0
+    fi
+}
+
 # Main uninstallation flow
 main() {
     check_root
     confirm_uninstall
     
+    native_uninstall
+    
     stop_service
     remove_service
     remove_binary